@@ -0,0 +1,415 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JSON API page sizes
+const (
+	defaultPageSize = 50  // Items per page when ?limit= is not given
+	maxPageSize     = 200 // Largest ?limit= accepted
+)
+
+// apiRoute is an endpoint of the JSON API. The route table both registers
+// the handlers and generates the OpenAPI document, so the two can't drift
+// apart.
+type apiRoute struct {
+	Method   string
+	Path     string // ServeMux pattern without the method
+	Tag      string // OpenAPI tag the endpoint is grouped under
+	Summary  string
+	Query    []apiParam // Query parameters
+	Body     any        // Zero value of the request body type, nil for none
+	Response any        // Zero value of the success response type
+	Status   int        // Success status, 200 if unset
+	Scopes   []string   // Scopes a bearer token needs
+	Handler  http.HandlerFunc
+}
+
+// apiParam is a query parameter of a JSON API endpoint
+type apiParam struct {
+	Name        string
+	Type        string // "string" (default), "integer" or "boolean"
+	Format      string // "date", "uuid", ...
+	Enum        []string
+	Description string
+}
+
+// Parameters shared by list endpoints
+var pageParams = []apiParam{
+	{Name: "cursor", Description: "next_cursor from the previous page"},
+	{Name: "limit", Type: "integer", Description: "Items per page, at most 200 (default 50)"},
+}
+
+// apiRoutes returns the endpoints of the JSON API
+func apiRoutes(db *pgxpool.Pool, q *database.Queries) []apiRoute {
+	dateRange := []apiParam{
+		{Name: "date_from", Format: "date", Description: "Earliest date, inclusive (YYYY-MM-DD)"},
+		{Name: "date_to", Format: "date", Description: "Latest date, inclusive (YYYY-MM-DD)"},
+	}
+	params := func(lists ...[]apiParam) []apiParam {
+		var all []apiParam
+		for _, l := range lists {
+			all = append(all, l...)
+		}
+		return all
+	}
+
+	return []apiRoute{
+		{
+			Method: "GET", Path: "/api/v1/projects", Tag: "projects",
+			Summary: "List projects",
+			Query: params([]apiParam{
+				{Name: "status", Enum: projectStatuses},
+				{Name: "inspector", Format: "uuid", Description: "Inspector user ID"},
+				{Name: "search", Description: "Text to find in the name, location or description"},
+				{Name: "sort_by", Enum: projectSorts, Description: "Sort key (default date, when the project was created)"},
+				{Name: "sort_order", Enum: []string{"asc", "desc"}, Description: "Sort direction (default desc)"},
+			}, dateRange, pageParams),
+			Response: dto.ListResponse[dto.Project]{},
+			Scopes:   []string{tokens.ReadProjects},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIListProjects(w, r, q)
+			},
+		},
+		{
+			Method: "POST", Path: "/api/v1/projects", Tag: "projects",
+			Summary:  "Create a project",
+			Body:     dto.ProjectRequest{},
+			Response: dto.DataResponse[dto.Project]{},
+			Status:   http.StatusCreated,
+			Scopes:   []string{tokens.WriteProjects},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPICreateProject(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/projects/{id}", Tag: "projects",
+			Summary:  "Get a project",
+			Response: dto.DataResponse[dto.Project]{},
+			Scopes:   []string{tokens.ReadProjects},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIGetProject(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/projects/{id}/photos", Tag: "photos",
+			Summary: "List a project's photos, newest first",
+			Query: params([]apiParam{
+				{Name: "area_type", Enum: photoAreaTypes},
+			}, dateRange, pageParams),
+			Response: dto.ListResponse[dto.Photo]{},
+			Scopes:   []string{tokens.ReadPhotos},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIListPhotos(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/projects/{id}/report", Tag: "reports",
+			Summary:  "Get a project's safety report",
+			Response: dto.DataResponse[dto.SafetyReportData]{},
+			Scopes:   []string{tokens.ReadReports},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIProjectReport(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/photos/{id}", Tag: "photos",
+			Summary:  "Get a photo",
+			Response: dto.DataResponse[dto.Photo]{},
+			Scopes:   []string{tokens.ReadPhotos},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIGetPhoto(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/violations", Tag: "violations",
+			Summary: "List violations, most recently found first",
+			Query: params([]apiParam{
+				{Name: "project_id", Format: "uuid"},
+				{Name: "status", Enum: violationStatuses},
+				{Name: "risk_level", Enum: riskLevels},
+				{Name: "category"},
+			}, dateRange, pageParams),
+			Response: dto.ListResponse[dto.Violation]{},
+			Scopes:   []string{tokens.ReadViolations},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIListViolations(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/violations/{id}", Tag: "violations",
+			Summary:  "Get a violation",
+			Response: dto.DataResponse[dto.Violation]{},
+			Scopes:   []string{tokens.ReadViolations},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIGetViolation(w, r, q)
+			},
+		},
+		{
+			Method: "POST", Path: "/api/v1/violations/{id}/status", Tag: "violations",
+			Summary:  "Validate, dismiss or reopen a violation",
+			Body:     dto.ViolationStatusRequest{},
+			Response: dto.DataResponse[dto.Violation]{},
+			Scopes:   []string{tokens.WriteViolations},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIViolationStatus(w, r, db, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/reports", Tag: "reports",
+			Summary: "List safety reports, most recently updated first",
+			Query: params([]apiParam{
+				{Name: "project_id", Format: "uuid"},
+				{Name: "type", Enum: reportTypes},
+			}, dateRange, pageParams),
+			Response: dto.ListResponse[dto.Report]{},
+			Scopes:   []string{tokens.ReadReports},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIListReports(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/users", Tag: "users",
+			Summary: "List users, newest first",
+			Query: params([]apiParam{
+				{Name: "role", Enum: []string{"admin", "inspector"}},
+				{Name: "active", Type: "boolean"},
+			}, pageParams),
+			Response: dto.ListResponse[dto.User]{},
+			Scopes:   []string{tokens.ReadUsers},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIListUsers(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/users/{id}", Tag: "users",
+			Summary:  "Get a user",
+			Response: dto.DataResponse[dto.User]{},
+			Scopes:   []string{tokens.ReadUsers},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleAPIGetUser(w, r, q)
+			},
+		},
+		{
+			Method: "GET", Path: "/api/v1/sync/pull", Tag: "sync",
+			Summary: "Pull changes since a sync cursor",
+			Query: []apiParam{
+				{Name: "cursor", Description: "cursor from the previous pull, empty for the first"},
+				{Name: "project_id", Format: "uuid", Description: "Only pull changes to this project"},
+			},
+			Response: dto.SyncPullResponse{},
+			Scopes:   []string{tokens.ReadProjects, tokens.ReadPhotos, tokens.ReadViolations},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleSyncPull(w, r, q)
+			},
+		},
+		{
+			Method: "POST", Path: "/api/v1/sync/push", Tag: "sync",
+			Summary:  "Push changes made offline",
+			Body:     dto.SyncPushRequest{},
+			Response: dto.SyncPushResponse{},
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				handleSyncPush(w, r, db, q)
+			},
+		},
+	}
+}
+
+// addAPIRoutes registers the JSON API and its OpenAPI document. Integrations
+// call it with a bearer token limited to the route's scopes; the browser
+// and offline clients use the session.
+func addAPIRoutes(mux *http.ServeMux, db *pgxpool.Pool, q *database.Queries, tm *tokens.Manager) {
+	routes := apiRoutes(db, q)
+	for _, route := range routes {
+		mux.HandleFunc(route.Method+" "+route.Path, requireToken(tm, q, route.Scopes, route.Handler))
+	}
+
+	doc := openAPIDocument(routes)
+	mux.HandleFunc("GET /api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if err := encode(w, http.StatusOK, doc); err != nil {
+			loggerFromRequest(r).Error("failed to write response", "error", err)
+		}
+	})
+
+	// anything else under the API gets a JSON error rather than an HTML page
+	mux.HandleFunc("GET /api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		apiError(w, r, http.StatusNotFound, "not_found", "No such endpoint", "")
+	})
+}
+
+// apiError writes a JSON API error response
+func apiError(w http.ResponseWriter, r *http.Request, status int, code, message, field string) {
+	resp := dto.ErrorResponse{Error: dto.APIError{Code: code, Message: message, Field: field, RequestID: requestIDFromContext(r.Context())}}
+	if err := encode(w, status, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// apiServerError logs an unexpected failure and writes a JSON 500
+func apiServerError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	loggerFromRequest(r).Error(msg, "error", err)
+	apiError(w, r, http.StatusInternalServerError, "internal", http.StatusText(http.StatusInternalServerError), "")
+}
+
+// apiWriteError writes the JSON error for err's kind. Internal errors are
+// logged with msg, like apiServerError.
+func apiWriteError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	var e *apperr.Error
+	kind := apperr.KindOf(err)
+	switch {
+	case kind == apperr.Internal:
+		apiServerError(w, r, msg, err)
+	case errors.As(err, &e):
+		apiError(w, r, kind.Status(), kind.Code(), e.Message, e.Field)
+	default:
+		apiError(w, r, kind.Status(), kind.Code(), apperr.Message(err), "")
+	}
+}
+
+// apiData writes a single resource
+func apiData[T any](w http.ResponseWriter, r *http.Request, status int, v T) {
+	if err := encode(w, status, dto.DataResponse[T]{Data: v}); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// apiList writes a page of resources. items holds up to one more than the
+// page size; the extra item only shows that there is a next page, which
+// starts after the item at the index passed to cursor.
+func apiList[T any](w http.ResponseWriter, r *http.Request, items []T, limit int, cursor func(last int) any) {
+	resp := dto.ListResponse[T]{Data: items}
+	if len(items) > limit {
+		resp.Data = items[:limit]
+		resp.HasMore = true
+		resp.NextCursor = encodeCursor(cursor(limit - 1))
+	}
+	if resp.Data == nil {
+		resp.Data = []T{}
+	}
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// encodeCursor encodes a pagination position for a client, which treats it
+// as opaque
+func encodeCursor(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes a position written by encodeCursor
+func decodeCursor(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errInvalidCursor
+	}
+	return nil
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// timeCursor is the position in a list ordered newest first
+type timeCursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"i"`
+}
+
+// after returns the bounds of the page following the cursor, none for the
+// first page
+func (c timeCursor) after() (pgtype.Timestamptz, pgtype.UUID) {
+	id, err := parseUUID(c.ID)
+	if err != nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}
+	}
+	return pgtype.Timestamptz{Time: c.Time, Valid: true}, id
+}
+
+// pageRequest holds the pagination parameters of a list request
+type pageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// fetchLimit is the number of rows to ask the database for: one more than
+// the page size, to find out whether another page follows
+func (p pageRequest) fetchLimit() int32 {
+	return int32(p.Limit + 1)
+}
+
+// parsePage reads ?limit= and ?cursor=, writing an error response if they
+// are invalid
+func parsePage(w http.ResponseWriter, r *http.Request, cursor any) (pageRequest, bool) {
+	query := r.URL.Query()
+	page := pageRequest{Limit: defaultPageSize, Cursor: query.Get("cursor")}
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "limit must be between 1 and 200", "limit")
+			return page, false
+		}
+		page.Limit = n
+	}
+	if page.Cursor != "" {
+		if err := decodeCursor(page.Cursor, cursor); err != nil {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "Invalid cursor", "cursor")
+			return page, false
+		}
+	}
+	return page, true
+}
+
+// parseDateRange reads ?date_from= and ?date_to= as whole days, returning
+// the start of the first and the end of the last
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, before pgtype.Timestamptz, ok bool) {
+	query := r.URL.Query()
+	if s := query.Get("date_from"); s != "" {
+		t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "date_from must be a date (YYYY-MM-DD)", "date_from")
+			return from, before, false
+		}
+		from = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if s := query.Get("date_to"); s != "" {
+		t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "date_to must be a date (YYYY-MM-DD)", "date_to")
+			return from, before, false
+		}
+		before = pgtype.Timestamptz{Time: t.AddDate(0, 0, 1), Valid: true}
+	}
+	return from, before, true
+}
+
+// parseIDParam reads an optional UUID query parameter, writing an error
+// response if it is malformed
+func parseIDParam(w http.ResponseWriter, r *http.Request, name string) (pgtype.UUID, bool) {
+	var id pgtype.UUID
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return id, true
+	}
+	id, err := parseUUID(s)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", name+" must be a UUID", name)
+		return id, false
+	}
+	return id, true
+}
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// answer returns the rows a query produces for args. Rows are database
+// structs, which are scanned field by field, or single column values.
+type answer func(args []any) ([]any, error)
+
+// fakeDB stands in for Postgres in handler tests. Queries are answered by
+// the name sqlc gives them in their leading comment; a query without an
+// answer returns no rows.
+type fakeDB struct {
+	mu      sync.Mutex
+	answers map[string]answer
+	calls   []string
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{answers: map[string]answer{}}
+}
+
+// on answers the named query with fn
+func (db *fakeDB) on(name string, fn answer) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.answers[name] = fn
+}
+
+// returns answers the named query with rows, whatever its arguments
+func (db *fakeDB) returns(name string, rows ...any) {
+	db.on(name, func([]any) ([]any, error) { return rows, nil })
+}
+
+// called reports whether the named query was run
+func (db *fakeDB) called(name string) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, c := range db.calls {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *fakeDB) run(query string, args []any) (string, []any, error) {
+	name := queryName(query)
+	db.mu.Lock()
+	fn := db.answers[name]
+	db.calls = append(db.calls, name)
+	db.mu.Unlock()
+	if fn == nil {
+		return name, nil, nil
+	}
+	rows, err := fn(args)
+	return name, rows, err
+}
+
+func (db *fakeDB) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	_, rows, err := db.run(query, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", len(rows))), nil
+}
+
+func (db *fakeDB) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	name, rows, err := db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{name: name, rows: rows, i: -1}, nil
+}
+
+func (db *fakeDB) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	name, rows, err := db.run(query, args)
+	switch {
+	case err != nil:
+		return fakeRow{err: err}
+	case len(rows) == 0:
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{name: name, row: rows[0]}
+}
+
+// queryName returns the name sqlc gives a query, such as GetUser
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return query
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+type fakeRow struct {
+	name string
+	row  any
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scanRow(r.name, r.row, dest)
+}
+
+type fakeRows struct {
+	name string
+	rows []any
+	i    int
+	err  error
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return r.err }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.err == nil && r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if err := scanRow(r.name, r.rows[r.i], dest); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return columns(r.rows[r.i]), nil
+}
+
+// databasePkg is where sqlc puts row structs, which are scanned one field
+// per column. Its nullable enums are structs too, but scan as one column.
+var databasePkg = reflect.TypeFor[database.Project]().PkgPath()
+
+// columns returns the column values of row in scan order
+func columns(row any) []any {
+	v := reflect.ValueOf(row)
+	if v.Kind() != reflect.Struct || v.Type().PkgPath() != databasePkg ||
+		reflect.PointerTo(v.Type()).Implements(reflect.TypeFor[sql.Scanner]()) {
+		return []any{row}
+	}
+	var cols []any
+	for i := range v.NumField() {
+		cols = append(cols, columns(v.Field(i).Interface())...)
+	}
+	return cols
+}
+
+func scanRow(name string, row any, dest []any) error {
+	cols := columns(row)
+	if len(cols) != len(dest) {
+		return fmt.Errorf("%s: answer has %d columns, query scans %d", name, len(cols), len(dest))
+	}
+	for i, col := range cols {
+		d := reflect.ValueOf(dest[i]).Elem()
+		c := reflect.ValueOf(col)
+		switch {
+		case !c.IsValid():
+			d.SetZero()
+		case c.Type().AssignableTo(d.Type()):
+			d.Set(c)
+		case c.Type().ConvertibleTo(d.Type()):
+			d.Set(c.Convert(d.Type()))
+		default:
+			return fmt.Errorf("%s: column %d is %T, query scans %s", name, i, col, d.Type())
+		}
+	}
+	return nil
+}
+
+// newPageDB returns a fakeDB that also answers the queries every app page
+// makes for its header
+func newPageDB() *fakeDB {
+	db := newFakeDB()
+	db.returns("CountUnreadNotifications", int64(0))
+	return db
+}
@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/web/templates"
+)
+
+// maxErrorMessage limits how much of a plain text error is kept for the
+// error page
+const maxErrorMessage = 1 << 10
+
+// writeError responds with the status for err's kind and a message that is
+// safe to show. Internal errors are logged with msg, like serverError; errors
+// built with the apperr constructors are never internal, so they can pass
+// an empty msg.
+func writeError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	kind := apperr.KindOf(err)
+	if kind == apperr.Internal {
+		serverError(w, r, msg, err)
+		return
+	}
+	http.Error(w, apperr.Message(err), kind.Status())
+}
+
+// render writes a page, or an error page if the template fails. The page is
+// rendered in full first, so a failure part way through isn't sent.
+func render(w http.ResponseWriter, r *http.Request, t *templates.Template, name string, data any) {
+	var buf bytes.Buffer
+	if err := t.RenderRequest(&buf, name, data, templateRequest(r)); err != nil {
+		serverError(w, r, "failed to render "+name, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		loggerFromRequest(r).Debug("failed to write response", "error", err)
+	}
+}
+
+// errorWriter holds back plain text error responses, such as those from
+// http.Error, so NewErrorPages can replace them
+type errorWriter struct {
+	http.ResponseWriter
+	status  int // Status of the held back error, 0 if none
+	wrote   bool
+	message bytes.Buffer
+}
+
+func (w *errorWriter) WriteHeader(status int) {
+	if w.wrote || w.status != 0 {
+		return
+	}
+	if status >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.status = status
+		return
+	}
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.status != 0 {
+		if room := maxErrorMessage - w.message.Len(); room > 0 {
+			w.message.Write(b[:min(len(b), room)])
+		}
+		return len(b), nil
+	}
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *errorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// NewErrorPages replaces plain text error responses with an HTML error page
+// for browsers or a JSON error for clients that accept JSON. Other clients
+// get the plain text, with the request ID added to server errors.
+func NewErrorPages(t *templates.Template) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ew := &errorWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			if ew.status != 0 {
+				writeErrorPage(w, r, t, ew.status, strings.TrimSpace(ew.message.String()))
+			}
+		})
+	}
+}
+
+// writeErrorPage writes an error in the form the client accepts
+func writeErrorPage(w http.ResponseWriter, r *http.Request, t *templates.Template, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	requestID := requestIDFromContext(r.Context())
+	w.Header().Del("Content-Length")
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, "text/html"):
+		data := dto.ErrorPageData{
+			Status:    status,
+			Title:     http.StatusText(status),
+			Message:   message,
+			RequestID: requestID,
+		}
+		var buf bytes.Buffer
+		if err := t.RenderRequest(&buf, "error", data, templateRequest(r)); err != nil {
+			loggerFromRequest(r).Error("failed to render error page", "error", err)
+			break
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		buf.WriteTo(w)
+		return
+
+	case strings.Contains(accept, "application/json"):
+		resp := dto.ErrorResponse{Error: dto.APIError{Code: errorCode(status), Message: message, RequestID: requestID}}
+		if err := encode(w, status, resp); err != nil {
+			loggerFromRequest(r).Error("failed to write response", "error", err)
+		}
+		return
+	}
+
+	if status >= http.StatusInternalServerError && requestID != "" {
+		message += "\nRequest ID: " + requestID
+	}
+	http.Error(w, message, status)
+}
+
+// errorCode returns the JSON error code for a status
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return apperr.Validation.Code()
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return apperr.Forbidden.Code()
+	case http.StatusNotFound:
+		return apperr.NotFound.Code()
+	case http.StatusConflict:
+		return apperr.Conflict.Code()
+	case http.StatusInternalServerError:
+		return apperr.Internal.Code()
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TestErrorShape checks that handlers' user errors reach JSON clients as an
+// error object with the code for their kind, the message and the request ID
+func TestErrorShape(t *testing.T) {
+	tmpl, err := templates.NewTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionID := pgtype.UUID{Bytes: [16]byte{0xbb, 1}, Valid: true}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		form       url.Values
+		pathValues map[string]string
+		setup      func(db *fakeDB)
+		handler    func(w http.ResponseWriter, r *http.Request, q *database.Queries)
+		status     int
+		want       dto.APIError
+	}{
+		{
+			name:   "locations: nearby search without coordinates",
+			method: "GET",
+			target: "/api/v1/projects/nearby?lat=north&lng=1",
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleProjectsNearby(w, r, q)
+			},
+			status: http.StatusBadRequest,
+			want:   dto.APIError{Code: "invalid_request", Message: "lat and lng must be valid coordinates"},
+		},
+		{
+			name:       "locations: missing project",
+			method:     "POST",
+			target:     "/app/projects/nope/location",
+			pathValues: map[string]string{"id": "00000000-0000-0000-0000-000000000001"},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleUpdateProjectLocation(w, r, q, nil)
+			},
+			status: http.StatusNotFound,
+			want:   dto.APIError{Code: "not_found", Message: "Project not found"},
+		},
+		{
+			name:   "notifications: bad cursor",
+			method: "GET",
+			target: "/app/notifications?cursor=%25%25",
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleNotifications(w, r, tmpl, q)
+			},
+			status: http.StatusBadRequest,
+			want:   dto.APIError{Code: "invalid_request", Message: "Invalid cursor"},
+		},
+		{
+			name:       "tokens: missing token",
+			method:     "POST",
+			target:     "/app/settings/tokens/nope/revoke",
+			pathValues: map[string]string{"id": "nope"},
+			setup: func(db *fakeDB) {
+				db.returns("GetUserByEmail", sessionUserRow(database.UserRoleUser, true))
+			},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleRevokeToken(w, r, q, tokens.New(newFakeDB()))
+			},
+			status: http.StatusNotFound,
+			want:   dto.APIError{Code: "not_found", Message: "Token not found"},
+		},
+		{
+			name:   "tokens: no scopes",
+			method: "POST",
+			target: "/app/settings/tokens",
+			form:   url.Values{"name": {"laptop"}, "kind": {"personal"}, "expires_in": {"30"}},
+			setup: func(db *fakeDB) {
+				db.returns("GetUserByEmail", sessionUserRow(database.UserRoleUser, true))
+			},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleCreateToken(w, r, tmpl, q, tokens.New(newFakeDB()))
+			},
+			status: http.StatusBadRequest,
+			want:   dto.APIError{Code: "invalid_request", Message: "Choose at least one scope"},
+		},
+		{
+			name:       "webhooks: missing subscription",
+			method:     "POST",
+			target:     "/app/settings/webhooks/nope",
+			pathValues: map[string]string{"id": "nope"},
+			setup: func(db *fakeDB) {
+				db.returns("GetUserByEmail", sessionUserRow(database.UserRoleAdmin, true))
+			},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleUpdateWebhook(w, r, q)
+			},
+			status: http.StatusNotFound,
+			want:   dto.APIError{Code: "not_found", Message: "Webhook not found"},
+		},
+		{
+			name:       "webhooks: private endpoint",
+			method:     "POST",
+			target:     "/app/settings/webhooks/" + subscriptionID.String(),
+			form:       url.Values{"name": {"CI"}, "url": {"http://127.0.0.1/hook"}, "event": {"violation.created"}},
+			pathValues: map[string]string{"id": subscriptionID.String()},
+			setup: func(db *fakeDB) {
+				db.returns("GetUserByEmail", sessionUserRow(database.UserRoleAdmin, true))
+				db.returns("GetWebhookSubscription", database.WebhookSubscription{ID: subscriptionID})
+			},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleUpdateWebhook(w, r, q)
+			},
+			status: http.StatusBadRequest,
+			want:   dto.APIError{Code: "invalid_request", Message: "URL must be a public address"},
+		},
+		{
+			name:   "events: missing project",
+			method: "GET",
+			target: "/app/events?project_id=nope",
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleEvents(w, r, q, nil)
+			},
+			status: http.StatusNotFound,
+			want:   dto.APIError{Code: "not_found", Message: "Project not found"},
+		},
+		{
+			name:       "photos: image missing from the store",
+			method:     "GET",
+			target:     "/app/photos/1/image",
+			pathValues: map[string]string{"id": "00000000-0000-0000-0000-000000000002"},
+			setup: func(db *fakeDB) {
+				db.returns("GetPhoto", database.Photo{StorageKey: "photos/missing.jpg", ContentType: "image/jpeg"})
+			},
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleServePhoto(w, r, store, q)
+			},
+			status: http.StatusNotFound,
+			want:   dto.APIError{Code: "not_found", Message: "Photo not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newPageDB()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+			q := database.New(db)
+			handler := RequestID(NewErrorPages(tmpl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, value := range tt.pathValues {
+					r.SetPathValue(name, value)
+				}
+				tt.handler(w, r, q)
+			})))
+
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.form.Encode()))
+			if tt.form != nil {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			r.Header.Set("Accept", "application/json")
+			r.Header.Set(requestIDHeader, "req-42")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var got dto.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode %q: %v", w.Body, err)
+			}
+			want := tt.want
+			want.RequestID = "req-42"
+			if got.Error != want {
+				t.Errorf("error = %+v, want %+v", got.Error, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/events"
+)
+
+// Event stream settings
+const (
+	eventsHeartbeat = 25 * time.Second // Comment sent on idle streams so proxies don't close them
+	eventsRetry     = 5 * time.Second  // Delay browsers wait before reconnecting
+)
+
+// handleEvents streams project events as Server-Sent Events: analysis
+// progress, new violations and status changes, and activity feed entries.
+// With a project_id it streams that project, otherwise every project the
+// user can see.
+func handleEvents(w http.ResponseWriter, r *http.Request, q *database.Queries, br *events.Broker) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	var projectIDs []string
+	if projectID := r.URL.Query().Get("project_id"); projectID != "" {
+		if _, err := getProjectById(ctx, q, projectID); err != nil {
+			writeError(w, r, "failed to load project", err)
+			return
+		}
+		if !canUserViewProject(user.ID, projectID) {
+			writeError(w, r, "", apperr.ForbiddenError("You don't have access to this project"))
+			return
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	sub := br.Subscribe(projectIDs...)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// the stream outlives any server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		loggerFromRequest(r).Error("event stream not supported", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				// the browser reconnects and the page reloads what it missed
+				return
+			}
+			if !canUserViewProject(user.ID, event.ProjectID) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				loggerFromRequest(r).Error("failed to encode project event", "kind", event.Kind, "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
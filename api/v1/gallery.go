@@ -0,0 +1,556 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/geo"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	photosPerPage    = 24  // Photos on one gallery page
+	recentPhotos     = 6   // Photos previewed on the project page
+	maxPhotoBatch    = 20  // Photos accepted in one upload
+	maxCaptionLength = 500 // Longest photo caption, in bytes
+
+	// farFromSiteKm is how far from the project's coordinates a photo can
+	// be taken before it is flagged; it allows for large sites and GPS drift
+	farFromSiteKm = 1.0
+)
+
+// photoAreaTypes are the work areas a photo can be filed under
+var photoAreaTypes = []string{"general", "excavation", "electrical", "roofing", "mechanical", "concrete", "demolition", "other"}
+
+// toPhoto converts a photo row into its page representation
+func toPhoto(p database.Photo, violationIDs []pgtype.UUID) dto.Photo {
+	photo := dto.Photo{
+		ID:             p.ID.String(),
+		ProjectID:      p.ProjectID.String(),
+		URL:            photoURL(p.ID),
+		ThumbnailURL:   thumbnailURL(p.ID),
+		Filename:       p.Filename,
+		UploadedAt:     p.CreatedAt.Time,
+		UploadedBy:     p.UploadedBy.String(),
+		UploadedByName: p.UploadedByName,
+		Caption:        p.Caption,
+		AreaType:       p.AreaType,
+		Purpose:        string(p.Purpose),
+		Analyzed:       p.AnalyzedAt.Valid,
+		TakenAt:        p.TakenAt.Time,
+		Latitude:       p.Latitude.Float64,
+		Longitude:      p.Longitude.Float64,
+		HasLocation:    p.Latitude.Valid && p.Longitude.Valid,
+		Camera:         cameraName(p.CameraMake, p.CameraModel),
+		DuplicateOfURL: photoURL(p.DuplicateOf),
+		ViolationIDs:   make([]string, 0, len(violationIDs)),
+		UpdatedAt:      p.UpdatedAt.Time,
+	}
+	if p.DuplicateOf.Valid {
+		photo.DuplicateOf = p.DuplicateOf.String()
+	}
+	for _, id := range violationIDs {
+		photo.ViolationIDs = append(photo.ViolationIDs, id.String())
+	}
+	return photo
+}
+
+// cameraName joins an EXIF make and model, which often repeats the make
+func cameraName(manufacturer, model string) string {
+	if manufacturer == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(manufacturer)) {
+		return model
+	}
+	if model == "" {
+		return manufacturer
+	}
+	return manufacturer + " " + model
+}
+
+// checkPhotoLocations flags photos whose GPS position is far from the
+// project site. Photos without a location, or projects without coordinates,
+// are left unflagged.
+func checkPhotoLocations(photos []dto.Photo, project dto.Project) {
+	if !project.HasCoordinates {
+		return
+	}
+	for i := range photos {
+		p := &photos[i]
+		if !p.HasLocation {
+			continue
+		}
+		p.DistanceFromSite = geo.Distance(project.Latitude, project.Longitude, p.Latitude, p.Longitude)
+		p.FarFromSite = p.DistanceFromSite > farFromSiteKm
+	}
+}
+
+// getProjectPhotos returns a page of a project's photos with the regions
+// drawn on them
+func getProjectPhotos(ctx context.Context, q *database.Queries, params database.ListPhotosByProjectParams) ([]dto.Photo, error) {
+	rows, err := q.ListPhotosByProject(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	photos := make([]dto.Photo, 0, len(rows))
+	ids := make([]pgtype.UUID, 0, len(rows))
+	index := make(map[string]int, len(rows))
+	for i, row := range rows {
+		photos = append(photos, toPhoto(row.Photo, row.ViolationIds))
+		ids = append(ids, row.Photo.ID)
+		index[row.Photo.ID.String()] = i
+	}
+	if len(ids) == 0 {
+		return photos, nil
+	}
+
+	regions, err := q.ListRegionsByPhotos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range regions {
+		i := index[row.ViolationRegion.PhotoID.String()]
+		photos[i].Regions = append(photos[i].Regions, toRegion(row.ViolationRegion, string(row.RiskLevel), row.ViolationDescription))
+	}
+	return photos, nil
+}
+
+// parsePhotoFilter reads the gallery filters from the query string, dropping
+// values that don't parse so a bad link still shows photos
+func parsePhotoFilter(r *http.Request, params *database.ListPhotosByProjectParams) dto.PhotoFilter {
+	query := r.URL.Query()
+	var filter dto.PhotoFilter
+
+	if area := query.Get("area_type"); slices.Contains(photoAreaTypes, area) {
+		filter.AreaType = area
+		params.AreaType = pgtype.Text{String: area, Valid: true}
+	}
+	if from, err := time.ParseInLocation(time.DateOnly, query.Get("from"), time.Local); err == nil {
+		filter.DateFrom = query.Get("from")
+		params.CreatedFrom = pgtype.Timestamptz{Time: from, Valid: true}
+	}
+	if to, err := time.ParseInLocation(time.DateOnly, query.Get("to"), time.Local); err == nil {
+		filter.DateTo = query.Get("to")
+		params.CreatedBefore = pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true}
+	}
+	return filter
+}
+
+func handleProjectPhotos(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, an *analysis.Analyzer) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	projectID := r.PathValue("id")
+	project, err := getProjectById(ctx, q, projectID)
+	if err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	id, _ := parseUUID(project.ID)
+
+	params := database.ListPhotosByProjectParams{ProjectID: id, PageLimit: photosPerPage}
+	filter := parsePhotoFilter(r, &params)
+
+	total, err := q.CountPhotosByProject(ctx, database.CountPhotosByProjectParams{
+		ProjectID:     params.ProjectID,
+		AreaType:      params.AreaType,
+		CreatedFrom:   params.CreatedFrom,
+		CreatedBefore: params.CreatedBefore,
+	})
+	if err != nil {
+		serverError(w, r, "failed to count photos", err)
+		return
+	}
+	totalPages := max(1, (int(total)+photosPerPage-1)/photosPerPage)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = min(max(page, 1), totalPages)
+	params.PageOffset = int32((page - 1) * photosPerPage)
+
+	photos, err := getProjectPhotos(ctx, q, params)
+	if err != nil {
+		serverError(w, r, "failed to load photos", err)
+		return
+	}
+	checkPhotoLocations(photos, *project)
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.ProjectPhotosData{
+		AppData: dto.AppData{
+			PageTitle:      project.Name + " Photos",
+			CurrentPage:    "projects",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Project: *project,
+		Photos:  photos,
+		Filter:  filter,
+		Pagination: dto.PhotoPagination{
+			CurrentPage:  page,
+			TotalPages:   totalPages,
+			TotalItems:   int(total),
+			ItemsPerPage: photosPerPage,
+			HasPrev:      page > 1,
+			HasNext:      page < totalPages,
+		},
+		AreaTypes:  photoAreaTypes,
+		CanEdit:    canUserEditProject(user.ID, projectID),
+		CanAnalyze: an.Available(),
+	}
+	data.DuplicatesUploaded, _ = strconv.Atoi(r.URL.Query().Get("duplicates"))
+	render(w, r, t, "project-photos", data)
+}
+
+func handleAddPhotosPage(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, an *analysis.Analyzer) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	projectID := r.PathValue("id")
+	project, err := getProjectById(ctx, q, projectID)
+	if err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	if !canUserEditProject(user.ID, projectID) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to add photos to this project"))
+		return
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.AddPhotosData{
+		AppData: dto.AppData{
+			PageTitle:      "Add Photos",
+			CurrentPage:    "projects",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Project:     *project,
+		AreaTypes:   photoAreaTypes,
+		MaxFiles:    maxPhotoBatch,
+		MaxFileSize: maxPhotoSize >> 20,
+		WillAnalyze: an.Available(),
+		ChunkSize:   uploadChunkSize,
+	}
+	render(w, r, t, "add-photos", data)
+}
+
+// handleUploadPhotos stores a batch of inspection photos and queues them for
+// hazard detection. The batch is all or nothing: one bad file rejects the
+// upload so the inspector can fix it and resubmit.
+func handleUploadPhotos(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries, store blob.Store, an *analysis.Analyzer) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	logger := loggerFromRequest(r)
+
+	projectID := r.PathValue("id")
+	if _, err := getProjectById(ctx, q, projectID); err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	// getProjectById has already checked the id parses
+	id, _ := parseUUID(projectID)
+	if !canUserEditProject(user.ID, projectID) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to add photos to this project"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoBatch*maxPhotoSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Upload is too large or malformed"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["photos"]
+	if len(files) == 0 {
+		writeError(w, r, "", apperr.ValidationError("photos", "Choose at least one photo"))
+		return
+	}
+	if len(files) > maxPhotoBatch {
+		writeError(w, r, "", apperr.ValidationError("photos", fmt.Sprintf("Upload at most %d photos at a time", maxPhotoBatch)))
+		return
+	}
+	areaType := r.FormValue("area_type")
+	if areaType == "" {
+		areaType = "general"
+	}
+	if !slices.Contains(photoAreaTypes, areaType) {
+		writeError(w, r, "", apperr.ValidationError("area_type", "Unknown area type"))
+		return
+	}
+	caption := strings.TrimSpace(r.FormValue("caption"))
+	if len(caption) > maxCaptionLength {
+		writeError(w, r, "", apperr.ValidationError("caption", fmt.Sprintf("Caption must be at most %d characters", maxCaptionLength)))
+		return
+	}
+
+	// photos are read and stored one at a time to bound memory; the blobs
+	// are removed again unless every row commits
+	var params []database.CreatePhotoParams
+	var stored []storedPhoto
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		for _, s := range stored {
+			if err := deletePhoto(context.WithoutCancel(ctx), store, s); err != nil {
+				logger.Warn("failed to delete orphaned photo", "key", s.Key, "error", err)
+			}
+		}
+	}()
+
+	for _, header := range files {
+		f, err := header.Open()
+		if err != nil {
+			serverError(w, r, "failed to open upload", err)
+			return
+		}
+		photo, err := readPhotoFile(f, header.Filename)
+		f.Close()
+		if err != nil {
+			if isPhotoError(err) {
+				writeError(w, r, "", apperr.ValidationError("photos", fmt.Sprintf("%s: %s", header.Filename, err)))
+				return
+			}
+			serverError(w, r, "failed to read photo", err)
+			return
+		}
+
+		s, err := putPhoto(ctx, store, id, photo)
+		if err != nil {
+			serverError(w, r, "failed to store photo", err)
+			return
+		}
+		stored = append(stored, s)
+
+		p := photoParams(id, photo, s, database.PhotoPurposeInspection, user)
+		p.Caption = caption
+		p.AreaType = areaType
+		params = append(params, p)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		serverError(w, r, "failed to begin transaction", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	batch, err := recordPhotos(ctx, qtx, id, params, user)
+	if err != nil {
+		serverError(w, r, "failed to record photos", err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		serverError(w, r, "failed to commit upload", err)
+		return
+	}
+	committed = true
+
+	enqueuePhotos(ctx, logger, an, batch.ToAnalyze)
+
+	redirect := "/app/projects/" + projectID + "/photos"
+	if d := batch.Duplicates(); d > 0 {
+		redirect += "?duplicates=" + strconv.Itoa(d)
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// photoBatch describes photos recorded by recordPhotos
+type photoBatch struct {
+	Created   []pgtype.UUID
+	ToAnalyze []pgtype.UUID // Created photos that weren't flagged as duplicates
+}
+
+// Duplicates returns how many photos in the batch were flagged as duplicates
+func (b photoBatch) Duplicates() int {
+	return len(b.Created) - len(b.ToAnalyze)
+}
+
+// recordPhotos creates the rows for inspection photos already in the blob
+// store, flagging near-duplicates and recording the upload on the project
+// timeline. It runs inside the caller's transaction.
+func recordPhotos(ctx context.Context, qtx *database.Queries, projectID pgtype.UUID, params []database.CreatePhotoParams, user dto.User) (photoBatch, error) {
+	var batch photoBatch
+
+	// near-duplicates of earlier photos, including ones earlier in this
+	// batch, are flagged and kept out of analysis
+	hashRows, err := qtx.ListPhotoHashesByProject(ctx, projectID)
+	if err != nil {
+		return batch, fmt.Errorf("load photo hashes: %w", err)
+	}
+	originals := make([]photoHash, 0, len(hashRows)+len(params))
+	for _, h := range hashRows {
+		originals = append(originals, photoHash{ID: h.ID, Hash: uint64(h.PerceptualHash)})
+	}
+
+	for _, p := range params {
+		hash := uint64(p.PerceptualHash.Int64)
+		if match, ok := findDuplicate(hash, originals); ok {
+			p.DuplicateOf = match
+		}
+		photo, err := qtx.CreatePhoto(ctx, p)
+		if err != nil {
+			return batch, fmt.Errorf("create photo: %w", err)
+		}
+		batch.Created = append(batch.Created, photo.ID)
+		if !photo.DuplicateOf.Valid {
+			originals = append(originals, photoHash{ID: photo.ID, Hash: hash})
+			batch.ToAnalyze = append(batch.ToAnalyze, photo.ID)
+		}
+	}
+
+	description := "Photo uploaded by"
+	if len(batch.Created) > 1 {
+		description = fmt.Sprintf("%d photos uploaded by", len(batch.Created))
+	}
+	ids := make([]string, 0, len(batch.Created))
+	for _, c := range batch.Created {
+		ids = append(ids, c.String())
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"photo_ids":  ids,
+		"area_type":  params[0].AreaType,
+		"duplicates": batch.Duplicates(),
+	})
+	if err != nil {
+		return batch, fmt.Errorf("encode timeline metadata: %w", err)
+	}
+	if _, err := qtx.CreateTimelineEvent(ctx, database.CreateTimelineEventParams{
+		ProjectID:   projectID,
+		Type:        "photo_uploaded",
+		Description: description,
+		UserID:      userUUID(user),
+		UserName:    user.Name,
+		Metadata:    metadata,
+	}); err != nil {
+		return batch, fmt.Errorf("record timeline event: %w", err)
+	}
+	if err := qtx.TouchProject(ctx, projectID); err != nil {
+		return batch, fmt.Errorf("touch project: %w", err)
+	}
+	// the first geotagged photos place a project that has no coordinates yet
+	if site, ok := photoLocationsMedian(params); ok {
+		lat, lon := coordinates(site)
+		if err := qtx.SetProjectCoordinatesFromPhotos(ctx, database.SetProjectCoordinatesFromPhotosParams{
+			ID:        projectID,
+			Latitude:  lat,
+			Longitude: lon,
+		}); err != nil {
+			return batch, fmt.Errorf("update project coordinates: %w", err)
+		}
+	}
+	return batch, nil
+}
+
+// enqueuePhotos queues new photos for background analysis
+func enqueuePhotos(ctx context.Context, logger *slog.Logger, an *analysis.Analyzer, ids []pgtype.UUID) {
+	if !an.Available() {
+		return
+	}
+	for _, id := range ids {
+		if !an.Enqueue(ctx, id) {
+			logger.Warn("analysis queue full, photo will be analyzed later", "photo_id", id.String())
+		}
+	}
+}
+
+// handleUpdatePhotoCaption replaces a photo's caption; an empty caption
+// clears it
+func handleUpdatePhotoCaption(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	user := getCurrentUser()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to edit photos on this project"))
+		return
+	}
+
+	req, err := decode[dto.CaptionRequest](r)
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Invalid request body"))
+		return
+	}
+	caption := strings.TrimSpace(req.Caption)
+	if len(caption) > maxCaptionLength {
+		writeError(w, r, "", apperr.ValidationError("caption", fmt.Sprintf("Caption must be at most %d characters", maxCaptionLength)))
+		return
+	}
+
+	updated, err := q.UpdatePhotoCaption(r.Context(), database.UpdatePhotoCaptionParams{
+		ID:      photo.ID,
+		Caption: caption,
+	})
+	if err != nil {
+		serverError(w, r, "failed to update caption", err)
+		return
+	}
+
+	resp := toPhoto(updated, nil)
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// handleClearPhotoDuplicate lets an inspector overrule a duplicate flag; the
+// photo is then analyzed like a new upload
+func handleClearPhotoDuplicate(w http.ResponseWriter, r *http.Request, q *database.Queries, an *analysis.Analyzer) {
+	user := getCurrentUser()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to edit photos on this project"))
+		return
+	}
+
+	if photo.DuplicateOf.Valid {
+		if _, err := q.ClearPhotoDuplicate(r.Context(), photo.ID); err != nil {
+			serverError(w, r, "failed to clear duplicate flag", err)
+			return
+		}
+		if an.Available() && !an.Enqueue(r.Context(), photo.ID) {
+			loggerFromRequest(r).Warn("analysis queue full, photo will be analyzed later", "photo_id", photo.ID.String())
+		}
+	}
+	http.Redirect(w, r, "/app/projects/"+photo.ProjectID.String()+"/photos#photo-"+photo.ID.String(), http.StatusSeeOther)
+}
@@ -1,20 +1,40 @@
 package v1
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"strings"
 
 	"net/http"
 	"time"
 
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/detector"
 	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/events"
+	"github.com/dukerupert/ironman/internal/geo"
+	"github.com/dukerupert/ironman/internal/metrics"
+	"github.com/dukerupert/ironman/internal/migrate"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/internal/uploads"
+	"github.com/dukerupert/ironman/internal/webhooks"
 	"github.com/dukerupert/ironman/web/static"
 	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func addRoutes(mux *http.ServeMux, t *templates.Template) {
+func addRoutes(mux *http.ServeMux, t *templates.Template, db *pgxpool.Pool, store blob.Store, det detector.Detector, an *analysis.Analyzer, gc geo.Geocoder, up *uploads.Manager, tm *tokens.Manager, wh *webhooks.Dispatcher, br *events.Broker, mg *migrate.Migrator, reqs *metrics.Requests) {
+	q := database.New(db)
+
 	// Create a FileServer handler for the embedded "static" directory
-	staticSubFS, err := fs.Sub(static.StaticFS, "public/static")
+	staticSubFS, err := fs.Sub(static.StaticFS, ".")
 	if err != nil {
 		panic(fmt.Sprintf("failed to create static sub-filesystem: %v", err))
 	}
@@ -22,22 +42,33 @@ func addRoutes(mux *http.ServeMux, t *templates.Template) {
 	// Handle all requests to /static/ using the embedded FileServer
 	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticSubFS))))
 	
+	// Health and metrics, for the load balancer and monitoring
+	mux.HandleFunc("GET /healthz", handleHealthz)
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(w, r, db, q, mg)
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		handleMetrics(w, r, db, q, an, reqs)
+	})
+
 	// Landing page
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "landing", nil)
+		render(w, r, t, "landing", nil)
 	})
 	
 	// Auth routes
 	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "login", nil)
+		render(w, r, t, "login", nil)
 	})
 	
 	mux.HandleFunc("GET /signup", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "signup", nil)
+		render(w, r, t, "signup", nil)
 	})
 	
 	mux.HandleFunc("GET /forgot-password", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "forgot-password", nil)
+		render(w, r, t, "forgot-password", nil)
 	})
 	
 	mux.HandleFunc("POST /forgot-password", func(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +81,7 @@ func addRoutes(mux *http.ServeMux, t *templates.Template) {
 		}{
 			Token: r.URL.Query().Get("token"),
 		}
-		t.Render(w, "reset-password", data)
+		render(w, r, t, "reset-password", data)
 	})
 	
 	mux.HandleFunc("POST /reset-password", func(w http.ResponseWriter, r *http.Request) {
@@ -59,15 +90,202 @@ func addRoutes(mux *http.ServeMux, t *templates.Template) {
 	
 	// App routes (require authentication in production)
 	mux.HandleFunc("GET /app/dashboard", func(w http.ResponseWriter, r *http.Request) {
-		handleDashboard(w, r, t)
+		handleDashboard(w, r, t, q)
 	})
 	
 	mux.HandleFunc("GET /app/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
-		handleProjectDetail(w, r, t)
+		handleProjectDetail(w, r, t, q)
+	})
+
+	mux.HandleFunc("GET /app/events", func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(w, r, q, br)
+	})
+
+	mux.HandleFunc("GET /app/projects/nearby", func(w http.ResponseWriter, r *http.Request) {
+		handleProjectsNearby(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/projects/{id}/location", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateProjectLocation(w, r, q, gc)
+	})
+
+	mux.HandleFunc("GET /app/map", func(w http.ResponseWriter, r *http.Request) {
+		handleMap(w, r, t, q)
+	})
+
+	mux.HandleFunc("POST /app/projects/{id}/violations/bulk", func(w http.ResponseWriter, r *http.Request) {
+		handleBulkViolations(w, r, db, q)
 	})
 	
+	mux.HandleFunc("GET /app/projects/{projectId}/violations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleViolationDetail(w, r, t, q, det)
+	})
+
+	mux.HandleFunc("POST /app/projects/{projectId}/violations/{id}/comments", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateViolationComment(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/projects/{projectId}/violations/{id}/assignment", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateViolationAssignment(w, r, db, q)
+	})
+
+	mux.HandleFunc("POST /app/projects/{projectId}/violations/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+		handleViolationStatus(w, r, db, q)
+	})
+
+	mux.HandleFunc("POST /app/projects/{projectId}/violations/{id}/resolve", func(w http.ResponseWriter, r *http.Request) {
+		handleResolveViolation(w, r, db, q, store, det)
+	})
+
+	mux.HandleFunc("GET /app/projects/{id}/report", func(w http.ResponseWriter, r *http.Request) {
+		handleSafetyReport(w, r, t, q)
+	})
+
+	mux.HandleFunc("GET /app/projects/{id}/photos", func(w http.ResponseWriter, r *http.Request) {
+		handleProjectPhotos(w, r, t, q, an)
+	})
+
+	mux.HandleFunc("POST /app/projects/{id}/photos", func(w http.ResponseWriter, r *http.Request) {
+		handleUploadPhotos(w, r, db, q, store, an)
+	})
+
+	mux.HandleFunc("POST /app/projects/{id}/uploads", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateUpload(w, r, q, up)
+	})
+
+	// tus clients probe the creation URL for the protocol's capabilities
+	mux.HandleFunc("OPTIONS /app/projects/{id}/uploads", handleUploadOptions)
+	mux.HandleFunc("OPTIONS /app/uploads", handleUploadOptions)
+
+	mux.HandleFunc("HEAD /app/uploads/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleUploadStatus(w, r, up)
+	})
+
+	mux.HandleFunc("PATCH /app/uploads/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleUploadChunk(w, r, db, q, store, up, an)
+	})
+
+	mux.HandleFunc("DELETE /app/uploads/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleTerminateUpload(w, r, up)
+	})
+
+	mux.HandleFunc("GET /app/projects/{id}/add-photos", func(w http.ResponseWriter, r *http.Request) {
+		handleAddPhotosPage(w, r, t, q, an)
+	})
+
+	mux.HandleFunc("GET /app/photos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleServePhoto(w, r, store, q)
+	})
+
+	mux.HandleFunc("GET /app/photos/{id}/thumbnail", func(w http.ResponseWriter, r *http.Request) {
+		handleServeThumbnail(w, r, store, q)
+	})
+
+	mux.HandleFunc("POST /app/photos/{id}/caption", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdatePhotoCaption(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/photos/{id}/not-duplicate", func(w http.ResponseWriter, r *http.Request) {
+		handleClearPhotoDuplicate(w, r, q, an)
+	})
+
+	mux.HandleFunc("GET /app/photos/{id}/annotated", func(w http.ResponseWriter, r *http.Request) {
+		handleAnnotatedPhoto(w, r, store, q)
+	})
+
+	mux.HandleFunc("POST /app/photos/{id}/analyze", func(w http.ResponseWriter, r *http.Request) {
+		handleAnalyzePhoto(w, r, an, q)
+	})
+
+	mux.HandleFunc("GET /app/photos/{id}/regions", func(w http.ResponseWriter, r *http.Request) {
+		handleListPhotoRegions(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/photos/{id}/regions", func(w http.ResponseWriter, r *http.Request) {
+		handleCreatePhotoRegion(w, r, q)
+	})
+
+	mux.HandleFunc("DELETE /app/photos/{id}/regions/{regionId}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeletePhotoRegion(w, r, q)
+	})
+
+	// API tokens
+	mux.HandleFunc("GET /app/settings", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/app/settings/tokens", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("GET /app/settings/tokens", func(w http.ResponseWriter, r *http.Request) {
+		handleTokens(w, r, t, q, tm)
+	})
+
+	mux.HandleFunc("POST /app/settings/tokens", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateToken(w, r, t, q, tm)
+	})
+
+	mux.HandleFunc("POST /app/settings/tokens/{id}/revoke", func(w http.ResponseWriter, r *http.Request) {
+		handleRevokeToken(w, r, q, tm)
+	})
+
+	// Notifications
+	mux.HandleFunc("GET /app/notifications", func(w http.ResponseWriter, r *http.Request) {
+		handleNotifications(w, r, t, q)
+	})
+
+	mux.HandleFunc("GET /app/notifications/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleOpenNotification(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/notifications/{id}/read", func(w http.ResponseWriter, r *http.Request) {
+		handleMarkNotificationRead(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/notifications/read-all", func(w http.ResponseWriter, r *http.Request) {
+		handleMarkAllNotificationsRead(w, r, q)
+	})
+
+	// Notification settings
+	mux.HandleFunc("GET /app/settings/notifications", func(w http.ResponseWriter, r *http.Request) {
+		handleNotificationSettings(w, r, t, q)
+	})
+
+	mux.HandleFunc("POST /app/settings/notifications", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateNotificationSettings(w, r, q)
+	})
+
+	// Webhooks
+	mux.HandleFunc("GET /app/settings/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		handleWebhooks(w, r, t, q)
+	})
+
+	mux.HandleFunc("POST /app/settings/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateWebhook(w, r, q, wh)
+	})
+
+	mux.HandleFunc("GET /app/settings/webhooks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleWebhookDetail(w, r, t, q)
+	})
+
+	mux.HandleFunc("POST /app/settings/webhooks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateWebhook(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/settings/webhooks/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteWebhook(w, r, q)
+	})
+
+	mux.HandleFunc("POST /app/settings/webhooks/{id}/ping", func(w http.ResponseWriter, r *http.Request) {
+		handlePingWebhook(w, r, q, wh)
+	})
+
+	mux.HandleFunc("POST /app/settings/webhooks/{id}/deliveries/{deliveryId}/redeliver", func(w http.ResponseWriter, r *http.Request) {
+		handleRedeliverWebhook(w, r, q, wh)
+	})
+
+	// JSON API for integrations and offline clients
+	addAPIRoutes(mux, db, q, tm)
+
 	mux.HandleFunc("GET /app/upload", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "upload", nil)
+		render(w, r, t, "upload", nil)
 	})
 	
 	mux.HandleFunc("POST /app/upload", func(w http.ResponseWriter, r *http.Request) {
@@ -76,32 +294,88 @@ func addRoutes(mux *http.ServeMux, t *templates.Template) {
 	
 	// Hello world example
 	mux.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
-		t.Render(w, "hello", "World")
+		render(w, r, t, "hello", "World")
 	})
 }
 
-func handleDashboard(w http.ResponseWriter, r *http.Request, t *templates.Template) {
+func handleDashboard(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
 	user := getCurrentUser()
 
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+	stats, err := getDashboardStats(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load dashboard stats", err)
+		return
+	}
+	projects, err := getDetailedProjects(ctx, q, 5)
+	if err != nil {
+		serverError(w, r, "failed to load projects", err)
+		return
+	}
+	criticalViolations, err := getCriticalViolations(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load critical violations", err)
+		return
+	}
+
 	data := dto.DashboardData{
 		AppData: dto.AppData{
 			PageTitle:      "Dashboard",
 			CurrentPage:    "dashboard",
 			User:           user,
-			RecentProjects: getRecentProjects(user.ID),
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
 		},
-		Stats:              getDashboardStats(user.ID),
-		RecentProjects:     getDetailedProjects(user.ID, 5),
-		CriticalViolations: getCriticalViolations(user.ID),
+		Stats:              stats,
+		RecentProjects:     projects,
+		CriticalViolations: criticalViolations,
 	}
-	t.Render(w, "dashboard", data)
+	render(w, r, t, "dashboard", data)
 }
 
-func handleProjectDetail(w http.ResponseWriter, r *http.Request, t *templates.Template) {
+func handleProjectDetail(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
 	projectID := r.PathValue("id")
-	project, err := getProjectById(projectID)
+	project, err := getProjectById(ctx, q, projectID)
 	if err != nil {
-		http.Error(w, "Project not found", http.StatusNotFound)
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+
+	violations, err := getViolationsByProject(ctx, q, projectID)
+	if err != nil {
+		serverError(w, r, "failed to load violations", err)
+		return
+	}
+	timeline, err := getProjectTimeline(ctx, q, projectID)
+	if err != nil {
+		serverError(w, r, "failed to load timeline", err)
+		return
+	}
+	id, _ := parseUUID(project.ID)
+	photos, err := getProjectPhotos(ctx, q, database.ListPhotosByProjectParams{ProjectID: id, PageLimit: recentPhotos})
+	if err != nil {
+		serverError(w, r, "failed to load photos", err)
+		return
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, getCurrentUser())
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
 		return
 	}
 
@@ -110,16 +384,26 @@ func handleProjectDetail(w http.ResponseWriter, r *http.Request, t *templates.Te
 			PageTitle:      project.Name,
 			CurrentPage:    "projects",
 			User:           getCurrentUser(),
-			RecentProjects: getRecentProjects(getCurrentUser().ID),
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
 		},
-		Project:     *project,
-		Violations:  getViolationsByProject(projectID),
-		Timeline:    getProjectTimeline(projectID),
-		CanEdit:     canUserEditProject(getCurrentUser().ID, projectID),
-		CanDelete:   canUserDeleteProject(getCurrentUser().ID, projectID),
+		Project:       *project,
+		Violations:    violations,
+		Photos:        photos,
+		Timeline:      timeline,
+		CanEdit:       canUserEditProject(getCurrentUser().ID, projectID),
+		CanDelete:     canUserDeleteProject(getCurrentUser().ID, projectID),
+		GeocodeFailed: r.URL.Query().Get("geocode") == "not_found",
 	}
 
-	t.Render(w, "project-detail", data)
+	render(w, r, t, "project-detail", data)
+}
+
+// serverError logs err with the request-scoped logger and responds with a
+// generic 500 so internal details never reach the client
+func serverError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	loggerFromRequest(r).Error(msg, "error", err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
 // getCurrentUser returns the current authenticated user
@@ -135,322 +419,339 @@ func getCurrentUser() dto.User {
 	}
 }
 
+// getSessionUser returns the users row of the signed-in user, for records
+// that must name a real user, such as the owner of an API token. A session
+// without an active row is refused rather than recorded with no owner.
+func getSessionUser(ctx context.Context, q *database.Queries) (dto.User, error) {
+	u, err := q.GetUserByEmail(ctx, getCurrentUser().Email)
+	if isNotFound(err) {
+		return dto.User{}, &apperr.Error{Kind: apperr.Forbidden, Message: "Your account has no user record", Err: err}
+	}
+	if err != nil {
+		return dto.User{}, err
+	}
+	if !u.IsActive {
+		return dto.User{}, apperr.ForbiddenError("Your account is deactivated")
+	}
+	return toUser(u), nil
+}
+
 // getRecentProjects returns recent projects for sidebar navigation
-func getRecentProjects(userID string) []dto.RecentProject {
-	return []dto.RecentProject{
-		{
-			ID:            "proj_001",
-			Name:          "Downtown Office Complex",
-			InitialLetter: "D",
-			Status:        "active",
-		},
-		{
-			ID:            "proj_002",
-			Name:          "Riverside Apartments",
-			InitialLetter: "R",
-			Status:        "active",
-		},
-		{
-			ID:            "proj_003",
-			Name:          "Metro Shopping Center",
-			InitialLetter: "M",
-			Status:        "completed",
-		},
-		{
-			ID:            "proj_004",
-			Name:          "Industrial Warehouse",
-			InitialLetter: "I",
-			Status:        "active",
-		},
+func getRecentProjects(ctx context.Context, q *database.Queries) ([]dto.RecentProject, error) {
+	rows, err := q.ListRecentProjects(ctx, 5)
+	if err != nil {
+		return nil, err
 	}
+
+	projects := make([]dto.RecentProject, 0, len(rows))
+	for _, row := range rows {
+		status := "active"
+		if row.Project.Status == database.ProjectStatusCompleted {
+			status = "completed"
+		}
+		projects = append(projects, dto.RecentProject{
+			ID:            row.Project.ID.String(),
+			Name:          row.Project.Name,
+			InitialLetter: initialLetter(row.Project.Name),
+			Status:        status,
+		})
+	}
+	return projects, nil
 }
 
 // getDashboardStats returns dashboard statistics
-func getDashboardStats(userID string) dto.DashboardStats {
-	return dto.DashboardStats{
-		TotalInspections: 147,
-		ViolationsFound:  23,
-		ComplianceRate:   94.3,
-		ActiveProjects:   8,
+func getDashboardStats(ctx context.Context, q *database.Queries) (dto.DashboardStats, error) {
+	row, err := q.GetDashboardStats(ctx)
+	if err != nil {
+		return dto.DashboardStats{}, err
 	}
+	return dto.DashboardStats{
+		TotalInspections: int(row.TotalInspections),
+		ViolationsFound:  int(row.ViolationsFound),
+		ComplianceRate:   row.ComplianceRate,
+		ActiveProjects:   int(row.ActiveProjects),
+	}, nil
 }
 
 // getDetailedProjects returns full project details for dashboard
-func getDetailedProjects(userID string, limit int) []dto.Project {
-	projects := []dto.Project{
-		{
-			ID:                   "proj_001",
-			Name:                 "Downtown Office Complex",
-			Description:          "15-story office building construction",
-			Status:               "in-progress",
-			Location:             "425 Market St, San Francisco, CA",
-			CreatedAt:            time.Now().AddDate(0, -2, -15),
-			LastUpdated:          time.Now().AddDate(0, 0, -2),
-			LastUpdatedFormatted: "2 days ago",
-			ViolationCount:       3,
-			ComplianceScore:      91.2,
-			Inspector:            "John Doe",
-			InspectorID:          "user_123",
-			PhotoCount:           24,
-			ReportGenerated:      false,
-		},
-		{
-			ID:                   "proj_002",
-			Name:                 "Riverside Apartments",
-			Description:          "120-unit residential complex",
-			Status:               "needs-review",
-			Location:             "1200 River Rd, Portland, OR",
-			CreatedAt:            time.Now().AddDate(0, -1, -20),
-			LastUpdated:          time.Now().AddDate(0, 0, -5),
-			LastUpdatedFormatted: "5 days ago",
-			ViolationCount:       7,
-			ComplianceScore:      85.6,
-			Inspector:            "Sarah Wilson",
-			InspectorID:          "user_456",
-			PhotoCount:           18,
-			ReportGenerated:      true,
-		},
-		{
-			ID:                   "proj_003",
-			Name:                 "Metro Shopping Center",
-			Description:          "250,000 sq ft retail complex",
-			Status:               "completed",
-			Location:             "3400 Metro Blvd, Seattle, WA",
-			CreatedAt:            time.Now().AddDate(0, -3, -10),
-			LastUpdated:          time.Now().AddDate(0, 0, -1),
-			LastUpdatedFormatted: "1 day ago",
-			ViolationCount:       2,
-			ComplianceScore:      96.8,
-			Inspector:            "Mike Johnson",
-			InspectorID:          "user_789",
-			PhotoCount:           32,
-			ReportGenerated:      true,
-		},
-		{
-			ID:                   "proj_004",
-			Name:                 "Industrial Warehouse",
-			Description:          "500,000 sq ft distribution center",
-			Status:               "in-progress",
-			Location:             "5500 Industrial Way, Phoenix, AZ",
-			CreatedAt:            time.Now().AddDate(0, -1, -5),
-			LastUpdated:          time.Now().AddDate(0, 0, -3),
-			LastUpdatedFormatted: "3 days ago",
-			ViolationCount:       5,
-			ComplianceScore:      88.4,
-			Inspector:            "Lisa Chen",
-			InspectorID:          "user_101",
-			PhotoCount:           15,
-			ReportGenerated:      false,
-		},
-		{
-			ID:                   "proj_005",
-			Name:                 "Hospital Expansion",
-			Description:          "New emergency wing construction",
-			Status:               "in-progress",
-			Location:             "1000 Medical Center Dr, Denver, CO",
-			CreatedAt:            time.Now().AddDate(0, 0, -30),
-			LastUpdated:          time.Now().AddDate(0, 0, -7),
-			LastUpdatedFormatted: "1 week ago",
-			ViolationCount:       1,
-			ComplianceScore:      98.2,
-			Inspector:            "John Doe",
-			InspectorID:          "user_123",
-			PhotoCount:           8,
-			ReportGenerated:      false,
-		},
+func getDetailedProjects(ctx context.Context, q *database.Queries, limit int) ([]dto.Project, error) {
+	rows, err := q.ListRecentProjects(ctx, int32(limit))
+	if err != nil {
+		return nil, err
 	}
 
-	// Return only the requested number of projects
-	if limit > 0 && limit < len(projects) {
-		return projects[:limit]
+	projects := make([]dto.Project, 0, len(rows))
+	for _, row := range rows {
+		projects = append(projects, toProject(database.GetProjectRow(row)))
 	}
-	return projects
+	return projects, nil
 }
 
 // getProjectTimeline returns activity timeline for a project
-func getProjectTimeline(projectID string) []dto.TimelineEvent {
-	return []dto.TimelineEvent{
-		{
-			ID:          "timeline_001",
-			ProjectID:   projectID,
-			Type:        "created",
-			Description: "Project created by",
-			UserID:      "user_123",
-			UserName:    "John Doe",
-			Timestamp:   time.Now().AddDate(0, -2, -15),
-			Metadata:    map[string]interface{}{},
-		},
-		{
-			ID:          "timeline_002",
-			ProjectID:   projectID,
-			Type:        "photo_uploaded",
-			Description: "Uploaded 8 photos by",
-			UserID:      "user_123",
-			UserName:    "John Doe",
-			Timestamp:   time.Now().AddDate(0, -2, -14),
-			Metadata:    map[string]interface{}{"photo_count": 8},
-		},
-		{
-			ID:          "timeline_003",
-			ProjectID:   projectID,
-			Type:        "violation_found",
-			Description: "Safety violation detected by AI -",
-			UserID:      "system",
-			UserName:    "SafeSite AI",
-			Timestamp:   time.Now().AddDate(0, -2, -13),
-			Metadata:    map[string]interface{}{"violation_id": "viol_001"},
-		},
-		{
-			ID:          "timeline_004",
-			ProjectID:   projectID,
-			Type:        "photo_uploaded",
-			Description: "Uploaded 12 additional photos by",
-			UserID:      "user_456",
-			UserName:    "Sarah Wilson",
-			Timestamp:   time.Now().AddDate(0, -1, -20),
-			Metadata:    map[string]interface{}{"photo_count": 12},
-		},
-		{
-			ID:          "timeline_005",
-			ProjectID:   projectID,
-			Type:        "violation_found",
-			Description: "Critical safety violation identified by",
-			UserID:      "user_456",
-			UserName:    "Sarah Wilson",
-			Timestamp:   time.Now().AddDate(0, 0, -5),
-			Metadata:    map[string]interface{}{"violation_id": "viol_002"},
-		},
-		{
-			ID:          "timeline_006",
-			ProjectID:   projectID,
-			Type:        "violation_resolved",
-			Description: "Safety violation marked as resolved by",
-			UserID:      "user_789",
-			UserName:    "Mike Johnson",
-			Timestamp:   time.Now().AddDate(0, 0, -2),
-			Metadata:    map[string]interface{}{"violation_id": "viol_003"},
-		},
-		{
-			ID:          "timeline_007",
-			ProjectID:   projectID,
-			Type:        "report_generated",
-			Description: "Safety inspection report generated by",
-			UserID:      "user_123",
-			UserName:    "John Doe",
-			Timestamp:   time.Now().AddDate(0, 0, -1),
-			Metadata:    map[string]interface{}{"report_id": "report_001"},
-		},
+func getProjectTimeline(ctx context.Context, q *database.Queries, projectID string) ([]dto.TimelineEvent, error) {
+	id, err := parseUUID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := q.ListTimelineEventsByProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]dto.TimelineEvent, 0, len(events))
+	for _, event := range events {
+		timeline = append(timeline, toTimelineEvent(event))
 	}
+	return timeline, nil
 }
 
 // getCriticalViolations returns high-priority violations needing attention
-func getCriticalViolations(userID string) []dto.Violation {
-	return []dto.Violation{
-		{
-			ID:           "viol_001",
-			ProjectID:    "proj_002",
-			ProjectName:  "Riverside Apartments",
-			Description:  "Workers not wearing hard hats in active construction zone",
-			Regulation:   "1926.95",
-			RiskLevel:    "critical",
-			Category:     "PPE",
-			Location:     "Building A, 3rd Floor",
-			PhotoURL:     "/photos/viol_001.jpg",
-			Status:       "open",
-			FoundAt:      time.Now().AddDate(0, 0, -5),
-			ResolvedAt:   nil,
-			Notes:        "Multiple workers observed without proper head protection during concrete pour",
-			AIConfidence: 0.94,
-		},
-		{
-			ID:           "viol_002",
-			ProjectID:    "proj_001",
-			ProjectName:  "Downtown Office Complex",
-			Description:  "Unsecured scaffolding exceeding height limits",
-			Regulation:   "1926.451",
-			RiskLevel:    "high",
-			Category:     "Fall Protection",
-			Location:     "East Side Exterior",
-			PhotoURL:     "/photos/viol_002.jpg",
-			Status:       "open",
-			FoundAt:      time.Now().AddDate(0, 0, -2),
-			ResolvedAt:   nil,
-			Notes:        "Scaffolding platform at 15ft height without proper guardrails",
-			AIConfidence: 0.87,
-		},
-		{
-			ID:           "viol_003",
-			ProjectID:    "proj_004",
-			ProjectName:  "Industrial Warehouse",
-			Description:  "Electrical panel left open and unlocked",
-			Regulation:   "1926.416",
-			RiskLevel:    "high",
-			Category:     "Electrical",
-			Location:     "Main Electrical Room",
-			PhotoURL:     "/photos/viol_003.jpg",
-			Status:       "open",
-			FoundAt:      time.Now().AddDate(0, 0, -3),
-			ResolvedAt:   nil,
-			Notes:        "480V panel accessible to unauthorized personnel",
-			AIConfidence: 0.91,
-		},
-		{
-			ID:           "viol_004",
-			ProjectID:    "proj_002",
-			ProjectName:  "Riverside Apartments",
-			Description:  "Improper ladder placement and angle",
-			Regulation:   "1926.1053",
-			RiskLevel:    "critical",
-			Category:     "Fall Protection",
-			Location:     "Building B, Stairwell",
-			PhotoURL:     "/photos/viol_004.jpg",
-			Status:       "open",
-			FoundAt:      time.Now().AddDate(0, 0, -1),
-			ResolvedAt:   nil,
-			Notes:        "Extension ladder at unsafe angle (>75 degrees) with no spotter",
-			AIConfidence: 0.89,
-		},
-		{
-			ID:           "viol_005",
-			ProjectID:    "proj_001",
-			ProjectName:  "Downtown Office Complex",
-			Description:  "Missing safety signage in excavation area",
-			Regulation:   "1926.651",
-			RiskLevel:    "high",
-			Category:     "Excavation",
-			Location:     "North Parking Area",
-			PhotoURL:     "/photos/viol_005.jpg",
-			Status:       "open",
-			FoundAt:      time.Now().AddDate(0, 0, -4),
-			ResolvedAt:   nil,
-			Notes:        "8ft deep excavation without proper warning signs or barriers",
-			AIConfidence: 0.93,
-		},
+func getCriticalViolations(ctx context.Context, q *database.Queries) ([]dto.Violation, error) {
+	rows, err := q.ListCriticalViolations(ctx, 10)
+	if err != nil {
+		return nil, err
 	}
+
+	violations := make([]dto.Violation, 0, len(rows))
+	for _, row := range rows {
+		violations = append(violations, toViolation(row.Violation, row.ProjectName))
+	}
+	return violations, nil
 }
 
-// getProjectById returns a single project by ID
-func getProjectById(projectID string) (*dto.Project, error) {
-	projects := getDetailedProjects("", 0) // Get all projects
-	for _, project := range projects {
-		if project.ID == projectID {
-			return &project, nil
-		}
+// getProjectById returns a single project by ID, or a not found error
+// users can be shown
+func getProjectById(ctx context.Context, q *database.Queries, projectID string) (*dto.Project, error) {
+	id, err := parseUUID(projectID)
+	if err != nil {
+		return nil, &apperr.Error{Kind: apperr.NotFound, Message: "Project not found", Err: err}
+	}
+	row, err := q.GetProject(ctx, id)
+	if isNotFound(err) {
+		return nil, &apperr.Error{Kind: apperr.NotFound, Message: "Project not found", Err: err}
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("project not found")
+	project := toProject(row)
+	return &project, nil
 }
 
 // getViolationsByProject returns all violations for a specific project
-func getViolationsByProject(projectID string) []dto.Violation {
-	allViolations := getCriticalViolations("") // Get all violations
-	var projectViolations []dto.Violation
+func getViolationsByProject(ctx context.Context, q *database.Queries, projectID string) ([]dto.Violation, error) {
+	id, err := parseUUID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListViolationsByProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]dto.Violation, 0, len(rows))
+	for _, row := range rows {
+		violations = append(violations, toViolation(row.Violation, row.ProjectName))
+	}
+	return violations, nil
+}
+
+// toProject converts a project row into its page representation
+func toProject(row database.GetProjectRow) dto.Project {
+	p := row.Project
+	return dto.Project{
+		ID:                   p.ID.String(),
+		Name:                 p.Name,
+		Description:          p.Description,
+		Status:               string(p.Status),
+		Location:             p.Location,
+		CreatedAt:            p.CreatedAt.Time,
+		LastUpdated:          p.UpdatedAt.Time,
+		LastUpdatedFormatted: timeAgo(p.UpdatedAt.Time),
+		ViolationCount:       int(row.ViolationCount),
+		ComplianceScore:      p.ComplianceScore,
+		Inspector:            row.InspectorName,
+		InspectorID:          p.InspectorID.String(),
+		PhotoCount:           int(row.PhotoCount),
+		Latitude:             p.Latitude.Float64,
+		Longitude:            p.Longitude.Float64,
+		HasCoordinates:       p.Latitude.Valid && p.Longitude.Valid,
+		CoordinatesSource:    string(p.CoordinatesSource.CoordinatesSource),
+	}
+}
+
+// toViolation converts a violation row into its page representation
+func toViolation(v database.Violation, projectName string) dto.Violation {
+	violation := dto.Violation{
+		ID:            v.ID.String(),
+		ProjectID:     v.ProjectID.String(),
+		ProjectName:   projectName,
+		Description:   v.Description,
+		Regulation:    v.Regulation,
+		RiskLevel:     string(v.RiskLevel),
+		Category:      v.Category,
+		Location:      v.Location,
+		PhotoURL:      photoURL(v.PhotoID),
+		Status:        string(v.Status),
+		FoundAt:       v.FoundAt.Time,
+		Notes:         v.Notes,
+		AIConfidence:  v.AiConfidence,
+		AssigneeID:    v.AssignedUserID.String(),
+		Subcontractor: v.AssignedSubcontractor,
+		UpdatedAt:     v.UpdatedAt.Time,
+	}
+	if v.ResolvedAt.Valid {
+		resolvedAt := v.ResolvedAt.Time
+		violation.ResolvedAt = &resolvedAt
+	}
+	if v.PhotoID.Valid {
+		violation.PhotoID = v.PhotoID.String()
+	}
+	if v.DueDate.Valid {
+		dueDate := v.DueDate.Time
+		violation.DueDate = &dueDate
+	}
+	return violation
+}
+
+// toUser converts a users row into its page representation
+func toUser(u database.User) dto.User {
+	name := strings.TrimSpace(u.FirstName.String + " " + u.LastName.String)
+	if name == "" {
+		name = u.Email
+	}
+	initials := initialLetter(u.FirstName.String) + initialLetter(u.LastName.String)
+	if initials == "" {
+		initials = initialLetter(name)
+	}
+	role := "inspector"
+	if u.Role == database.UserRoleAdmin {
+		role = "admin"
+	}
+	return dto.User{
+		ID:       u.ID.String(),
+		Name:     name,
+		Email:    u.Email,
+		Initials: initials,
+		Role:     role,
+		Avatar:   u.ProfilePictureUrl.String,
+	}
+}
+
+// toResolution converts a violation resolution row into its page representation
+func toResolution(r database.ViolationResolution) *dto.Resolution {
+	return &dto.Resolution{
+		ID:                     r.ID.String(),
+		PhotoURL:               photoURL(r.PhotoID),
+		Note:                   r.Note,
+		Verification:           string(r.Verification),
+		VerificationConfidence: r.VerificationConfidence.Float64,
+		VerificationDetail:     r.VerificationDetail,
+		Accepted:               r.Accepted,
+		UserName:               r.UserName,
+		CreatedAt:              r.CreatedAt.Time,
+	}
+}
+
+// toRegion converts a violation region row into its page representation
+func toRegion(r database.ViolationRegion, riskLevel, label string) dto.Region {
+	region := dto.Region{
+		ID:          r.ID.String(),
+		ViolationID: r.ViolationID.String(),
+		PhotoID:     r.PhotoID.String(),
+		Shape:       string(r.Shape),
+		X:           r.X,
+		Y:           r.Y,
+		Width:       r.Width,
+		Height:      r.Height,
+		Source:      string(r.Source),
+		Confidence:  r.Confidence.Float64,
+		RiskLevel:   riskLevel,
+		Label:       label,
+		CreatedBy:   r.UserName,
+	}
+	if len(r.Points) > 0 {
+		// points were validated on the way in; a decode failure leaves the
+		// bounding box, which is still a useful outline
+		_ = json.Unmarshal(r.Points, &region.Points)
+	}
+	return region
+}
+
+// toTimelineEvent converts a timeline row into its page representation
+func toTimelineEvent(e database.TimelineEvent) dto.TimelineEvent {
+	metadata := map[string]interface{}{}
+	if len(e.Metadata) > 0 {
+		// Metadata is written by this package; a decode failure only loses detail
+		_ = json.Unmarshal(e.Metadata, &metadata)
+	}
+	return dto.TimelineEvent{
+		ID:          e.ID.String(),
+		ProjectID:   e.ProjectID.String(),
+		Type:        e.Type,
+		Description: e.Description,
+		UserID:      e.UserID.String(),
+		UserName:    e.UserName,
+		Timestamp:   e.CreatedAt.Time,
+		Metadata:    metadata,
+	}
+}
+
+// parseUUID parses an ID taken from a path or request body
+func parseUUID(s string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	if err := id.Scan(s); err != nil {
+		return id, errInvalidID
+	}
+	return id, nil
+}
+
+// userUUID returns the database ID for a user, or NULL when the user is not
+// backed by a users row
+func userUUID(user dto.User) pgtype.UUID {
+	id, _ := parseUUID(user.ID)
+	return id
+}
+
+var errInvalidID = errors.New("invalid id")
+
+// isNotFound reports whether err means the requested row does not exist
+func isNotFound(err error) bool {
+	return apperr.KindOf(err) == apperr.NotFound || errors.Is(err, errInvalidID)
+}
 
-	for _, violation := range allViolations {
-		if violation.ProjectID == projectID {
-			projectViolations = append(projectViolations, violation)
+// initialLetter returns the upper-cased first letter of name
+func initialLetter(name string) string {
+	for _, r := range name {
+		return strings.ToUpper(string(r))
+	}
+	return ""
+}
+
+// timeAgo formats t relative to now, e.g. "2 days ago"
+func timeAgo(t time.Time) string {
+	d := time.Since(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
 		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	case d < 7*24*time.Hour:
+		return plural(int(d.Hours()/24), "day")
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/(24*7)), "week")
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/(24*30)), "month")
+	default:
+		return plural(int(d.Hours()/(24*365)), "year")
 	}
-	return projectViolations
 }
 
 // getUserRole returns the role for permission checks
@@ -459,6 +760,12 @@ func getUserRole(userID string) string {
 	return user.Role
 }
 
+// canUserViewProject checks if user can see a project and its activity
+func canUserViewProject(userID, projectID string) bool {
+	role := getUserRole(userID)
+	return role == "admin" || role == "inspector" || role == "viewer"
+}
+
 // canUserEditProject checks if user can edit a project
 func canUserEditProject(userID, projectID string) bool {
 	role := getUserRole(userID)
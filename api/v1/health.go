@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/metrics"
+	"github.com/dukerupert/ironman/internal/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readyTimeout limits the readiness checks, so a probe gets an answer
+// before the load balancer gives up on it
+const readyTimeout = 2 * time.Second
+
+// handleHealthz reports that the process is up and serving requests
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz reports whether the instance can serve traffic: the database
+// answers, its schema is up to date and the job queues can be read. Failed
+// checks are logged; the response only names them.
+func handleReadyz(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries, mg *migrate.Migrator) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	logger := loggerFromRequest(r)
+
+	resp := dto.Readiness{Status: "ok", Checks: make(map[string]string)}
+	check := func(name string, err error) {
+		if err != nil {
+			logger.Warn("readiness check failed", "check", name, "error", err)
+			resp.Status = "unavailable"
+			resp.Checks[name] = "failed"
+			return
+		}
+		resp.Checks[name] = "ok"
+	}
+
+	check("database", db.Ping(ctx))
+
+	pending, err := mg.Pending(ctx)
+	if err == nil && pending > 0 {
+		err = fmt.Errorf("%d migrations pending", pending)
+	}
+	check("migrations", err)
+
+	_, err = q.GetQueueDepths(ctx)
+	check("queue", err)
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	if err := encode(w, status, resp); err != nil {
+		logger.Error("failed to write response", "error", err)
+	}
+}
+
+// handleMetrics writes request metrics, connection pool statistics and job
+// queue depths for Prometheus to scrape
+func handleMetrics(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries, an *analysis.Analyzer, reqs *metrics.Requests) {
+	logger := loggerFromRequest(r)
+
+	// the queues are counted first, so a failure doesn't cut the response
+	// short
+	depths, err := q.GetQueueDepths(r.Context())
+	if err != nil {
+		logger.Error("failed to count queued jobs", "error", err)
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	mw := metrics.NewWriter(w)
+	reqs.Write(mw)
+
+	stat := db.Stat()
+	mw.Gauge("ironman_db_pool_max_connections", "Largest number of connections the pool opens.", float64(stat.MaxConns()))
+	mw.GaugeVec("ironman_db_pool_connections", "Open database connections, by state.", "state", map[string]float64{
+		"acquired":     float64(stat.AcquiredConns()),
+		"idle":         float64(stat.IdleConns()),
+		"constructing": float64(stat.ConstructingConns()),
+	})
+	mw.Counter("ironman_db_pool_acquires_total", "Connections acquired from the pool.", float64(stat.AcquireCount()))
+	mw.Counter("ironman_db_pool_empty_acquires_total", "Acquires that waited because no connection was idle.", float64(stat.EmptyAcquireCount()))
+	mw.Counter("ironman_db_pool_canceled_acquires_total", "Acquires cancelled before a connection was available.", float64(stat.CanceledAcquireCount()))
+	mw.Counter("ironman_db_pool_acquire_seconds_total", "Time spent acquiring connections.", stat.AcquireDuration().Seconds())
+
+	queues := map[string]float64{"analysis": float64(an.Queued())}
+	if err == nil {
+		queues["webhook_deliveries"] = float64(depths.WebhookDeliveries)
+		queues["notification_emails"] = float64(depths.NotificationEmails)
+		queues["unanalyzed_photos"] = float64(depths.UnanalyzedPhotos)
+	}
+	mw.GaugeVec("ironman_job_queue_depth", "Jobs waiting for the background workers, by queue.", "queue", queues)
+
+	if err := mw.Err(); err != nil {
+		logger.Error("failed to write metrics", "error", err)
+	}
+}
@@ -1,29 +1,47 @@
 package v1
 
 import (
-	"log"
+	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/events"
+	"github.com/dukerupert/ironman/internal/geo"
+	"github.com/dukerupert/ironman/internal/metrics"
+	"github.com/dukerupert/ironman/internal/migrate"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/internal/uploads"
+	"github.com/dukerupert/ironman/internal/webhooks"
 	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewServer(logger *slog.Logger) http.Handler {
+func NewServer(logger *slog.Logger, db *pgxpool.Pool, store blob.Store, det detector.Detector, an *analysis.Analyzer, gc geo.Geocoder, up *uploads.Manager, tm *tokens.Manager, wh *webhooks.Dispatcher, br *events.Broker, mg *migrate.Migrator, production bool) (http.Handler, error) {
 	mux := http.NewServeMux()
 	tr, err := templates.NewTemplate()
 	if err != nil {
-		log.Fatal("failed to create template", err)
+		return nil, fmt.Errorf("load templates: %w", err)
 	}
-	addRoutes(mux, tr)
-	handler := addGlobalMiddleware(mux, logger)
-	return handler
+	reqs := metrics.NewRequests()
+	addRoutes(mux, tr, db, store, det, an, gc, up, tm, wh, br, mg, reqs)
+	handler := addGlobalMiddleware(mux, tr, logger, reqs, production)
+	return handler, nil
 }
 
-func addGlobalMiddleware(mux *http.ServeMux, logger *slog.Logger) http.Handler {
+func addGlobalMiddleware(mux *http.ServeMux, t *templates.Template, logger *slog.Logger, reqs *metrics.Requests, production bool) http.Handler {
 	var handler http.Handler
-	handler = mux
+	handler = NewMetrics(reqs)(traceRoute(Recover(mux)))
+
+	// NewCSRF and NewSecurityHeaders add to the request's context, so must
+	// wrap the route middleware. Error pages wrap NewCSRF to cover its
+	// rejections.
+	handler = NewErrorPages(t)(NewCSRF(production)(handler))
+	handler = NewSecurityHeaders(production)(handler)
 
 	loggingMiddleware := NewLogging(logger)
-	handler = RequestID(loggingMiddleware(handler))
+	handler = RequestID(Tracing(loggingMiddleware(handler)))
 	return handler
 }
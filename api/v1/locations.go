@@ -0,0 +1,263 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/geo"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxLocationLength     = 500 // Longest project address, in bytes
+	defaultNearbyRadiusKm = 25  // Search radius when none is given
+	maxNearbyRadiusKm     = 500 // Largest search radius accepted
+	maxNearbyResults      = 50  // Projects returned by one nearby search
+	maxHotspots           = 500 // Violation clusters drawn on the map
+)
+
+// coordinates converts a point to nullable column values
+func coordinates(p geo.Point) (pgtype.Float8, pgtype.Float8) {
+	return pgtype.Float8{Float64: p.Latitude, Valid: true}, pgtype.Float8{Float64: p.Longitude, Valid: true}
+}
+
+// photoLocationsMedian returns the middle of the GPS positions of photos
+// about to be created, or false if none has one
+func photoLocationsMedian(params []database.CreatePhotoParams) (geo.Point, bool) {
+	var points []geo.Point
+	for _, p := range params {
+		if p.Latitude.Valid && p.Longitude.Valid {
+			points = append(points, geo.Point{Latitude: p.Latitude.Float64, Longitude: p.Longitude.Float64})
+		}
+	}
+	return geo.Median(points)
+}
+
+// handleUpdateProjectLocation sets a project's address and site coordinates.
+// Coordinates entered on the form win; otherwise they come from the photos'
+// GPS positions when requested, or from geocoding the address.
+func handleUpdateProjectLocation(w http.ResponseWriter, r *http.Request, q *database.Queries, gc geo.Geocoder) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	logger := loggerFromRequest(r)
+
+	projectID := r.PathValue("id")
+	id, err := parseUUID(projectID)
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Project not found"))
+		return
+	}
+	row, err := q.GetProject(ctx, id)
+	if isNotFound(err) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Project not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	if !canUserEditProject(user.ID, projectID) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to edit this project"))
+		return
+	}
+	current := row.Project
+
+	location := strings.TrimSpace(r.FormValue("location"))
+	if len(location) > maxLocationLength {
+		writeError(w, r, "", apperr.ValidationError("location", fmt.Sprintf("Location must be at most %d characters", maxLocationLength)))
+		return
+	}
+	params := database.UpdateProjectLocationParams{ID: id, Location: location}
+	redirect := "/app/projects/" + projectID + "#location"
+
+	lat, lon := strings.TrimSpace(r.FormValue("latitude")), strings.TrimSpace(r.FormValue("longitude"))
+	switch {
+	case r.FormValue("source") == "photos":
+		rows, err := q.ListProjectPhotoLocations(ctx, id)
+		if err != nil {
+			serverError(w, r, "failed to load photo locations", err)
+			return
+		}
+		points := make([]geo.Point, 0, len(rows))
+		for _, p := range rows {
+			points = append(points, geo.Point{Latitude: p.Latitude, Longitude: p.Longitude})
+		}
+		p, ok := geo.Median(points)
+		if !ok {
+			writeError(w, r, "", apperr.ValidationError("source", "None of this project's photos have a GPS position"))
+			return
+		}
+		params.Latitude, params.Longitude = coordinates(p)
+		params.CoordinatesSource = database.NullCoordinatesSource{CoordinatesSource: database.CoordinatesSourcePhotos, Valid: true}
+
+	case lat != "" || lon != "":
+		p, ok := geo.ParseCoordinates(lat + "," + lon)
+		if !ok {
+			writeError(w, r, "", apperr.ValidationError("latitude", "Latitude must be between -90 and 90 and longitude between -180 and 180"))
+			return
+		}
+		params.Latitude, params.Longitude = coordinates(p)
+		params.CoordinatesSource = database.NullCoordinatesSource{CoordinatesSource: database.CoordinatesSourceManual, Valid: true}
+		// resubmitting the form unchanged keeps where the coordinates came from
+		if current.Latitude.Float64 == p.Latitude && current.Longitude.Float64 == p.Longitude && current.CoordinatesSource.Valid {
+			params.CoordinatesSource = current.CoordinatesSource
+		}
+
+	case location != "":
+		p, err := gc.Geocode(ctx, location)
+		if err != nil {
+			if !errors.Is(err, geo.ErrNoMatch) {
+				logger.Warn("geocoding failed", "project_id", projectID, "error", err)
+			}
+			redirect = "/app/projects/" + projectID + "?geocode=not_found#location"
+			break
+		}
+		params.Latitude, params.Longitude = coordinates(p)
+		params.CoordinatesSource = database.NullCoordinatesSource{CoordinatesSource: database.CoordinatesSourceGeocoded, Valid: true}
+	}
+
+	if _, err := q.UpdateProjectLocation(ctx, params); err != nil {
+		serverError(w, r, "failed to update location", err)
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handleProjectsNearby lists active projects within radius_km of the lat and
+// lng query parameters, nearest first
+func handleProjectsNearby(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	query := r.URL.Query()
+	center, ok := geo.ParseCoordinates(query.Get("lat") + "," + query.Get("lng"))
+	if !ok {
+		writeError(w, r, "", apperr.ValidationError("lat", "lat and lng must be valid coordinates"))
+		return
+	}
+	radius := float64(defaultNearbyRadiusKm)
+	if s := query.Get("radius_km"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) || v <= 0 || v > maxNearbyRadiusKm {
+			writeError(w, r, "", apperr.ValidationError("radius_km", fmt.Sprintf("radius_km must be between 0 and %d", maxNearbyRadiusKm)))
+			return
+		}
+		radius = v
+	}
+
+	projects, err := getProjectsNear(r.Context(), q, center, radius)
+	if err != nil {
+		serverError(w, r, "failed to search projects", err)
+		return
+	}
+	if err := encode(w, http.StatusOK, projects); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// getProjectsNear returns active projects within radiusKm of center. The
+// query only narrows by bounding box, so its corners are trimmed here.
+func getProjectsNear(ctx context.Context, q *database.Queries, center geo.Point, radiusKm float64) ([]dto.NearbyProject, error) {
+	sw, ne := geo.BoundingBox(center, radiusKm)
+	rows, err := q.ListProjectsNear(ctx, database.ListProjectsNearParams{
+		Latitude:     center.Latitude,
+		Longitude:    center.Longitude,
+		MinLatitude:  sw.Latitude,
+		MaxLatitude:  ne.Latitude,
+		MinLongitude: sw.Longitude,
+		MaxLongitude: ne.Longitude,
+		MaxResults:   maxNearbyResults,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]dto.NearbyProject, 0, len(rows))
+	for _, row := range rows {
+		if row.DistanceKm > radiusKm {
+			break // ordered by distance
+		}
+		projects = append(projects, dto.NearbyProject{
+			Project: toProject(database.GetProjectRow{
+				Project:        row.Project,
+				InspectorName:  row.InspectorName,
+				ViolationCount: row.ViolationCount,
+				PhotoCount:     row.PhotoCount,
+			}),
+			DistanceKm: row.DistanceKm,
+		})
+	}
+	return projects, nil
+}
+
+func handleMap(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
+
+	rows, err := q.ListMappedProjects(ctx)
+	if err != nil {
+		serverError(w, r, "failed to load projects", err)
+		return
+	}
+	projects := make([]dto.MapProject, 0, len(rows))
+	for _, row := range rows {
+		projects = append(projects, dto.MapProject{
+			ID:             row.ID.String(),
+			Name:           row.Name,
+			Status:         string(row.Status),
+			Location:       row.Location,
+			Latitude:       row.Latitude,
+			Longitude:      row.Longitude,
+			OpenViolations: int(row.OpenViolationCount),
+		})
+	}
+
+	hotspotRows, err := q.ListOpenViolationHotspots(ctx, maxHotspots)
+	if err != nil {
+		serverError(w, r, "failed to load violation hotspots", err)
+		return
+	}
+	hotspots := make([]dto.Hotspot, 0, len(hotspotRows))
+	for _, row := range hotspotRows {
+		hotspots = append(hotspots, dto.Hotspot{
+			Latitude:       row.Latitude,
+			Longitude:      row.Longitude,
+			ViolationCount: int(row.ViolationCount),
+			SevereCount:    int(row.SevereCount),
+		})
+	}
+
+	unmapped, err := q.CountUnmappedProjects(ctx)
+	if err != nil {
+		serverError(w, r, "failed to count unmapped projects", err)
+		return
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, getCurrentUser())
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.MapData{
+		AppData: dto.AppData{
+			PageTitle:      "Map",
+			CurrentPage:    "map",
+			User:           getCurrentUser(),
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Projects: projects,
+		Hotspots: hotspots,
+		Unmapped: int(unmapped),
+	}
+	render(w, r, t, "map", data)
+}
@@ -4,23 +4,50 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
+
+	"github.com/dukerupert/ironman/internal/logger"
+	"github.com/dukerupert/ironman/internal/metrics"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type contextKey string
 
 const (
-	LoggerKey    contextKey = "logger"
 	StartTimeKey contextKey = "startTime"
 	UserIDKey    contextKey = "userID"
 	RequestIDKey contextKey = "requestID"
+	NonceKey     contextKey = "nonce"
+	CSRFTokenKey contextKey = "csrfToken"
 )
 
+// requestIDHeader carries the request ID in both directions, so a proxy in
+// front can choose it and clients can quote it when reporting a problem
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest inbound request ID that is kept
+const maxRequestIDLength = 64
+
+// RequestID gives each request an ID, keeping a valid one sent in the
+// X-Request-ID header, and returns it in the response's header
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := generateRequestID()
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = generateRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
 
 		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
 		r = r.WithContext(ctx)
@@ -28,24 +55,97 @@ func RequestID(next http.Handler) http.Handler {
 	})
 }
 
-func NewLogging(logger *slog.Logger) func(http.Handler) http.Handler {
+// validRequestID reports whether an inbound request ID is safe to log and
+// echo: up to maxRequestIDLength letters, digits, dashes, underscores and
+// dots
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFromContext returns the ID RequestID gave the request, or ""
+// outside the middleware chain
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// Tracing records each request as a span, continuing the trace named in a
+// W3C traceparent header if there is one. The span is named after the
+// route by traceRoute, further in.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		requestID := requestIDFromContext(ctx)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				attribute.String("request.id", requestID),
+			),
+		)
+		defer span.End()
+
+		wrapped := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		status := wrapped.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// traceRoute names the request's span after the route pattern it matched.
+// Like NewMetrics, it must wrap the mux directly.
+func traceRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if route := routePattern(r); route != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	})
+}
+
+func NewLogging(baseLogger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			requestID := r.Context().Value(RequestIDKey).(string)
+			requestID := requestIDFromContext(r.Context())
 
 			// Create request-scoped logger with context
-			requestLogger := logger.With(
+			requestLogger := baseLogger.With(
 				"request_id", requestID,
 				"method", r.Method,
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
 				"user_agent", r.UserAgent(),
 			)
+			// link the request's logs to its trace
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				requestLogger = requestLogger.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+			}
 
 			// Add logger to request context
-			ctx := context.WithValue(r.Context(), LoggerKey, requestLogger)
-			r = r.WithContext(ctx)
+			r = r.WithContext(logger.NewContext(r.Context(), requestLogger))
 
 			// Wrap response writer to capture status
 			wrapped := &responseWriter{ResponseWriter: w}
@@ -64,6 +164,78 @@ func NewLogging(logger *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// Recover turns a panicking handler into a 500 response, logging the panic
+// and its stack with the request ID. Panics with http.ErrAbortHandler are
+// left to the server, which uses them to abort a response without logging.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := &responseWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			loggerFromRequest(r).Error("panic serving request",
+				"panic", fmt.Sprint(p),
+				"stack", string(debug.Stack()),
+			)
+			// a response already under way can't be replaced
+			if wrapped.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(wrapped, r)
+	})
+}
+
+// NewMetrics records each request in m by the route pattern it matched. It
+// must wrap the mux directly, as the pattern is set on the request the mux
+// is given.
+func NewMetrics(m *metrics.Requests) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(wrapped, r)
+
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+			status := wrapped.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.Observe(methodLabel(r.Method), route, status, time.Since(start))
+		})
+	}
+}
+
+// methodLabel returns the method to record a request under. The server
+// accepts any token as a method, so others are recorded as OTHER to keep
+// the number of series bounded.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// routePattern returns the pattern the mux matched the request to, without
+// the method, or "" before the mux has handled it
+func routePattern(r *http.Request) string {
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	status int
@@ -84,8 +256,20 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, which
+// streaming responses need to flush
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func generateRequestID() string {
 	bytes := make([]byte, 8) // 16 character hex string
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
 }
+
+// loggerFromRequest returns the request-scoped logger added by NewLogging,
+// falling back to the default logger outside the middleware chain
+func loggerFromRequest(r *http.Request) *slog.Logger {
+	return logger.FromContext(r.Context())
+}
@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/metrics"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMetrics(t *testing.T) {
+	reqs := metrics.NewRequests()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projects/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := NewMetrics(reqs)(mux)
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/projects/1"},
+		{"GET", "/projects/2"},
+		{"GET", "/nowhere"},
+		{"FOO", "/projects/1"},
+		{"get", "/projects/1"},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+	reqs.Observe("GET", "/odd\"\\route\n", http.StatusOK, 0)
+
+	// the pool connects lazily, so its statistics can be read without a
+	// database
+	pool, err := pgxpool.New(context.Background(), "postgres://ironman@127.0.0.1:1/ironman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	db := newFakeDB()
+	db.returns("GetQueueDepths", database.GetQueueDepthsRow{WebhookDeliveries: 3, NotificationEmails: 2, UnanalyzedPhotos: 1})
+
+	w := httptest.NewRecorder()
+	handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil), pool, database.New(db), analysis.New(pool, nil, nil), reqs)
+
+	if got := w.Header().Get("Content-Type"); got != metrics.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, metrics.ContentType)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		"# TYPE http_request_duration_seconds histogram\n",
+		"# TYPE ironman_db_pool_max_connections gauge\n",
+		"# TYPE ironman_db_pool_connections gauge\n",
+		"# TYPE ironman_db_pool_acquires_total counter\n",
+		"# TYPE ironman_job_queue_depth gauge\n",
+		`http_requests_total{method="GET",route="/projects/{id}",status="200"} 2` + "\n",
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1` + "\n",
+		`http_requests_total{method="OTHER",route="unmatched",status="405"} 2` + "\n",
+		`http_requests_total{method="GET",route="/odd\"\\route\n",status="200"} 1` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/projects/{id}",status="200",le="+Inf"} 2` + "\n",
+		`http_request_duration_seconds_count{method="GET",route="/projects/{id}",status="200"} 2` + "\n",
+		`ironman_job_queue_depth{queue="webhook_deliveries"} 3` + "\n",
+		`ironman_job_queue_depth{queue="analysis"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q", want)
+		}
+	}
+	for _, method := range []string{`method="FOO"`, `method="get"`} {
+		if strings.Contains(body, method) {
+			t.Errorf("metrics record non-standard %s", method)
+		}
+	}
+	if t.Failed() {
+		t.Logf("metrics:\n%s", body)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	ctx := context.Background()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	if _, err := tracing.Setup(ctx, "", "ironman", 1); err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.Install(exporter, "ironman", 1)
+	defer tp.Shutdown(ctx)
+
+	var handlerTrace trace.TraceID
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerTrace = trace.SpanContextFromContext(r.Context()).TraceID()
+	})
+	mux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	handler := Tracing(traceRoute(mux))
+
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+	r := httptest.NewRequest("GET", "/projects/42", nil)
+	r.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/boom", nil))
+
+	if err := tp.ForceFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	got := spans[0]
+	if got.Name != "GET /projects/{id}" {
+		t.Errorf("span name = %q, want %q", got.Name, "GET /projects/{id}")
+	}
+	if got.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", got.SpanKind)
+	}
+	if id := got.SpanContext.TraceID().String(); id != traceID {
+		t.Errorf("trace ID = %s, want the traceparent's %s", id, traceID)
+	}
+	if id := got.Parent.SpanID().String(); id != parentID || !got.Parent.IsRemote() {
+		t.Errorf("parent = %s remote=%v, want the traceparent's %s", id, got.Parent.IsRemote(), parentID)
+	}
+	if handlerTrace.String() != traceID {
+		t.Errorf("handler saw trace %s, want %s", handlerTrace, traceID)
+	}
+	if got.Status.Code != codes.Unset {
+		t.Errorf("status = %v, want unset", got.Status.Code)
+	}
+	attrs := map[string]string{}
+	for _, a := range got.Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs[string(semconv.HTTPRouteKey)] != "/projects/{id}" || attrs[string(semconv.HTTPResponseStatusCodeKey)] != "200" {
+		t.Errorf("attributes = %v, want route /projects/{id} and status 200", attrs)
+	}
+
+	failed := spans[1]
+	if failed.Name != "GET /boom" {
+		t.Errorf("span name = %q, want %q", failed.Name, "GET /boom")
+	}
+	if failed.Status.Code != codes.Error || failed.Status.Description != "Internal Server Error" {
+		t.Errorf("status = %v %q, want an error", failed.Status.Code, failed.Status.Description)
+	}
+	if failed.Parent.IsValid() || failed.SpanContext.TraceID().String() == traceID {
+		t.Errorf("span without a traceparent continued trace %s", failed.SpanContext.TraceID())
+	}
+}
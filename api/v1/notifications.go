@@ -0,0 +1,365 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/notify"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// recentAlertsSize is how many alerts the notification settings page lists
+const recentAlertsSize = 20
+
+// notificationMenuSize is how many notifications the header dropdown lists
+const notificationMenuSize = 5
+
+// notificationsPageSize is how many notifications the notification center
+// lists per page
+const notificationsPageSize = 50
+
+// notificationsPath is the notification center page
+const notificationsPath = "/app/notifications"
+
+// handleNotifications lists the current user's notifications, newest first,
+// optionally only the unread ones
+func handleNotifications(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	userID := userUUID(user)
+	unreadOnly := r.URL.Query().Get("unread") == "1"
+
+	var cursor timeCursor
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		if err := decodeCursor(raw, &cursor); err != nil {
+			writeError(w, r, "", &apperr.Error{Kind: apperr.Validation, Message: "Invalid cursor", Field: "cursor", Err: err})
+			return
+		}
+	}
+
+	var list []dto.Notification
+	var nextCursor string
+	if userID.Valid {
+		before, beforeID := cursor.after()
+		rows, err := q.ListNotificationsPage(ctx, database.ListNotificationsPageParams{
+			UserID:           userID,
+			BeforeCreatedAt:  before,
+			BeforeID:         beforeID,
+			UnreadOnly:       unreadOnly,
+			MaxNotifications: notificationsPageSize + 1,
+		})
+		if err != nil {
+			serverError(w, r, "failed to load notifications", err)
+			return
+		}
+		if len(rows) > notificationsPageSize {
+			rows = rows[:notificationsPageSize]
+			last := rows[len(rows)-1]
+			nextCursor = encodeCursor(timeCursor{Time: last.CreatedAt.Time, ID: last.ID.String()})
+		}
+		for _, row := range rows {
+			list = append(list, toNotification(row))
+		}
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.NotificationsData{
+		AppData: dto.AppData{
+			PageTitle:      "Notifications",
+			CurrentPage:    "notifications",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		List:       list,
+		UnreadOnly: unreadOnly,
+		NextCursor: nextCursor,
+		HasAccount: userID.Valid,
+	}
+	render(w, r, t, "notifications", data)
+}
+
+// handleOpenNotification marks a notification read and takes the user to
+// what it is about
+func handleOpenNotification(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	n, ok := getUserNotification(w, r, q)
+	if !ok {
+		return
+	}
+	if err := q.MarkNotificationRead(ctx, database.MarkNotificationReadParams{ID: n.ID, UserID: n.UserID}); err != nil {
+		serverError(w, r, "failed to mark notification read", err)
+		return
+	}
+	http.Redirect(w, r, localPath(n.Link, notificationsPath), http.StatusSeeOther)
+}
+
+// handleMarkNotificationRead marks one notification read and returns to the
+// page the form was on
+func handleMarkNotificationRead(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	n, ok := getUserNotification(w, r, q)
+	if !ok {
+		return
+	}
+	if err := q.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{ID: n.ID, UserID: n.UserID}); err != nil {
+		serverError(w, r, "failed to mark notification read", err)
+		return
+	}
+	http.Redirect(w, r, localPath(r.FormValue("return_to"), notificationsPath), http.StatusSeeOther)
+}
+
+// handleMarkAllNotificationsRead marks all of the current user's
+// notifications read and returns to the page the form was on
+func handleMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	userID := userUUID(getCurrentUser())
+	if userID.Valid {
+		if _, err := q.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+			serverError(w, r, "failed to mark notifications read", err)
+			return
+		}
+	}
+	http.Redirect(w, r, localPath(r.FormValue("return_to"), notificationsPath), http.StatusSeeOther)
+}
+
+// getUserNotification loads the notification named in the path, treating
+// other users' notifications as not found. It writes an error response and
+// returns false if it can't.
+func getUserNotification(w http.ResponseWriter, r *http.Request, q *database.Queries) (database.Notification, bool) {
+	userID := userUUID(getCurrentUser())
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil || !userID.Valid {
+		writeError(w, r, "", apperr.NotFoundError("Notification not found"))
+		return database.Notification{}, false
+	}
+	n, err := q.GetNotification(r.Context(), database.GetNotificationParams{ID: id, UserID: userID})
+	if isNotFound(err) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Notification not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to load notification", err)
+		return n, false
+	}
+	return n, true
+}
+
+// getNotificationMenu returns the unread count and latest notifications of
+// user for the app header. Users without an account have none.
+func getNotificationMenu(ctx context.Context, q *database.Queries, user dto.User) (dto.NotificationMenu, error) {
+	var menu dto.NotificationMenu
+	userID := userUUID(user)
+	if !userID.Valid {
+		return menu, nil
+	}
+	unread, err := q.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return menu, err
+	}
+	rows, err := q.ListNotificationsByUser(ctx, database.ListNotificationsByUserParams{
+		UserID: userID,
+		Limit:  notificationMenuSize,
+	})
+	if err != nil {
+		return menu, err
+	}
+	menu.Unread = unread
+	for _, row := range rows {
+		menu.Recent = append(menu.Recent, toNotification(row))
+	}
+	return menu, nil
+}
+
+// localPath returns path if it is a path on this site, or fallback, so
+// redirects to it can't leave the app
+func localPath(path, fallback string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return fallback
+	}
+	return path
+}
+
+// notifyActor returns user as the cause of a notification
+func notifyActor(user dto.User) notify.Actor {
+	return notify.Actor{ID: userUUID(user), Name: user.Name}
+}
+
+func handleNotificationSettings(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	userID := userUUID(user)
+
+	prefs := notify.DefaultPreferences(userID)
+	timezone := "UTC"
+	var alerts []dto.Notification
+	if userID.Valid {
+		var err error
+		prefs, err = notify.Preferences(ctx, q, userID)
+		if err != nil {
+			serverError(w, r, "failed to load notification preferences", err)
+			return
+		}
+		u, err := q.GetUser(ctx, userID)
+		if err != nil {
+			serverError(w, r, "failed to load user", err)
+			return
+		}
+		if u.Timezone.String != "" {
+			timezone = u.Timezone.String
+		}
+		rows, err := q.ListNotificationsByUser(ctx, database.ListNotificationsByUserParams{
+			UserID: userID,
+			Limit:  recentAlertsSize,
+		})
+		if err != nil {
+			serverError(w, r, "failed to load notifications", err)
+			return
+		}
+		for _, row := range rows {
+			alerts = append(alerts, toNotification(row))
+		}
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	modes := make([]string, 0, len(notify.Modes))
+	for _, mode := range notify.Modes {
+		modes = append(modes, string(mode))
+	}
+	frequencies := make([]string, 0, len(notify.DigestFrequencies))
+	for _, frequency := range notify.DigestFrequencies {
+		frequencies = append(frequencies, string(frequency))
+	}
+	data := dto.NotificationSettingsData{
+		AppData: dto.AppData{
+			PageTitle:      "Notifications",
+			CurrentPage:    "settings",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Preferences: dto.NotificationPreferences{
+			CriticalViolations: string(prefs.CriticalViolations),
+			OverdueActions:     string(prefs.OverdueActions),
+			DigestTime:         formatTimeOfDay(prefs.DigestTime),
+			QuietHours:         prefs.QuietHoursStart.Valid,
+			QuietHoursStart:    formatTimeOfDay(prefs.QuietHoursStart),
+			QuietHoursEnd:      formatTimeOfDay(prefs.QuietHoursEnd),
+			SafetyDigest:       string(prefs.SafetyDigest),
+		},
+		Modes:       modes,
+		Frequencies: frequencies,
+		Timezone:    timezone,
+		HasAccount:  userID.Valid,
+		Saved:       r.URL.Query().Get("saved") == "1",
+		Alerts:      alerts,
+	}
+	render(w, r, t, "notification-settings", data)
+}
+
+func handleUpdateNotificationSettings(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	user := getCurrentUser()
+	userID := userUUID(user)
+	if !userID.Valid {
+		writeError(w, r, "", apperr.ValidationError("", "Notification settings are saved per user account"))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, "", &apperr.Error{Kind: apperr.Validation, Message: "Invalid form", Err: err})
+		return
+	}
+
+	params := database.UpsertNotificationPreferencesParams{
+		UserID:             userID,
+		CriticalViolations: database.NotificationMode(r.FormValue("critical_violations")),
+		OverdueActions:     database.NotificationMode(r.FormValue("overdue_actions")),
+		SafetyDigest:       database.DigestFrequency(r.FormValue("safety_digest")),
+	}
+	if !slices.Contains(notify.Modes, params.CriticalViolations) || !slices.Contains(notify.Modes, params.OverdueActions) {
+		writeError(w, r, "", apperr.ValidationError("critical_violations", "Unknown delivery mode"))
+		return
+	}
+	if !slices.Contains(notify.DigestFrequencies, params.SafetyDigest) {
+		writeError(w, r, "", apperr.ValidationError("safety_digest", "Unknown safety digest frequency"))
+		return
+	}
+	var ok bool
+	if params.DigestTime, ok = parseTimeOfDay(r.FormValue("digest_time")); !ok {
+		writeError(w, r, "", apperr.ValidationError("digest_time", "Digest time must be a time of day"))
+		return
+	}
+	if r.FormValue("quiet_hours") == "on" {
+		start, startOK := parseTimeOfDay(r.FormValue("quiet_hours_start"))
+		end, endOK := parseTimeOfDay(r.FormValue("quiet_hours_end"))
+		if !startOK || !endOK {
+			writeError(w, r, "", apperr.ValidationError("quiet_hours_start", "Quiet hours must start and end at a time of day"))
+			return
+		}
+		if start == end {
+			writeError(w, r, "", apperr.ValidationError("quiet_hours_end", "Quiet hours must end at a different time than they start"))
+			return
+		}
+		params.QuietHoursStart, params.QuietHoursEnd = start, end
+	}
+
+	if _, err := q.UpsertNotificationPreferences(r.Context(), params); err != nil {
+		serverError(w, r, "failed to save notification preferences", err)
+		return
+	}
+	http.Redirect(w, r, notify.SettingsPath+"?saved=1", http.StatusSeeOther)
+}
+
+// parseTimeOfDay parses a form time such as "22:30"
+func parseTimeOfDay(s string) (pgtype.Time, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return pgtype.Time{}, false
+	}
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return pgtype.Time{Microseconds: d.Microseconds(), Valid: true}, true
+}
+
+// formatTimeOfDay formats a time of day for a time input, or returns an
+// empty string if it is null
+func formatTimeOfDay(t pgtype.Time) string {
+	if !t.Valid {
+		return ""
+	}
+	return time.Time{}.Add(time.Duration(t.Microseconds) * time.Microsecond).Format("15:04")
+}
+
+// toNotification converts a notifications row into its page representation
+func toNotification(n database.Notification) dto.Notification {
+	return dto.Notification{
+		ID:        n.ID.String(),
+		Kind:      string(n.Kind),
+		Title:     n.Title,
+		Body:      n.Body,
+		Link:      n.Link,
+		ActorName: n.ActorName,
+		Read:      n.ReadAt.Valid,
+		CreatedAt: n.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/dto"
+)
+
+// openAPIVersion is the version of the JSON API described in the document
+const openAPIVersion = "1.0.0"
+
+// pathParam matches the wildcards of a ServeMux pattern
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPIDocument describes the routes as an OpenAPI 3.1 document. Schemas
+// come from the dto types the handlers encode, so the document changes with
+// them.
+func openAPIDocument(routes []apiRoute) map[string]any {
+	s := &schemaSet{components: map[string]any{}}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": s.schema(reflect.TypeFor[dto.ErrorResponse]())},
+		},
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		op := map[string]any{
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"operationId": operationID(route),
+		}
+
+		var params []map[string]any
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string", "format": "uuid"},
+			})
+		}
+		for _, p := range route.Query {
+			schema := map[string]any{"type": "string"}
+			if p.Type != "" {
+				schema["type"] = p.Type
+			}
+			if p.Format != "" {
+				schema["format"] = p.Format
+			}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			param := map[string]any{"name": p.Name, "in": "query", "schema": schema}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if route.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": s.schema(reflect.TypeOf(route.Body))},
+				},
+			}
+		}
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): map[string]any{
+				"description": http.StatusText(status),
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": s.schema(reflect.TypeOf(route.Response))},
+				},
+			},
+			"default": errorResponse,
+		}
+
+		// the browser session, or a bearer token with the route's scopes
+		scopes := route.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		op["security"] = []map[string]any{{}, {"bearerAuth": scopes}}
+
+		item, _ := paths[route.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "SafeSite Inspector API",
+			"version": openAPIVersion,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Personal or service token created under Settings → API tokens",
+				},
+			},
+		},
+	}
+}
+
+// operationID names an operation after its method and path, e.g.
+// "getApiV1ProjectsId"
+func operationID(route apiRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// schemaSet builds JSON schemas for Go types, collecting named structs as
+// components referenced from the operations
+type schemaSet struct {
+	components map[string]any
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// schema returns the JSON schema of values of type t as encoding/json
+// writes them
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{s.schema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Slice:
+		// nil slices and maps are written as null
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": []string{"array", "null"}, "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		// generic envelopes are written inline; their names aren't valid
+		// component keys
+		name := t.Name()
+		if name == "" || strings.Contains(name, "[") {
+			return s.object(t)
+		}
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := s.components[name]; !ok {
+			s.components[name] = nil // placeholder for recursive types
+			s.components[name] = s.object(t)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+// object returns the schema of a struct's JSON fields
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.fields(t, properties, &required)
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields adds the JSON fields of t to properties, flattening embedded
+// structs the way encoding/json does. Fields without omitempty are always
+// present, so they are listed as required.
+func (s *schemaSet) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(f.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
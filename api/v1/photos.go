@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/exif"
+	"github.com/dukerupert/ironman/internal/imaging"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxPhotoSize caps a single uploaded photo
+const maxPhotoSize = 20 << 20
+
+// maxCameraLength is the longest camera make or model recorded
+const maxCameraLength = 100
+
+// photoExtensions maps the accepted image types to their file extensions
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+var (
+	errPhotoMissing  = errors.New("a photo is required")
+	errPhotoTooLarge = fmt.Errorf("photo must be at most %d MB", maxPhotoSize>>20)
+	errPhotoType     = errors.New("photo must be a JPEG, PNG or WebP image")
+)
+
+// uploadedPhoto is an image read from a multipart form
+type uploadedPhoto struct {
+	Filename string
+	Image    detector.Image
+	Metadata exif.Metadata // Capture details from the original file
+	Hash     uint64        // Perceptual hash of the stored image
+}
+
+// readPhoto reads the image in a multipart form field, sniffing its content
+// type rather than trusting the client
+func readPhoto(r *http.Request, field string) (uploadedPhoto, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return uploadedPhoto{}, errPhotoMissing
+		}
+		return uploadedPhoto{}, err
+	}
+	defer file.Close()
+	return readPhotoFile(file, header.Filename)
+}
+
+// readPhotoFile reads and validates one uploaded image
+func readPhotoFile(file io.Reader, filename string) (uploadedPhoto, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxPhotoSize+1))
+	if err != nil {
+		return uploadedPhoto{}, err
+	}
+	if len(data) == 0 {
+		return uploadedPhoto{}, errPhotoMissing
+	}
+	if len(data) > maxPhotoSize {
+		return uploadedPhoto{}, errPhotoTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := photoExtensions[contentType]; !ok {
+		return uploadedPhoto{}, errPhotoType
+	}
+	// a valid header is enough to know thumbnails and analysis can decode it
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return uploadedPhoto{}, errPhotoType
+	}
+
+	// photos without EXIF are common (screenshots, edited copies); the zero
+	// metadata is recorded for them
+	meta, _ := exif.Parse(data)
+	if meta.Orientation != 1 {
+		// store the pixels upright so regions line up everywhere the photo
+		// is drawn, whether or not the viewer honours the EXIF tag
+		data, err = imaging.Orient(data, meta.Orientation)
+		if err != nil {
+			return uploadedPhoto{}, errPhotoType
+		}
+		contentType = "image/jpeg"
+	}
+	hash, err := imaging.DHash(data)
+	if err != nil {
+		return uploadedPhoto{}, errPhotoType
+	}
+
+	return uploadedPhoto{
+		Filename: filename,
+		Image:    detector.Image{Data: data, ContentType: contentType},
+		Metadata: meta,
+		Hash:     hash,
+	}, nil
+}
+
+// isPhotoError reports whether err describes a bad upload rather than a
+// server failure
+func isPhotoError(err error) bool {
+	return errors.Is(err, errPhotoMissing) || errors.Is(err, errPhotoTooLarge) || errors.Is(err, errPhotoType)
+}
+
+// storedPhoto locates a photo and its thumbnail in the blob store
+type storedPhoto struct {
+	Key          string
+	ThumbnailKey string
+}
+
+// putPhoto writes a photo and its thumbnail to the blob store under fresh
+// keys. The caller records them with CreatePhoto, calling deletePhoto on
+// failure.
+func putPhoto(ctx context.Context, store blob.Store, projectID pgtype.UUID, photo uploadedPhoto) (storedPhoto, error) {
+	name := fmt.Sprintf("projects/%s/photos/%s", projectID.String(), strings.ToLower(rand.Text()))
+
+	var thumb bytes.Buffer
+	if err := imaging.Thumbnail(&thumb, photo.Image.Data, imaging.ThumbnailSize); err != nil {
+		return storedPhoto{}, fmt.Errorf("generate thumbnail: %w", err)
+	}
+
+	stored := storedPhoto{
+		Key:          name + photoExtensions[photo.Image.ContentType],
+		ThumbnailKey: name + "_thumb.jpg",
+	}
+	if err := store.Put(ctx, stored.Key, bytes.NewReader(photo.Image.Data)); err != nil {
+		return storedPhoto{}, err
+	}
+	if err := store.Put(ctx, stored.ThumbnailKey, &thumb); err != nil {
+		store.Delete(ctx, stored.Key)
+		return storedPhoto{}, err
+	}
+	return stored, nil
+}
+
+// deletePhoto removes a photo written by putPhoto
+func deletePhoto(ctx context.Context, store blob.Store, stored storedPhoto) error {
+	return errors.Join(store.Delete(ctx, stored.Key), store.Delete(ctx, stored.ThumbnailKey))
+}
+
+// photoParams fills in the CreatePhoto fields describing the stored image
+func photoParams(projectID pgtype.UUID, photo uploadedPhoto, stored storedPhoto, purpose database.PhotoPurpose, user dto.User) database.CreatePhotoParams {
+	meta := photo.Metadata
+	return database.CreatePhotoParams{
+		ProjectID:      projectID,
+		StorageKey:     stored.Key,
+		Filename:       photo.Filename,
+		ContentType:    photo.Image.ContentType,
+		SizeBytes:      int64(len(photo.Image.Data)),
+		Purpose:        purpose,
+		UploadedBy:     userUUID(user),
+		UploadedByName: user.Name,
+		AreaType:       "general",
+		ThumbnailKey:   pgtype.Text{String: stored.ThumbnailKey, Valid: true},
+		TakenAt:        pgtype.Timestamptz{Time: meta.TakenAt, Valid: !meta.TakenAt.IsZero()},
+		Latitude:       pgtype.Float8{Float64: meta.Latitude, Valid: meta.HasLocation},
+		Longitude:      pgtype.Float8{Float64: meta.Longitude, Valid: meta.HasLocation},
+		Orientation:    int16(meta.Orientation),
+		CameraMake:     clip(meta.Make, maxCameraLength),
+		CameraModel:    clip(meta.Model, maxCameraLength),
+		PerceptualHash: pgtype.Int8{Int64: int64(photo.Hash), Valid: true},
+	}
+}
+
+// photoHash is an original photo that new uploads are compared against
+type photoHash struct {
+	ID   pgtype.UUID
+	Hash uint64
+}
+
+// findDuplicate returns the photo whose hash is closest to hash, if any is
+// within imaging.DuplicateDistance. Earlier photos win ties.
+func findDuplicate(hash uint64, photos []photoHash) (pgtype.UUID, bool) {
+	var match pgtype.UUID
+	best := imaging.DuplicateDistance + 1
+	for _, p := range photos {
+		if d := imaging.Distance(hash, p.Hash); d < best {
+			match, best = p.ID, d
+		}
+	}
+	return match, match.Valid
+}
+
+// clip shortens s to at most n runes
+func clip(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// photoURL returns the path a photo is served from, or "" for no photo
+func photoURL(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return "/app/photos/" + id.String()
+}
+
+// thumbnailURL returns the path a photo's thumbnail is served from
+func thumbnailURL(id pgtype.UUID) string {
+	return photoURL(id) + "/thumbnail"
+}
+
+func handleServePhoto(w http.ResponseWriter, r *http.Request, store blob.Store, q *database.Queries) {
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	serveBlob(w, r, store, photo.StorageKey, photo.ContentType)
+}
+
+// handleServeThumbnail serves a photo's gallery thumbnail, falling back to the
+// full image for photos stored before thumbnails were generated
+func handleServeThumbnail(w http.ResponseWriter, r *http.Request, store blob.Store, q *database.Queries) {
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !photo.ThumbnailKey.Valid {
+		serveBlob(w, r, store, photo.StorageKey, photo.ContentType)
+		return
+	}
+	serveBlob(w, r, store, photo.ThumbnailKey.String, "image/jpeg")
+}
+
+// serveBlob writes an image from the blob store with its EXIF and other
+// metadata removed; the stored original keeps it, but GPS coordinates and
+// device details must not leave the app with a shared copy. Stored images
+// never change, so clients may cache them.
+func serveBlob(w http.ResponseWriter, r *http.Request, store blob.Store, key, contentType string) {
+	f, err := store.Open(r.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Photo not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to open photo", err)
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		serverError(w, r, "failed to read photo", err)
+		return
+	}
+	data = exif.Strip(data)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(data); err != nil {
+		loggerFromRequest(r).Warn("failed to write photo", "key", key, "error", err)
+	}
+}
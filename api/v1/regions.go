@@ -0,0 +1,282 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/annotate"
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// getPhoto loads the photo named by the request's {id} path value, writing a
+// 404 or 500 and returning false when it can't
+func getPhoto(w http.ResponseWriter, r *http.Request, q *database.Queries) (database.Photo, bool) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Photo not found"))
+		return database.Photo{}, false
+	}
+	photo, err := q.GetPhoto(r.Context(), id)
+	if err != nil {
+		if isNotFound(err) {
+			writeError(w, r, "", apperr.NotFoundError("Photo not found"))
+			return photo, false
+		}
+		serverError(w, r, "failed to load photo", err)
+		return photo, false
+	}
+	return photo, true
+}
+
+// getPhotoRegions returns every region drawn on a photo
+func getPhotoRegions(r *http.Request, q *database.Queries, photoID pgtype.UUID) ([]dto.Region, error) {
+	rows, err := q.ListRegionsByPhoto(r.Context(), photoID)
+	if err != nil {
+		return nil, err
+	}
+	regions := make([]dto.Region, 0, len(rows))
+	for _, row := range rows {
+		regions = append(regions, toRegion(row.ViolationRegion, string(row.RiskLevel), row.ViolationDescription))
+	}
+	return regions, nil
+}
+
+func handleListPhotoRegions(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	regions, err := getPhotoRegions(r, q, photo.ID)
+	if err != nil {
+		serverError(w, r, "failed to load regions", err)
+		return
+	}
+	if err := encode(w, http.StatusOK, regions); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// handleCreatePhotoRegion records a region drawn by an inspector
+func handleCreatePhotoRegion(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to annotate photos on this project"))
+		return
+	}
+
+	req, err := decode[dto.RegionRequest](r)
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Invalid request body"))
+		return
+	}
+	violationID, err := parseUUID(req.ViolationID)
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("violation_id", "Unknown violation"))
+		return
+	}
+	violation, err := q.GetViolation(ctx, violationID)
+	if err != nil || violation.Violation.ProjectID != photo.ProjectID {
+		if err == nil || isNotFound(err) {
+			writeError(w, r, "", apperr.ValidationError("violation_id", "Unknown violation"))
+			return
+		}
+		serverError(w, r, "failed to load violation", err)
+		return
+	}
+
+	region := annotate.Region{
+		Shape:  req.Shape,
+		X:      req.X,
+		Y:      req.Y,
+		Width:  req.Width,
+		Height: req.Height,
+	}
+	for _, p := range req.Points {
+		region.Points = append(region.Points, annotate.Point{X: p[0], Y: p[1]})
+	}
+	if err := annotate.Normalize(&region); err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Invalid region: "+err.Error()))
+		return
+	}
+
+	params := database.CreateRegionParams{
+		ViolationID: violationID,
+		PhotoID:     photo.ID,
+		Shape:       database.RegionShape(region.Shape),
+		X:           region.X,
+		Y:           region.Y,
+		Width:       region.Width,
+		Height:      region.Height,
+		Source:      database.RegionSourceInspector,
+		UserID:      userUUID(user),
+		UserName:    user.Name,
+	}
+	if region.Shape == annotate.ShapePolygon {
+		params.Points, err = json.Marshal(req.Points)
+		if err != nil {
+			serverError(w, r, "failed to encode polygon", err)
+			return
+		}
+	}
+	created, err := q.CreateRegion(ctx, params)
+	if err != nil {
+		serverError(w, r, "failed to create region", err)
+		return
+	}
+
+	resp := toRegion(created, string(violation.Violation.RiskLevel), violation.Violation.Description)
+	if err := encode(w, http.StatusCreated, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+func handleDeletePhotoRegion(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to annotate photos on this project"))
+		return
+	}
+
+	id, err := parseUUID(r.PathValue("regionId"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Region not found"))
+		return
+	}
+	region, err := q.GetRegion(ctx, id)
+	if err != nil || region.PhotoID != photo.ID {
+		if err == nil || isNotFound(err) {
+			writeError(w, r, "", apperr.NotFoundError("Region not found"))
+			return
+		}
+		serverError(w, r, "failed to load region", err)
+		return
+	}
+	if err := q.DeleteRegion(ctx, id); err != nil {
+		serverError(w, r, "failed to delete region", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAnnotatedPhoto serves a photo as a JPEG with its regions burned in,
+// for reports and exports where overlays can't be drawn client side. The
+// optional violation query parameter limits the outlines to one violation.
+func handleAnnotatedPhoto(w http.ResponseWriter, r *http.Request, store blob.Store, q *database.Queries) {
+	ctx := r.Context()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	regions, err := getPhotoRegions(r, q, photo.ID)
+	if err != nil {
+		serverError(w, r, "failed to load regions", err)
+		return
+	}
+	only := r.URL.Query().Get("violation")
+
+	var outlines []annotate.Region
+	for _, reg := range regions {
+		if only != "" && reg.ViolationID != only {
+			continue
+		}
+		outline := annotate.Region{
+			Shape:  reg.Shape,
+			X:      reg.X,
+			Y:      reg.Y,
+			Width:  reg.Width,
+			Height: reg.Height,
+			Color:  annotate.RiskColor(reg.RiskLevel),
+		}
+		for _, p := range reg.Points {
+			outline.Points = append(outline.Points, annotate.Point{X: p[0], Y: p[1]})
+		}
+		outlines = append(outlines, outline)
+	}
+
+	f, err := store.Open(ctx, photo.StorageKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			writeError(w, r, "", apperr.NotFoundError("Photo not found"))
+			return
+		}
+		serverError(w, r, "failed to open photo", err)
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		serverError(w, r, "failed to read photo", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := annotate.Burn(&buf, data, outlines); err != nil {
+		serverError(w, r, "failed to annotate photo", err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(buf.Bytes())
+}
+
+// handleAnalyzePhoto re-runs hazard detection on a photo
+func handleAnalyzePhoto(w http.ResponseWriter, r *http.Request, an *analysis.Analyzer, q *database.Queries) {
+	user := getCurrentUser()
+
+	photo, ok := getPhoto(w, r, q)
+	if !ok {
+		return
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to analyze photos on this project"))
+		return
+	}
+
+	result, err := an.AnalyzePhoto(r.Context(), photo.ID)
+	if err != nil {
+		if errors.Is(err, analysis.ErrUnavailable) {
+			http.Error(w, "Hazard detection is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, analysis.ErrDuplicate) {
+			writeError(w, r, "", apperr.ConflictError("Photo is a duplicate of an earlier photo; mark it as not a duplicate to analyze it"))
+			return
+		}
+		serverError(w, r, "failed to analyze photo", err)
+		return
+	}
+
+	resp := dto.AnalysisResult{
+		PhotoID:    photo.ID.String(),
+		Violations: make([]dto.Violation, 0, len(result.Created)),
+		Skipped:    result.Skipped,
+	}
+	for _, v := range result.Created {
+		resp.Violations = append(resp.Violations, toViolation(v, ""))
+	}
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/webhooks"
+	"github.com/dukerupert/ironman/web/templates"
+)
+
+// handleSafetyReport renders the print-optimized safety report for a project.
+// Validated and resolved violations are included; resolved ones carry their
+// corrective action evidence so the report shows before and after photos.
+func handleSafetyReport(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	data, err := getSafetyReport(r.Context(), q, r.PathValue("id"), getCurrentUser())
+	if err != nil {
+		writeError(w, r, "failed to build safety report", err)
+		return
+	}
+	render(w, r, t, "safety-report", data)
+}
+
+// WriteSafetyReport renders a project's safety report outside a request, as
+// a standalone page whose photos load from the app at baseURL
+func WriteSafetyReport(ctx context.Context, w io.Writer, t *templates.Template, q *database.Queries, projectID, baseURL string, generatedBy dto.User) error {
+	data, err := getSafetyReport(ctx, q, projectID, generatedBy)
+	if err != nil {
+		return err
+	}
+	data.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return t.Render(w, "safety-report", data)
+}
+
+// getSafetyReport gathers the contents of a project's safety report
+func getSafetyReport(ctx context.Context, q *database.Queries, projectID string, user dto.User) (dto.SafetyReportData, error) {
+	project, err := getProjectById(ctx, q, projectID)
+	if err != nil {
+		return dto.SafetyReportData{}, err
+	}
+	violations, err := getViolationsByProject(ctx, q, projectID)
+	if err != nil {
+		return dto.SafetyReportData{}, fmt.Errorf("load violations: %w", err)
+	}
+	id, _ := parseUUID(projectID)
+	resolutions, err := q.ListAcceptedResolutionsByProject(ctx, id)
+	if err != nil {
+		return dto.SafetyReportData{}, fmt.Errorf("load resolutions: %w", err)
+	}
+	byViolation := make(map[string]database.ViolationResolution, len(resolutions))
+	for _, res := range resolutions {
+		byViolation[res.ViolationID.String()] = res
+	}
+
+	data := dto.SafetyReportData{
+		Project:    *project,
+		Violations: []dto.Violation{},
+		Summary: dto.ReportSummary{
+			ComplianceRate: project.ComplianceScore,
+		},
+		CompanyInfo: dto.CompanyInfo{
+			Name:  user.Company,
+			Email: user.Email,
+		},
+		GeneratedAt: time.Now(),
+		GeneratedBy: user,
+	}
+	for _, v := range violations {
+		if v.Status != "validated" && v.Status != "resolved" {
+			continue
+		}
+		if res, ok := byViolation[v.ID]; ok && v.Status == "resolved" {
+			v.Resolution = toResolution(res)
+		}
+		switch v.RiskLevel {
+		case "critical":
+			data.Summary.CriticalCount++
+		case "high":
+			data.Summary.HighCount++
+		case "medium":
+			data.Summary.MediumCount++
+		default:
+			data.Summary.LowCount++
+		}
+		data.Violations = append(data.Violations, v)
+	}
+	data.Summary.OverallAssessment = overallAssessment(data.Violations)
+	return data, nil
+}
+
+// publishReportCompleted queues report.completed for a project that was
+// just marked completed, summarizing its now final safety report
+func publishReportCompleted(ctx context.Context, q *database.Queries, projectID string, user dto.User) error {
+	report, err := getSafetyReport(ctx, q, projectID, user)
+	if err != nil {
+		return fmt.Errorf("load safety report: %w", err)
+	}
+	return webhooks.Publish(ctx, q, webhooks.ReportCompleted, webhooks.Report{
+		ProjectID:         report.Project.ID,
+		ProjectName:       report.Project.Name,
+		Location:          report.Project.Location,
+		ComplianceRate:    report.Summary.ComplianceRate,
+		CriticalCount:     report.Summary.CriticalCount,
+		HighCount:         report.Summary.HighCount,
+		MediumCount:       report.Summary.MediumCount,
+		LowCount:          report.Summary.LowCount,
+		OverallAssessment: report.Summary.OverallAssessment,
+		CompletedBy:       user.Name,
+		CompletedAt:       report.GeneratedAt,
+	})
+}
+
+// overallAssessment summarizes the outstanding risk in a report
+func overallAssessment(violations []dto.Violation) string {
+	open := map[string]int{}
+	total := 0
+	for _, v := range violations {
+		if v.Status != "resolved" {
+			open[v.RiskLevel]++
+			total++
+		}
+	}
+	switch {
+	case len(violations) == 0:
+		return "No safety violations were confirmed during this inspection."
+	case total == 0:
+		return "All confirmed violations have been corrected and verified."
+	case open["critical"] > 0:
+		return fmt.Sprintf("Immediate action required: %d critical violation(s) remain uncorrected.", open["critical"])
+	case open["high"] > 0:
+		return fmt.Sprintf("Prompt corrective action required for %d high-risk violation(s).", open["high"])
+	default:
+		return fmt.Sprintf("%d lower-risk violation(s) remain open and should be corrected at the next opportunity.", total)
+	}
+}
@@ -0,0 +1,512 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// projectSorts are the keys projects can be listed by
+var projectSorts = []string{"date", "name", "violations", "compliance"}
+
+// violationStatuses and riskLevels are the values violations can be
+// filtered by
+var (
+	violationStatuses = []string{"open", "validated", "dismissed", "resolved"}
+	riskLevels        = []string{"low", "medium", "high", "critical"}
+)
+
+// reportTypes are the report types a list can be filtered to. Only
+// inspection reports exist so far; the others always list empty.
+var reportTypes = []string{"inspection", "compliance", "summary"}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// projectCursor is the position in a project list. The sort it was issued
+// for is kept so a cursor isn't reused with a different one.
+type projectCursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d"`
+	Name       string    `json:"n,omitempty"`
+	Number     float64   `json:"v,omitempty"`
+	Time       time.Time `json:"t"`
+	ID         string    `json:"i"`
+}
+
+// validateProject checks the editable fields of a project, returning the
+// field at fault and why, or empty strings if they are fine
+func validateProject(name, description, location, status string) (field, message string) {
+	switch {
+	case name == "":
+		return "name", "Name is required"
+	case len(name) > maxProjectNameLength:
+		return "name", fmt.Sprintf("Name must be at most %d characters", maxProjectNameLength)
+	case len(description) > maxDescriptionLength:
+		return "description", fmt.Sprintf("Description must be at most %d characters", maxDescriptionLength)
+	case len(location) > maxLocationLength:
+		return "location", fmt.Sprintf("Location must be at most %d characters", maxLocationLength)
+	case !slices.Contains(projectStatuses, status):
+		return "status", fmt.Sprintf("Unknown status %q", status)
+	}
+	return "", ""
+}
+
+func handleAPIListProjects(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	query := r.URL.Query()
+	params := database.ListProjectsPageParams{SortBy: "date", Descending: true}
+
+	if s := query.Get("status"); s != "" {
+		if !slices.Contains(projectStatuses, s) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown status %q", s), "status")
+			return
+		}
+		params.Status = database.NullProjectStatus{ProjectStatus: database.ProjectStatus(s), Valid: true}
+	}
+	inspector, ok := parseIDParam(w, r, "inspector")
+	if !ok {
+		return
+	}
+	params.InspectorID = inspector
+	if s := strings.TrimSpace(query.Get("search")); s != "" {
+		params.Search = pgtype.Text{String: likeEscaper.Replace(s), Valid: true}
+	}
+	if s := query.Get("sort_by"); s != "" {
+		if !slices.Contains(projectSorts, s) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown sort_by %q", s), "sort_by")
+			return
+		}
+		params.SortBy = s
+	}
+	switch query.Get("sort_order") {
+	case "", "desc":
+	case "asc":
+		params.Descending = false
+	default:
+		apiError(w, r, http.StatusBadRequest, "invalid_request", `sort_order must be "asc" or "desc"`, "sort_order")
+		return
+	}
+	if params.CreatedFrom, params.CreatedBefore, ok = parseDateRange(w, r); !ok {
+		return
+	}
+
+	var cursor projectCursor
+	page, ok := parsePage(w, r, &cursor)
+	if !ok {
+		return
+	}
+	if page.Cursor != "" {
+		id, err := parseUUID(cursor.ID)
+		if err != nil || cursor.SortBy != params.SortBy || cursor.Descending != params.Descending {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "Cursor was issued for a different sort", "cursor")
+			return
+		}
+		params.AfterID = id
+		params.AfterName = cursor.Name
+		params.AfterNumber = cursor.Number
+		params.AfterTime = pgtype.Timestamptz{Time: cursor.Time, Valid: true}
+	}
+	params.PageLimit = page.fetchLimit()
+
+	rows, err := q.ListProjectsPage(r.Context(), params)
+	if err != nil {
+		apiServerError(w, r, "failed to list projects", err)
+		return
+	}
+	projects := make([]dto.Project, 0, len(rows))
+	for _, row := range rows {
+		projects = append(projects, toProject(database.GetProjectRow(row)))
+	}
+	apiList(w, r, projects, page.Limit, func(i int) any {
+		p := projects[i]
+		c := projectCursor{SortBy: params.SortBy, Descending: params.Descending, Time: p.CreatedAt, ID: p.ID}
+		switch params.SortBy {
+		case "name":
+			c.Name = p.Name
+		case "violations":
+			c.Number = float64(p.ViolationCount)
+		case "compliance":
+			c.Number = p.ComplianceScore
+		}
+		return c
+	})
+}
+
+func handleAPICreateProject(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	user := currentUser(r)
+
+	req, err := decode[dto.ProjectRequest](r)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", "Invalid request body", "")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	location := strings.TrimSpace(req.Location)
+	if req.Status == "" {
+		req.Status = "in-progress"
+	}
+	if field, message := validateProject(name, req.Description, location, req.Status); field != "" {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", message, field)
+		return
+	}
+	if !canUserEditProject(user.ID, "") {
+		apiError(w, r, http.StatusForbidden, "forbidden", "Not permitted to create projects", "")
+		return
+	}
+
+	created, err := q.CreateProject(ctx, database.CreateProjectParams{
+		Name:        name,
+		Description: req.Description,
+		Status:      database.ProjectStatus(req.Status),
+		Location:    location,
+		InspectorID: userUUID(user),
+	})
+	if err != nil {
+		apiServerError(w, r, "failed to create project", err)
+		return
+	}
+	project, err := getProjectById(ctx, q, created.ID.String())
+	if err != nil {
+		apiServerError(w, r, "failed to load project", err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/projects/"+project.ID)
+	apiData(w, r, http.StatusCreated, *project)
+}
+
+func handleAPIGetProject(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	project, err := getProjectById(r.Context(), q, r.PathValue("id"))
+	if err != nil {
+		apiWriteError(w, r, "failed to load project", err)
+		return
+	}
+	apiData(w, r, http.StatusOK, *project)
+}
+
+func handleAPIListPhotos(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+
+	projectID, err := parseUUID(r.PathValue("id"))
+	if err == nil {
+		_, err = q.GetProject(ctx, projectID)
+	}
+	if err != nil {
+		if isNotFound(err) {
+			apiError(w, r, http.StatusNotFound, "not_found", "Project not found", "")
+			return
+		}
+		apiServerError(w, r, "failed to load project", err)
+		return
+	}
+
+	params := database.ListPhotosPageParams{ProjectID: projectID}
+	if s := r.URL.Query().Get("area_type"); s != "" {
+		if !slices.Contains(photoAreaTypes, s) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown area_type %q", s), "area_type")
+			return
+		}
+		params.AreaType = pgtype.Text{String: s, Valid: true}
+	}
+	var ok bool
+	if params.CreatedFrom, params.CreatedBefore, ok = parseDateRange(w, r); !ok {
+		return
+	}
+	var cursor timeCursor
+	page, ok := parsePage(w, r, &cursor)
+	if !ok {
+		return
+	}
+	params.AfterCreatedAt, params.AfterID = cursor.after()
+	params.PageLimit = page.fetchLimit()
+
+	rows, err := q.ListPhotosPage(ctx, params)
+	if err != nil {
+		apiServerError(w, r, "failed to list photos", err)
+		return
+	}
+	photos := make([]dto.Photo, 0, len(rows))
+	for _, row := range rows {
+		photos = append(photos, toPhoto(row.Photo, row.ViolationIds))
+	}
+	apiList(w, r, photos, page.Limit, func(i int) any {
+		return timeCursor{Time: rows[i].Photo.CreatedAt.Time, ID: photos[i].ID}
+	})
+}
+
+func handleAPIGetPhoto(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		apiError(w, r, http.StatusNotFound, "not_found", "Photo not found", "")
+		return
+	}
+	rows, err := q.ListPhotosByIDs(r.Context(), []pgtype.UUID{id})
+	if err != nil {
+		apiServerError(w, r, "failed to load photo", err)
+		return
+	}
+	if len(rows) == 0 {
+		apiError(w, r, http.StatusNotFound, "not_found", "Photo not found", "")
+		return
+	}
+	apiData(w, r, http.StatusOK, toPhoto(rows[0].Photo, rows[0].ViolationIds))
+}
+
+func handleAPIProjectReport(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	data, err := getSafetyReport(r.Context(), q, r.PathValue("id"), currentUser(r))
+	if err != nil {
+		if isNotFound(err) {
+			apiError(w, r, http.StatusNotFound, "not_found", "Project not found", "")
+			return
+		}
+		apiServerError(w, r, "failed to build safety report", err)
+		return
+	}
+	apiData(w, r, http.StatusOK, data)
+}
+
+func handleAPIListViolations(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	query := r.URL.Query()
+	var params database.ListViolationsPageParams
+
+	projectID, ok := parseIDParam(w, r, "project_id")
+	if !ok {
+		return
+	}
+	params.ProjectID = projectID
+	if s := query.Get("status"); s != "" {
+		if !slices.Contains(violationStatuses, s) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown status %q", s), "status")
+			return
+		}
+		params.Status = database.NullViolationStatus{ViolationStatus: database.ViolationStatus(s), Valid: true}
+	}
+	if s := query.Get("risk_level"); s != "" {
+		if !slices.Contains(riskLevels, s) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown risk_level %q", s), "risk_level")
+			return
+		}
+		params.RiskLevel = database.NullRiskLevel{RiskLevel: database.RiskLevel(s), Valid: true}
+	}
+	if s := query.Get("category"); s != "" {
+		params.Category = pgtype.Text{String: s, Valid: true}
+	}
+	if params.FoundFrom, params.FoundBefore, ok = parseDateRange(w, r); !ok {
+		return
+	}
+	var cursor timeCursor
+	page, ok := parsePage(w, r, &cursor)
+	if !ok {
+		return
+	}
+	params.AfterFoundAt, params.AfterID = cursor.after()
+	params.PageLimit = page.fetchLimit()
+
+	rows, err := q.ListViolationsPage(r.Context(), params)
+	if err != nil {
+		apiServerError(w, r, "failed to list violations", err)
+		return
+	}
+	violations := make([]dto.Violation, 0, len(rows))
+	for _, row := range rows {
+		violations = append(violations, toViolation(row.Violation, row.ProjectName))
+	}
+	apiList(w, r, violations, page.Limit, func(i int) any {
+		return timeCursor{Time: violations[i].FoundAt, ID: violations[i].ID}
+	})
+}
+
+func handleAPIGetViolation(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	violation, err := getAPIViolation(r, q, r.PathValue("id"))
+	if err != nil {
+		if isNotFound(err) {
+			apiError(w, r, http.StatusNotFound, "not_found", "Violation not found", "")
+			return
+		}
+		apiServerError(w, r, "failed to load violation", err)
+		return
+	}
+	apiData(w, r, http.StatusOK, violation)
+}
+
+// getAPIViolation loads a violation with its assignee's name
+func getAPIViolation(r *http.Request, q *database.Queries, violationID string) (dto.Violation, error) {
+	id, err := parseUUID(violationID)
+	if err != nil {
+		return dto.Violation{}, err
+	}
+	row, err := q.GetViolation(r.Context(), id)
+	if err != nil {
+		return dto.Violation{}, err
+	}
+	violation := toViolation(row.Violation, row.ProjectName)
+	violation.AssigneeName = row.AssigneeName
+	return violation, nil
+}
+
+func handleAPIViolationStatus(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries) {
+	ctx := r.Context()
+	user := currentUser(r)
+
+	violation, err := getAPIViolation(r, q, r.PathValue("id"))
+	if err != nil {
+		if isNotFound(err) {
+			apiError(w, r, http.StatusNotFound, "not_found", "Violation not found", "")
+			return
+		}
+		apiServerError(w, r, "failed to load violation", err)
+		return
+	}
+	if !canUserEditProject(user.ID, violation.ProjectID) {
+		apiError(w, r, http.StatusForbidden, "forbidden", "Not permitted to review violations on this project", "")
+		return
+	}
+	req, err := decode[dto.ViolationStatusRequest](r)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", "Invalid request body", "")
+		return
+	}
+
+	projectID, _ := parseUUID(violation.ProjectID)
+	resp, err := transitionViolations(ctx, db, q, projectID, user, req.Action, []string{violation.ID})
+	if err != nil {
+		if errors.Is(err, errUnknownAction) {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown action %q", req.Action), "action")
+			return
+		}
+		apiServerError(w, r, "failed to apply violation action", err)
+		return
+	}
+	if result := resp.Results[0]; !result.OK && result.Error != "unchanged" {
+		apiError(w, r, http.StatusConflict, "conflict", result.Message, "action")
+		return
+	}
+
+	if violation, err = getAPIViolation(r, q, violation.ID); err != nil {
+		apiServerError(w, r, "failed to load violation", err)
+		return
+	}
+	apiData(w, r, http.StatusOK, violation)
+}
+
+func handleAPIListReports(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	var params database.ListProjectReportsPageParams
+
+	projectID, ok := parseIDParam(w, r, "project_id")
+	if !ok {
+		return
+	}
+	params.ProjectID = projectID
+	reportType := r.URL.Query().Get("type")
+	if reportType != "" && !slices.Contains(reportTypes, reportType) {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown type %q", reportType), "type")
+		return
+	}
+	if params.UpdatedFrom, params.UpdatedBefore, ok = parseDateRange(w, r); !ok {
+		return
+	}
+	var cursor timeCursor
+	page, ok := parsePage(w, r, &cursor)
+	if !ok {
+		return
+	}
+	if reportType != "" && reportType != "inspection" {
+		apiList(w, r, []dto.Report{}, page.Limit, nil)
+		return
+	}
+	params.AfterUpdatedAt, params.AfterID = cursor.after()
+	params.PageLimit = page.fetchLimit()
+
+	rows, err := q.ListProjectReportsPage(r.Context(), params)
+	if err != nil {
+		apiServerError(w, r, "failed to list reports", err)
+		return
+	}
+	reports := make([]dto.Report, 0, len(rows))
+	for _, row := range rows {
+		reports = append(reports, dto.Report{
+			ID:          row.ID.String(),
+			ProjectID:   row.ID.String(),
+			ProjectName: row.Name,
+			Title:       row.Name + " Safety Report",
+			Type:        "inspection",
+			Status:      "completed",
+			GeneratedAt: row.UpdatedAt.Time,
+			FileURL:     "/app/projects/" + row.ID.String() + "/report",
+		})
+	}
+	apiList(w, r, reports, page.Limit, func(i int) any {
+		return timeCursor{Time: reports[i].GeneratedAt, ID: reports[i].ID}
+	})
+}
+
+func handleAPIListUsers(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	query := r.URL.Query()
+	var params database.ListUsersPageParams
+
+	switch s := query.Get("role"); s {
+	case "":
+	case "admin":
+		params.Role = database.NullUserRole{UserRole: database.UserRoleAdmin, Valid: true}
+	case "inspector":
+		params.Role = database.NullUserRole{UserRole: database.UserRoleUser, Valid: true}
+	default:
+		apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unknown role %q", s), "role")
+		return
+	}
+	if s := query.Get("active"); s != "" {
+		active, err := strconv.ParseBool(s)
+		if err != nil {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "active must be true or false", "active")
+			return
+		}
+		params.IsActive = pgtype.Bool{Bool: active, Valid: true}
+	}
+	var cursor timeCursor
+	page, ok := parsePage(w, r, &cursor)
+	if !ok {
+		return
+	}
+	params.AfterCreatedAt, params.AfterID = cursor.after()
+	params.PageLimit = page.fetchLimit()
+
+	rows, err := q.ListUsersPage(r.Context(), params)
+	if err != nil {
+		apiServerError(w, r, "failed to list users", err)
+		return
+	}
+	users := make([]dto.User, 0, len(rows))
+	for _, u := range rows {
+		users = append(users, toUser(u))
+	}
+	apiList(w, r, users, page.Limit, func(i int) any {
+		return timeCursor{Time: rows[i].CreatedAt.Time, ID: users[i].ID}
+	})
+}
+
+func handleAPIGetUser(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		apiError(w, r, http.StatusNotFound, "not_found", "User not found", "")
+		return
+	}
+	u, err := q.GetUser(r.Context(), id)
+	if err != nil {
+		if isNotFound(err) {
+			apiError(w, r, http.StatusNotFound, "not_found", "User not found", "")
+			return
+		}
+		apiServerError(w, r, "failed to load user", err)
+		return
+	}
+	apiData(w, r, http.StatusOK, toUser(u))
+}
@@ -0,0 +1,217 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/ironman/web/templates"
+)
+
+// csrfHeader carries the CSRF token on requests made by scripts
+const csrfHeader = "X-CSRF-Token"
+
+// csrfTokenLength is the length of an encoded CSRF token
+const csrfTokenLength = 43
+
+// maxCSRFPeek limits how much of a multipart body is read looking for the
+// CSRF token
+const maxCSRFPeek = 8 << 10
+
+// contentSecurityPolicy allows scripts from the app, the CDNs its pages use
+// and inline scripts carrying the request's nonce. Inline styles stay
+// allowed, as the Tailwind and Leaflet scripts add them at runtime.
+func contentSecurityPolicy(nonce string) string {
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self' 'nonce-" + nonce + "' https://cdn.jsdelivr.net https://cdn.tailwindcss.com https://unpkg.com",
+		"style-src 'self' 'unsafe-inline' https://unpkg.com",
+		"img-src 'self' data: blob: https://unpkg.com https://tile.openstreetmap.org",
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
+
+// NewSecurityHeaders sets headers limiting what the app's pages may do in
+// the browser, and gives each request the nonce its inline scripts are
+// allowed by. HSTS is only sent in production, which is served over HTTPS.
+func NewSecurityHeaders(production bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := generateNonce()
+
+			h := w.Header()
+			h.Set("Content-Security-Policy", contentSecurityPolicy(nonce))
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			if production {
+				h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+			}
+
+			ctx := context.WithValue(r.Context(), NonceKey, nonce)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewCSRF rejects unsafe requests that don't carry the token from the
+// CSRF cookie, which pages copy into forms with csrfField and scripts send
+// in the X-CSRF-Token header. Another site can make the browser send the
+// cookie, but can't read it to send the copy. Requests authenticated by a
+// bearer token don't rely on cookies, so aren't checked.
+func NewCSRF(production bool) func(http.Handler) http.Handler {
+	name := csrfCookieName(production)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var token string
+			if c, err := r.Cookie(name); err == nil && validCSRFToken(c.Value) {
+				token = c.Value
+			}
+
+			if !safeMethod(r.Method) {
+				sent := submittedCSRFToken(r)
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(sent)) != 1 {
+					loggerFromRequest(r).Warn("rejected request with invalid CSRF token",
+						"has_cookie", token != "",
+						"has_token", sent != "",
+					)
+					http.Error(w, "This form has expired or was sent from another site. Reload the page and try again.", http.StatusForbidden)
+					return
+				}
+			}
+
+			if token == "" {
+				token = generateCSRFToken()
+				http.SetCookie(w, newCookie(name, token, production))
+			}
+
+			ctx := context.WithValue(r.Context(), CSRFTokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// csrfCookieName names the CSRF cookie. In production the __Host- prefix
+// stops other subdomains from setting it.
+func csrfCookieName(production bool) string {
+	if production {
+		return "__Host-csrf"
+	}
+	return "csrf"
+}
+
+// newCookie returns a cookie with the app's defaults: hidden from scripts,
+// not sent on cross-site requests, and in production only sent over HTTPS
+func newCookie(name, value string, production bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   production,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// safeMethod reports whether requests with method only read, so can't be
+// forged to change anything
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// submittedCSRFToken returns the token sent in the X-CSRF-Token header or,
+// for forms, the csrf_token field
+func submittedCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(csrfHeader); token != "" {
+		return token
+	}
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return r.PostFormValue(templates.CSRFField)
+	case "multipart/form-data":
+		return multipartCSRFToken(r, params["boundary"])
+	}
+	return ""
+}
+
+// multipartCSRFToken reads the token from the first part of a multipart
+// body, where csrfField puts it. The body is restored for the handler,
+// which parses the form within its own size limits.
+func multipartCSRFToken(r *http.Request, boundary string) string {
+	if boundary == "" {
+		return ""
+	}
+	body := r.Body
+	var read bytes.Buffer
+	defer func() {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&read, body), body}
+	}()
+
+	mr := multipart.NewReader(io.TeeReader(io.LimitReader(body, maxCSRFPeek), &read), boundary)
+	part, err := mr.NextPart()
+	if err != nil || part.FormName() != templates.CSRFField {
+		return ""
+	}
+	token, err := io.ReadAll(io.LimitReader(part, csrfTokenLength+1))
+	if err != nil {
+		return ""
+	}
+	return string(token)
+}
+
+// validCSRFToken reports whether a token from a cookie could have been made
+// by generateCSRFToken
+func validCSRFToken(token string) bool {
+	if len(token) != csrfTokenLength {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil
+}
+
+func generateCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func generateNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// templateRequest returns the values pages take from the request, set by
+// NewCSRF and NewSecurityHeaders
+func templateRequest(r *http.Request) templates.Request {
+	token, _ := r.Context().Value(CSRFTokenKey).(string)
+	nonce, _ := r.Context().Value(NonceKey).(string)
+	return templates.Request{CSRFToken: token, Nonce: nonce}
+}
@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/ironman/web/templates"
+)
+
+// field is one form field of a multipart test body
+type field struct {
+	name, value string
+}
+
+// multipartBody encodes fields in order, returning the body and its boundary
+func multipartBody(t *testing.T, fields ...field) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		if err := mw.WriteField(f.name, f.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), mw.Boundary()
+}
+
+func TestMultipartCSRFToken(t *testing.T) {
+	token := generateCSRFToken()
+	tests := []struct {
+		name       string
+		fields     []field
+		noBoundary bool
+		want       string
+	}{
+		{
+			name:   "token first",
+			fields: []field{{templates.CSRFField, token}, {"caption", "north wall"}},
+			want:   token,
+		},
+		{
+			name:   "token only",
+			fields: []field{{templates.CSRFField, token}},
+			want:   token,
+		},
+		{
+			name:   "token after another field",
+			fields: []field{{"caption", "north wall"}, {templates.CSRFField, token}},
+			want:   "",
+		},
+		{
+			name:   "token past the peek limit",
+			fields: []field{{"caption", strings.Repeat("x", maxCSRFPeek)}, {templates.CSRFField, token}},
+			want:   "",
+		},
+		{
+			name:       "no boundary",
+			fields:     []field{{templates.CSRFField, token}},
+			noBoundary: true,
+			want:       "",
+		},
+		{
+			name:   "oversized token stops one byte past the limit",
+			fields: []field{{templates.CSRFField, strings.Repeat("a", 1<<20)}},
+			want:   strings.Repeat("a", csrfTokenLength+1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, boundary := multipartBody(t, tt.fields...)
+			if tt.noBoundary {
+				boundary = ""
+			}
+			r := httptest.NewRequest("POST", "/app/projects/1/photos", bytes.NewReader(body))
+
+			if got := multipartCSRFToken(r, boundary); got != tt.want {
+				t.Errorf("multipartCSRFToken() = %.50q, want %.50q", got, tt.want)
+			}
+			// the handler must still see the whole body
+			rest, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("read restored body: %v", err)
+			}
+			if !bytes.Equal(rest, body) {
+				t.Errorf("restored body is %d bytes, want the original %d", len(rest), len(body))
+			}
+		})
+	}
+}
+
+func TestMultipartCSRFTokenLeavesFormParseable(t *testing.T) {
+	token := generateCSRFToken()
+	body, boundary := multipartBody(t, field{templates.CSRFField, token}, field{"caption", "north wall"})
+	r := httptest.NewRequest("POST", "/app/projects/1/photos", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+
+	if got := multipartCSRFToken(r, boundary); got != token {
+		t.Fatalf("multipartCSRFToken() = %q, want %q", got, token)
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm() error = %v", err)
+	}
+	if got := r.FormValue(templates.CSRFField); got != token {
+		t.Errorf("form token = %q, want %q", got, token)
+	}
+	if got := r.FormValue("caption"); got != "north wall" {
+		t.Errorf("form caption = %q, want %q", got, "north wall")
+	}
+}
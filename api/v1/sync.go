@@ -0,0 +1,611 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxSyncChanges       = 500     // Changes returned by one pull
+	maxSyncPush          = 200     // Changes accepted in one push
+	maxSyncPushBytes     = 4 << 20 // Largest push request body
+	maxProjectNameLength = 200     // Longest project name, in bytes
+	maxDescriptionLength = 5000    // Longest project description, in bytes
+	maxNotesLength       = 5000    // Longest violation notes, in bytes
+)
+
+// initialSyncSnapshot is where a client's first pull starts. It is older
+// than any change the feed keeps, so the pull starts over with every record.
+const initialSyncSnapshot = "1:1:"
+
+// syncEntities are the kinds of record offline clients keep copies of
+var syncEntities = []database.SyncEntity{database.SyncEntityProject, database.SyncEntityPhoto, database.SyncEntityViolation}
+
+// projectStatuses are the statuses a project can be given
+var projectStatuses = []string{"in-progress", "needs-review", "completed", "archived"}
+
+// syncCursor is a client's position in the change feed. Since and Until are
+// database snapshots: a pull returns the changes committed between them,
+// and the next pull starts from Until. A pull split into pages keeps its
+// window and records the last change sent in After.
+//
+// A client whose Since is older than the changes the feed keeps starts over
+// with a Full pull, which pages through every record instead and records
+// the last one sent in AfterEntity and AfterID. The next pull takes the
+// changes committed since the snapshot in Until, taken before the first
+// page.
+type syncCursor struct {
+	Since       string `json:"s,omitempty"`
+	Until       string `json:"u,omitempty"`
+	After       int64  `json:"a,omitempty"`
+	Full        bool   `json:"f,omitempty"`
+	AfterEntity string `json:"e,omitempty"`
+	AfterID     string `json:"i,omitempty"`
+	Project     string `json:"p,omitempty"` // Project the pulls are limited to, empty for all
+}
+
+// syncRecord identifies a record sent by a pull
+type syncRecord struct {
+	Entity database.SyncEntity
+	ID     pgtype.UUID
+}
+
+// String encodes the cursor for the client, which treats it as opaque
+func (c syncCursor) String() string {
+	return encodeCursor(c)
+}
+
+// parseSyncCursor decodes a cursor returned by an earlier pull. An empty
+// cursor starts from the beginning.
+func parseSyncCursor(s string) (syncCursor, error) {
+	c := syncCursor{Since: initialSyncSnapshot}
+	if s == "" {
+		return c, nil
+	}
+	c = syncCursor{}
+	if err := decodeCursor(s, &c); err != nil {
+		return c, err
+	}
+	if c.Until != "" && !validSnapshot(c.Until) {
+		return c, errInvalidCursor
+	}
+	if c.Full {
+		if c.Until == "" || (c.AfterEntity == "") != (c.AfterID == "") {
+			return c, errInvalidCursor
+		}
+		if c.AfterEntity != "" {
+			if !slices.Contains(syncEntities, database.SyncEntity(c.AfterEntity)) {
+				return c, errInvalidCursor
+			}
+			if _, err := parseUUID(c.AfterID); err != nil {
+				return c, errInvalidCursor
+			}
+		}
+		return c, nil
+	}
+	if !validSnapshot(c.Since) || c.After < 0 {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
+
+// validSnapshot reports whether s looks like the text form of a
+// pg_snapshot, "xmin:xmax:xip,xip,...", so a tampered cursor is rejected
+// before it reaches the database
+func validSnapshot(s string) bool {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return false
+	}
+	ids := parts[:2]
+	if parts[2] != "" {
+		ids = append(ids, strings.Split(parts[2], ",")...)
+	}
+	for _, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// handleSyncPull returns the projects, photos and violations that changed
+// since the client's cursor, optionally limited to one project. Clients
+// replace their copy of each record with the one returned. A cursor older
+// than the changes the feed keeps gets a response with reset set, and the
+// pull starts over with every record.
+func handleSyncPull(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	cursor, err := parseSyncCursor(query.Get("cursor"))
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", "Invalid cursor", "cursor")
+		return
+	}
+	var projectID pgtype.UUID
+	if s := query.Get("project_id"); s != "" {
+		if projectID, err = parseUUID(s); err != nil {
+			apiError(w, r, http.StatusBadRequest, "invalid_request", "project_id must be a UUID", "project_id")
+			return
+		}
+	}
+	if query.Get("cursor") == "" {
+		cursor.Project = query.Get("project_id")
+	}
+	if cursor.Project != query.Get("project_id") {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", "Cursor was issued for a different project_id", "cursor")
+		return
+	}
+
+	// a new window may reach back past changes that have been pruned
+	reset := false
+	if cursor.Until == "" {
+		expired, err := q.SyncSnapshotExpired(ctx, cursor.Since)
+		if err != nil {
+			apiServerError(w, r, "failed to check cursor", err)
+			return
+		}
+		if expired {
+			cursor = syncCursor{Full: true, Project: cursor.Project}
+			reset = true
+		}
+		if cursor.Until, err = q.GetSyncSnapshot(ctx); err != nil {
+			apiServerError(w, r, "failed to take snapshot", err)
+			return
+		}
+	}
+
+	var records []syncRecord
+	var next syncCursor
+	if cursor.Full {
+		records, next, err = listSyncRecords(ctx, q, cursor, projectID)
+	} else {
+		records, next, err = listSyncChanges(ctx, q, cursor, projectID)
+	}
+	if err != nil {
+		apiServerError(w, r, "failed to list changes", err)
+		return
+	}
+
+	resp, err := loadSyncChanges(ctx, q, records)
+	if err != nil {
+		apiServerError(w, r, "failed to load changed records", err)
+		return
+	}
+	resp.Cursor = next.String()
+	resp.HasMore = next.Until != ""
+	resp.Reset = reset
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// listSyncChanges returns a page of the records changed in the cursor's
+// window, and the cursor to pull from next
+func listSyncChanges(ctx context.Context, q *database.Queries, cursor syncCursor, projectID pgtype.UUID) ([]syncRecord, syncCursor, error) {
+	changes, err := q.ListSyncChanges(ctx, database.ListSyncChangesParams{
+		Since:      cursor.Since,
+		Until:      cursor.Until,
+		AfterSeq:   cursor.After,
+		ProjectID:  projectID,
+		MaxChanges: maxSyncChanges + 1,
+	})
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	next := syncCursor{Since: cursor.Until, Project: cursor.Project}
+	if len(changes) > maxSyncChanges {
+		changes = changes[:maxSyncChanges]
+		next = syncCursor{Since: cursor.Since, Until: cursor.Until, After: changes[len(changes)-1].Seq, Project: cursor.Project}
+	}
+	records := make([]syncRecord, len(changes))
+	for i, c := range changes {
+		records[i] = syncRecord{Entity: c.Entity, ID: c.EntityID}
+	}
+	return records, next, nil
+}
+
+// listSyncRecords returns a page of every record for a full pull, and the
+// cursor to pull from next
+func listSyncRecords(ctx context.Context, q *database.Queries, cursor syncCursor, projectID pgtype.UUID) ([]syncRecord, syncCursor, error) {
+	params := database.ListSyncRecordsParams{
+		ProjectID:  projectID,
+		MaxRecords: maxSyncChanges + 1,
+	}
+	if cursor.AfterEntity != "" {
+		params.AfterEntity = database.NullSyncEntity{SyncEntity: database.SyncEntity(cursor.AfterEntity), Valid: true}
+		params.AfterID, _ = parseUUID(cursor.AfterID)
+	}
+	rows, err := q.ListSyncRecords(ctx, params)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	next := syncCursor{Since: cursor.Until, Project: cursor.Project}
+	if len(rows) > maxSyncChanges {
+		rows = rows[:maxSyncChanges]
+		last := rows[len(rows)-1]
+		next = syncCursor{Until: cursor.Until, Full: true, AfterEntity: string(last.Entity), AfterID: last.EntityID.String(), Project: cursor.Project}
+	}
+	records := make([]syncRecord, len(rows))
+	for i, row := range rows {
+		records[i] = syncRecord{Entity: row.Entity, ID: row.EntityID}
+	}
+	return records, next, nil
+}
+
+// loadSyncChanges returns the current version of each changed record. A
+// record that no longer exists is reported as deleted, whatever the change
+// was, so clients never keep a copy of something that is gone.
+func loadSyncChanges(ctx context.Context, q *database.Queries, changes []syncRecord) (dto.SyncPullResponse, error) {
+	resp := dto.SyncPullResponse{
+		Projects:   []dto.Project{},
+		Photos:     []dto.Photo{},
+		Violations: []dto.Violation{},
+		Deleted:    []dto.SyncDeleted{},
+	}
+
+	ids := make(map[database.SyncEntity][]pgtype.UUID)
+	seen := make(map[string]bool, len(changes))
+	for _, c := range changes {
+		key := string(c.Entity) + ":" + c.ID.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ids[c.Entity] = append(ids[c.Entity], c.ID)
+	}
+	found := make(map[string]bool, len(seen))
+
+	if len(ids[database.SyncEntityProject]) > 0 {
+		rows, err := q.ListProjectsByIDs(ctx, ids[database.SyncEntityProject])
+		if err != nil {
+			return resp, fmt.Errorf("load projects: %w", err)
+		}
+		for _, row := range rows {
+			resp.Projects = append(resp.Projects, toProject(database.GetProjectRow(row)))
+			found["project:"+row.Project.ID.String()] = true
+		}
+	}
+	if len(ids[database.SyncEntityPhoto]) > 0 {
+		rows, err := q.ListPhotosByIDs(ctx, ids[database.SyncEntityPhoto])
+		if err != nil {
+			return resp, fmt.Errorf("load photos: %w", err)
+		}
+		for _, row := range rows {
+			resp.Photos = append(resp.Photos, toPhoto(row.Photo, row.ViolationIds))
+			found["photo:"+row.Photo.ID.String()] = true
+		}
+	}
+	if len(ids[database.SyncEntityViolation]) > 0 {
+		rows, err := q.ListViolationsByIDs(ctx, ids[database.SyncEntityViolation])
+		if err != nil {
+			return resp, fmt.Errorf("load violations: %w", err)
+		}
+		for _, row := range rows {
+			resp.Violations = append(resp.Violations, toViolation(row.Violation, row.ProjectName))
+			found["violation:"+row.Violation.ID.String()] = true
+		}
+	}
+
+	for _, entity := range syncEntities {
+		for _, id := range ids[entity] {
+			if !found[string(entity)+":"+id.String()] {
+				resp.Deleted = append(resp.Deleted, dto.SyncDeleted{Entity: string(entity), ID: id.String()})
+			}
+		}
+	}
+	return resp, nil
+}
+
+// handleSyncPush applies changes a client queued while offline. Each change
+// gets its own result: an edit made to a version the server has since
+// changed is a conflict and is not applied, and the server's version is
+// returned so the client can reconcile. Replaying a push whose response was
+// lost reports the changes as applied again. A bearer token without the
+// write scope for a kind of change gets those changes rejected.
+func handleSyncPush(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries) {
+	ctx := r.Context()
+	user := currentUser(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSyncPushBytes)
+	req, err := decode[dto.SyncPushRequest](r)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", "Invalid request body", "")
+		return
+	}
+	total := len(req.Projects) + len(req.Photos) + len(req.ViolationNotes)
+	if total > maxSyncPush {
+		apiError(w, r, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Push at most %d changes at a time", maxSyncPush), "")
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		apiServerError(w, r, "failed to begin transaction", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	resp := dto.SyncPushResponse{Results: make([]dto.SyncResult, 0, total)}
+	for _, c := range req.Projects {
+		if !hasScope(r, tokens.WriteProjects) {
+			result := dto.SyncResult{Entity: string(database.SyncEntityProject), ID: c.ID}
+			resp.Results = append(resp.Results, rejected(result, "not_permitted", "Token lacks the "+tokens.WriteProjects+" scope"))
+			continue
+		}
+		result, err := pushProject(ctx, qtx, user, c)
+		if err != nil {
+			apiServerError(w, r, "failed to apply project change", err)
+			return
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	for _, c := range req.Photos {
+		if !hasScope(r, tokens.WritePhotos) {
+			result := dto.SyncResult{Entity: string(database.SyncEntityPhoto), ID: c.ID}
+			resp.Results = append(resp.Results, rejected(result, "not_permitted", "Token lacks the "+tokens.WritePhotos+" scope"))
+			continue
+		}
+		result, err := pushPhoto(ctx, qtx, user, c)
+		if err != nil {
+			apiServerError(w, r, "failed to apply photo change", err)
+			return
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	for _, c := range req.ViolationNotes {
+		if !hasScope(r, tokens.WriteViolations) {
+			result := dto.SyncResult{Entity: string(database.SyncEntityViolation), ID: c.ID}
+			resp.Results = append(resp.Results, rejected(result, "not_permitted", "Token lacks the "+tokens.WriteViolations+" scope"))
+			continue
+		}
+		result, err := pushViolationNotes(ctx, qtx, user, c)
+		if err != nil {
+			apiServerError(w, r, "failed to apply violation notes", err)
+			return
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		apiServerError(w, r, "failed to commit push", err)
+		return
+	}
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// pushProject creates or updates a project. Creates use the client's ID, so
+// a create that already went through is not repeated; it is reported as
+// applied only if the stored project is the one the client created.
+func pushProject(ctx context.Context, qtx *database.Queries, user dto.User, c dto.SyncProjectChange) (dto.SyncResult, error) {
+	result := dto.SyncResult{Entity: string(database.SyncEntityProject), ID: c.ID}
+	id, err := parseUUID(c.ID)
+	if err != nil {
+		return rejected(result, "invalid", "id must be a UUID"), nil
+	}
+	result.ID = id.String()
+
+	name := strings.TrimSpace(c.Name)
+	location := strings.TrimSpace(c.Location)
+	status := c.Status
+	if status == "" && c.Op == "create" {
+		status = "in-progress"
+	}
+	switch {
+	case c.Op != "create" && c.Op != "update":
+		return rejected(result, "invalid", `op must be "create" or "update"`), nil
+	case c.Op == "update" && c.BaseUpdatedAt == nil:
+		return rejected(result, "invalid", "base_updated_at is required for updates"), nil
+	case !canUserEditProject(user.ID, result.ID):
+		return rejected(result, "not_permitted", "Not permitted to edit this project"), nil
+	}
+	if _, message := validateProject(name, c.Description, location, status); message != "" {
+		return rejected(result, "invalid", message), nil
+	}
+
+	conflict := false
+	if c.Op == "create" {
+		_, err := qtx.CreateProjectWithID(ctx, database.CreateProjectWithIDParams{
+			ID:          id,
+			Name:        name,
+			Description: c.Description,
+			Status:      database.ProjectStatus(status),
+			Location:    location,
+			InspectorID: userUUID(user),
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			conflict = true
+		case err != nil:
+			return result, fmt.Errorf("create project: %w", err)
+		}
+	} else {
+		before, err := qtx.GetProject(ctx, id)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return result, fmt.Errorf("load project: %w", err)
+		}
+		updated, err := qtx.UpdateProjectDetails(ctx, database.UpdateProjectDetailsParams{
+			ID:            id,
+			BaseUpdatedAt: pgtype.Timestamptz{Time: *c.BaseUpdatedAt, Valid: true},
+			Name:          name,
+			Description:   c.Description,
+			Status:        database.ProjectStatus(status),
+			Location:      location,
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			conflict = true
+		case err != nil:
+			return result, fmt.Errorf("update project: %w", err)
+		case updated.Status == database.ProjectStatusCompleted && before.Project.Status != database.ProjectStatusCompleted:
+			if err := publishReportCompleted(ctx, qtx, id.String(), user); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	rows, err := qtx.ListProjectsByIDs(ctx, []pgtype.UUID{id})
+	if err != nil {
+		return result, fmt.Errorf("load project: %w", err)
+	}
+	if len(rows) == 0 {
+		return rejected(result, "not_found", "Project not found"), nil
+	}
+	project := toProject(database.GetProjectRow(rows[0]))
+	result.Project = &project
+	result.Status = "applied"
+	// a replayed create or update finds the project already as the client
+	// wants it
+	if conflict && (project.Name != name || project.Description != c.Description ||
+		project.Location != location || project.Status != status) {
+		result.Status = "conflict"
+		result.Message = "Project was changed on the server since it was pulled"
+		if c.Op == "create" {
+			result.Message = "A different project already has this id"
+		}
+	}
+	return result, nil
+}
+
+// pushPhoto updates a photo's caption and area type
+func pushPhoto(ctx context.Context, qtx *database.Queries, user dto.User, c dto.SyncPhotoChange) (dto.SyncResult, error) {
+	result := dto.SyncResult{Entity: string(database.SyncEntityPhoto), ID: c.ID}
+	id, err := parseUUID(c.ID)
+	if err != nil {
+		return rejected(result, "invalid", "id must be a UUID"), nil
+	}
+	result.ID = id.String()
+
+	caption := strings.TrimSpace(c.Caption)
+	areaType := c.AreaType
+	if areaType == "" {
+		areaType = "general"
+	}
+	switch {
+	case len(caption) > maxCaptionLength:
+		return rejected(result, "invalid", fmt.Sprintf("Caption must be at most %d characters", maxCaptionLength)), nil
+	case !slices.Contains(photoAreaTypes, areaType):
+		return rejected(result, "invalid", "Unknown area type"), nil
+	case c.BaseUpdatedAt.IsZero():
+		return rejected(result, "invalid", "base_updated_at is required"), nil
+	}
+
+	photo, err := qtx.GetPhoto(ctx, id)
+	if err != nil {
+		if isNotFound(err) {
+			return rejected(result, "not_found", "Photo not found"), nil
+		}
+		return result, fmt.Errorf("load photo: %w", err)
+	}
+	if !canUserEditProject(user.ID, photo.ProjectID.String()) {
+		return rejected(result, "not_permitted", "Not permitted to edit photos on this project"), nil
+	}
+
+	result.Status = "applied"
+	_, err = qtx.UpdatePhotoDetails(ctx, database.UpdatePhotoDetailsParams{
+		ID:            id,
+		BaseUpdatedAt: pgtype.Timestamptz{Time: c.BaseUpdatedAt, Valid: true},
+		Caption:       caption,
+		AreaType:      areaType,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if photo.Caption != caption || photo.AreaType != areaType {
+			result.Status = "conflict"
+			result.Message = "Photo was changed on the server since it was pulled"
+		}
+	} else if err != nil {
+		return result, fmt.Errorf("update photo: %w", err)
+	}
+
+	rows, err := qtx.ListPhotosByIDs(ctx, []pgtype.UUID{id})
+	if err != nil {
+		return result, fmt.Errorf("load photo: %w", err)
+	}
+	if len(rows) == 0 {
+		return rejected(result, "not_found", "Photo not found"), nil
+	}
+	current := toPhoto(rows[0].Photo, rows[0].ViolationIds)
+	result.Photo = &current
+	return result, nil
+}
+
+// pushViolationNotes replaces a violation's notes
+func pushViolationNotes(ctx context.Context, qtx *database.Queries, user dto.User, c dto.SyncViolationNotes) (dto.SyncResult, error) {
+	result := dto.SyncResult{Entity: string(database.SyncEntityViolation), ID: c.ID}
+	id, err := parseUUID(c.ID)
+	if err != nil {
+		return rejected(result, "invalid", "id must be a UUID"), nil
+	}
+	result.ID = id.String()
+
+	notes := strings.TrimSpace(c.Notes)
+	switch {
+	case len(notes) > maxNotesLength:
+		return rejected(result, "invalid", fmt.Sprintf("Notes must be at most %d characters", maxNotesLength)), nil
+	case c.BaseUpdatedAt.IsZero():
+		return rejected(result, "invalid", "base_updated_at is required"), nil
+	}
+
+	row, err := qtx.GetViolation(ctx, id)
+	if err != nil {
+		if isNotFound(err) {
+			return rejected(result, "not_found", "Violation not found"), nil
+		}
+		return result, fmt.Errorf("load violation: %w", err)
+	}
+	if !canUserEditProject(user.ID, row.Violation.ProjectID.String()) {
+		return rejected(result, "not_permitted", "Not permitted to edit violations on this project"), nil
+	}
+
+	result.Status = "applied"
+	_, err = qtx.UpdateViolationNotes(ctx, database.UpdateViolationNotesParams{
+		ID:            id,
+		BaseUpdatedAt: pgtype.Timestamptz{Time: c.BaseUpdatedAt, Valid: true},
+		Notes:         notes,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if row.Violation.Notes != notes {
+			result.Status = "conflict"
+			result.Message = "Violation was changed on the server since it was pulled"
+		}
+	} else if err != nil {
+		return result, fmt.Errorf("update violation notes: %w", err)
+	}
+
+	rows, err := qtx.ListViolationsByIDs(ctx, []pgtype.UUID{id})
+	if err != nil {
+		return result, fmt.Errorf("load violation: %w", err)
+	}
+	if len(rows) == 0 {
+		return rejected(result, "not_found", "Violation not found"), nil
+	}
+	current := toViolation(rows[0].Violation, rows[0].ProjectName)
+	result.Violation = &current
+	return result, nil
+}
+
+// rejected marks a pushed change as refused without being applied
+func rejected(result dto.SyncResult, code, message string) dto.SyncResult {
+	result.Status = "rejected"
+	result.Error = code
+	result.Message = message
+	return result
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestValidSnapshot(t *testing.T) {
+	tests := []struct {
+		snapshot string
+		want     bool
+	}{
+		{"1:1:", true},
+		{"742:742:", true},
+		{"742:750:745", true},
+		{"742:750:745,746,749", true},
+		{"18446744073709551615:18446744073709551615:", true},
+		{"", false},
+		{"742", false},
+		{"742:750", false},
+		{"742:750:745:746", false},
+		{":750:", false},
+		{"742::", false},
+		{"742:750:745,", false},
+		{"742:750:,745", false},
+		{"-1:750:", false},
+		{"742:750:7a5", false},
+		{"742:750:745 ", false},
+		{"18446744073709551616:1:", false},
+		{"1:1:'; DROP TABLE sync_changes; --", false},
+	}
+	for _, tt := range tests {
+		if got := validSnapshot(tt.snapshot); got != tt.want {
+			t.Errorf("validSnapshot(%q) = %v, want %v", tt.snapshot, got, tt.want)
+		}
+	}
+}
+
+func TestParseSyncCursor(t *testing.T) {
+	const projectID = "0b9a7c5e-3f1d-4d2a-9c8b-6e5f4a3b2c1d"
+	tests := []struct {
+		name    string
+		cursor  string
+		want    syncCursor
+		wantErr bool
+	}{
+		{
+			name:   "first pull",
+			cursor: "",
+			want:   syncCursor{Since: initialSyncSnapshot},
+		},
+		{
+			name:   "next window",
+			cursor: syncCursor{Since: "742:750:745", Project: projectID}.String(),
+			want:   syncCursor{Since: "742:750:745", Project: projectID},
+		},
+		{
+			name:   "next page",
+			cursor: syncCursor{Since: "742:742:", Until: "800:802:801", After: 1500}.String(),
+			want:   syncCursor{Since: "742:742:", Until: "800:802:801", After: 1500},
+		},
+		{
+			name:   "next page of a full pull",
+			cursor: syncCursor{Until: "800:802:801", Full: true, AfterEntity: "photo", AfterID: projectID}.String(),
+			want:   syncCursor{Until: "800:802:801", Full: true, AfterEntity: "photo", AfterID: projectID},
+		},
+		{
+			name:    "not base64",
+			cursor:  "not a cursor!",
+			wantErr: true,
+		},
+		{
+			name:    "tampered since",
+			cursor:  syncCursor{Since: "1:1:0) OR (1=1"}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "tampered until",
+			cursor:  syncCursor{Since: "742:742:", Until: "now"}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "negative after",
+			cursor:  syncCursor{Since: "742:742:", Until: "800:802:", After: -1}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "full pull without snapshot",
+			cursor:  syncCursor{Full: true, AfterEntity: "photo", AfterID: projectID}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "full pull with unknown entity",
+			cursor:  syncCursor{Until: "800:802:", Full: true, AfterEntity: "user", AfterID: projectID}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "full pull with invalid id",
+			cursor:  syncCursor{Until: "800:802:", Full: true, AfterEntity: "photo", AfterID: "42"}.String(),
+			wantErr: true,
+		},
+		{
+			name:    "full pull with entity but no id",
+			cursor:  syncCursor{Until: "800:802:", Full: true, AfterEntity: "photo"}.String(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSyncCursor(tt.cursor)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidCursor) {
+					t.Fatalf("parseSyncCursor() error = %v, want %v", err, errInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSyncCursor() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseSyncCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushProjectCreate(t *testing.T) {
+	const projectID = "0b9a7c5e-3f1d-4d2a-9c8b-6e5f4a3b2c1d"
+	id, _ := parseUUID(projectID)
+	change := dto.SyncProjectChange{
+		Op:          "create",
+		ID:          projectID,
+		Name:        "Harbor Point",
+		Description: "Tower crane inspection",
+		Location:    "Seattle, WA",
+	}
+	stored := func(name string) database.ListProjectsByIDsRow {
+		return database.ListProjectsByIDsRow{Project: database.Project{
+			ID:          id,
+			Name:        name,
+			Description: change.Description,
+			Status:      database.ProjectStatusInProgress,
+			Location:    change.Location,
+		}}
+	}
+	tests := []struct {
+		name        string
+		exists      bool
+		storedName  string
+		wantStatus  string
+		wantMessage string
+	}{
+		{name: "new project", storedName: change.Name, wantStatus: "applied"},
+		{name: "replayed create", exists: true, storedName: change.Name, wantStatus: "applied"},
+		{
+			name:        "id taken by a different project",
+			exists:      true,
+			storedName:  "Pier 91",
+			wantStatus:  "conflict",
+			wantMessage: "A different project already has this id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.exists {
+				db.on("CreateProjectWithID", func([]any) ([]any, error) { return nil, pgx.ErrNoRows })
+			} else {
+				db.returns("CreateProjectWithID", stored(tt.storedName).Project)
+			}
+			db.returns("ListProjectsByIDs", stored(tt.storedName))
+
+			result, err := pushProject(context.Background(), database.New(db), getCurrentUser(), change)
+			if err != nil {
+				t.Fatalf("pushProject() error = %v", err)
+			}
+			if result.Status != tt.wantStatus || result.Message != tt.wantMessage {
+				t.Errorf("pushProject() = %q %q, want %q %q", result.Status, result.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if result.Project == nil || result.Project.Name != tt.storedName {
+				t.Errorf("pushProject() project = %+v, want the stored one named %q", result.Project, tt.storedName)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// principalKey holds the apiPrincipal of a request made with a bearer token
+const principalKey contextKey = "principal"
+
+// maxTokenNameLength matches api_tokens.name
+const maxTokenNameLength = 100
+
+// Lifetimes offered for new tokens, in days; 0 never expires
+var tokenExpiryDays = []int{30, 90, 365, 0}
+
+// errInactiveOwner is returned for personal tokens whose owner was
+// deactivated or has no users row
+var errInactiveOwner = errors.New("token owner is inactive")
+
+// apiPrincipal is who a bearer token acts as
+type apiPrincipal struct {
+	User  dto.User
+	Token database.ApiToken
+}
+
+// requireToken authenticates requests that carry an Authorization header
+// and checks that the token was granted every scope in scopes. Requests
+// without the header fall through to the browser session.
+func requireToken(tm *tokens.Manager, q *database.Queries, scopes []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next(w, r)
+			return
+		}
+
+		secret, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			unauthorized(w, r, "Authorization must be a bearer token")
+			return
+		}
+		token, err := tm.Authenticate(r.Context(), strings.TrimSpace(secret))
+		if err != nil {
+			if errors.Is(err, tokens.ErrInvalid) {
+				unauthorized(w, r, "Token is invalid, expired or revoked")
+				return
+			}
+			apiServerError(w, r, "failed to authenticate token", err)
+			return
+		}
+
+		principal, err := tokenPrincipal(r.Context(), q, token)
+		if err != nil {
+			if errors.Is(err, errInactiveOwner) || isNotFound(err) {
+				unauthorized(w, r, "Token owner is no longer active")
+				return
+			}
+			apiServerError(w, r, "failed to load token owner", err)
+			return
+		}
+
+		for _, scope := range scopes {
+			if !tokens.HasScope(token, scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+				apiError(w, r, http.StatusForbidden, "insufficient_scope", "Token lacks the "+scope+" scope", "")
+				return
+			}
+		}
+
+		loggerFromRequest(r).Debug("authenticated api token", "token_id", token.ID.String(), "kind", token.Kind)
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey, principal)))
+	}
+}
+
+// tokenPrincipal resolves who a token acts as. Personal tokens act as
+// their owner, who must still be active; service tokens act as themselves.
+func tokenPrincipal(ctx context.Context, q *database.Queries, token database.ApiToken) (apiPrincipal, error) {
+	p := apiPrincipal{Token: token}
+	switch {
+	case token.Kind == database.ApiTokenKindService:
+		p.User = dto.User{
+			ID:       token.ID.String(),
+			Name:     token.Name,
+			Initials: initialLetter(token.Name),
+			Role:     "service",
+		}
+	case !token.UserID.Valid:
+		// a personal token that names no owner can't be checked, so it
+		// isn't allowed to act as anyone
+		return p, errInactiveOwner
+	default:
+		u, err := q.GetUser(ctx, token.UserID)
+		if err != nil {
+			return p, err
+		}
+		if !u.IsActive {
+			return p, errInactiveOwner
+		}
+		p.User = toUser(u)
+	}
+	return p, nil
+}
+
+// unauthorized writes a JSON 401 asking for a bearer token
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	apiError(w, r, http.StatusUnauthorized, "unauthorized", message, "")
+}
+
+// currentUser returns who a JSON API request acts as: the owner of its
+// bearer token, or the signed-in user
+func currentUser(r *http.Request) dto.User {
+	if p, ok := r.Context().Value(principalKey).(apiPrincipal); ok {
+		return p.User
+	}
+	return getCurrentUser()
+}
+
+// hasScope reports whether the request may act within scope. Session
+// requests aren't limited by scopes.
+func hasScope(r *http.Request, scope string) bool {
+	if p, ok := r.Context().Value(principalKey).(apiPrincipal); ok {
+		return tokens.HasScope(p.Token, scope)
+	}
+	return true
+}
+
+func handleTokens(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, tm *tokens.Manager) {
+	renderTokens(w, r, t, q, tm, nil)
+}
+
+func handleCreateToken(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, tm *tokens.Manager) {
+	ctx := r.Context()
+	user, err := getSessionUser(ctx, q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, "", &apperr.Error{Kind: apperr.Validation, Message: "Invalid form", Err: err})
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		writeError(w, r, "", apperr.ValidationError("name", "Name is required"))
+		return
+	}
+	if len(name) > maxTokenNameLength {
+		writeError(w, r, "", apperr.ValidationError("name", fmt.Sprintf("Name must be at most %d characters", maxTokenNameLength)))
+		return
+	}
+
+	kind := database.ApiTokenKind(r.FormValue("kind"))
+	switch kind {
+	case "", database.ApiTokenKindPersonal:
+		kind = database.ApiTokenKindPersonal
+	case database.ApiTokenKindService:
+		if user.Role != "admin" {
+			writeError(w, r, "", apperr.ForbiddenError("Only admins can create service tokens"))
+			return
+		}
+	default:
+		writeError(w, r, "", apperr.ValidationError("kind", "Unknown token kind"))
+		return
+	}
+
+	scopes := r.Form["scope"]
+	if len(scopes) == 0 {
+		writeError(w, r, "", apperr.ValidationError("scope", "Choose at least one scope"))
+		return
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(tokens.Scopes, scope) {
+			writeError(w, r, "", apperr.ValidationError("scope", "Unknown scope"))
+			return
+		}
+	}
+
+	days, err := strconv.Atoi(r.FormValue("expires_in"))
+	if err != nil || !slices.Contains(tokenExpiryDays, days) {
+		writeError(w, r, "", apperr.ValidationError("expires_in", "Unknown expiry"))
+		return
+	}
+
+	params := database.CreateAPITokenParams{
+		Kind:          kind,
+		Name:          name,
+		Scopes:        scopes,
+		CreatedBy:     userUUID(user),
+		CreatedByName: user.Name,
+	}
+	if kind == database.ApiTokenKindPersonal {
+		params.UserID = userUUID(user)
+	}
+	if days > 0 {
+		params.ExpiresAt = pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, days), Valid: true}
+	}
+
+	token, secret, err := tm.Create(ctx, params)
+	if err != nil {
+		serverError(w, r, "failed to create api token", err)
+		return
+	}
+	loggerFromRequest(r).Info("api token created", "token_id", token.ID.String(), "kind", token.Kind, "scopes", token.Scopes)
+
+	// the secret is only ever shown in this response
+	created := toAPIToken(token, user)
+	created.Secret = secret
+	renderTokens(w, r, t, q, tm, &created)
+}
+
+func handleRevokeToken(w http.ResponseWriter, r *http.Request, q *database.Queries, tm *tokens.Manager) {
+	ctx := r.Context()
+	user, err := getSessionUser(ctx, q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return
+	}
+
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Token not found"))
+		return
+	}
+	token, err := tm.Get(ctx, id)
+	if errors.Is(err, tokens.ErrNotFound) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Token not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to load api token", err)
+		return
+	}
+	if !canRevokeToken(token, user) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to revoke this token"))
+		return
+	}
+
+	if err := tm.Revoke(ctx, token.ID); err != nil {
+		serverError(w, r, "failed to revoke api token", err)
+		return
+	}
+	loggerFromRequest(r).Info("api token revoked", "token_id", token.ID.String())
+	http.Redirect(w, r, "/app/settings/tokens", http.StatusSeeOther)
+}
+
+// renderTokens renders the API tokens page, with created shown at the top
+// if a token was just issued
+func renderTokens(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, tm *tokens.Manager, created *dto.APIToken) {
+	ctx := r.Context()
+	user, err := getSessionUser(ctx, q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return
+	}
+
+	rows, err := tm.List(ctx, userUUID(user))
+	if err != nil {
+		serverError(w, r, "failed to load api tokens", err)
+		return
+	}
+	list := make([]dto.APIToken, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, toAPIToken(row, user))
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.TokensData{
+		AppData: dto.AppData{
+			PageTitle:      "API tokens",
+			CurrentPage:    "settings",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Tokens:   list,
+		Scopes:   tokens.Scopes,
+		CanAdmin: user.Role == "admin",
+		NewToken: created,
+	}
+	render(w, r, t, "tokens", data)
+}
+
+// canRevokeToken reports whether user may revoke token: their own personal
+// tokens, or any token if they are an admin
+func canRevokeToken(token database.ApiToken, user dto.User) bool {
+	if user.Role == "admin" {
+		return true
+	}
+	return token.Kind == database.ApiTokenKindPersonal && token.UserID == userUUID(user)
+}
+
+// toAPIToken converts an api_tokens row into its page representation
+func toAPIToken(token database.ApiToken, user dto.User) dto.APIToken {
+	t := dto.APIToken{
+		ID:            token.ID.String(),
+		Kind:          string(token.Kind),
+		Name:          token.Name,
+		Prefix:        token.TokenPrefix,
+		Scopes:        token.Scopes,
+		CreatedByName: token.CreatedByName,
+		CreatedAt:     token.CreatedAt.Time,
+		Revoked:       token.RevokedAt.Valid,
+		CanRevoke:     !token.RevokedAt.Valid && canRevokeToken(token, user),
+	}
+	if token.LastUsedAt.Valid {
+		lastUsed := token.LastUsedAt.Time
+		t.LastUsedAt = &lastUsed
+	}
+	if token.ExpiresAt.Valid {
+		expires := token.ExpiresAt.Time
+		t.ExpiresAt = &expires
+		t.Expired = !expires.After(time.Now())
+	}
+	return t
+}
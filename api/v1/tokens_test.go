@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// tokenSecret finds a newly issued token on the tokens page
+var tokenSecret = regexp.MustCompile(`ssi_(pat|svc)_[A-Z2-7]+`)
+
+// tokenStore keeps the api_tokens rows a test creates
+type tokenStore struct {
+	tokens []database.ApiToken
+}
+
+// answerTokens answers the token queries from store
+func answerTokens(db *fakeDB, store *tokenStore) {
+	db.on("CreateAPIToken", func(args []any) ([]any, error) {
+		token := database.ApiToken{
+			ID:            pgtype.UUID{Bytes: [16]byte{byte(len(store.tokens) + 1)}, Valid: true},
+			Kind:          args[0].(database.ApiTokenKind),
+			Name:          args[1].(string),
+			TokenHash:     args[2].([]byte),
+			TokenPrefix:   args[3].(string),
+			Scopes:        args[4].([]string),
+			UserID:        args[5].(pgtype.UUID),
+			CreatedBy:     args[6].(pgtype.UUID),
+			CreatedByName: args[7].(string),
+			ExpiresAt:     args[8].(pgtype.Timestamptz),
+		}
+		store.tokens = append(store.tokens, token)
+		return []any{token}, nil
+	})
+	db.on("GetActiveAPITokenByHash", func(args []any) ([]any, error) {
+		for _, token := range store.tokens {
+			if bytes.Equal(token.TokenHash, args[0].([]byte)) {
+				return []any{token}, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// sessionUserRow is the users row of the signed-in user
+func sessionUserRow(role database.UserRole, active bool) database.User {
+	return database.User{
+		ID:        pgtype.UUID{Bytes: [16]byte{0xaa, 1}, Valid: true},
+		Email:     getCurrentUser().Email,
+		FirstName: pgtype.Text{String: "John", Valid: true},
+		LastName:  pgtype.Text{String: "Doe", Valid: true},
+		IsActive:  active,
+		Role:      role,
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tmpl, err := templates.NewTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		role     database.UserRole
+		kind     string
+		wantUser func(owner database.User) dto.User
+	}{
+		{
+			name: "personal token acts as its owner",
+			role: database.UserRoleUser,
+			kind: "personal",
+			wantUser: func(owner database.User) dto.User {
+				return toUser(owner)
+			},
+		},
+		{
+			name: "service token acts as itself",
+			role: database.UserRoleAdmin,
+			kind: "service",
+			wantUser: func(database.User) dto.User {
+				return dto.User{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}.String(), Name: "CI", Initials: "C", Role: "service"}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := sessionUserRow(tt.role, true)
+			db := newPageDB()
+			db.returns("GetUserByEmail", owner)
+			db.returns("GetUser", owner)
+			store := &tokenStore{}
+			answerTokens(db, store)
+			q := database.New(db)
+			tm := tokens.New(db)
+
+			form := url.Values{"name": {"CI"}, "kind": {tt.kind}, "scope": {tokens.ReadProjects}, "expires_in": {"30"}}
+			req := httptest.NewRequest("POST", "/app/settings/tokens", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handleCreateToken(rec, req, tmpl, q, tm)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("create token status = %d, body %s", rec.Code, rec.Body)
+			}
+			if len(store.tokens) != 1 {
+				t.Fatalf("created %d tokens, want 1", len(store.tokens))
+			}
+			if created := store.tokens[0]; tt.kind == "personal" && created.UserID != owner.ID {
+				t.Errorf("personal token owner = %v, want %v", created.UserID, owner.ID)
+			}
+			secret := tokenSecret.FindString(rec.Body.String())
+			if secret == "" {
+				t.Fatal("tokens page doesn't show the new token")
+			}
+
+			var got dto.User
+			next := func(w http.ResponseWriter, r *http.Request) {
+				got = currentUser(r)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			req = httptest.NewRequest("GET", "/api/v1/projects", nil)
+			req.Header.Set("Authorization", "Bearer "+secret)
+			rec = httptest.NewRecorder()
+			requireToken(tm, q, []string{tokens.ReadProjects}, next)(rec, req)
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("authenticated request status = %d, body %s", rec.Code, rec.Body)
+			}
+			if want := tt.wantUser(owner); got != want {
+				t.Errorf("request acted as %+v, want %+v", got, want)
+			}
+
+			req = httptest.NewRequest("GET", "/api/v1/users", nil)
+			req.Header.Set("Authorization", "Bearer "+secret)
+			rec = httptest.NewRecorder()
+			requireToken(tm, q, []string{tokens.ReadUsers}, next)(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("request outside the token's scopes status = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestCreateTokenRefused(t *testing.T) {
+	tmpl, err := templates.NewTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		hasRow bool
+		user   database.User
+		kind   string
+	}{
+		{name: "no users row", kind: "personal"},
+		{name: "deactivated user", hasRow: true, user: sessionUserRow(database.UserRoleUser, false), kind: "personal"},
+		{name: "service token by a non-admin", hasRow: true, user: sessionUserRow(database.UserRoleUser, true), kind: "service"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newPageDB()
+			if tt.hasRow {
+				db.returns("GetUserByEmail", tt.user)
+			}
+			form := url.Values{"name": {"CI"}, "kind": {tt.kind}, "scope": {tokens.ReadProjects}, "expires_in": {"30"}}
+			req := httptest.NewRequest("POST", "/app/settings/tokens", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handleCreateToken(rec, req, tmpl, database.New(db), tokens.New(db))
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+			if db.called("CreateAPIToken") {
+				t.Error("token was created")
+			}
+		})
+	}
+}
+
+func TestTokenOfInactiveOwner(t *testing.T) {
+	owner := sessionUserRow(database.UserRoleUser, false)
+	tests := []struct {
+		name  string
+		token database.ApiToken
+	}{
+		{"owner deactivated", database.ApiToken{Kind: database.ApiTokenKindPersonal, UserID: owner.ID}},
+		{"no owner", database.ApiToken{Kind: database.ApiTokenKindPersonal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.returns("GetUser", owner)
+			_, err := tokenPrincipal(t.Context(), database.New(db), tt.token)
+			if !errors.Is(err, errInactiveOwner) {
+				t.Errorf("tokenPrincipal() error = %v, want %v", err, errInactiveOwner)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/uploads"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Resumable uploads follow the tus 1.0 protocol (https://tus.io) with the
+// creation, termination, checksum and expiration extensions, so an upload
+// interrupted by a dropped connection continues from the last byte received
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,checksum,expiration"
+	tusChecksums  = "sha1,sha256"
+	tusChunkType  = "application/offset+octet-stream"
+
+	// uploadChunkSize is the chunk size the web client uses, small enough
+	// that a chunk lost on a weak connection is cheap to resend
+	uploadChunkSize = 1 << 20
+
+	// statusChecksumMismatch is the tus response for a chunk whose
+	// Upload-Checksum doesn't match its body
+	statusChecksumMismatch = 460
+)
+
+// uploadURL returns the path an upload is continued at
+func uploadURL(u database.Upload) string {
+	return "/app/uploads/" + u.ID.String()
+}
+
+// tusRequest sets the protocol headers on the response and rejects clients
+// speaking another version of tus
+func tusRequest(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// setUploadHeaders describes an upload's progress on the response
+func setUploadHeaders(w http.ResponseWriter, u database.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.UploadOffset, 10))
+	if !u.CompletedAt.Valid {
+		w.Header().Set("Upload-Expires", u.ExpiresAt.Time.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma separated
+// pairs of a key and a base64 encoded value
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for pair := range strings.SplitSeq(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q is not base64 encoded", key)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// uploadChecksum is a parsed Upload-Checksum header
+type uploadChecksum struct {
+	hash hash.Hash
+	sum  []byte
+}
+
+// parseUploadChecksum reads an Upload-Checksum header, returning nil when
+// the client didn't send one
+func parseUploadChecksum(header string) (*uploadChecksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(header, " ")
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("checksum is not base64 encoded")
+	}
+	switch algorithm {
+	case "sha1":
+		return &uploadChecksum{hash: sha1.New(), sum: sum}, nil
+	case "sha256":
+		return &uploadChecksum{hash: sha256.New(), sum: sum}, nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+func (c *uploadChecksum) matches(data []byte) bool {
+	c.hash.Write(data)
+	return bytes.Equal(c.hash.Sum(nil), c.sum)
+}
+
+// getUpload loads the upload named in the path, writing an error response
+// if it is missing or the user can't add photos to its project
+func getUpload(w http.ResponseWriter, r *http.Request, up *uploads.Manager) (database.Upload, bool) {
+	user := getCurrentUser()
+
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Upload not found"))
+		return database.Upload{}, false
+	}
+	u, err := up.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, uploads.ErrNotFound) {
+			writeError(w, r, "", apperr.NotFoundError("Upload not found"))
+			return u, false
+		}
+		serverError(w, r, "failed to load upload", err)
+		return u, false
+	}
+	if !canUserEditProject(user.ID, u.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to add photos to this project"))
+		return u, false
+	}
+	return u, true
+}
+
+// handleUploadOptions advertises the supported protocol
+func handleUploadOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Checksum-Algorithm", tusChecksums)
+	w.Header().Set("Tus-Max-Size", strconv.Itoa(maxPhotoSize))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateUpload starts a resumable upload of one inspection photo. The
+// filename, area_type and caption come from Upload-Metadata, along with an
+// optional id for clients that named the photo while offline.
+func handleCreateUpload(w http.ResponseWriter, r *http.Request, q *database.Queries, up *uploads.Manager) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	if !tusRequest(w, r) {
+		return
+	}
+
+	projectID := r.PathValue("id")
+	if _, err := getProjectById(ctx, q, projectID); err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	// getProjectById has already checked the id parses
+	id, _ := parseUUID(projectID)
+	if !canUserEditProject(user.ID, projectID) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to add photos to this project"))
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		writeError(w, r, "", apperr.ValidationError("Upload-Length", "Upload-Length must be a positive number of bytes"))
+		return
+	}
+	if length > maxPhotoSize {
+		http.Error(w, errPhotoTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	meta, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("Upload-Metadata", err.Error()))
+		return
+	}
+	areaType := meta["area_type"]
+	if areaType == "" {
+		areaType = "general"
+	}
+	if !slices.Contains(photoAreaTypes, areaType) {
+		writeError(w, r, "", apperr.ValidationError("area_type", "Unknown area type"))
+		return
+	}
+	caption := strings.TrimSpace(meta["caption"])
+	if len(caption) > maxCaptionLength {
+		writeError(w, r, "", apperr.ValidationError("caption", fmt.Sprintf("Caption must be at most %d characters", maxCaptionLength)))
+		return
+	}
+	var photoID pgtype.UUID
+	if s := meta["id"]; s != "" {
+		if photoID, err = parseUUID(s); err != nil {
+			writeError(w, r, "", apperr.ValidationError("id", "Photo id must be a UUID"))
+			return
+		}
+		// a client replaying its queue after a lost response must not
+		// upload the photo twice
+		if _, err := q.GetPhoto(ctx, photoID); err == nil {
+			writeError(w, r, "", apperr.ConflictError("Photo has already been uploaded"))
+			return
+		} else if !isNotFound(err) {
+			serverError(w, r, "failed to load photo", err)
+			return
+		}
+	}
+
+	u, err := up.Create(ctx, database.CreateUploadParams{
+		ProjectID:        id,
+		UploadLength:     length,
+		Filename:         clip(meta["filename"], 255),
+		AreaType:         areaType,
+		Caption:          caption,
+		CreatedBy:        userUUID(user),
+		CreatedByName:    user.Name,
+		RequestedPhotoID: photoID,
+	})
+	if err != nil {
+		serverError(w, r, "failed to create upload", err)
+		return
+	}
+	w.Header().Set("Location", uploadURL(u))
+	w.Header().Set("Upload-Expires", u.ExpiresAt.Time.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleUploadStatus reports how much of an upload the server has, which is
+// where the client resumes from
+func handleUploadStatus(w http.ResponseWriter, r *http.Request, up *uploads.Manager) {
+	if !tusRequest(w, r) {
+		return
+	}
+	u, ok := getUpload(w, r, up)
+	if !ok {
+		return
+	}
+	setUploadHeaders(w, u)
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.UploadLength, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUploadChunk receives the bytes of an upload starting at
+// Upload-Offset. The chunk that completes the upload creates the photo.
+func handleUploadChunk(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries, store blob.Store, up *uploads.Manager, an *analysis.Analyzer) {
+	ctx := r.Context()
+	logger := loggerFromRequest(r)
+	if !tusRequest(w, r) {
+		return
+	}
+	u, ok := getUpload(w, r, up)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != tusChunkType {
+		http.Error(w, "Content-Type must be "+tusChunkType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, r, "", apperr.ValidationError("Upload-Offset", "Upload-Offset must be a number of bytes"))
+		return
+	}
+	if offset != u.UploadOffset || u.CompletedAt.Valid {
+		setUploadHeaders(w, u)
+		writeError(w, r, "", apperr.ConflictError("Upload-Offset does not match the upload"))
+		return
+	}
+	checksum, err := parseUploadChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("Upload-Checksum", err.Error()))
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, u.UploadLength-offset))
+	if err != nil {
+		var tooLong *http.MaxBytesError
+		if errors.As(err, &tooLong) {
+			http.Error(w, "Chunk runs past the end of the upload", http.StatusRequestEntityTooLarge)
+			return
+		}
+		// the connection dropped: keep what arrived so the client resumes
+		// from there rather than resending the whole chunk. Without a
+		// checksum for the partial data there's nothing to verify.
+		if checksum == nil && len(data) > 0 && offset+int64(len(data)) < u.UploadLength {
+			if _, err := up.Append(context.WithoutCancel(ctx), u, offset, data); err != nil {
+				logger.Warn("failed to keep partial chunk", "upload_id", u.ID.String(), "error", err)
+			}
+		}
+		return
+	}
+	if checksum != nil && !checksum.matches(data) {
+		http.Error(w, "Checksum mismatch", statusChecksumMismatch)
+		return
+	}
+
+	if offset+int64(len(data)) < u.UploadLength {
+		u, err = up.Append(ctx, u, offset, data)
+		if err != nil {
+			if errors.Is(err, uploads.ErrConflict) {
+				writeError(w, r, "", apperr.ConflictError("Upload-Offset does not match the upload"))
+				return
+			}
+			serverError(w, r, "failed to store chunk", err)
+			return
+		}
+		setUploadHeaders(w, u)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	u, err = completeUpload(ctx, logger, db, q, store, up, an, u, data)
+	if err != nil {
+		switch {
+		case isPhotoError(err):
+			http.Error(w, fmt.Sprintf("%s: %s", u.Filename, err), http.StatusUnprocessableEntity)
+		case errors.Is(err, uploads.ErrConflict):
+			writeError(w, r, "", apperr.ConflictError("Upload-Offset does not match the upload"))
+		default:
+			serverError(w, r, "failed to complete upload", err)
+		}
+		return
+	}
+	setUploadHeaders(w, u)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeUpload turns a finished upload into an inspection photo. Nothing is
+// recorded unless the photo is created, so a failed attempt can be retried
+// by resending the final chunk.
+func completeUpload(ctx context.Context, logger *slog.Logger, db *pgxpool.Pool, q *database.Queries, store blob.Store, up *uploads.Manager, an *analysis.Analyzer, u database.Upload, final []byte) (database.Upload, error) {
+	user := getCurrentUser()
+
+	data, err := up.Assemble(ctx, u, final)
+	if err != nil {
+		return u, err
+	}
+	photo, err := readPhotoFile(bytes.NewReader(data), u.Filename)
+	if err != nil {
+		return u, err
+	}
+	stored, err := putPhoto(ctx, store, u.ProjectID, photo)
+	if err != nil {
+		return u, fmt.Errorf("store photo: %w", err)
+	}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := deletePhoto(context.WithoutCancel(ctx), store, stored); err != nil {
+			logger.Warn("failed to delete orphaned photo", "key", stored.Key, "error", err)
+		}
+	}()
+
+	params := photoParams(u.ProjectID, photo, stored, database.PhotoPurposeInspection, user)
+	params.ID = u.RequestedPhotoID
+	params.Caption = u.Caption
+	params.AreaType = u.AreaType
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return u, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	batch, err := recordPhotos(ctx, qtx, u.ProjectID, []database.CreatePhotoParams{params}, user)
+	if err != nil {
+		return u, err
+	}
+	completed, err := up.Complete(ctx, qtx, u, batch.Created[0])
+	if err != nil {
+		return u, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return u, fmt.Errorf("commit upload: %w", err)
+	}
+	committed = true
+
+	if err := up.Discard(context.WithoutCancel(ctx), u.ID); err != nil {
+		logger.Warn("failed to discard upload chunks", "upload_id", u.ID.String(), "error", err)
+	}
+	enqueuePhotos(ctx, logger, an, batch.ToAnalyze)
+	return completed, nil
+}
+
+// handleTerminateUpload abandons an upload and frees its chunks
+func handleTerminateUpload(w http.ResponseWriter, r *http.Request, up *uploads.Manager) {
+	if !tusRequest(w, r) {
+		return
+	}
+	u, ok := getUpload(w, r, up)
+	if !ok {
+		return
+	}
+	if err := up.Terminate(r.Context(), u.ID); err != nil {
+		serverError(w, r, "failed to delete upload", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
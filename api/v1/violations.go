@@ -0,0 +1,773 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/notify"
+	"github.com/dukerupert/ironman/internal/regulations"
+	"github.com/dukerupert/ironman/internal/webhooks"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxBulkViolations caps how many violations a single bulk request may touch
+const maxBulkViolations = 500
+
+// violationAction describes a review transition applied from the project page
+type violationAction struct {
+	to          database.ViolationStatus
+	from        []database.ViolationStatus
+	eventType   string
+	description string
+}
+
+var violationActions = map[string]violationAction{
+	"validate": {
+		to:          database.ViolationStatusValidated,
+		from:        []database.ViolationStatus{database.ViolationStatusOpen},
+		eventType:   "violation_validated",
+		description: "Safety violation validated by",
+	},
+	"dismiss": {
+		to:          database.ViolationStatusDismissed,
+		from:        []database.ViolationStatus{database.ViolationStatusOpen, database.ViolationStatusValidated},
+		eventType:   "violation_dismissed",
+		description: "Safety violation dismissed by",
+	},
+	"reopen": {
+		to:          database.ViolationStatusOpen,
+		from:        []database.ViolationStatus{database.ViolationStatusValidated, database.ViolationStatusDismissed},
+		eventType:   "violation_reopened",
+		description: "Safety violation reopened by",
+	},
+}
+
+// allows reports whether the action may be applied to a violation in status s
+func (a violationAction) allows(s database.ViolationStatus) bool {
+	for _, from := range a.from {
+		if from == s {
+			return true
+		}
+	}
+	return false
+}
+
+var errUnknownAction = errors.New("unknown violation action")
+
+// handleBulkViolations applies one review action to many violations of a
+// project. Violations that cannot transition are reported per item and do
+// not fail the request.
+func handleBulkViolations(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	if _, err := getProjectById(ctx, q, r.PathValue("id")); err != nil {
+		writeError(w, r, "failed to load project", err)
+		return
+	}
+	// getProjectById has already checked the id parses
+	projectID, _ := parseUUID(r.PathValue("id"))
+	if !canUserEditProject(user.ID, projectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to review violations on this project"))
+		return
+	}
+
+	req, err := decode[dto.BulkViolationRequest](r)
+	if err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Invalid request body"))
+		return
+	}
+	if len(req.ViolationIDs) == 0 || len(req.ViolationIDs) > maxBulkViolations {
+		writeError(w, r, "", apperr.ValidationError("violation_ids", fmt.Sprintf("Between 1 and %d violation IDs are required", maxBulkViolations)))
+		return
+	}
+
+	resp, err := transitionViolations(ctx, db, q, projectID, user, req.Action, req.ViolationIDs)
+	if err != nil {
+		if errors.Is(err, errUnknownAction) {
+			writeError(w, r, "", apperr.ValidationError("action", fmt.Sprintf("Unknown action %q", req.Action)))
+			return
+		}
+		serverError(w, r, "failed to apply bulk violation action", err)
+		return
+	}
+
+	if err := encode(w, http.StatusOK, resp); err != nil {
+		loggerFromRequest(r).Error("failed to write response", "error", err)
+	}
+}
+
+// transitionViolations applies a review action to violations of a project in
+// a single transaction, locking the rows and recording a timeline event for
+// every violation that changes status. The response has one result per
+// distinct requested ID, in request order.
+func transitionViolations(ctx context.Context, db *pgxpool.Pool, q *database.Queries, projectID pgtype.UUID, user dto.User, actionName string, rawIDs []string) (dto.BulkViolationResponse, error) {
+	resp := dto.BulkViolationResponse{Action: actionName}
+	action, ok := violationActions[actionName]
+	if !ok {
+		return resp, errUnknownAction
+	}
+
+	// Parse and de-duplicate IDs while preserving request order for results.
+	// IDs that fail to parse keep their raw form and are reported as not found.
+	var requested []string
+	var ids []pgtype.UUID
+	seen := make(map[string]bool, len(rawIDs))
+	for _, raw := range rawIDs {
+		key := raw
+		id, err := parseUUID(raw)
+		if err == nil {
+			key = id.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		requested = append(requested, key)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return resp, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	rows, err := qtx.ListViolationsForUpdate(ctx, database.ListViolationsForUpdateParams{
+		ProjectID: projectID,
+		Ids:       ids,
+	})
+	if err != nil {
+		return resp, fmt.Errorf("lock violations: %w", err)
+	}
+	current := make(map[string]database.Violation, len(rows))
+	for _, v := range rows {
+		current[v.ID.String()] = v
+	}
+
+	var changed []database.Violation
+	for _, raw := range requested {
+		v, ok := current[raw]
+		switch {
+		case !ok:
+			resp.Results = append(resp.Results, dto.BulkViolationResult{
+				ID:      raw,
+				Error:   "not_found",
+				Message: "violation does not exist in this project",
+			})
+		case v.Status == database.ViolationStatusResolved:
+			resp.Results = append(resp.Results, dto.BulkViolationResult{
+				ID:      raw,
+				Status:  string(v.Status),
+				Error:   "already_resolved",
+				Message: "violation has already been resolved",
+			})
+		case v.Status == action.to:
+			resp.Results = append(resp.Results, dto.BulkViolationResult{
+				ID:      raw,
+				Status:  string(v.Status),
+				Error:   "unchanged",
+				Message: fmt.Sprintf("violation is already %s", v.Status),
+			})
+		case !action.allows(v.Status):
+			resp.Results = append(resp.Results, dto.BulkViolationResult{
+				ID:      raw,
+				Status:  string(v.Status),
+				Error:   "not_permitted",
+				Message: fmt.Sprintf("cannot %s a %s violation", actionName, v.Status),
+			})
+		default:
+			changed = append(changed, v)
+			resp.Results = append(resp.Results, dto.BulkViolationResult{
+				ID:     raw,
+				OK:     true,
+				Status: string(action.to),
+			})
+		}
+	}
+
+	if len(changed) > 0 {
+		changedIDs := make([]pgtype.UUID, 0, len(changed))
+		for _, v := range changed {
+			changedIDs = append(changedIDs, v.ID)
+		}
+		if err := qtx.UpdateViolationsStatus(ctx, database.UpdateViolationsStatusParams{
+			Status: action.to,
+			Ids:    changedIDs,
+		}); err != nil {
+			return resp, fmt.Errorf("update violations: %w", err)
+		}
+
+		for _, v := range changed {
+			metadata, err := json.Marshal(map[string]interface{}{
+				"violation_id": v.ID.String(),
+				"from":         v.Status,
+				"to":           action.to,
+				"bulk":         len(changed) > 1,
+			})
+			if err != nil {
+				return resp, fmt.Errorf("encode timeline metadata: %w", err)
+			}
+			if _, err := qtx.CreateTimelineEvent(ctx, database.CreateTimelineEventParams{
+				ProjectID:   projectID,
+				ViolationID: v.ID,
+				Type:        action.eventType,
+				Description: action.description,
+				UserID:      userUUID(user),
+				UserName:    user.Name,
+				Metadata:    metadata,
+			}); err != nil {
+				return resp, fmt.Errorf("record timeline event: %w", err)
+			}
+
+			data := webhooks.ViolationData(v)
+			data.PreviousStatus = string(v.Status)
+			data.Status = string(action.to)
+			data.ChangedBy = user.Name
+			if err := webhooks.Publish(ctx, qtx, webhooks.ViolationStatusChanged, data); err != nil {
+				return resp, err
+			}
+			if err := notify.StatusChanged(ctx, qtx, v, action.to, notifyActor(user)); err != nil {
+				return resp, err
+			}
+		}
+
+		if err := qtx.TouchProject(ctx, projectID); err != nil {
+			return resp, fmt.Errorf("touch project: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return resp, fmt.Errorf("commit: %w", err)
+	}
+
+	resp.Updated = len(changed)
+	return resp, nil
+}
+
+// maxCommentLength caps the size of a single violation comment
+const maxCommentLength = 5000
+
+func handleViolationDetail(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries, det detector.Detector) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	row, err := getViolationInProject(ctx, q, r.PathValue("projectId"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "failed to load violation", err)
+		return
+	}
+	violation := toViolation(row.Violation, row.ProjectName)
+	violation.AssigneeName = row.AssigneeName
+
+	resolution, err := q.GetLatestViolationResolution(ctx, row.Violation.ID)
+	switch {
+	case err == nil:
+		violation.Resolution = toResolution(resolution)
+	case !errors.Is(err, pgx.ErrNoRows):
+		serverError(w, r, "failed to load resolution", err)
+		return
+	}
+
+	regions, err := q.ListRegionsByViolation(ctx, row.Violation.ID)
+	if err != nil {
+		serverError(w, r, "failed to load regions", err)
+		return
+	}
+	comments, err := q.ListViolationComments(ctx, row.Violation.ID)
+	if err != nil {
+		serverError(w, r, "failed to load comments", err)
+		return
+	}
+	events, err := q.ListTimelineEventsByViolation(ctx, row.Violation.ID)
+	if err != nil {
+		serverError(w, r, "failed to load violation history", err)
+		return
+	}
+	users, err := q.ListActiveUsers(ctx)
+	if err != nil {
+		serverError(w, r, "failed to load assignees", err)
+		return
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.ViolationDetailData{
+		AppData: dto.AppData{
+			PageTitle:      violation.Description,
+			CurrentPage:    "projects",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Violation: violation,
+		Overdue:   isOverdue(violation),
+		CanEdit:   canUserEditProject(user.ID, violation.ProjectID),
+		CanVerify: det != nil,
+	}
+	reg, _ := regulations.Lookup(violation.Regulation)
+	data.Regulation = dto.Regulation{
+		Number:  reg.Number,
+		Title:   reg.Title,
+		Summary: reg.Summary,
+		URL:     reg.URL,
+	}
+	for _, reg := range regions {
+		if reg.PhotoID == row.Violation.PhotoID {
+			data.Regions = append(data.Regions, toRegion(reg, violation.RiskLevel, violation.Description))
+		}
+	}
+	for _, c := range comments {
+		data.Comments = append(data.Comments, dto.Comment{
+			ID:          c.ID.String(),
+			ViolationID: c.ViolationID.String(),
+			UserID:      c.UserID.String(),
+			UserName:    c.UserName,
+			Body:        c.Body,
+			CreatedAt:   c.CreatedAt.Time,
+		})
+	}
+	for _, e := range events {
+		data.History = append(data.History, toTimelineEvent(e))
+	}
+	for _, u := range users {
+		data.Assignees = append(data.Assignees, toUser(u))
+	}
+
+	render(w, r, t, "violation-detail", data)
+}
+
+// handleCreateViolationComment adds a comment to a violation and notifies
+// the users it mentions
+func handleCreateViolationComment(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	row, err := getViolationInProject(ctx, q, r.PathValue("projectId"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "failed to load violation", err)
+		return
+	}
+	if !canUserEditProject(user.ID, row.Violation.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to comment on violations on this project"))
+		return
+	}
+
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		writeError(w, r, "", apperr.ValidationError("body", "Comment cannot be empty"))
+		return
+	}
+	if len(body) > maxCommentLength {
+		writeError(w, r, "", apperr.ValidationError("body", fmt.Sprintf("Comment must be at most %d characters", maxCommentLength)))
+		return
+	}
+
+	if _, err := q.CreateViolationComment(ctx, database.CreateViolationCommentParams{
+		ViolationID: row.Violation.ID,
+		UserID:      userUUID(user),
+		UserName:    user.Name,
+		Body:        body,
+	}); err != nil {
+		serverError(w, r, "failed to create comment", err)
+		return
+	}
+	// the comment is saved either way, so a failed notification is only logged
+	if err := notify.Mentioned(ctx, q, row.Violation, body, notifyActor(user)); err != nil {
+		loggerFromRequest(r).Error("failed to notify mentioned users", "error", err)
+	}
+
+	http.Redirect(w, r, violationURL(row.Violation)+"#comments", http.StatusSeeOther)
+}
+
+// handleUpdateViolationAssignment sets who is responsible for correcting a
+// violation and by when. Empty form values clear the field.
+func handleUpdateViolationAssignment(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	row, err := getViolationInProject(ctx, q, r.PathValue("projectId"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "failed to load violation", err)
+		return
+	}
+	if !canUserEditProject(user.ID, row.Violation.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to assign violations on this project"))
+		return
+	}
+
+	params := database.UpdateViolationAssignmentParams{
+		ID:                    row.Violation.ID,
+		AssignedSubcontractor: strings.TrimSpace(r.FormValue("subcontractor")),
+	}
+	if len(params.AssignedSubcontractor) > 200 {
+		writeError(w, r, "", apperr.ValidationError("subcontractor", "Subcontractor name must be at most 200 characters"))
+		return
+	}
+
+	assigneeName := ""
+	if raw := r.FormValue("assignee_id"); raw != "" {
+		id, err := parseUUID(raw)
+		if err != nil {
+			writeError(w, r, "", apperr.ValidationError("assignee_id", "Unknown assignee"))
+			return
+		}
+		assignee, err := q.GetUser(ctx, id)
+		if err != nil || !assignee.IsActive {
+			if err == nil || isNotFound(err) {
+				writeError(w, r, "", apperr.ValidationError("assignee_id", "Unknown assignee"))
+				return
+			}
+			serverError(w, r, "failed to load assignee", err)
+			return
+		}
+		params.AssignedUserID = assignee.ID
+		assigneeName = toUser(assignee).Name
+	}
+
+	if raw := r.FormValue("due_date"); raw != "" {
+		due, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			writeError(w, r, "", apperr.ValidationError("due_date", "Due date must be in YYYY-MM-DD format"))
+			return
+		}
+		params.DueDate = pgtype.Date{Time: due, Valid: true}
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"violation_id":  row.Violation.ID.String(),
+		"assignee_id":   params.AssignedUserID.String(),
+		"assignee_name": assigneeName,
+		"subcontractor": params.AssignedSubcontractor,
+		"due_date":      r.FormValue("due_date"),
+	})
+	if err != nil {
+		serverError(w, r, "failed to encode timeline metadata", err)
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		serverError(w, r, "failed to begin transaction", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	if err := qtx.UpdateViolationAssignment(ctx, params); err != nil {
+		serverError(w, r, "failed to update assignment", err)
+		return
+	}
+	if _, err := qtx.CreateTimelineEvent(ctx, database.CreateTimelineEventParams{
+		ProjectID:   row.Violation.ProjectID,
+		ViolationID: row.Violation.ID,
+		Type:        "violation_assigned",
+		Description: "Corrective action assigned by",
+		UserID:      userUUID(user),
+		UserName:    user.Name,
+		Metadata:    metadata,
+	}); err != nil {
+		serverError(w, r, "failed to record timeline event", err)
+		return
+	}
+	if params.AssignedUserID != row.Violation.AssignedUserID {
+		if err := notify.Assigned(ctx, qtx, row.Violation, params.AssignedUserID, notifyActor(user)); err != nil {
+			serverError(w, r, "failed to notify assignee", err)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		serverError(w, r, "failed to commit assignment", err)
+		return
+	}
+
+	http.Redirect(w, r, violationURL(row.Violation), http.StatusSeeOther)
+}
+
+// handleViolationStatus applies a single review action submitted from the
+// violation detail page
+func handleViolationStatus(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries) {
+	ctx := r.Context()
+	user := getCurrentUser()
+
+	row, err := getViolationInProject(ctx, q, r.PathValue("projectId"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "failed to load violation", err)
+		return
+	}
+	if !canUserEditProject(user.ID, row.Violation.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to review violations on this project"))
+		return
+	}
+
+	action := r.FormValue("action")
+	resp, err := transitionViolations(ctx, db, q, row.Violation.ProjectID, user, action, []string{row.Violation.ID.String()})
+	if err != nil {
+		if errors.Is(err, errUnknownAction) {
+			writeError(w, r, "", apperr.ValidationError("action", fmt.Sprintf("Unknown action %q", action)))
+			return
+		}
+		serverError(w, r, "failed to apply violation action", err)
+		return
+	}
+	if result := resp.Results[0]; !result.OK && result.Error != "unchanged" {
+		writeError(w, r, "", apperr.ConflictError(result.Message))
+		return
+	}
+
+	http.Redirect(w, r, violationURL(row.Violation), http.StatusSeeOther)
+}
+
+// getViolationInProject loads a violation by ID, treating violations that
+// belong to a different project as not found. Not found errors have a
+// message users can be shown.
+func getViolationInProject(ctx context.Context, q *database.Queries, projectID, violationID string) (database.GetViolationRow, error) {
+	pid, err := parseUUID(projectID)
+	if err != nil {
+		return database.GetViolationRow{}, violationNotFound(err)
+	}
+	vid, err := parseUUID(violationID)
+	if err != nil {
+		return database.GetViolationRow{}, violationNotFound(err)
+	}
+	row, err := q.GetViolation(ctx, vid)
+	if isNotFound(err) {
+		return row, violationNotFound(err)
+	}
+	if err != nil {
+		return row, err
+	}
+	if row.Violation.ProjectID != pid {
+		return row, violationNotFound(pgx.ErrNoRows)
+	}
+	return row, nil
+}
+
+// violationNotFound wraps err as a not found error for a violation
+func violationNotFound(err error) error {
+	return &apperr.Error{Kind: apperr.NotFound, Message: "Violation not found", Err: err}
+}
+
+// violationURL returns the detail page path for a violation
+func violationURL(v database.Violation) string {
+	return fmt.Sprintf("/app/projects/%s/violations/%s", v.ProjectID.String(), v.ID.String())
+}
+
+// isOverdue reports whether a violation's corrective action is past due
+func isOverdue(v dto.Violation) bool {
+	if v.DueDate == nil || v.Status == "resolved" || v.Status == "dismissed" {
+		return false
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return v.DueDate.Before(today)
+}
+
+// verifyTimeout bounds how long a resolution waits on hazard detection
+const verifyTimeout = 60 * time.Second
+
+// handleResolveViolation resolves a validated violation. Resolution requires
+// an "after" photo and a note describing the corrective action; when
+// requested, hazard detection is re-run on the photo and a submission in
+// which the hazard is still detected is recorded but not accepted.
+func handleResolveViolation(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, q *database.Queries, store blob.Store, det detector.Detector) {
+	ctx := r.Context()
+	user := getCurrentUser()
+	logger := loggerFromRequest(r)
+
+	row, err := getViolationInProject(ctx, q, r.PathValue("projectId"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "failed to load violation", err)
+		return
+	}
+	v := row.Violation
+	if !canUserEditProject(user.ID, v.ProjectID.String()) {
+		writeError(w, r, "", apperr.ForbiddenError("Not permitted to resolve violations on this project"))
+		return
+	}
+	if v.Status != database.ViolationStatusValidated {
+		writeError(w, r, "", apperr.ConflictError("Only validated violations can be resolved"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoSize+1<<20)
+	if err := r.ParseMultipartForm(maxPhotoSize); err != nil {
+		writeError(w, r, "", apperr.ValidationError("", "Upload is too large or malformed"))
+		return
+	}
+	note := strings.TrimSpace(r.FormValue("note"))
+	if note == "" {
+		writeError(w, r, "", apperr.ValidationError("note", "Describe the corrective action taken"))
+		return
+	}
+	if len(note) > maxCommentLength {
+		writeError(w, r, "", apperr.ValidationError("note", fmt.Sprintf("Note must be at most %d characters", maxCommentLength)))
+		return
+	}
+	photo, err := readPhoto(r, "photo")
+	if err != nil {
+		if isPhotoError(err) {
+			writeError(w, r, "", apperr.ValidationError("photo", err.Error()))
+			return
+		}
+		writeError(w, r, "", apperr.ValidationError("photo", "Could not read uploaded photo"))
+		return
+	}
+
+	params := database.CreateViolationResolutionParams{
+		ViolationID:  v.ID,
+		Note:         note,
+		Verification: database.VerificationResultNotRun,
+		UserID:       userUUID(user),
+		UserName:     user.Name,
+	}
+	if det != nil && r.FormValue("verify") == "on" {
+		verifyCtx, cancel := context.WithTimeout(ctx, verifyTimeout)
+		findings, err := det.Detect(verifyCtx, photo.Image)
+		cancel()
+		switch {
+		case err != nil:
+			logger.Warn("hazard verification failed", "violation_id", v.ID.String(), "error", err)
+			params.Verification = database.VerificationResultFailed
+			params.VerificationDetail = "Hazard detection was unavailable"
+		default:
+			if f, found := detector.FindHazard(findings, v.Regulation, v.Category); found {
+				params.Verification = database.VerificationResultHazardPresent
+				params.VerificationConfidence = pgtype.Float8{Float64: f.Confidence, Valid: true}
+				params.VerificationDetail = f.Description
+			} else {
+				params.Verification = database.VerificationResultHazardAbsent
+			}
+		}
+	}
+	params.Accepted = params.Verification != database.VerificationResultHazardPresent
+
+	blobs, err := putPhoto(ctx, store, v.ProjectID, photo)
+	if err != nil {
+		serverError(w, r, "failed to store photo", err)
+		return
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := deletePhoto(context.WithoutCancel(ctx), store, blobs); err != nil {
+				logger.Warn("failed to delete orphaned photo", "key", blobs.Key, "error", err)
+			}
+		}
+	}()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		serverError(w, r, "failed to begin transaction", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	// re-check the status under lock so concurrent reviews can't interleave
+	locked, err := qtx.ListViolationsForUpdate(ctx, database.ListViolationsForUpdateParams{
+		ProjectID: v.ProjectID,
+		Ids:       []pgtype.UUID{v.ID},
+	})
+	if err != nil {
+		serverError(w, r, "failed to lock violation", err)
+		return
+	}
+	if len(locked) != 1 || locked[0].Status != database.ViolationStatusValidated {
+		writeError(w, r, "", apperr.ConflictError("Only validated violations can be resolved"))
+		return
+	}
+
+	stored, err := qtx.CreatePhoto(ctx, photoParams(v.ProjectID, photo, blobs, database.PhotoPurposeVerification, user))
+	if err != nil {
+		serverError(w, r, "failed to record photo", err)
+		return
+	}
+	params.PhotoID = stored.ID
+	resolution, err := qtx.CreateViolationResolution(ctx, params)
+	if err != nil {
+		serverError(w, r, "failed to record resolution", err)
+		return
+	}
+
+	event := database.CreateTimelineEventParams{
+		ProjectID:   v.ProjectID,
+		ViolationID: v.ID,
+		Type:        "violation_resolved",
+		Description: "Violation resolved by",
+		UserID:      userUUID(user),
+		UserName:    user.Name,
+	}
+	if resolution.Accepted {
+		if err := qtx.UpdateViolationsStatus(ctx, database.UpdateViolationsStatusParams{
+			Status: database.ViolationStatusResolved,
+			Ids:    []pgtype.UUID{v.ID},
+		}); err != nil {
+			serverError(w, r, "failed to resolve violation", err)
+			return
+		}
+		data := webhooks.ViolationData(v)
+		data.PreviousStatus = string(v.Status)
+		data.Status = string(database.ViolationStatusResolved)
+		data.ChangedBy = user.Name
+		if err := webhooks.Publish(ctx, qtx, webhooks.ViolationResolved, data); err != nil {
+			serverError(w, r, "failed to queue webhook", err)
+			return
+		}
+		if err := notify.StatusChanged(ctx, qtx, v, database.ViolationStatusResolved, notifyActor(user)); err != nil {
+			serverError(w, r, "failed to record notifications", err)
+			return
+		}
+	} else {
+		event.Type = "violation_resolution_rejected"
+		event.Description = "Hazard still detected in corrective action photo from"
+	}
+	event.Metadata, err = json.Marshal(map[string]interface{}{
+		"violation_id":  v.ID.String(),
+		"resolution_id": resolution.ID.String(),
+		"photo_id":      stored.ID.String(),
+		"verification":  string(resolution.Verification),
+	})
+	if err != nil {
+		serverError(w, r, "failed to encode timeline metadata", err)
+		return
+	}
+	if _, err := qtx.CreateTimelineEvent(ctx, event); err != nil {
+		serverError(w, r, "failed to record timeline event", err)
+		return
+	}
+	if err := qtx.TouchProject(ctx, v.ProjectID); err != nil {
+		serverError(w, r, "failed to update project", err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		serverError(w, r, "failed to commit resolution", err)
+		return
+	}
+	committed = true
+
+	http.Redirect(w, r, violationURL(v)+"#resolution", http.StatusSeeOther)
+}
@@ -0,0 +1,379 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/apperr"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/internal/webhooks"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5"
+)
+
+// Webhook form limits
+const (
+	maxWebhookNameLength = 100  // Matches webhook_subscriptions.name
+	maxWebhookURLLength  = 2000 // Longest endpoint URL accepted
+)
+
+// webhookLogSize is how many recent deliveries the delivery log shows
+const webhookLogSize = 100
+
+func handleWebhooks(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
+	user, err := getSessionUser(ctx, q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return
+	}
+
+	rows, err := q.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		serverError(w, r, "failed to load webhook subscriptions", err)
+		return
+	}
+	subscriptions := make([]dto.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		s := toWebhookSubscription(row.WebhookSubscription, user)
+		s.LastStatus = row.LastStatus
+		if row.LastAttemptAt.Valid {
+			lastAttempt := row.LastAttemptAt.Time
+			s.LastAttemptAt = &lastAttempt
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.WebhooksData{
+		AppData: dto.AppData{
+			PageTitle:      "Webhooks",
+			CurrentPage:    "settings",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Subscriptions: subscriptions,
+		Events:        webhooks.Events,
+		CanAdmin:      user.Role == "admin",
+	}
+	render(w, r, t, "webhooks", data)
+}
+
+func handleCreateWebhook(w http.ResponseWriter, r *http.Request, q *database.Queries, wh *webhooks.Dispatcher) {
+	user, ok := requireWebhookAdmin(w, r, q)
+	if !ok {
+		return
+	}
+
+	name, endpoint, events, ok := parseWebhookForm(w, r)
+	if !ok {
+		return
+	}
+	s, err := wh.Create(r.Context(), database.CreateWebhookSubscriptionParams{
+		Name:          name,
+		Url:           endpoint,
+		EventTypes:    events,
+		CreatedBy:     userUUID(user),
+		CreatedByName: user.Name,
+	})
+	if err != nil {
+		serverError(w, r, "failed to create webhook subscription", err)
+		return
+	}
+	loggerFromRequest(r).Info("webhook subscription created", "subscription_id", s.ID.String(), "events", events)
+	http.Redirect(w, r, "/app/settings/webhooks/"+s.ID.String(), http.StatusSeeOther)
+}
+
+func handleWebhookDetail(w http.ResponseWriter, r *http.Request, t *templates.Template, q *database.Queries) {
+	ctx := r.Context()
+	user, err := getSessionUser(ctx, q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return
+	}
+
+	s, ok := getWebhookSubscription(w, r, q)
+	if !ok {
+		return
+	}
+	rows, err := q.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{
+		SubscriptionID: s.ID,
+		Limit:          webhookLogSize,
+	})
+	if err != nil {
+		serverError(w, r, "failed to load webhook deliveries", err)
+		return
+	}
+	deliveries := make([]dto.WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, toWebhookDelivery(row))
+	}
+	recentProjects, err := getRecentProjects(ctx, q)
+	if err != nil {
+		serverError(w, r, "failed to load recent projects", err)
+		return
+	}
+	notifications, err := getNotificationMenu(ctx, q, user)
+	if err != nil {
+		serverError(w, r, "failed to load notifications", err)
+		return
+	}
+
+	data := dto.WebhookData{
+		AppData: dto.AppData{
+			PageTitle:      s.Name,
+			CurrentPage:    "settings",
+			User:           user,
+			RecentProjects: recentProjects,
+			Notifications:  notifications,
+		},
+		Subscription: toWebhookSubscription(s, user),
+		Deliveries:   deliveries,
+		Events:       webhooks.Events,
+		CanAdmin:     user.Role == "admin",
+	}
+	render(w, r, t, "webhook-detail", data)
+}
+
+func handleUpdateWebhook(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	if _, ok := requireWebhookAdmin(w, r, q); !ok {
+		return
+	}
+	s, ok := getWebhookSubscription(w, r, q)
+	if !ok {
+		return
+	}
+	name, endpoint, events, ok := parseWebhookForm(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := q.UpdateWebhookSubscription(r.Context(), database.UpdateWebhookSubscriptionParams{
+		ID:         s.ID,
+		Name:       name,
+		Url:        endpoint,
+		EventTypes: events,
+		Active:     r.FormValue("active") == "on",
+	}); err != nil {
+		serverError(w, r, "failed to update webhook subscription", err)
+		return
+	}
+	http.Redirect(w, r, "/app/settings/webhooks/"+s.ID.String(), http.StatusSeeOther)
+}
+
+func handleDeleteWebhook(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+	if _, ok := requireWebhookAdmin(w, r, q); !ok {
+		return
+	}
+	s, ok := getWebhookSubscription(w, r, q)
+	if !ok {
+		return
+	}
+	if err := q.DeleteWebhookSubscription(r.Context(), s.ID); err != nil {
+		serverError(w, r, "failed to delete webhook subscription", err)
+		return
+	}
+	loggerFromRequest(r).Info("webhook subscription deleted", "subscription_id", s.ID.String())
+	http.Redirect(w, r, "/app/settings/webhooks", http.StatusSeeOther)
+}
+
+func handlePingWebhook(w http.ResponseWriter, r *http.Request, q *database.Queries, wh *webhooks.Dispatcher) {
+	if _, ok := requireWebhookAdmin(w, r, q); !ok {
+		return
+	}
+	s, ok := getWebhookSubscription(w, r, q)
+	if !ok {
+		return
+	}
+	if _, err := wh.Ping(r.Context(), s.ID); err != nil {
+		serverError(w, r, "failed to queue webhook ping", err)
+		return
+	}
+	http.Redirect(w, r, "/app/settings/webhooks/"+s.ID.String()+"#deliveries", http.StatusSeeOther)
+}
+
+func handleRedeliverWebhook(w http.ResponseWriter, r *http.Request, q *database.Queries, wh *webhooks.Dispatcher) {
+	ctx := r.Context()
+	if _, ok := requireWebhookAdmin(w, r, q); !ok {
+		return
+	}
+	s, ok := getWebhookSubscription(w, r, q)
+	if !ok {
+		return
+	}
+
+	id, err := parseUUID(r.PathValue("deliveryId"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Delivery not found"))
+		return
+	}
+	delivery, err := q.GetWebhookDelivery(ctx, id)
+	if err == nil && delivery.SubscriptionID != s.ID {
+		err = pgx.ErrNoRows
+	}
+	if isNotFound(err) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Delivery not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to load webhook delivery", err)
+		return
+	}
+
+	if _, err := wh.Redeliver(ctx, delivery.ID); err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			err = &apperr.Error{Kind: apperr.NotFound, Message: "Delivery not found", Err: err}
+		}
+		writeError(w, r, "failed to queue redelivery", err)
+		return
+	}
+	loggerFromRequest(r).Info("webhook redelivery queued", "delivery_id", delivery.ID.String())
+	http.Redirect(w, r, "/app/settings/webhooks/"+s.ID.String()+"#deliveries", http.StatusSeeOther)
+}
+
+// requireWebhookAdmin returns the signed-in user if they are an admin, who
+// alone may change subscriptions, writing a 403 if not
+func requireWebhookAdmin(w http.ResponseWriter, r *http.Request, q *database.Queries) (dto.User, bool) {
+	user, err := getSessionUser(r.Context(), q)
+	if err != nil {
+		writeError(w, r, "failed to load user", err)
+		return user, false
+	}
+	if user.Role != "admin" {
+		writeError(w, r, "", apperr.ForbiddenError("Only admins can manage webhooks"))
+		return user, false
+	}
+	return user, true
+}
+
+// getWebhookSubscription loads the subscription named in the path, writing
+// a 404 if it doesn't exist
+func getWebhookSubscription(w http.ResponseWriter, r *http.Request, q *database.Queries) (database.WebhookSubscription, bool) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, "", apperr.NotFoundError("Webhook not found"))
+		return database.WebhookSubscription{}, false
+	}
+	s, err := q.GetWebhookSubscription(r.Context(), id)
+	if isNotFound(err) {
+		err = &apperr.Error{Kind: apperr.NotFound, Message: "Webhook not found", Err: err}
+	}
+	if err != nil {
+		writeError(w, r, "failed to load webhook subscription", err)
+		return s, false
+	}
+	return s, true
+}
+
+// parseWebhookForm reads and validates the fields shared by the create and
+// edit forms, writing a 400 if any are invalid
+func parseWebhookForm(w http.ResponseWriter, r *http.Request) (name, endpoint string, events []string, ok bool) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, "", &apperr.Error{Kind: apperr.Validation, Message: "Invalid form", Err: err})
+		return "", "", nil, false
+	}
+
+	name = strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		writeError(w, r, "", apperr.ValidationError("name", "Name is required"))
+		return "", "", nil, false
+	}
+	if len(name) > maxWebhookNameLength {
+		writeError(w, r, "", apperr.ValidationError("name", fmt.Sprintf("Name must be at most %d characters", maxWebhookNameLength)))
+		return "", "", nil, false
+	}
+
+	endpoint = strings.TrimSpace(r.FormValue("url"))
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		writeError(w, r, "", apperr.ValidationError("url", "URL must be an http or https address"))
+		return "", "", nil, false
+	}
+	if !webhooks.AllowedHost(u.Hostname()) {
+		writeError(w, r, "", apperr.ValidationError("url", "URL must be a public address"))
+		return "", "", nil, false
+	}
+	if len(endpoint) > maxWebhookURLLength {
+		writeError(w, r, "", apperr.ValidationError("url", fmt.Sprintf("URL must be at most %d characters", maxWebhookURLLength)))
+		return "", "", nil, false
+	}
+
+	events = r.Form["event"]
+	if len(events) == 0 {
+		writeError(w, r, "", apperr.ValidationError("event", "Choose at least one event"))
+		return "", "", nil, false
+	}
+	for _, event := range events {
+		if !slices.Contains(webhooks.Events, event) {
+			writeError(w, r, "", apperr.ValidationError("event", "Unknown event"))
+			return "", "", nil, false
+		}
+	}
+	return name, endpoint, events, true
+}
+
+// toWebhookSubscription converts a webhook_subscriptions row into its page
+// representation. Only admins see the signing secret.
+func toWebhookSubscription(s database.WebhookSubscription, user dto.User) dto.WebhookSubscription {
+	subscription := dto.WebhookSubscription{
+		ID:            s.ID.String(),
+		Name:          s.Name,
+		URL:           s.Url,
+		EventTypes:    s.EventTypes,
+		Active:        s.Active,
+		CreatedByName: s.CreatedByName,
+		CreatedAt:     s.CreatedAt.Time,
+	}
+	if user.Role == "admin" {
+		subscription.Secret = s.Secret
+	}
+	return subscription
+}
+
+// toWebhookDelivery converts a webhook_deliveries row into its page
+// representation
+func toWebhookDelivery(d database.WebhookDelivery) dto.WebhookDelivery {
+	delivery := dto.WebhookDelivery{
+		ID:             d.ID.String(),
+		EventID:        d.EventID.String(),
+		EventType:      d.EventType,
+		Status:         string(d.Status),
+		Attempts:       int(d.Attempts),
+		ResponseStatus: int(d.ResponseStatus.Int32),
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DurationMs:     int(d.DurationMs.Int32),
+		Payload:        string(d.Data),
+		Redelivery:     d.RedeliveryOf.Valid,
+		CreatedAt:      d.CreatedAt.Time,
+	}
+	var indented bytes.Buffer
+	if json.Indent(&indented, d.Data, "", "  ") == nil {
+		delivery.Payload = indented.String()
+	}
+	if d.LastAttemptAt.Valid {
+		lastAttempt := d.LastAttemptAt.Time
+		delivery.LastAttemptAt = &lastAttempt
+	}
+	if d.Status == database.WebhookDeliveryStatusPending {
+		next := d.NextAttemptAt.Time
+		delivery.NextAttemptAt = &next
+	}
+	return delivery
+}
@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestWebhookAdmin(t *testing.T) {
+	tmpl, err := templates.NewTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscription := database.WebhookSubscription{
+		ID:         pgtype.UUID{Bytes: [16]byte{0xbb, 1}, Valid: true},
+		Name:       "Ops",
+		Url:        "https://hooks.example.com/ironman",
+		Secret:     "whsec_test",
+		EventTypes: []string{"violation.critical"},
+		Active:     true,
+	}
+	path := "/app/settings/webhooks/" + subscription.ID.String()
+	form := url.Values{"name": {"Ops alerts"}, "url": {subscription.Url}, "event": {"violation.critical"}, "active": {"on"}}
+
+	tests := []struct {
+		name       string
+		role       database.UserRole
+		method     string
+		handler    func(w http.ResponseWriter, r *http.Request, q *database.Queries)
+		wantStatus int
+		wantQuery  string // Query the request should run, if any
+		wantBody   string // Text the response should contain, if any
+	}{
+		{
+			name:   "admin lists subscriptions",
+			role:   database.UserRoleAdmin,
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleWebhooks(w, r, tmpl, q)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   subscription.Url,
+		},
+		{
+			name:   "admin views the delivery log",
+			role:   database.UserRoleAdmin,
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request, q *database.Queries) {
+				handleWebhookDetail(w, r, tmpl, q)
+			},
+			wantStatus: http.StatusOK,
+			wantQuery:  "ListWebhookDeliveries",
+			wantBody:   subscription.Secret,
+		},
+		{
+			name:       "admin edits a subscription",
+			role:       database.UserRoleAdmin,
+			method:     "POST",
+			handler:    handleUpdateWebhook,
+			wantStatus: http.StatusSeeOther,
+			wantQuery:  "UpdateWebhookSubscription",
+		},
+		{
+			name:       "admin deletes a subscription",
+			role:       database.UserRoleAdmin,
+			method:     "POST",
+			handler:    handleDeleteWebhook,
+			wantStatus: http.StatusSeeOther,
+			wantQuery:  "DeleteWebhookSubscription",
+		},
+		{
+			name:       "user can't edit a subscription",
+			role:       database.UserRoleUser,
+			method:     "POST",
+			handler:    handleUpdateWebhook,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "user can't delete a subscription",
+			role:       database.UserRoleUser,
+			method:     "POST",
+			handler:    handleDeleteWebhook,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newPageDB()
+			db.returns("GetUserByEmail", sessionUserRow(tt.role, true))
+			db.returns("ListWebhookSubscriptions", database.ListWebhookSubscriptionsRow{WebhookSubscription: subscription})
+			db.returns("GetWebhookSubscription", subscription)
+			db.returns("UpdateWebhookSubscription", subscription)
+
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetPathValue("id", subscription.ID.String())
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req, database.New(db))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("response doesn't contain %q", tt.wantBody)
+			}
+			if tt.wantQuery != "" && !db.called(tt.wantQuery) {
+				t.Errorf("%s wasn't run", tt.wantQuery)
+			}
+			if tt.wantStatus == http.StatusForbidden && (db.called("UpdateWebhookSubscription") || db.called("DeleteWebhookSubscription")) {
+				t.Error("subscription was changed")
+			}
+		})
+	}
+}
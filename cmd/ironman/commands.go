@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// command runs one subcommand. args are the arguments after the command
+// name, flags included.
+type command func(ctx context.Context, w io.Writer, config config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error
+
+var commands = map[string]command{
+	"serve":     serve,
+	"migrate":   runMigrate,
+	"user":      runUser,
+	"reanalyze": runReanalyze,
+	"report":    runReport,
+	"seed":      runSeed,
+}
+
+const usage = `usage: ironman [command] [flags]
+
+Commands:
+  serve                            Run the web server and background workers (default)
+  migrate up|down|status|redo      Apply, roll back or list database migrations
+  user create --email EMAIL        Add a user; also --first_name, --last_name, --role admin|user
+  user promote --email EMAIL       Change a user's role; --role defaults to admin
+  user deactivate --email EMAIL    Stop a user signing in and being notified
+  reanalyze --project ID           Run hazard detection again on a project's photos
+  report generate --project ID     Write a project's safety report as HTML; --output FILE, default stdout
+  seed                             Add demo users, projects and violations to an empty database
+  help                             Show this message
+
+Every command reads the database and other settings from the environment
+and flags such as --db_host, the same way the server does.
+`
+
+// positional returns the arguments that aren't flags or flag values
+func positional(args []string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "--") {
+			if !strings.Contains(args[i], "=") {
+				i++ // --flag value
+			}
+			continue
+		}
+		values = append(values, args[i])
+	}
+	return values
+}
+
+// projectFlag reads a command's --project ID
+func projectFlag(args []string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	raw := config.Flag(args, "project")
+	if raw == "" || id.Scan(raw) != nil {
+		return id, errors.New("--project must be a project ID")
+	}
+	return id, nil
+}
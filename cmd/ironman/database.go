@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Startup retry settings
+const (
+	retryBackoff    = 500 * time.Millisecond // Wait after the first failed attempt
+	maxRetryBackoff = 5 * time.Second        // Longest wait between attempts
+)
+
+// connect opens the connection pool, retrying with backoff until the database
+// accepts connections or DB_STARTUP_WAIT runs out, so the app can start
+// alongside Postgres
+func connect(ctx context.Context, config config.Config, logger *slog.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := poolConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := time.ParseDuration(config.DB_STARTUP_WAIT)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_STARTUP_WAIT: %w", err)
+	}
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create connection pool: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.Ping(ctx)
+		if err == nil {
+			return db, nil
+		}
+		remaining := time.Until(deadline)
+		if !retryable(err) || remaining <= 0 {
+			db.Close()
+			return nil, fmt.Errorf("connect to database: %w", err)
+		}
+		backoff = min(backoff, remaining)
+		logger.Warn("database unavailable, retrying", "attempt", attempt, "retry_in", backoff.Round(time.Millisecond).String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// poolConfig returns the pool settings from the config
+func poolConfig(config config.Config) (*pgxpool.Config, error) {
+	connectionURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.DB_USER, config.DB_PASSWORD),
+		Host:     net.JoinHostPort(config.DB_HOST, config.DB_PORT),
+		Path:     config.DB_NAME,
+		RawQuery: "sslmode=disable",
+	}
+	poolConfig, err := pgxpool.ParseConfig(connectionURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("parse database config: %w", err)
+	}
+
+	maxConns, err := strconv.ParseInt(config.DB_MAX_CONNS, 10, 32)
+	if err != nil || maxConns < 1 {
+		return nil, fmt.Errorf("invalid DB_MAX_CONNS %q", config.DB_MAX_CONNS)
+	}
+	minConns, err := strconv.ParseInt(config.DB_MIN_CONNS, 10, 32)
+	if err != nil || minConns < 0 || minConns > maxConns {
+		return nil, fmt.Errorf("invalid DB_MIN_CONNS %q", config.DB_MIN_CONNS)
+	}
+	lifetime, err := time.ParseDuration(config.DB_CONN_LIFETIME)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_LIFETIME: %w", err)
+	}
+	idleTime, err := time.ParseDuration(config.DB_CONN_IDLE_TIME)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_IDLE_TIME: %w", err)
+	}
+	dialTimeout, err := time.ParseDuration(config.DB_DIAL_TIMEOUT)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_DIAL_TIMEOUT: %w", err)
+	}
+
+	poolConfig.MaxConns = int32(maxConns)
+	poolConfig.MinConns = int32(minConns)
+	poolConfig.MaxConnLifetime = lifetime
+	poolConfig.MaxConnIdleTime = idleTime
+	poolConfig.ConnConfig.ConnectTimeout = dialTimeout
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	return poolConfig, nil
+}
+
+// retryable reports whether a failed connection may succeed later. Errors
+// from the server, such as bad credentials, are final except while it is
+// still starting up.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "57P03" // cannot_connect_now
+	}
+	return true
+}
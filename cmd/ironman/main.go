@@ -5,18 +5,38 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	// users' timezones are needed for quiet hours even where the host has
+	// no timezone database
+	_ "time/tzdata"
 
 	// "github.com/dukerupert/go-claude"
 	"github.com/dukerupert/ironman/api/v1"
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/blob"
 	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/events"
+	"github.com/dukerupert/ironman/internal/geo"
 	"github.com/dukerupert/ironman/internal/logger"
-	"github.com/jackc/pgx/v5"
+	"github.com/dukerupert/ironman/internal/mail"
+	"github.com/dukerupert/ironman/internal/migrate"
+	"github.com/dukerupert/ironman/internal/migrations"
+	"github.com/dukerupert/ironman/internal/notify"
+	"github.com/dukerupert/ironman/internal/syncfeed"
+	"github.com/dukerupert/ironman/internal/tokens"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"github.com/dukerupert/ironman/internal/uploads"
+	"github.com/dukerupert/ironman/internal/webhooks"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 
@@ -29,25 +49,118 @@ func run(ctx context.Context, w io.Writer, environ []string, args []string) erro
 
 	logger := logger.New(w, config.LOG_LEVEL, config.ENVIRONMENT)
 
-	// Debug environment
-	logger.Debug("config", "environ", config, "args", args)
+	// Debug environment. Args aren't logged, as flags can hold secrets;
+	// the config redacts them.
+	logger.Debug("config", "environ", config)
+
+	// the first argument names the command unless it is a flag; with none,
+	// the server starts
+	name, commandArgs := "serve", args[1:]
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		name, commandArgs = args[1], args[2:]
+	}
+	if name == "help" {
+		fmt.Fprint(w, usage)
+		return nil
+	}
+	command, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", name, usage)
+	}
 	
-	// establish database connection
-	connectionString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", config.DB_USER, config.DB_PASSWORD, config.DB_HOST, config.DB_PORT, config.DB_NAME)
-	db, err := pgx.Connect(ctx, connectionString)
+	// traces are exported while the command runs and flushed once it ends
+	sampling, err := strconv.ParseFloat(config.TRACE_SAMPLING, 64)
+	if err != nil || sampling < 0 || sampling > 1 {
+		return fmt.Errorf("invalid TRACE_SAMPLING %q", config.TRACE_SAMPLING)
+	}
+	if config.OTLP_ENDPOINT == "" {
+		logger.Info("OTLP_ENDPOINT not set, traces will not be exported")
+	}
+	shutdownTracing, err := tracing.Setup(ctx, config.OTLP_ENDPOINT, config.OTEL_SERVICE_NAME, sampling)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer db.Close(ctx)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
 
-	err = db.Ping(ctx)
+	// establish database connection
+	db, err := connect(ctx, config, logger)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer db.Close()
 
 	logger.Info("database connection established...")
 
-	srv := v1.NewServer(logger)
+	return command(ctx, w, config, logger, db, commandArgs)
+}
+
+// serve runs the web server and background workers until interrupted
+func serve(ctx context.Context, w io.Writer, config config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error {
+	mg, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	// bring the schema up to date before anything queries it
+	if auto, _ := strconv.ParseBool(config.AUTO_MIGRATE); auto {
+		if err := autoMigrate(ctx, logger, mg); err != nil {
+			return err
+		}
+	}
+
+	fileStore, err := blob.NewFileStore(config.BLOB_DIR)
+	if err != nil {
+		return err
+	}
+	store := tracing.Store(fileStore)
+
+	// hazard detection is optional; without an API key verification is disabled
+	var det detector.Detector
+	if config.ANTHROPIC_API_KEY != "" {
+		det = tracing.Detector(detector.NewClaude(config.ANTHROPIC_API_KEY, config.ANTHROPIC_MODEL))
+	} else {
+		logger.Warn("ANTHROPIC_API_KEY not set, hazard detection disabled")
+	}
+
+	an := analysis.New(db, store, det)
+
+	// addresses are matched offline until a hosted geocoder is configured
+	gc := geo.NewStub(nil)
+
+	up := uploads.New(db, store)
+	tm := tokens.New(db)
+	wh := webhooks.New(db)
+	br := events.New(db)
+	sf := syncfeed.New(db)
+
+	// email is logged instead of sent until a mail server is configured
+	var mailer mail.Mailer
+	if config.SMTP_HOST != "" {
+		smtpMailer, err := mail.NewSMTP(config.SMTP_HOST, config.SMTP_PORT, config.SMTP_USERNAME, config.SMTP_PASSWORD, config.MAIL_FROM)
+		if err != nil {
+			return err
+		}
+		mailer = smtpMailer
+	} else {
+		logger.Warn("SMTP_HOST not set, emails will be logged instead of sent")
+		mailer = mail.NewLog(logger)
+	}
+	nt := notify.New(db, mailer, config.APP_URL)
+
+	srv, err := v1.NewServer(logger, db, store, det, an, gc, up, tm, wh, br, mg, config.Production())
+	if err != nil {
+		return err
+	}
+
+	// a server that can't listen stops the workers too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort(config.APP_HOST, config.APP_PORT),
@@ -55,11 +168,43 @@ func run(ctx context.Context, w io.Writer, environ []string, args []string) erro
 	}
 	var wg sync.WaitGroup
 
+	// Analyze uploaded photos in the background
+	wg.Go(func() {
+		an.Run(ctx, logger)
+	})
+
+	// Purge resumable uploads that were abandoned
+	wg.Go(func() {
+		up.Run(ctx, logger)
+	})
+
+	// Prune the change feed offline clients pull from
+	wg.Go(func() {
+		sf.Run(ctx, logger)
+	})
+
+	// Send queued webhook deliveries
+	wg.Go(func() {
+		wh.Run(ctx, logger)
+	})
+
+	// Relay project events to live pages
+	wg.Go(func() {
+		br.Run(ctx, logger)
+	})
+
+	// Alert users to critical violations and overdue corrective actions
+	wg.Go(func() {
+		nt.Run(ctx, logger)
+	})
+
 	// Start the HTTP server
+	var serveErr error
 	wg.Go(func() {
 		log.Printf("listening on %s\n", httpServer.Addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Fprintf(os.Stderr, "error listening and serving: %s\n", err)
+			serveErr = fmt.Errorf("error listening and serving: %w", err)
+			cancel()
 		}
 	})
 
@@ -74,7 +219,7 @@ func run(ctx context.Context, w io.Writer, environ []string, args []string) erro
 	})
 
 	wg.Wait()
-	return nil
+	return serveErr
 }
 
 func main() {
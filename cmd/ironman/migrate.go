@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/migrate"
+	"github.com/dukerupert/ironman/internal/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = "usage: ironman migrate up|down|status|redo"
+
+// runMigrate runs `ironman migrate up|down|status|redo`
+func runMigrate(ctx context.Context, w io.Writer, _ config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error {
+	var command string
+	if values := positional(args); len(values) > 0 {
+		command = values[0]
+	}
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		n, err := m.Up(ctx, logger)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		} else {
+			fmt.Fprintf(w, "applied %d migrations\n", n)
+		}
+	case "down":
+		mig, err := m.Down(ctx, logger)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "rolled back %s\n", mig.Name)
+	case "redo":
+		mig, err := m.Redo(ctx, logger)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "reapplied %s\n", mig.Name)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "    Applied At                  Migration")
+		fmt.Fprintln(w, "    =======================================")
+		for _, s := range statuses {
+			appliedAt := "Pending                 "
+			if s.Applied() {
+				appliedAt = s.AppliedAt.Format("Mon Jan _2 15:04:05 2006")
+			}
+			fmt.Fprintf(w, "    %s -- %s\n", appliedAt, s.Name)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+// autoMigrate applies pending migrations before the server starts. Instances
+// starting together wait on the migration lock, and find nothing left to do.
+func autoMigrate(ctx context.Context, logger *slog.Logger, m *migrate.Migrator) error {
+	n, err := m.Up(ctx, logger)
+	if err != nil {
+		return err
+	}
+	logger.Info("database schema up to date", "applied", n)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/dukerupert/ironman/internal/analysis"
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runReanalyze runs `ironman reanalyze --project ID`, detecting hazards in
+// each of the project's photos again one at a time. Violations inspectors
+// have already reviewed are kept.
+func runReanalyze(ctx context.Context, w io.Writer, cfg config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error {
+	projectID, err := projectFlag(args)
+	if err != nil {
+		return err
+	}
+	if cfg.ANTHROPIC_API_KEY == "" {
+		return errors.New("ANTHROPIC_API_KEY must be set to detect hazards")
+	}
+	store, err := blob.NewFileStore(cfg.BLOB_DIR)
+	if err != nil {
+		return err
+	}
+	an := analysis.New(db, tracing.Store(store), tracing.Detector(detector.NewClaude(cfg.ANTHROPIC_API_KEY, cfg.ANTHROPIC_MODEL)))
+
+	q := database.New(db)
+	if _, err := q.GetProject(ctx, projectID); err != nil {
+		return fmt.Errorf("load project: %w", err)
+	}
+	photos, err := q.ListInspectionPhotoIDsByProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("list photos: %w", err)
+	}
+
+	var created, failed int
+	for i, photoID := range photos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := an.AnalyzePhoto(ctx, photoID)
+		if err != nil {
+			logger.Error("failed to analyze photo", "photo_id", photoID.String(), "error", err)
+			failed++
+			continue
+		}
+		created += len(result.Created)
+		fmt.Fprintf(w, "[%d/%d] %s: %d violations, %d already reviewed\n", i+1, len(photos), photoID.String(), len(result.Created), result.Skipped)
+	}
+	fmt.Fprintf(w, "analyzed %d photos, %d violations found\n", len(photos)-failed, created)
+	if failed > 0 {
+		return fmt.Errorf("%d photos could not be analyzed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/dukerupert/ironman/api/v1"
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/dto"
+	"github.com/dukerupert/ironman/web/templates"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const reportUsage = "usage: ironman report generate --project ID [--output FILE]"
+
+// runReport runs `ironman report generate`, writing a project's safety
+// report as HTML to --output, or to w without one
+func runReport(ctx context.Context, w io.Writer, cfg config.Config, _ *slog.Logger, db *pgxpool.Pool, args []string) error {
+	if values := positional(args); len(values) == 0 || values[0] != "generate" {
+		return errors.New(reportUsage)
+	}
+	projectID, err := projectFlag(args)
+	if err != nil {
+		return err
+	}
+	t, err := templates.NewTemplate()
+	if err != nil {
+		return err
+	}
+
+	q := database.New(db)
+	generatedBy := dto.User{Name: "SafeSite Inspector"}
+	output := config.Flag(args, "output")
+	if output == "" {
+		return v1.WriteSafetyReport(ctx, w, t, q, projectID.String(), cfg.APP_URL, generatedBy)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := v1.WriteSafetyReport(ctx, f, t, q, projectID.String(), cfg.APP_URL, generatedBy); err != nil {
+		f.Close()
+		return fmt.Errorf("generate report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "wrote %s\n", output)
+	return nil
+}
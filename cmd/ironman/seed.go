@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// seedViolation is a demo violation and the status it is reviewed into
+type seedViolation struct {
+	description string
+	regulation  string
+	risk        database.RiskLevel
+	category    string
+	location    string
+	status      database.ViolationStatus
+}
+
+// seedProjects are the demo projects, each with its violations
+var seedProjects = []struct {
+	name        string
+	description string
+	location    string
+	status      database.ProjectStatus
+	violations  []seedViolation
+}{
+	{
+		name:        "Riverside Tower",
+		description: "Twelve-story residential tower, structural steel and decking",
+		location:    "1200 River Rd, Portland, OR",
+		status:      database.ProjectStatusInProgress,
+		violations: []seedViolation{
+			{"Worker on open deck edge without fall protection", "29 CFR 1926.501(b)(1)", database.RiskLevelCritical, "Fall Protection", "Level 9, east edge", database.ViolationStatusOpen},
+			{"Guardrail top rail below 39 inches", "29 CFR 1926.502(b)(1)", database.RiskLevelHigh, "Fall Protection", "Level 7 stair opening", database.ViolationStatusValidated},
+			{"Extension cord with damaged insulation", "29 CFR 1926.405(a)(2)(ii)(I)", database.RiskLevelMedium, "Electrical", "Level 3 electrical room", database.ViolationStatusValidated},
+			{"Debris in walkway near hoist", "29 CFR 1926.25(a)", database.RiskLevelLow, "Housekeeping", "Ground floor, hoist landing", database.ViolationStatusOpen},
+		},
+	},
+	{
+		name:        "Eastside Clinic Renovation",
+		description: "Interior renovation of an occupied outpatient clinic",
+		location:    "455 SE Division St, Portland, OR",
+		status:      database.ProjectStatusNeedsReview,
+		violations: []seedViolation{
+			{"Scaffold missing base plates", "29 CFR 1926.451(c)(2)", database.RiskLevelHigh, "Scaffolding", "North wing corridor", database.ViolationStatusOpen},
+			{"Worker without eye protection while grinding", "29 CFR 1926.102(a)(1)", database.RiskLevelMedium, "PPE", "Exam room 4", database.ViolationStatusDismissed},
+		},
+	},
+	{
+		name:        "Harbor Warehouse",
+		description: "Tilt-up warehouse with mezzanine",
+		location:    "88 Terminal Way, Tacoma, WA",
+		status:      database.ProjectStatusCompleted,
+		violations: []seedViolation{
+			{"Ladder not extending 3 feet above landing", "29 CFR 1926.1053(b)(1)", database.RiskLevelMedium, "Ladders", "Mezzanine access", database.ViolationStatusResolved},
+		},
+	},
+}
+
+// runSeed runs `ironman seed`, filling an empty database with demo users,
+// projects and violations to explore the app with
+func runSeed(ctx context.Context, w io.Writer, _ config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error {
+	q := database.New(db)
+	users, err := q.CountUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("count users: %w", err)
+	}
+	if users > 0 {
+		return errors.New("the database already has users; seed only fills an empty database")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+	if _, err := qtx.CreateUser(ctx, seedUser("admin@example.com", "Alex", "Rivera", database.UserRoleAdmin)); err != nil {
+		return fmt.Errorf("create admin: %w", err)
+	}
+	inspector, err := qtx.CreateUser(ctx, seedUser("inspector@example.com", "Sam", "Chen", database.UserRoleUser))
+	if err != nil {
+		return fmt.Errorf("create inspector: %w", err)
+	}
+	inspectorName := inspector.FirstName.String + " " + inspector.LastName.String
+
+	for _, p := range seedProjects {
+		project, err := qtx.CreateProject(ctx, database.CreateProjectParams{
+			Name:        p.name,
+			Description: p.description,
+			Status:      p.status,
+			Location:    p.location,
+			InspectorID: inspector.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("create project %s: %w", p.name, err)
+		}
+		for _, sv := range p.violations {
+			v, err := qtx.CreateViolation(ctx, database.CreateViolationParams{
+				ProjectID:   project.ID,
+				Description: sv.description,
+				Regulation:  sv.regulation,
+				RiskLevel:   sv.risk,
+				Category:    sv.category,
+				Location:    sv.location,
+			})
+			if err != nil {
+				return fmt.Errorf("create violation: %w", err)
+			}
+			metadata, err := json.Marshal(map[string]interface{}{
+				"violation_id": v.ID.String(),
+				"risk_level":   v.RiskLevel,
+			})
+			if err != nil {
+				return fmt.Errorf("encode timeline metadata: %w", err)
+			}
+			if _, err := qtx.CreateTimelineEvent(ctx, database.CreateTimelineEventParams{
+				ProjectID:   project.ID,
+				ViolationID: v.ID,
+				Type:        "violation_found",
+				Description: fmt.Sprintf("Violation %q recorded by", v.Description),
+				UserID:      inspector.ID,
+				UserName:    inspectorName,
+				Metadata:    metadata,
+			}); err != nil {
+				return fmt.Errorf("record timeline event: %w", err)
+			}
+			if sv.status == database.ViolationStatusOpen {
+				continue
+			}
+			if err := qtx.UpdateViolationsStatus(ctx, database.UpdateViolationsStatusParams{
+				Status: sv.status,
+				Ids:    []pgtype.UUID{v.ID},
+			}); err != nil {
+				return fmt.Errorf("update violation status: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit demo data: %w", err)
+	}
+	logger.Info("demo data added", "projects", len(seedProjects))
+	fmt.Fprintln(w, "added demo users admin@example.com and inspector@example.com with their projects")
+	return nil
+}
+
+// seedUser returns the parameters of an active demo user
+func seedUser(email, firstName, lastName string, role database.UserRole) database.CreateUserParams {
+	return database.CreateUserParams{
+		Email:         email,
+		LoginMethod:   database.LoginMethodEmailPassword,
+		FirstName:     pgtype.Text{String: firstName, Valid: true},
+		LastName:      pgtype.Text{String: lastName, Valid: true},
+		Timezone:      pgtype.Text{String: "America/Los_Angeles", Valid: true},
+		IsActive:      true,
+		EmailVerified: true,
+		Role:          role,
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/config"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userUsage = "usage: ironman user create|promote|deactivate --email EMAIL"
+
+// runUser runs `ironman user create|promote|deactivate`
+func runUser(ctx context.Context, w io.Writer, _ config.Config, logger *slog.Logger, db *pgxpool.Pool, args []string) error {
+	var action string
+	if values := positional(args); len(values) > 0 {
+		action = values[0]
+	}
+	email := strings.TrimSpace(config.Flag(args, "email"))
+	if email == "" {
+		return errors.New(userUsage)
+	}
+	q := database.New(db)
+
+	switch action {
+	case "create":
+		role, err := parseRole(config.Flag(args, "role"), database.UserRoleUser)
+		if err != nil {
+			return err
+		}
+		user, err := q.CreateUser(ctx, database.CreateUserParams{
+			Email:       email,
+			LoginMethod: database.LoginMethodEmailPassword,
+			FirstName:   optionalText(config.Flag(args, "first_name")),
+			LastName:    optionalText(config.Flag(args, "last_name")),
+			Timezone:    pgtype.Text{String: "UTC", Valid: true},
+			IsActive:    true,
+			Role:        role,
+		})
+		if err != nil {
+			return fmt.Errorf("create user: %w", err)
+		}
+		fmt.Fprintf(w, "created %s user %s (%s)\n", user.Role, user.Email, user.ID.String())
+	case "promote":
+		role, err := parseRole(config.Flag(args, "role"), database.UserRoleAdmin)
+		if err != nil {
+			return err
+		}
+		user, err := userByEmail(ctx, q, email)
+		if err != nil {
+			return err
+		}
+		if err := q.UpdateUserRole(ctx, database.UpdateUserRoleParams{ID: user.ID, Role: role}); err != nil {
+			return fmt.Errorf("update role: %w", err)
+		}
+		fmt.Fprintf(w, "%s is now %s\n", user.Email, role)
+	case "deactivate":
+		user, err := userByEmail(ctx, q, email)
+		if err != nil {
+			return err
+		}
+		if err := q.DeactivateUser(ctx, user.ID); err != nil {
+			return fmt.Errorf("deactivate user: %w", err)
+		}
+		fmt.Fprintf(w, "deactivated %s\n", user.Email)
+	default:
+		return errors.New(userUsage)
+	}
+	return nil
+}
+
+// userByEmail loads the user with an email address
+func userByEmail(ctx context.Context, q *database.Queries, email string) (database.User, error) {
+	user, err := q.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, fmt.Errorf("no user with email %s", email)
+	}
+	if err != nil {
+		return user, fmt.Errorf("load user: %w", err)
+	}
+	return user, nil
+}
+
+// parseRole reads a --role value, or returns fallback if there is none
+func parseRole(s string, fallback database.UserRole) (database.UserRole, error) {
+	switch role := database.UserRole(s); role {
+	case "":
+		return fallback, nil
+	case database.UserRoleAdmin, database.UserRoleUser:
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q, expected admin or user", s)
+}
+
+// optionalText returns s as a nullable column, null when empty
+func optionalText(s string) pgtype.Text {
+	s = strings.TrimSpace(s)
+	return pgtype.Text{String: s, Valid: s != ""}
+}
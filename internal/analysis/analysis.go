@@ -0,0 +1,334 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/detector"
+	"github.com/dukerupert/ironman/internal/events"
+	"github.com/dukerupert/ironman/internal/logger"
+	"github.com/dukerupert/ironman/internal/tracing"
+	"github.com/dukerupert/ironman/internal/webhooks"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrUnavailable is returned when no detector is configured
+var ErrUnavailable = errors.New("analysis: hazard detection is not configured")
+
+// ErrDuplicate is returned for photos flagged as duplicates of an earlier
+// photo, whose hazards were already recorded from the original
+var ErrDuplicate = errors.New("analysis: photo is a duplicate")
+
+// minFindingConfidence is the confidence below which findings are discarded.
+// It is lower than detector.MinConfidence because inspectors review every
+// finding before it counts.
+const minFindingConfidence = 0.3
+
+// Background analysis settings
+const (
+	queueSize       = 256             // Photos waiting for analysis before Enqueue drops them
+	analysisTimeout = 2 * time.Minute // Limit on a single background analysis
+	backlogBatch    = 100             // Unanalyzed photos queued when Run starts
+)
+
+// Analyzer runs hazard detection on photos and records the findings as open
+// violations, with detector regions where the hazard was located
+type Analyzer struct {
+	db    *pgxpool.Pool
+	q     *database.Queries
+	store blob.Store
+	det   detector.Detector
+	queue chan job
+}
+
+// job is a photo queued for analysis, with the logger of whatever queued it,
+// such as the request that uploaded the photo
+type job struct {
+	photoID pgtype.UUID
+	logger  *slog.Logger
+}
+
+// New returns an Analyzer. det may be nil, in which case every analysis
+// fails with ErrUnavailable.
+func New(db *pgxpool.Pool, store blob.Store, det detector.Detector) *Analyzer {
+	return &Analyzer{
+		db:    db,
+		q:     database.New(db),
+		store: store,
+		det:   det,
+		queue: make(chan job, queueSize),
+	}
+}
+
+// Available reports whether a detector is configured
+func (a *Analyzer) Available() bool {
+	return a.det != nil
+}
+
+// Enqueue schedules a photo for background analysis and reports whether it
+// was accepted. The analysis logs with the logger in ctx, so its messages
+// can be traced back to the request that queued it. Photos dropped because
+// the queue is full or detection is unavailable stay unanalyzed and are
+// picked up the next time Run starts.
+func (a *Analyzer) Enqueue(ctx context.Context, photoID pgtype.UUID) bool {
+	if a.det == nil {
+		return false
+	}
+	return a.enqueue(job{photoID: photoID, logger: logger.FromContext(ctx)})
+}
+
+// enqueue queues a job unless the queue is full
+func (a *Analyzer) enqueue(j job) bool {
+	select {
+	case a.queue <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+// Queued returns how many photos are waiting for background analysis
+func (a *Analyzer) Queued() int {
+	return len(a.queue)
+}
+
+// Run analyzes queued photos until ctx is cancelled, after first queueing the
+// inspection photos that were never analyzed. It returns immediately when no
+// detector is configured.
+func (a *Analyzer) Run(ctx context.Context, logger *slog.Logger) {
+	if a.det == nil {
+		return
+	}
+
+	pending, err := a.q.ListUnanalyzedPhotos(ctx, backlogBatch)
+	if err != nil {
+		logger.Error("failed to list unanalyzed photos", "error", err)
+	}
+	for _, id := range pending {
+		a.enqueue(job{photoID: id, logger: logger})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-a.queue:
+			a.process(ctx, j)
+		}
+	}
+}
+
+// process runs a queued analysis and logs the outcome
+func (a *Analyzer) process(ctx context.Context, j job) {
+	// analysis runs to completion on shutdown rather than leaving a
+	// half-finished detector request behind
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analysisTimeout)
+	defer cancel()
+	ctx = logger.NewContext(ctx, j.logger)
+
+	id := j.photoID.String()
+	result, err := a.AnalyzePhoto(ctx, j.photoID)
+	if errors.Is(err, ErrDuplicate) {
+		j.logger.Info("skipped duplicate photo", "photo_id", id)
+		return
+	}
+	if err != nil {
+		j.logger.Error("photo analysis failed", "photo_id", id, "error", err)
+		return
+	}
+	j.logger.Info("photo analyzed", "photo_id", id, "violations", len(result.Created), "skipped", result.Skipped)
+}
+
+// Result summarizes one analysis run
+type Result struct {
+	PhotoID pgtype.UUID
+	Created []database.Violation // New open violations
+	Skipped int                  // Findings matching violations already reviewed
+}
+
+// AnalyzePhoto detects hazards in a photo. Re-analyzing replaces the open,
+// AI-detected violations from the previous run; violations an inspector has
+// already reviewed are kept and matching findings are skipped. Duplicate
+// photos are not sent to the detector, so the same hazard isn't recorded
+// twice.
+func (a *Analyzer) AnalyzePhoto(ctx context.Context, photoID pgtype.UUID) (result Result, err error) {
+	result = Result{PhotoID: photoID}
+	if a.det == nil {
+		return result, ErrUnavailable
+	}
+
+	// the root of a trace when run in the background
+	ctx, span := tracing.Tracer().Start(ctx, "analysis.AnalyzePhoto", trace.WithAttributes(attribute.String("photo.id", photoID.String())))
+	defer func() {
+		span.SetAttributes(attribute.Int("analysis.violations", len(result.Created)), attribute.Int("analysis.skipped", result.Skipped))
+		tracing.End(span, err)
+	}()
+
+	photo, err := a.q.GetPhoto(ctx, photoID)
+	if err != nil {
+		return result, fmt.Errorf("load photo: %w", err)
+	}
+	if photo.DuplicateOf.Valid {
+		return result, ErrDuplicate
+	}
+
+	// progress is best effort; the results are there on reload either way
+	_ = events.Publish(ctx, a.q, events.KindAnalysis, photo.ProjectID, events.Analysis{
+		PhotoID: photo.ID.String(),
+		Status:  events.AnalysisStarted,
+	})
+	result, err = a.analyze(ctx, photo)
+	if err != nil {
+		// sent even when ctx ran out, so the page stops showing progress
+		_ = events.Publish(context.WithoutCancel(ctx), a.q, events.KindAnalysis, photo.ProjectID, events.Analysis{
+			PhotoID: photo.ID.String(),
+			Status:  events.AnalysisFailed,
+		})
+	}
+	return result, err
+}
+
+// analyze runs detection on a photo and records the findings
+func (a *Analyzer) analyze(ctx context.Context, photo database.Photo) (Result, error) {
+	result := Result{PhotoID: photo.ID}
+	f, err := a.store.Open(ctx, photo.StorageKey)
+	if err != nil {
+		return result, fmt.Errorf("open photo: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return result, fmt.Errorf("read photo: %w", err)
+	}
+
+	findings, err := a.det.Detect(ctx, detector.Image{Data: data, ContentType: photo.ContentType})
+	if err != nil {
+		return result, fmt.Errorf("detect hazards: %w", err)
+	}
+
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := a.q.WithTx(tx)
+
+	if err := qtx.DeleteOpenDetectedViolationsByPhoto(ctx, photo.ID); err != nil {
+		return result, fmt.Errorf("clear previous findings: %w", err)
+	}
+	reviewed, err := qtx.ListViolationsByPhoto(ctx, photo.ID)
+	if err != nil {
+		return result, fmt.Errorf("list reviewed violations: %w", err)
+	}
+
+	for _, finding := range findings {
+		if finding.Confidence < minFindingConfidence {
+			continue
+		}
+		if matchesAny(finding, reviewed) {
+			result.Skipped++
+			continue
+		}
+
+		v, err := qtx.CreateViolation(ctx, database.CreateViolationParams{
+			ProjectID:    photo.ProjectID,
+			Description:  finding.Description,
+			Regulation:   finding.Regulation,
+			RiskLevel:    database.RiskLevel(finding.RiskLevel),
+			Category:     finding.Category,
+			AiConfidence: finding.Confidence,
+			PhotoID:      photo.ID,
+		})
+		if err != nil {
+			return result, fmt.Errorf("create violation: %w", err)
+		}
+		result.Created = append(result.Created, v)
+
+		if b := finding.Box; b != nil {
+			if _, err := qtx.CreateRegion(ctx, database.CreateRegionParams{
+				ViolationID: v.ID,
+				PhotoID:     photo.ID,
+				Shape:       database.RegionShapeBox,
+				X:           b.X,
+				Y:           b.Y,
+				Width:       b.Width,
+				Height:      b.Height,
+				Source:      database.RegionSourceDetector,
+				Confidence:  pgtype.Float8{Float64: finding.Confidence, Valid: true},
+			}); err != nil {
+				return result, fmt.Errorf("create region: %w", err)
+			}
+		}
+
+		metadata, err := json.Marshal(map[string]interface{}{
+			"violation_id": v.ID.String(),
+			"photo_id":     photo.ID.String(),
+			"risk_level":   finding.RiskLevel,
+			"confidence":   finding.Confidence,
+		})
+		if err != nil {
+			return result, fmt.Errorf("encode timeline metadata: %w", err)
+		}
+		if _, err := qtx.CreateTimelineEvent(ctx, database.CreateTimelineEventParams{
+			ProjectID:   photo.ProjectID,
+			ViolationID: v.ID,
+			Type:        "violation_found",
+			Description: fmt.Sprintf("AI detected %q in a photo uploaded by", finding.Description),
+			UserID:      photo.UploadedBy,
+			UserName:    photo.UploadedByName,
+			Metadata:    metadata,
+		}); err != nil {
+			return result, fmt.Errorf("record timeline event: %w", err)
+		}
+
+		if err := webhooks.Publish(ctx, qtx, webhooks.ViolationCreated, webhooks.ViolationData(v)); err != nil {
+			return result, err
+		}
+		if v.RiskLevel == database.RiskLevelCritical {
+			if err := webhooks.Publish(ctx, qtx, webhooks.ViolationCritical, webhooks.ViolationData(v)); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := qtx.MarkPhotoAnalyzed(ctx, photo.ID); err != nil {
+		return result, fmt.Errorf("mark photo analyzed: %w", err)
+	}
+	if err := qtx.TouchProject(ctx, photo.ProjectID); err != nil {
+		return result, fmt.Errorf("touch project: %w", err)
+	}
+	if err := events.Publish(ctx, qtx, events.KindAnalysis, photo.ProjectID, events.Analysis{
+		PhotoID:    photo.ID.String(),
+		Status:     events.AnalysisCompleted,
+		Violations: len(result.Created),
+		Skipped:    result.Skipped,
+	}); err != nil {
+		return result, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("commit analysis: %w", err)
+	}
+	return result, nil
+}
+
+// matchesAny reports whether a finding duplicates one of the violations
+func matchesAny(f detector.Finding, violations []database.Violation) bool {
+	// the confidence threshold applies to new findings, not to matching
+	f.Confidence = 1
+	for _, v := range violations {
+		if _, ok := detector.FindHazard([]detector.Finding{f}, v.Regulation, v.Category); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Shapes a region may take
+const (
+	ShapeBox     = "box"
+	ShapePolygon = "polygon"
+)
+
+// maxPolygonPoints caps the vertices of a single polygon region
+const maxPolygonPoints = 100
+
+// epsilon is the rounding error tolerated at the image edges
+const epsilon = 1e-6
+
+// Point is a position normalized to the image size (0-1, origin top left)
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Region outlines where a hazard appears in an image. X, Y, Width and Height
+// are the bounding box for every shape; polygons also carry their vertices.
+type Region struct {
+	Shape  string
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	Points []Point
+	Color  color.Color
+}
+
+var errOutOfBounds = errors.New("coordinates must be between 0 and 1")
+
+// Normalize validates a region and fills in derived fields. Polygons get
+// their bounding box computed from the vertices.
+func Normalize(r *Region) error {
+	switch r.Shape {
+	case "", ShapeBox:
+		r.Shape = ShapeBox
+		r.Points = nil
+		if !inUnit(r.X) || !inUnit(r.Y) || r.X+r.Width > 1+epsilon || r.Y+r.Height > 1+epsilon {
+			return errOutOfBounds
+		}
+		// absorb rounding from clients that compute width as right - left
+		r.Width, r.Height = min(r.Width, 1-r.X), min(r.Height, 1-r.Y)
+	case ShapePolygon:
+		if len(r.Points) < 3 || len(r.Points) > maxPolygonPoints {
+			return fmt.Errorf("polygons need between 3 and %d points", maxPolygonPoints)
+		}
+		minX, minY, maxX, maxY := 1.0, 1.0, 0.0, 0.0
+		for _, p := range r.Points {
+			if !inUnit(p.X) || !inUnit(p.Y) {
+				return errOutOfBounds
+			}
+			minX, minY = min(minX, p.X), min(minY, p.Y)
+			maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+		}
+		r.X, r.Y, r.Width, r.Height = minX, minY, maxX-minX, maxY-minY
+	default:
+		return fmt.Errorf("unknown shape %q", r.Shape)
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return errors.New("region must have a non-zero width and height")
+	}
+	return nil
+}
+
+func inUnit(v float64) bool {
+	return v >= 0 && v <= 1 && !math.IsNaN(v)
+}
+
+// RiskColor returns the outline color used for a violation risk level,
+// matching the badges used in the app
+func RiskColor(riskLevel string) color.Color {
+	switch riskLevel {
+	case "critical":
+		return color.RGBA{R: 0xdc, G: 0x26, B: 0x26, A: 0xff}
+	case "high":
+		return color.RGBA{R: 0xea, G: 0x58, B: 0x0c, A: 0xff}
+	case "medium":
+		return color.RGBA{R: 0xca, G: 0x8a, B: 0x04, A: 0xff}
+	default:
+		return color.RGBA{R: 0x4b, G: 0x55, B: 0x63, A: 0xff}
+	}
+}
+
+// Burn decodes a JPEG, PNG or WebP image, draws the region outlines onto it
+// and writes the result to w as a JPEG
+func Burn(w io.Writer, data []byte, regions []Region) error {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, src, b.Min, draw.Src)
+
+	// stroke scales with the image so outlines stay visible on large photos
+	stroke := max(2, min(b.Dx(), b.Dy())/200)
+	for _, r := range regions {
+		c := r.Color
+		if c == nil {
+			c = RiskColor("")
+		}
+		var pts []image.Point
+		if r.Shape == ShapePolygon {
+			for _, p := range r.Points {
+				pts = append(pts, toPixel(b, p))
+			}
+		} else {
+			pts = []image.Point{
+				toPixel(b, Point{r.X, r.Y}),
+				toPixel(b, Point{r.X + r.Width, r.Y}),
+				toPixel(b, Point{r.X + r.Width, r.Y + r.Height}),
+				toPixel(b, Point{r.X, r.Y + r.Height}),
+			}
+		}
+		for i := range pts {
+			line(dst, pts[i], pts[(i+1)%len(pts)], stroke, c)
+		}
+	}
+
+	if err := jpeg.Encode(w, dst, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("encode image: %w", err)
+	}
+	return nil
+}
+
+func toPixel(b image.Rectangle, p Point) image.Point {
+	return image.Point{
+		X: b.Min.X + int(math.Round(p.X*float64(b.Dx()-1))),
+		Y: b.Min.Y + int(math.Round(p.Y*float64(b.Dy()-1))),
+	}
+}
+
+// line draws a segment of the given thickness by stamping squares along it
+func line(dst draw.Image, from, to image.Point, stroke int, c color.Color) {
+	fill := image.NewUniform(c)
+	dx, dy := to.X-from.X, to.Y-from.Y
+	steps := max(abs(dx), abs(dy), 1)
+	half := stroke / 2
+	for i := 0; i <= steps; i++ {
+		x := from.X + dx*i/steps
+		y := from.Y + dy*i/steps
+		r := image.Rect(x-half, y-half, x-half+stroke, y-half+stroke)
+		draw.Draw(dst, r.Intersect(dst.Bounds()), fill, image.Point{}, draw.Src)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
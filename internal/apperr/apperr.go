@@ -0,0 +1,119 @@
+// Package apperr describes failures that users can act on, so that any layer
+// can say a request was wrong without knowing how the response is written.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Kind classifies an error by what went wrong
+type Kind int
+
+const (
+	Internal   Kind = iota // Unexpected failure; details are logged, not shown
+	NotFound               // The resource doesn't exist
+	Forbidden              // The user may not do this
+	Validation             // The input was invalid
+	Conflict               // The resource's state doesn't allow this
+)
+
+// Status returns the HTTP status code for the kind
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Forbidden:
+		return http.StatusForbidden
+	case Validation:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Code returns the machine-readable code JSON API errors use for the kind
+func (k Kind) Code() string {
+	switch k {
+	case NotFound:
+		return "not_found"
+	case Forbidden:
+		return "forbidden"
+	case Validation:
+		return "invalid_request"
+	case Conflict:
+		return "conflict"
+	default:
+		return "internal"
+	}
+}
+
+// Error is an error with a message that is safe to show to users
+type Error struct {
+	Kind    Kind
+	Message string // Shown to users
+	Field   string // Input at fault, for validation errors
+	Err     error  // Underlying cause, if any; logged but not shown
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError returns an error saying the resource doesn't exist
+func NotFoundError(message string) *Error {
+	return &Error{Kind: NotFound, Message: message}
+}
+
+// ForbiddenError returns an error saying the user may not do this
+func ForbiddenError(message string) *Error {
+	return &Error{Kind: Forbidden, Message: message}
+}
+
+// ValidationError returns an error saying the named input was invalid
+func ValidationError(field, message string) *Error {
+	return &Error{Kind: Validation, Message: message, Field: field}
+}
+
+// ConflictError returns an error saying the resource's state doesn't allow
+// this
+func ConflictError(message string) *Error {
+	return &Error{Kind: Conflict, Message: message}
+}
+
+// KindOf returns the kind of err. Rows that weren't found are NotFound;
+// anything else not wrapping an *Error is Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound
+	}
+	return Internal
+}
+
+// Message returns what users are told about err. Internal errors only
+// say something went wrong.
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Kind != Internal {
+		return e.Message
+	}
+	if KindOf(err) == NotFound {
+		return http.StatusText(http.StatusNotFound)
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"log/slog"
 	"strings"
 )
 
@@ -12,9 +13,27 @@ type Config struct {
 	DB_USER           string
 	DB_PASSWORD       string
 	DB_NAME           string
+	DB_MAX_CONNS      string // largest number of pooled connections
+	DB_MIN_CONNS      string // connections kept open while idle
+	DB_CONN_LIFETIME  string // duration after which a connection is replaced
+	DB_CONN_IDLE_TIME string // duration after which an idle connection is closed
+	DB_DIAL_TIMEOUT   string // limit on opening a single connection
+	DB_STARTUP_WAIT   string // how long to wait for the database on start
 	LOG_LEVEL         string // debug, info, warn, error
 	ENVIRONMENT       string // prod, dev
 	ANTHROPIC_API_KEY string
+	ANTHROPIC_MODEL   string // model used for hazard detection
+	BLOB_DIR          string // directory for uploaded photos and reports
+	APP_URL           string // public address of the app, for links in emails
+	SMTP_HOST         string // mail server; email is logged instead when empty
+	SMTP_PORT         string
+	SMTP_USERNAME     string
+	SMTP_PASSWORD     string
+	MAIL_FROM         string // sender of notification emails
+	AUTO_MIGRATE      string // "true" to apply pending migrations on start
+	OTLP_ENDPOINT     string // OTLP/HTTP collector traces are sent to; tracing is off when empty
+	OTEL_SERVICE_NAME string // service name traces are reported under
+	TRACE_SAMPLING    string // fraction of new traces recorded, from 0 to 1
 }
 
 // Order of precedence from least to greatest is
@@ -29,8 +48,26 @@ func GetConfig(environ []string, args []string) Config {
 		DB_USER:           "postgres",
 		DB_PASSWORD:       "",
 		DB_NAME:           "postgres",
+		DB_MAX_CONNS:      "10",
+		DB_MIN_CONNS:      "0",
+		DB_CONN_LIFETIME:  "1h",
+		DB_CONN_IDLE_TIME: "30m",
+		DB_DIAL_TIMEOUT:   "5s",
+		DB_STARTUP_WAIT:   "1m",
 		LOG_LEVEL:         "info",
 		ANTHROPIC_API_KEY: "",
+		ANTHROPIC_MODEL:   "",
+		BLOB_DIR:          "data/blobs",
+		APP_URL:           "http://localhost:8080",
+		SMTP_HOST:         "",
+		SMTP_PORT:         "587",
+		SMTP_USERNAME:     "",
+		SMTP_PASSWORD:     "",
+		MAIL_FROM:         "SafeSite Inspector <noreply@localhost>",
+		AUTO_MIGRATE:      "false",
+		OTLP_ENDPOINT:     "",
+		OTEL_SERVICE_NAME: "ironman",
+		TRACE_SAMPLING:    "1",
 	}
 
 	if appHost := getEnv(environ, "APP_HOST"); appHost != "" {
@@ -61,6 +98,30 @@ func GetConfig(environ []string, args []string) Config {
 		config.DB_NAME = dbName
 	}
 
+	if dbMaxConns := getEnv(environ, "DB_MAX_CONNS"); dbMaxConns != "" {
+		config.DB_MAX_CONNS = dbMaxConns
+	}
+
+	if dbMinConns := getEnv(environ, "DB_MIN_CONNS"); dbMinConns != "" {
+		config.DB_MIN_CONNS = dbMinConns
+	}
+
+	if dbConnLifetime := getEnv(environ, "DB_CONN_LIFETIME"); dbConnLifetime != "" {
+		config.DB_CONN_LIFETIME = dbConnLifetime
+	}
+
+	if dbConnIdleTime := getEnv(environ, "DB_CONN_IDLE_TIME"); dbConnIdleTime != "" {
+		config.DB_CONN_IDLE_TIME = dbConnIdleTime
+	}
+
+	if dbDialTimeout := getEnv(environ, "DB_DIAL_TIMEOUT"); dbDialTimeout != "" {
+		config.DB_DIAL_TIMEOUT = dbDialTimeout
+	}
+
+	if dbStartupWait := getEnv(environ, "DB_STARTUP_WAIT"); dbStartupWait != "" {
+		config.DB_STARTUP_WAIT = dbStartupWait
+	}
+
 	if logLevel := getEnv(environ, "LOG_LEVEL"); logLevel != "" {
 		config.LOG_LEVEL = logLevel
 	}
@@ -73,6 +134,54 @@ func GetConfig(environ []string, args []string) Config {
 		config.ANTHROPIC_API_KEY = anthropicApiKey
 	}
 
+	if anthropicModel := getEnv(environ, "ANTHROPIC_MODEL"); anthropicModel != "" {
+		config.ANTHROPIC_MODEL = anthropicModel
+	}
+
+	if blobDir := getEnv(environ, "BLOB_DIR"); blobDir != "" {
+		config.BLOB_DIR = blobDir
+	}
+
+	if appUrl := getEnv(environ, "APP_URL"); appUrl != "" {
+		config.APP_URL = appUrl
+	}
+
+	if smtpHost := getEnv(environ, "SMTP_HOST"); smtpHost != "" {
+		config.SMTP_HOST = smtpHost
+	}
+
+	if smtpPort := getEnv(environ, "SMTP_PORT"); smtpPort != "" {
+		config.SMTP_PORT = smtpPort
+	}
+
+	if smtpUsername := getEnv(environ, "SMTP_USERNAME"); smtpUsername != "" {
+		config.SMTP_USERNAME = smtpUsername
+	}
+
+	if smtpPassword := getEnv(environ, "SMTP_PASSWORD"); smtpPassword != "" {
+		config.SMTP_PASSWORD = smtpPassword
+	}
+
+	if mailFrom := getEnv(environ, "MAIL_FROM"); mailFrom != "" {
+		config.MAIL_FROM = mailFrom
+	}
+
+	if autoMigrate := getEnv(environ, "AUTO_MIGRATE"); autoMigrate != "" {
+		config.AUTO_MIGRATE = autoMigrate
+	}
+
+	if otlpEndpoint := getEnv(environ, "OTLP_ENDPOINT"); otlpEndpoint != "" {
+		config.OTLP_ENDPOINT = otlpEndpoint
+	}
+
+	if otelServiceName := getEnv(environ, "OTEL_SERVICE_NAME"); otelServiceName != "" {
+		config.OTEL_SERVICE_NAME = otelServiceName
+	}
+
+	if traceSampling := getEnv(environ, "TRACE_SAMPLING"); traceSampling != "" {
+		config.TRACE_SAMPLING = traceSampling
+	}
+
 	// Flags
 	if appHost := getFlag(args, "app_host"); appHost != "" {
 		config.APP_HOST = appHost
@@ -102,6 +211,30 @@ func GetConfig(environ []string, args []string) Config {
 		config.DB_NAME = dbName
 	}
 
+	if dbMaxConns := getFlag(args, "db_max_conns"); dbMaxConns != "" {
+		config.DB_MAX_CONNS = dbMaxConns
+	}
+
+	if dbMinConns := getFlag(args, "db_min_conns"); dbMinConns != "" {
+		config.DB_MIN_CONNS = dbMinConns
+	}
+
+	if dbConnLifetime := getFlag(args, "db_conn_lifetime"); dbConnLifetime != "" {
+		config.DB_CONN_LIFETIME = dbConnLifetime
+	}
+
+	if dbConnIdleTime := getFlag(args, "db_conn_idle_time"); dbConnIdleTime != "" {
+		config.DB_CONN_IDLE_TIME = dbConnIdleTime
+	}
+
+	if dbDialTimeout := getFlag(args, "db_dial_timeout"); dbDialTimeout != "" {
+		config.DB_DIAL_TIMEOUT = dbDialTimeout
+	}
+
+	if dbStartupWait := getFlag(args, "db_startup_wait"); dbStartupWait != "" {
+		config.DB_STARTUP_WAIT = dbStartupWait
+	}
+
 	if environment := getFlag(args, "environment"); environment != "" {
 		config.ENVIRONMENT = environment
 	}
@@ -114,11 +247,114 @@ func GetConfig(environ []string, args []string) Config {
 		config.ANTHROPIC_API_KEY = anthropicApiKey
 	}
 
+	if anthropicModel := getFlag(args, "anthropic_model"); anthropicModel != "" {
+		config.ANTHROPIC_MODEL = anthropicModel
+	}
+
+	if blobDir := getFlag(args, "blob_dir"); blobDir != "" {
+		config.BLOB_DIR = blobDir
+	}
+
+	if appUrl := getFlag(args, "app_url"); appUrl != "" {
+		config.APP_URL = appUrl
+	}
+
+	if smtpHost := getFlag(args, "smtp_host"); smtpHost != "" {
+		config.SMTP_HOST = smtpHost
+	}
+
+	if smtpPort := getFlag(args, "smtp_port"); smtpPort != "" {
+		config.SMTP_PORT = smtpPort
+	}
+
+	if smtpUsername := getFlag(args, "smtp_username"); smtpUsername != "" {
+		config.SMTP_USERNAME = smtpUsername
+	}
+
+	if smtpPassword := getFlag(args, "smtp_password"); smtpPassword != "" {
+		config.SMTP_PASSWORD = smtpPassword
+	}
+
+	if mailFrom := getFlag(args, "mail_from"); mailFrom != "" {
+		config.MAIL_FROM = mailFrom
+	}
+
+	if autoMigrate := getFlag(args, "auto_migrate"); autoMigrate != "" {
+		config.AUTO_MIGRATE = autoMigrate
+	}
+
+	if otlpEndpoint := getFlag(args, "otlp_endpoint"); otlpEndpoint != "" {
+		config.OTLP_ENDPOINT = otlpEndpoint
+	}
+
+	if otelServiceName := getFlag(args, "otel_service_name"); otelServiceName != "" {
+		config.OTEL_SERVICE_NAME = otelServiceName
+	}
+
+	if traceSampling := getFlag(args, "trace_sampling"); traceSampling != "" {
+		config.TRACE_SAMPLING = traceSampling
+	}
 
 	return config
 }
 
+// Flag returns the value of --name in args, for options of a single command
+// written the same way as the config flags
+func Flag(args []string, name string) string {
+	return getFlag(args, name)
+}
+
+// Production reports whether the app runs in production, served over
+// HTTPS, rather than in development. Like the logger, anything but "dev" or
+// "development" is production.
+func (c Config) Production() bool {
+	return c.ENVIRONMENT != "dev" && c.ENVIRONMENT != "development"
+}
+
+// LogValue logs the config with its passwords and keys redacted
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("APP_HOST", c.APP_HOST),
+		slog.String("APP_PORT", c.APP_PORT),
+		slog.String("DB_HOST", c.DB_HOST),
+		slog.String("DB_PORT", c.DB_PORT),
+		slog.String("DB_USER", c.DB_USER),
+		slog.String("DB_PASSWORD", redact(c.DB_PASSWORD)),
+		slog.String("DB_NAME", c.DB_NAME),
+		slog.String("DB_MAX_CONNS", c.DB_MAX_CONNS),
+		slog.String("DB_MIN_CONNS", c.DB_MIN_CONNS),
+		slog.String("DB_CONN_LIFETIME", c.DB_CONN_LIFETIME),
+		slog.String("DB_CONN_IDLE_TIME", c.DB_CONN_IDLE_TIME),
+		slog.String("DB_DIAL_TIMEOUT", c.DB_DIAL_TIMEOUT),
+		slog.String("DB_STARTUP_WAIT", c.DB_STARTUP_WAIT),
+		slog.String("LOG_LEVEL", c.LOG_LEVEL),
+		slog.String("ENVIRONMENT", c.ENVIRONMENT),
+		slog.String("ANTHROPIC_API_KEY", redact(c.ANTHROPIC_API_KEY)),
+		slog.String("ANTHROPIC_MODEL", c.ANTHROPIC_MODEL),
+		slog.String("BLOB_DIR", c.BLOB_DIR),
+		slog.String("APP_URL", c.APP_URL),
+		slog.String("SMTP_HOST", c.SMTP_HOST),
+		slog.String("SMTP_PORT", c.SMTP_PORT),
+		slog.String("SMTP_USERNAME", c.SMTP_USERNAME),
+		slog.String("SMTP_PASSWORD", redact(c.SMTP_PASSWORD)),
+		slog.String("MAIL_FROM", c.MAIL_FROM),
+		slog.String("AUTO_MIGRATE", c.AUTO_MIGRATE),
+		slog.String("OTLP_ENDPOINT", c.OTLP_ENDPOINT),
+		slog.String("OTEL_SERVICE_NAME", c.OTEL_SERVICE_NAME),
+		slog.String("TRACE_SAMPLING", c.TRACE_SAMPLING),
+	)
+}
+
 // helpers
+
+// redact hides a secret, keeping whether it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
 func getFlag(args []string, flag string) string {
 	// Look for --flag=value format
 	prefix := "--" + flag + "="
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogValueRedactsSecrets(t *testing.T) {
+	secrets := []string{"db-hunter2", "sk-ant-key", "smtp-hunter2"}
+	config := GetConfig(
+		[]string{"DB_PASSWORD=db-hunter2", "ANTHROPIC_API_KEY=sk-ant-key", "DB_HOST=db.internal"},
+		[]string{"ironman", "--smtp_password", "smtp-hunter2", "--smtp_host=mail.internal"},
+	)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("config", "environ", config)
+	logged := buf.String()
+
+	for _, secret := range secrets {
+		if strings.Contains(logged, secret) {
+			t.Errorf("log contains secret %q: %s", secret, logged)
+		}
+	}
+	for _, want := range []string{`"DB_PASSWORD":"[redacted]"`, `"SMTP_PASSWORD":"[redacted]"`, `"DB_HOST":"db.internal"`, `"SMTP_HOST":"mail.internal"`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log lacks %s: %s", want, logged)
+		}
+	}
+	// an unset secret is logged empty, so it's clear it is missing
+	buf.Reset()
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("config", "environ", Config{})
+	if !strings.Contains(buf.String(), `"DB_PASSWORD":""`) {
+		t.Errorf("log lacks empty DB_PASSWORD: %s", buf.String())
+	}
+}
@@ -0,0 +1,312 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: digest.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimSafetyDigest = `-- name: ClaimSafetyDigest :one
+INSERT INTO safety_digests (user_id, frequency, period_start, period_end)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, frequency, period_start) DO UPDATE
+SET attempts = safety_digests.attempts + 1,
+    claimed_at = CURRENT_TIMESTAMP
+WHERE safety_digests.sent_at IS NULL
+  AND safety_digests.claimed_at < CURRENT_TIMESTAMP - make_interval(secs => $5::float8)
+  AND safety_digests.attempts < $6::int
+RETURNING user_id, frequency, period_start, period_end, attempts, claimed_at, sent_at, error
+`
+
+type ClaimSafetyDigestParams struct {
+	UserID       pgtype.UUID
+	Frequency    DigestFrequency
+	PeriodStart  pgtype.Date
+	PeriodEnd    pgtype.Date
+	RetrySeconds float64
+	MaxAttempts  int32
+}
+
+// Returns no row when the period was already sent, is being sent by another
+// instance, or has run out of attempts
+func (q *Queries) ClaimSafetyDigest(ctx context.Context, arg ClaimSafetyDigestParams) (SafetyDigest, error) {
+	row := q.db.QueryRow(ctx, claimSafetyDigest,
+		arg.UserID,
+		arg.Frequency,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.RetrySeconds,
+		arg.MaxAttempts,
+	)
+	var i SafetyDigest
+	err := row.Scan(
+		&i.UserID,
+		&i.Frequency,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.Attempts,
+		&i.ClaimedAt,
+		&i.SentAt,
+		&i.Error,
+	)
+	return i, err
+}
+
+const listDigestOverdueActions = `-- name: ListDigestOverdueActions :many
+SELECT
+  v.id,
+  v.project_id,
+  v.description,
+  v.risk_level,
+  v.due_date,
+  v.assigned_subcontractor,
+  p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.status IN ('open', 'validated')
+  AND v.due_date < $1::date
+  AND p.status <> 'archived'
+  AND ($2::bool OR p.inspector_id = $3 OR v.assigned_user_id = $3)
+ORDER BY v.due_date, v.id
+LIMIT $4
+`
+
+type ListDigestOverdueActionsParams struct {
+	Today       pgtype.Date
+	AllProjects bool
+	UserID      pgtype.UUID
+	MaxActions  int32
+}
+
+type ListDigestOverdueActionsRow struct {
+	ID                    pgtype.UUID
+	ProjectID             pgtype.UUID
+	Description           string
+	RiskLevel             RiskLevel
+	DueDate               pgtype.Date
+	AssignedSubcontractor string
+	ProjectName           string
+}
+
+// Unresolved violations past their due date on the projects in a user's
+// summary or assigned to them, most overdue first
+func (q *Queries) ListDigestOverdueActions(ctx context.Context, arg ListDigestOverdueActionsParams) ([]ListDigestOverdueActionsRow, error) {
+	rows, err := q.db.Query(ctx, listDigestOverdueActions,
+		arg.Today,
+		arg.AllProjects,
+		arg.UserID,
+		arg.MaxActions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDigestOverdueActionsRow
+	for rows.Next() {
+		var i ListDigestOverdueActionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Description,
+			&i.RiskLevel,
+			&i.DueDate,
+			&i.AssignedSubcontractor,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDigestProjects = `-- name: ListDigestProjects :many
+SELECT
+  p.id,
+  p.name,
+  p.compliance_score,
+  (start_score.compliance_score IS NOT NULL)::bool AS has_history,
+  COALESCE(start_score.compliance_score, p.compliance_score)::float8 AS start_compliance,
+  COALESCE(end_score.compliance_score, p.compliance_score)::float8 AS end_compliance,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'critical') AS critical_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'high') AS high_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'medium') AS medium_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'low') AS low_count
+FROM projects p
+LEFT JOIN violations v ON v.project_id = p.id
+  AND v.created_at >= $1 AND v.created_at < $2
+LEFT JOIN LATERAL (
+  SELECT h.compliance_score FROM project_compliance_history h
+  WHERE h.project_id = p.id AND h.recorded_at < $1
+  ORDER BY h.recorded_at DESC
+  LIMIT 1
+) start_score ON TRUE
+LEFT JOIN LATERAL (
+  SELECT h.compliance_score FROM project_compliance_history h
+  WHERE h.project_id = p.id AND h.recorded_at < $2
+  ORDER BY h.recorded_at DESC
+  LIMIT 1
+) end_score ON TRUE
+WHERE p.status <> 'archived'
+  AND ($3::bool OR p.inspector_id = $4)
+GROUP BY p.id, start_score.compliance_score, end_score.compliance_score
+ORDER BY p.name
+`
+
+type ListDigestProjectsParams struct {
+	PeriodStart pgtype.Timestamptz
+	PeriodEnd   pgtype.Timestamptz
+	AllProjects bool
+	UserID      pgtype.UUID
+}
+
+type ListDigestProjectsRow struct {
+	ID              pgtype.UUID
+	Name            string
+	ComplianceScore float64
+	HasHistory      bool
+	StartCompliance float64
+	EndCompliance   float64
+	CriticalCount   int64
+	HighCount       int64
+	MediumCount     int64
+	LowCount        int64
+}
+
+// Projects in a user's summary, with the violations found and the
+// compliance score at each end of the period. Projects without history from
+// before the period started have no change to report. Admins see every
+// project; others see the projects they inspect.
+func (q *Queries) ListDigestProjects(ctx context.Context, arg ListDigestProjectsParams) ([]ListDigestProjectsRow, error) {
+	rows, err := q.db.Query(ctx, listDigestProjects,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.AllProjects,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDigestProjectsRow
+	for rows.Next() {
+		var i ListDigestProjectsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.ComplianceScore,
+			&i.HasHistory,
+			&i.StartCompliance,
+			&i.EndCompliance,
+			&i.CriticalCount,
+			&i.HighCount,
+			&i.MediumCount,
+			&i.LowCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSafetyDigestSubscribers = `-- name: ListSafetyDigestSubscribers :many
+SELECT
+  u.id AS user_id,
+  u.email,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email)::text AS user_name,
+  u.role,
+  COALESCE(u.timezone, '')::text AS timezone,
+  np.safety_digest,
+  np.digest_time,
+  last.period_start AS last_period_start
+FROM notification_preferences np
+JOIN users u ON u.id = np.user_id AND u.is_active
+LEFT JOIN LATERAL (
+  SELECT d.period_start FROM safety_digests d
+  WHERE d.user_id = u.id AND d.frequency = np.safety_digest AND d.sent_at IS NOT NULL
+  ORDER BY d.period_start DESC
+  LIMIT 1
+) last ON TRUE
+WHERE np.safety_digest <> 'off'
+`
+
+type ListSafetyDigestSubscribersRow struct {
+	UserID          pgtype.UUID
+	Email           string
+	UserName        string
+	Role            UserRole
+	Timezone        string
+	SafetyDigest    DigestFrequency
+	DigestTime      pgtype.Time
+	LastPeriodStart pgtype.Date
+}
+
+// Safety Digests Table --
+// Users who get summary emails, with the start of the latest period they
+// were sent
+func (q *Queries) ListSafetyDigestSubscribers(ctx context.Context) ([]ListSafetyDigestSubscribersRow, error) {
+	rows, err := q.db.Query(ctx, listSafetyDigestSubscribers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSafetyDigestSubscribersRow
+	for rows.Next() {
+		var i ListSafetyDigestSubscribersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.UserName,
+			&i.Role,
+			&i.Timezone,
+			&i.SafetyDigest,
+			&i.DigestTime,
+			&i.LastPeriodStart,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordSafetyDigest = `-- name: RecordSafetyDigest :exec
+UPDATE safety_digests
+SET sent_at = $1,
+    error = $2
+WHERE user_id = $3 AND frequency = $4 AND period_start = $5
+`
+
+type RecordSafetyDigestParams struct {
+	SentAt      pgtype.Timestamptz
+	Error       string
+	UserID      pgtype.UUID
+	Frequency   DigestFrequency
+	PeriodStart pgtype.Date
+}
+
+func (q *Queries) RecordSafetyDigest(ctx context.Context, arg RecordSafetyDigestParams) error {
+	_, err := q.db.Exec(ctx, recordSafetyDigest,
+		arg.SentAt,
+		arg.Error,
+		arg.UserID,
+		arg.Frequency,
+		arg.PeriodStart,
+	)
+	return err
+}
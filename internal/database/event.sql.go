@@ -0,0 +1,20 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: event.sql
+
+package database
+
+import (
+	"context"
+)
+
+const notifyProjectEvent = `-- name: NotifyProjectEvent :exec
+SELECT pg_notify('project_events', $1::text)
+`
+
+// Project Events --
+func (q *Queries) NotifyProjectEvent(ctx context.Context, payload string) error {
+	_, err := q.db.Exec(ctx, notifyProjectEvent, payload)
+	return err
+}
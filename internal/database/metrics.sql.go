@@ -0,0 +1,33 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: metrics.sql
+
+package database
+
+import (
+	"context"
+)
+
+const getQueueDepths = `-- name: GetQueueDepths :one
+SELECT
+    (SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')::bigint AS webhook_deliveries,
+    (SELECT COUNT(*) FROM notifications WHERE email_status = 'pending')::bigint AS notification_emails,
+    (SELECT COUNT(*) FROM photos
+     WHERE purpose = 'inspection' AND analyzed_at IS NULL AND duplicate_of IS NULL)::bigint AS unanalyzed_photos
+`
+
+type GetQueueDepthsRow struct {
+	WebhookDeliveries  int64
+	NotificationEmails int64
+	UnanalyzedPhotos   int64
+}
+
+// Job Queues --
+// Work waiting for the background workers
+func (q *Queries) GetQueueDepths(ctx context.Context) (GetQueueDepthsRow, error) {
+	row := q.db.QueryRow(ctx, getQueueDepths)
+	var i GetQueueDepthsRow
+	err := row.Scan(&i.WebhookDeliveries, &i.NotificationEmails, &i.UnanalyzedPhotos)
+	return i, err
+}
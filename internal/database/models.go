@@ -11,6 +11,134 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiTokenKind string
+
+const (
+	ApiTokenKindPersonal ApiTokenKind = "personal"
+	ApiTokenKindService  ApiTokenKind = "service"
+)
+
+func (e *ApiTokenKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ApiTokenKind(s)
+	case string:
+		*e = ApiTokenKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ApiTokenKind: %T", src)
+	}
+	return nil
+}
+
+type NullApiTokenKind struct {
+	ApiTokenKind ApiTokenKind
+	Valid        bool // Valid is true if ApiTokenKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullApiTokenKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.ApiTokenKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ApiTokenKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullApiTokenKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ApiTokenKind), nil
+}
+
+type CoordinatesSource string
+
+const (
+	CoordinatesSourceManual   CoordinatesSource = "manual"
+	CoordinatesSourceGeocoded CoordinatesSource = "geocoded"
+	CoordinatesSourcePhotos   CoordinatesSource = "photos"
+)
+
+func (e *CoordinatesSource) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = CoordinatesSource(s)
+	case string:
+		*e = CoordinatesSource(s)
+	default:
+		return fmt.Errorf("unsupported scan type for CoordinatesSource: %T", src)
+	}
+	return nil
+}
+
+type NullCoordinatesSource struct {
+	CoordinatesSource CoordinatesSource
+	Valid             bool // Valid is true if CoordinatesSource is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullCoordinatesSource) Scan(value interface{}) error {
+	if value == nil {
+		ns.CoordinatesSource, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.CoordinatesSource.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullCoordinatesSource) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.CoordinatesSource), nil
+}
+
+type DigestFrequency string
+
+const (
+	DigestFrequencyOff    DigestFrequency = "off"
+	DigestFrequencyDaily  DigestFrequency = "daily"
+	DigestFrequencyWeekly DigestFrequency = "weekly"
+)
+
+func (e *DigestFrequency) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DigestFrequency(s)
+	case string:
+		*e = DigestFrequency(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DigestFrequency: %T", src)
+	}
+	return nil
+}
+
+type NullDigestFrequency struct {
+	DigestFrequency DigestFrequency
+	Valid           bool // Valid is true if DigestFrequency is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDigestFrequency) Scan(value interface{}) error {
+	if value == nil {
+		ns.DigestFrequency, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DigestFrequency.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDigestFrequency) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DigestFrequency), nil
+}
+
 type LoginMethod string
 
 const (
@@ -55,6 +183,395 @@ func (ns NullLoginMethod) Value() (driver.Value, error) {
 	return string(ns.LoginMethod), nil
 }
 
+type NotificationEmailStatus string
+
+const (
+	NotificationEmailStatusPending NotificationEmailStatus = "pending"
+	NotificationEmailStatusSent    NotificationEmailStatus = "sent"
+	NotificationEmailStatusFailed  NotificationEmailStatus = "failed"
+	NotificationEmailStatusNone    NotificationEmailStatus = "none"
+)
+
+func (e *NotificationEmailStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationEmailStatus(s)
+	case string:
+		*e = NotificationEmailStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationEmailStatus: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationEmailStatus struct {
+	NotificationEmailStatus NotificationEmailStatus
+	Valid                   bool // Valid is true if NotificationEmailStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationEmailStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationEmailStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationEmailStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationEmailStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationEmailStatus), nil
+}
+
+type NotificationKind string
+
+const (
+	NotificationKindCriticalViolation NotificationKind = "critical_violation"
+	NotificationKindOverdueAction     NotificationKind = "overdue_action"
+	NotificationKindMention           NotificationKind = "mention"
+	NotificationKindAssignment        NotificationKind = "assignment"
+	NotificationKindStatusChange      NotificationKind = "status_change"
+)
+
+func (e *NotificationKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationKind(s)
+	case string:
+		*e = NotificationKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationKind: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationKind struct {
+	NotificationKind NotificationKind
+	Valid            bool // Valid is true if NotificationKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationKind), nil
+}
+
+type NotificationMode string
+
+const (
+	NotificationModeImmediate NotificationMode = "immediate"
+	NotificationModeDigest    NotificationMode = "digest"
+	NotificationModeOff       NotificationMode = "off"
+)
+
+func (e *NotificationMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationMode(s)
+	case string:
+		*e = NotificationMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationMode: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationMode struct {
+	NotificationMode NotificationMode
+	Valid            bool // Valid is true if NotificationMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationMode), nil
+}
+
+type PhotoPurpose string
+
+const (
+	PhotoPurposeInspection   PhotoPurpose = "inspection"
+	PhotoPurposeVerification PhotoPurpose = "verification"
+)
+
+func (e *PhotoPurpose) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PhotoPurpose(s)
+	case string:
+		*e = PhotoPurpose(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PhotoPurpose: %T", src)
+	}
+	return nil
+}
+
+type NullPhotoPurpose struct {
+	PhotoPurpose PhotoPurpose
+	Valid        bool // Valid is true if PhotoPurpose is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPhotoPurpose) Scan(value interface{}) error {
+	if value == nil {
+		ns.PhotoPurpose, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PhotoPurpose.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPhotoPurpose) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PhotoPurpose), nil
+}
+
+type ProjectStatus string
+
+const (
+	ProjectStatusInProgress  ProjectStatus = "in-progress"
+	ProjectStatusNeedsReview ProjectStatus = "needs-review"
+	ProjectStatusCompleted   ProjectStatus = "completed"
+	ProjectStatusArchived    ProjectStatus = "archived"
+)
+
+func (e *ProjectStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ProjectStatus(s)
+	case string:
+		*e = ProjectStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ProjectStatus: %T", src)
+	}
+	return nil
+}
+
+type NullProjectStatus struct {
+	ProjectStatus ProjectStatus
+	Valid         bool // Valid is true if ProjectStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullProjectStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ProjectStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ProjectStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullProjectStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ProjectStatus), nil
+}
+
+type RegionShape string
+
+const (
+	RegionShapeBox     RegionShape = "box"
+	RegionShapePolygon RegionShape = "polygon"
+)
+
+func (e *RegionShape) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RegionShape(s)
+	case string:
+		*e = RegionShape(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RegionShape: %T", src)
+	}
+	return nil
+}
+
+type NullRegionShape struct {
+	RegionShape RegionShape
+	Valid       bool // Valid is true if RegionShape is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRegionShape) Scan(value interface{}) error {
+	if value == nil {
+		ns.RegionShape, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RegionShape.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRegionShape) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RegionShape), nil
+}
+
+type RegionSource string
+
+const (
+	RegionSourceDetector  RegionSource = "detector"
+	RegionSourceInspector RegionSource = "inspector"
+)
+
+func (e *RegionSource) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RegionSource(s)
+	case string:
+		*e = RegionSource(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RegionSource: %T", src)
+	}
+	return nil
+}
+
+type NullRegionSource struct {
+	RegionSource RegionSource
+	Valid        bool // Valid is true if RegionSource is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRegionSource) Scan(value interface{}) error {
+	if value == nil {
+		ns.RegionSource, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RegionSource.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRegionSource) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RegionSource), nil
+}
+
+type RiskLevel string
+
+const (
+	RiskLevelLow      RiskLevel = "low"
+	RiskLevelMedium   RiskLevel = "medium"
+	RiskLevelHigh     RiskLevel = "high"
+	RiskLevelCritical RiskLevel = "critical"
+)
+
+func (e *RiskLevel) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RiskLevel(s)
+	case string:
+		*e = RiskLevel(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RiskLevel: %T", src)
+	}
+	return nil
+}
+
+type NullRiskLevel struct {
+	RiskLevel RiskLevel
+	Valid     bool // Valid is true if RiskLevel is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRiskLevel) Scan(value interface{}) error {
+	if value == nil {
+		ns.RiskLevel, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RiskLevel.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRiskLevel) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RiskLevel), nil
+}
+
+type SyncEntity string
+
+const (
+	SyncEntityProject   SyncEntity = "project"
+	SyncEntityPhoto     SyncEntity = "photo"
+	SyncEntityViolation SyncEntity = "violation"
+)
+
+func (e *SyncEntity) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SyncEntity(s)
+	case string:
+		*e = SyncEntity(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SyncEntity: %T", src)
+	}
+	return nil
+}
+
+type NullSyncEntity struct {
+	SyncEntity SyncEntity
+	Valid      bool // Valid is true if SyncEntity is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSyncEntity) Scan(value interface{}) error {
+	if value == nil {
+		ns.SyncEntity, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SyncEntity.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSyncEntity) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SyncEntity), nil
+}
+
 type UserRole string
 
 const (
@@ -97,6 +614,332 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type VerificationResult string
+
+const (
+	VerificationResultNotRun        VerificationResult = "not_run"
+	VerificationResultHazardAbsent  VerificationResult = "hazard_absent"
+	VerificationResultHazardPresent VerificationResult = "hazard_present"
+	VerificationResultFailed        VerificationResult = "failed"
+)
+
+func (e *VerificationResult) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = VerificationResult(s)
+	case string:
+		*e = VerificationResult(s)
+	default:
+		return fmt.Errorf("unsupported scan type for VerificationResult: %T", src)
+	}
+	return nil
+}
+
+type NullVerificationResult struct {
+	VerificationResult VerificationResult
+	Valid              bool // Valid is true if VerificationResult is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVerificationResult) Scan(value interface{}) error {
+	if value == nil {
+		ns.VerificationResult, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.VerificationResult.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVerificationResult) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.VerificationResult), nil
+}
+
+type ViolationStatus string
+
+const (
+	ViolationStatusOpen      ViolationStatus = "open"
+	ViolationStatusValidated ViolationStatus = "validated"
+	ViolationStatusDismissed ViolationStatus = "dismissed"
+	ViolationStatusResolved  ViolationStatus = "resolved"
+)
+
+func (e *ViolationStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ViolationStatus(s)
+	case string:
+		*e = ViolationStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ViolationStatus: %T", src)
+	}
+	return nil
+}
+
+type NullViolationStatus struct {
+	ViolationStatus ViolationStatus
+	Valid           bool // Valid is true if ViolationStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullViolationStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ViolationStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ViolationStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullViolationStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ViolationStatus), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus
+	Valid                 bool // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
+// Personal and service tokens accepted as Authorization: Bearer by the JSON API
+type ApiToken struct {
+	ID   pgtype.UUID
+	Kind ApiTokenKind
+	Name string
+	// SHA-256 of the token; the token itself is never stored
+	TokenHash []byte
+	// Start of the token, shown so users can tell their tokens apart
+	TokenPrefix string
+	Scopes      []string
+	// User a personal token acts as, null for service tokens
+	UserID        pgtype.UUID
+	CreatedBy     pgtype.UUID
+	CreatedByName string
+	CreatedAt     pgtype.Timestamptz
+	LastUsedAt    pgtype.Timestamptz
+	ExpiresAt     pgtype.Timestamptz
+	RevokedAt     pgtype.Timestamptz
+}
+
+// Alerts for users, shown in the app and emailed
+type Notification struct {
+	ID          pgtype.UUID
+	UserID      pgtype.UUID
+	Kind        NotificationKind
+	ProjectID   pgtype.UUID
+	ViolationID pgtype.UUID
+	Title       string
+	Body        string
+	// App path the alert points to
+	Link        string
+	EmailStatus NotificationEmailStatus
+	// When a pending email is next tried
+	EmailAfter    pgtype.Timestamptz
+	EmailAttempts int32
+	EmailError    string
+	EmailedAt     pgtype.Timestamptz
+	ReadAt        pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+	// Name of the user whose comment, assignment or review raised the notification
+	ActorName string
+}
+
+// How each user is alerted, with quiet hours in their timezone
+type NotificationPreference struct {
+	UserID             pgtype.UUID
+	CriticalViolations NotificationMode
+	OverdueActions     NotificationMode
+	// Local time digest-mode alerts are emailed
+	DigestTime pgtype.Time
+	// Local time from which immediate emails are held; may be after quiet_hours_end to span midnight
+	QuietHoursStart pgtype.Time
+	QuietHoursEnd   pgtype.Time
+	UpdatedAt       pgtype.Timestamptz
+	// Summary email frequency; sent at digest_time, weekly ones on Mondays
+	SafetyDigest DigestFrequency
+}
+
+// Photos uploaded to a project; file contents live in the blob store
+type Photo struct {
+	ID        pgtype.UUID
+	ProjectID pgtype.UUID
+	// Blob store key of the original upload
+	StorageKey  string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	// inspection (site survey) or verification (corrective action evidence)
+	Purpose        PhotoPurpose
+	Caption        string
+	UploadedBy     pgtype.UUID
+	UploadedByName string
+	CreatedAt      pgtype.Timestamptz
+	// When hazard detection last ran, null if never
+	AnalyzedAt pgtype.Timestamptz
+	// Work area shown: general, excavation, electrical, roofing, mechanical, concrete, demolition, other
+	AreaType string
+	// Blob key of the gallery thumbnail, null for photos uploaded before thumbnails
+	ThumbnailKey pgtype.Text
+	// Capture time from EXIF, null if the camera did not record it
+	TakenAt pgtype.Timestamptz
+	// GPS latitude from EXIF in decimal degrees, null if not recorded
+	Latitude pgtype.Float8
+	// GPS longitude from EXIF in decimal degrees, null if not recorded
+	Longitude pgtype.Float8
+	// EXIF orientation of the upload; stored pixels are always upright
+	Orientation int16
+	CameraMake  string
+	CameraModel string
+	// 64-bit difference hash of the stored image, null for photos uploaded before hashing
+	PerceptualHash pgtype.Int8
+	// Earlier photo in the same project this one nearly matches; duplicates are not analyzed
+	DuplicateOf pgtype.UUID
+	// When the caption or area type last changed
+	UpdatedAt pgtype.Timestamptz
+}
+
+// Construction sites under inspection
+type Project struct {
+	ID          pgtype.UUID
+	Name        string
+	Description string
+	Status      ProjectStatus
+	Location    string
+	InspectorID pgtype.UUID
+	// Percentage of inspected items found compliant (0-100)
+	ComplianceScore float64
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	// Site latitude in decimal degrees, null if unknown
+	Latitude pgtype.Float8
+	// Site longitude in decimal degrees, null if unknown
+	Longitude pgtype.Float8
+	// manual (entered), geocoded (from the address) or photos (from photo GPS), null without coordinates
+	CoordinatesSource NullCoordinatesSource
+}
+
+// Compliance score changes, written by a trigger on projects
+type ProjectComplianceHistory struct {
+	ID              int64
+	ProjectID       pgtype.UUID
+	ComplianceScore float64
+	RecordedAt      pgtype.Timestamptz
+}
+
+// Summary emails sent or being sent, one per user and period
+type SafetyDigest struct {
+	UserID      pgtype.UUID
+	Frequency   DigestFrequency
+	PeriodStart pgtype.Date
+	PeriodEnd   pgtype.Date
+	Attempts    int32
+	// When the latest attempt started; an unsent period is retried once the claim is stale
+	ClaimedAt pgtype.Timestamptz
+	SentAt    pgtype.Timestamptz
+	Error     string
+}
+
+// Change feed for offline clients, written by triggers
+type SyncChange struct {
+	Seq       int64
+	Entity    SyncEntity
+	EntityID  pgtype.UUID
+	ProjectID pgtype.UUID
+	Deleted   bool
+	// Transaction that made the change, compared against client snapshots
+	Txid      interface{}
+	ChangedAt pgtype.Timestamptz
+}
+
+// Project activity feed
+type TimelineEvent struct {
+	ID          pgtype.UUID
+	ProjectID   pgtype.UUID
+	ViolationID pgtype.UUID
+	Type        string
+	Description string
+	UserID      pgtype.UUID
+	// Display name of the actor at the time of the event
+	UserName  string
+	Metadata  []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+// Resumable photo uploads, kept until they expire so clients can check on finished ones
+type Upload struct {
+	ID           pgtype.UUID
+	ProjectID    pgtype.UUID
+	UploadLength int64
+	// Bytes received; equals upload_length once the photo is created
+	UploadOffset  int64
+	Filename      string
+	AreaType      string
+	Caption       string
+	CreatedBy     pgtype.UUID
+	CreatedByName string
+	PhotoID       pgtype.UUID
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	// When an idle upload and its chunks are purged
+	ExpiresAt   pgtype.Timestamptz
+	CompletedAt pgtype.Timestamptz
+	// Client-generated ID for the photo, null to let the server choose
+	RequestedPhotoID pgtype.UUID
+}
+
+// Blob store objects holding the bytes of unfinished uploads
+type UploadChunk struct {
+	UploadID    pgtype.UUID
+	ChunkOffset int64
+	SizeBytes   int64
+	StorageKey  string
+	CreatedAt   pgtype.Timestamptz
+}
+
 // Main user accounts table
 type User struct {
 	// Unique user identifier (UUID)
@@ -120,3 +963,117 @@ type User struct {
 	UpdatedAt     pgtype.Timestamptz
 	LastLoginAt   pgtype.Timestamptz
 }
+
+// Safety violations detected by AI or recorded by inspectors
+type Violation struct {
+	ID          pgtype.UUID
+	ProjectID   pgtype.UUID
+	Description string
+	// OSHA regulation number, e.g. 1926.95
+	Regulation string
+	RiskLevel  RiskLevel
+	Category   string
+	Location   string
+	Notes      string
+	// AI detection confidence (0-1)
+	AiConfidence float64
+	// open (pending review), validated, dismissed or resolved
+	Status     ViolationStatus
+	FoundAt    pgtype.Timestamptz
+	ResolvedAt pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+	// User responsible for the corrective action
+	AssignedUserID pgtype.UUID
+	// Subcontractor responsible for the corrective action
+	AssignedSubcontractor string
+	// Date the corrective action must be completed by
+	DueDate pgtype.Date
+	// Photo the violation was found in (the "before" photo)
+	PhotoID pgtype.UUID
+}
+
+// Discussion thread on a violation
+type ViolationComment struct {
+	ID          pgtype.UUID
+	ViolationID pgtype.UUID
+	UserID      pgtype.UUID
+	UserName    string
+	Body        string
+	CreatedAt   pgtype.Timestamptz
+}
+
+// Where in a photo a violation appears
+type ViolationRegion struct {
+	ID          pgtype.UUID
+	ViolationID pgtype.UUID
+	PhotoID     pgtype.UUID
+	Shape       RegionShape
+	X           float64
+	Y           float64
+	Width       float64
+	Height      float64
+	// Polygon vertices as [[x, y], ...], normalized 0-1
+	Points []byte
+	// detector (AI) or inspector (drawn by hand)
+	Source     RegionSource
+	Confidence pgtype.Float8
+	UserID     pgtype.UUID
+	UserName   string
+	CreatedAt  pgtype.Timestamptz
+}
+
+// Corrective action evidence submitted to resolve a violation
+type ViolationResolution struct {
+	ID          pgtype.UUID
+	ViolationID pgtype.UUID
+	PhotoID     pgtype.UUID
+	Note        string
+	// Result of re-running hazard detection on the after photo
+	Verification           VerificationResult
+	VerificationConfidence pgtype.Float8
+	VerificationDetail     string
+	// Whether the submission resolved the violation
+	Accepted  bool
+	UserID    pgtype.UUID
+	UserName  string
+	CreatedAt pgtype.Timestamptz
+}
+
+// Webhook events queued for or sent to a subscription
+type WebhookDelivery struct {
+	ID             pgtype.UUID
+	SubscriptionID pgtype.UUID
+	// Shared by every delivery of the same event, including redeliveries
+	EventID    pgtype.UUID
+	EventType  string
+	OccurredAt pgtype.Timestamptz
+	Data       []byte
+	Status     WebhookDeliveryStatus
+	Attempts   int32
+	// When a pending delivery is next tried
+	NextAttemptAt  pgtype.Timestamptz
+	LastAttemptAt  pgtype.Timestamptz
+	ResponseStatus pgtype.Int4
+	ResponseBody   string
+	Error          string
+	DurationMs     pgtype.Int4
+	// Delivery this one was manually resent from
+	RedeliveryOf pgtype.UUID
+	CreatedAt    pgtype.Timestamptz
+}
+
+// Outbound webhook endpoints and the event types they receive
+type WebhookSubscription struct {
+	ID     pgtype.UUID
+	Name   string
+	Url    string
+	Secret string
+	// Event types delivered, e.g. violation.critical
+	EventTypes    []string
+	Active        bool
+	CreatedBy     pgtype.UUID
+	CreatedByName string
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+}
@@ -0,0 +1,669 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: notification.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimNotificationEmails = `-- name: ClaimNotificationEmails :many
+UPDATE notifications n
+SET email_after = CURRENT_TIMESTAMP + make_interval(secs => $1::float8),
+    email_attempts = n.email_attempts + 1
+FROM users u
+WHERE u.id = n.user_id
+  AND n.id IN (
+    SELECT id FROM notifications
+    WHERE email_status = 'pending' AND email_after <= CURRENT_TIMESTAMP
+    ORDER BY email_after
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING n.id, n.user_id, n.title, n.body, n.link, n.email_attempts, n.created_at,
+  u.email, COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email)::text AS user_name
+`
+
+type ClaimNotificationEmailsParams struct {
+	LeaseSeconds     float64
+	MaxNotifications int32
+}
+
+type ClaimNotificationEmailsRow struct {
+	ID            pgtype.UUID
+	UserID        pgtype.UUID
+	Title         string
+	Body          string
+	Link          string
+	EmailAttempts int32
+	CreatedAt     pgtype.Timestamptz
+	Email         string
+	UserName      string
+}
+
+// Due emails are pushed back by the lease while they are sent, so other
+// instances don't send them too
+func (q *Queries) ClaimNotificationEmails(ctx context.Context, arg ClaimNotificationEmailsParams) ([]ClaimNotificationEmailsRow, error) {
+	rows, err := q.db.Query(ctx, claimNotificationEmails, arg.LeaseSeconds, arg.MaxNotifications)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimNotificationEmailsRow
+	for rows.Next() {
+		var i ClaimNotificationEmailsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Body,
+			&i.Link,
+			&i.EmailAttempts,
+			&i.CreatedAt,
+			&i.Email,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createActivityNotifications = `-- name: CreateActivityNotifications :execrows
+INSERT INTO notifications (
+  user_id,
+  kind,
+  project_id,
+  violation_id,
+  title,
+  body,
+  link,
+  actor_name,
+  email_status
+)
+SELECT DISTINCT
+  u.id, $1, v.project_id, v.id, CONCAT($2::text, ' at ', p.name), $3, $4, $5, 'none'::notification_email_status
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.is_active
+  AND (u.id = ANY($6::uuid[])
+    OR ($7::bool AND (u.id = p.inspector_id OR u.id = v.assigned_user_id)))
+WHERE v.id = $8
+  AND u.id IS DISTINCT FROM $9::uuid
+`
+
+type CreateActivityNotificationsParams struct {
+	Kind        NotificationKind
+	Title       string
+	Body        string
+	Link        string
+	ActorName   string
+	UserIds     []pgtype.UUID
+	Watchers    bool
+	ViolationID pgtype.UUID
+	ActorID     pgtype.UUID
+}
+
+// Records an in-app notification about a violation for the given users and,
+// when watchers is set, its project's inspector and assignee. The user who
+// caused it and inactive accounts are skipped.
+func (q *Queries) CreateActivityNotifications(ctx context.Context, arg CreateActivityNotificationsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createActivityNotifications,
+		arg.Kind,
+		arg.Title,
+		arg.Body,
+		arg.Link,
+		arg.ActorName,
+		arg.UserIds,
+		arg.Watchers,
+		arg.ViolationID,
+		arg.ActorID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createAlertNotification = `-- name: CreateAlertNotification :execrows
+INSERT INTO notifications (
+  user_id,
+  kind,
+  project_id,
+  violation_id,
+  title,
+  body,
+  link,
+  email_status,
+  email_after
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (user_id, kind, violation_id) WHERE kind IN ('critical_violation', 'overdue_action')
+DO NOTHING
+`
+
+type CreateAlertNotificationParams struct {
+	UserID      pgtype.UUID
+	Kind        NotificationKind
+	ProjectID   pgtype.UUID
+	ViolationID pgtype.UUID
+	Title       string
+	Body        string
+	Link        string
+	EmailStatus NotificationEmailStatus
+	EmailAfter  pgtype.Timestamptz
+}
+
+// Does nothing if the user was already alerted about the violation
+func (q *Queries) CreateAlertNotification(ctx context.Context, arg CreateAlertNotificationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createAlertNotification,
+		arg.UserID,
+		arg.Kind,
+		arg.ProjectID,
+		arg.ViolationID,
+		arg.Title,
+		arg.Body,
+		arg.Link,
+		arg.EmailStatus,
+		arg.EmailAfter,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredNotifications = `-- name: DeleteExpiredNotifications :execrows
+DELETE FROM notifications n
+WHERE (n.read_at < $1 OR n.created_at < $2)
+  AND n.email_status <> 'pending'
+  AND NOT (n.kind = 'overdue_action' AND EXISTS (
+    SELECT 1 FROM violations v
+    WHERE v.id = n.violation_id AND v.status IN ('open', 'validated')
+  ))
+`
+
+type DeleteExpiredNotificationsParams struct {
+	ReadBefore    pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+}
+
+// Removes notifications read before read_before and any created before
+// created_before, keeping those with an email still to send and overdue
+// alerts for actions that are still open, which stop them being raised again
+func (q *Queries) DeleteExpiredNotifications(ctx context.Context, arg DeleteExpiredNotificationsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredNotifications, arg.ReadBefore, arg.CreatedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getNotification = `-- name: GetNotification :one
+SELECT id, user_id, kind, project_id, violation_id, title, body, link, email_status, email_after, email_attempts, email_error, emailed_at, read_at, created_at, actor_name FROM notifications
+WHERE id = $1 AND user_id = $2
+`
+
+type GetNotificationParams struct {
+	ID     pgtype.UUID
+	UserID pgtype.UUID
+}
+
+func (q *Queries) GetNotification(ctx context.Context, arg GetNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, getNotification, arg.ID, arg.UserID)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.ProjectID,
+		&i.ViolationID,
+		&i.Title,
+		&i.Body,
+		&i.Link,
+		&i.EmailStatus,
+		&i.EmailAfter,
+		&i.EmailAttempts,
+		&i.EmailError,
+		&i.EmailedAt,
+		&i.ReadAt,
+		&i.CreatedAt,
+		&i.ActorName,
+	)
+	return i, err
+}
+
+const getNotificationPreferences = `-- name: GetNotificationPreferences :one
+SELECT user_id, critical_violations, overdue_actions, digest_time, quiet_hours_start, quiet_hours_end, updated_at, safety_digest FROM notification_preferences
+WHERE user_id = $1
+`
+
+// Notification Preferences Table --
+func (q *Queries) GetNotificationPreferences(ctx context.Context, userID pgtype.UUID) (NotificationPreference, error) {
+	row := q.db.QueryRow(ctx, getNotificationPreferences, userID)
+	var i NotificationPreference
+	err := row.Scan(
+		&i.UserID,
+		&i.CriticalViolations,
+		&i.OverdueActions,
+		&i.DigestTime,
+		&i.QuietHoursStart,
+		&i.QuietHoursEnd,
+		&i.UpdatedAt,
+		&i.SafetyDigest,
+	)
+	return i, err
+}
+
+const listCriticalViolationAlerts = `-- name: ListCriticalViolationAlerts :many
+SELECT
+  v.id AS violation_id,
+  v.project_id,
+  v.description,
+  v.regulation,
+  v.found_at,
+  p.name AS project_name,
+  u.id AS user_id,
+  COALESCE(u.timezone, '')::text AS timezone
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.id = p.inspector_id AND u.is_active
+WHERE v.risk_level = 'critical'
+  AND v.status IN ('open', 'validated')
+  AND v.created_at > $1
+  AND NOT EXISTS (
+    SELECT 1 FROM notifications n
+    WHERE n.user_id = u.id AND n.kind = 'critical_violation' AND n.violation_id = v.id
+  )
+ORDER BY v.created_at
+`
+
+type ListCriticalViolationAlertsRow struct {
+	ViolationID pgtype.UUID
+	ProjectID   pgtype.UUID
+	Description string
+	Regulation  string
+	FoundAt     pgtype.Timestamptz
+	ProjectName string
+	UserID      pgtype.UUID
+	Timezone    string
+}
+
+// Notifications Table --
+// Critical violations found since the given time that a project owner hasn't
+// been alerted about
+func (q *Queries) ListCriticalViolationAlerts(ctx context.Context, since pgtype.Timestamptz) ([]ListCriticalViolationAlertsRow, error) {
+	rows, err := q.db.Query(ctx, listCriticalViolationAlerts, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCriticalViolationAlertsRow
+	for rows.Next() {
+		var i ListCriticalViolationAlertsRow
+		if err := rows.Scan(
+			&i.ViolationID,
+			&i.ProjectID,
+			&i.Description,
+			&i.Regulation,
+			&i.FoundAt,
+			&i.ProjectName,
+			&i.UserID,
+			&i.Timezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMentionedUsers = `-- name: ListMentionedUsers :many
+SELECT id FROM users u
+WHERE u.is_active
+  AND (LOWER(u.email) = ANY($1::text[])
+    OR (LOWER(u.username) = ANY($1::text[])
+      AND NOT EXISTS (
+        SELECT 1 FROM users o
+        WHERE LOWER(o.username) = LOWER(u.username) AND o.id <> u.id
+      )))
+`
+
+// Active users whose email address or username is one of the handles.
+// Usernames that differ only in case are ambiguous, so they match no one.
+func (q *Queries) ListMentionedUsers(ctx context.Context, handles []string) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listMentionedUsers, handles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotificationsByUser = `-- name: ListNotificationsByUser :many
+SELECT id, user_id, kind, project_id, violation_id, title, body, link, email_status, email_after, email_attempts, email_error, emailed_at, read_at, created_at, actor_name FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type ListNotificationsByUserParams struct {
+	UserID pgtype.UUID
+	Limit  int32
+}
+
+func (q *Queries) ListNotificationsByUser(ctx context.Context, arg ListNotificationsByUserParams) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, listNotificationsByUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.ProjectID,
+			&i.ViolationID,
+			&i.Title,
+			&i.Body,
+			&i.Link,
+			&i.EmailStatus,
+			&i.EmailAfter,
+			&i.EmailAttempts,
+			&i.EmailError,
+			&i.EmailedAt,
+			&i.ReadAt,
+			&i.CreatedAt,
+			&i.ActorName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotificationsPage = `-- name: ListNotificationsPage :many
+SELECT id, user_id, kind, project_id, violation_id, title, body, link, email_status, email_after, email_attempts, email_error, emailed_at, read_at, created_at, actor_name FROM notifications
+WHERE user_id = $1
+  AND ($2::timestamptz IS NULL
+    OR (created_at, id) < ($2::timestamptz, $3::uuid))
+  AND (NOT $4::bool OR read_at IS NULL)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListNotificationsPageParams struct {
+	UserID           pgtype.UUID
+	BeforeCreatedAt  pgtype.Timestamptz
+	BeforeID         pgtype.UUID
+	UnreadOnly       bool
+	MaxNotifications int32
+}
+
+// A user's notifications, newest first, older than the cursor if one is given
+func (q *Queries) ListNotificationsPage(ctx context.Context, arg ListNotificationsPageParams) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, listNotificationsPage,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.UnreadOnly,
+		arg.MaxNotifications,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.ProjectID,
+			&i.ViolationID,
+			&i.Title,
+			&i.Body,
+			&i.Link,
+			&i.EmailStatus,
+			&i.EmailAfter,
+			&i.EmailAttempts,
+			&i.EmailError,
+			&i.EmailedAt,
+			&i.ReadAt,
+			&i.CreatedAt,
+			&i.ActorName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOverdueActionAlerts = `-- name: ListOverdueActionAlerts :many
+SELECT
+  v.id AS violation_id,
+  v.project_id,
+  v.description,
+  v.due_date,
+  v.assigned_subcontractor,
+  p.name AS project_name,
+  u.id AS user_id,
+  COALESCE(u.timezone, '')::text AS timezone
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.is_active AND (u.id = p.inspector_id OR u.id = v.assigned_user_id)
+WHERE v.status IN ('open', 'validated')
+  AND v.due_date < $1::date
+  AND NOT EXISTS (
+    SELECT 1 FROM notifications n
+    WHERE n.user_id = u.id AND n.kind = 'overdue_action' AND n.violation_id = v.id
+  )
+ORDER BY v.due_date
+`
+
+type ListOverdueActionAlertsRow struct {
+	ViolationID           pgtype.UUID
+	ProjectID             pgtype.UUID
+	Description           string
+	DueDate               pgtype.Date
+	AssignedSubcontractor string
+	ProjectName           string
+	UserID                pgtype.UUID
+	Timezone              string
+}
+
+// Unresolved violations due before the given date that the project owner or
+// assignee hasn't been alerted about. Whether a due date has passed depends
+// on the user's timezone, so callers pass a date far enough ahead to cover
+// every timezone and check each row.
+func (q *Queries) ListOverdueActionAlerts(ctx context.Context, before pgtype.Date) ([]ListOverdueActionAlertsRow, error) {
+	rows, err := q.db.Query(ctx, listOverdueActionAlerts, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOverdueActionAlertsRow
+	for rows.Next() {
+		var i ListOverdueActionAlertsRow
+		if err := rows.Scan(
+			&i.ViolationID,
+			&i.ProjectID,
+			&i.Description,
+			&i.DueDate,
+			&i.AssignedSubcontractor,
+			&i.ProjectName,
+			&i.UserID,
+			&i.Timezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, markAllNotificationsRead, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :exec
+UPDATE notifications
+SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+WHERE id = $1 AND user_id = $2
+`
+
+type MarkNotificationReadParams struct {
+	ID     pgtype.UUID
+	UserID pgtype.UUID
+}
+
+func (q *Queries) MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) error {
+	_, err := q.db.Exec(ctx, markNotificationRead, arg.ID, arg.UserID)
+	return err
+}
+
+const recordNotificationEmails = `-- name: RecordNotificationEmails :exec
+UPDATE notifications
+SET email_status = $1,
+    email_after = $2,
+    email_error = $3,
+    emailed_at = $4
+WHERE id = ANY($5::uuid[])
+`
+
+type RecordNotificationEmailsParams struct {
+	EmailStatus NotificationEmailStatus
+	EmailAfter  pgtype.Timestamptz
+	EmailError  string
+	EmailedAt   pgtype.Timestamptz
+	Ids         []pgtype.UUID
+}
+
+func (q *Queries) RecordNotificationEmails(ctx context.Context, arg RecordNotificationEmailsParams) error {
+	_, err := q.db.Exec(ctx, recordNotificationEmails,
+		arg.EmailStatus,
+		arg.EmailAfter,
+		arg.EmailError,
+		arg.EmailedAt,
+		arg.Ids,
+	)
+	return err
+}
+
+const upsertNotificationPreferences = `-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (
+  user_id,
+  critical_violations,
+  overdue_actions,
+  digest_time,
+  quiet_hours_start,
+  quiet_hours_end,
+  safety_digest
+) VALUES (
+  $1, $2, $3, $4,
+  $5, $6, $7
+)
+ON CONFLICT (user_id) DO UPDATE
+SET critical_violations = EXCLUDED.critical_violations,
+    overdue_actions = EXCLUDED.overdue_actions,
+    digest_time = EXCLUDED.digest_time,
+    quiet_hours_start = EXCLUDED.quiet_hours_start,
+    quiet_hours_end = EXCLUDED.quiet_hours_end,
+    safety_digest = EXCLUDED.safety_digest,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING user_id, critical_violations, overdue_actions, digest_time, quiet_hours_start, quiet_hours_end, updated_at, safety_digest
+`
+
+type UpsertNotificationPreferencesParams struct {
+	UserID             pgtype.UUID
+	CriticalViolations NotificationMode
+	OverdueActions     NotificationMode
+	DigestTime         pgtype.Time
+	QuietHoursStart    pgtype.Time
+	QuietHoursEnd      pgtype.Time
+	SafetyDigest       DigestFrequency
+}
+
+func (q *Queries) UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error) {
+	row := q.db.QueryRow(ctx, upsertNotificationPreferences,
+		arg.UserID,
+		arg.CriticalViolations,
+		arg.OverdueActions,
+		arg.DigestTime,
+		arg.QuietHoursStart,
+		arg.QuietHoursEnd,
+		arg.SafetyDigest,
+	)
+	var i NotificationPreference
+	err := row.Scan(
+		&i.UserID,
+		&i.CriticalViolations,
+		&i.OverdueActions,
+		&i.DigestTime,
+		&i.QuietHoursStart,
+		&i.QuietHoursEnd,
+		&i.UpdatedAt,
+		&i.SafetyDigest,
+	)
+	return i, err
+}
@@ -0,0 +1,538 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: photo.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const clearPhotoDuplicate = `-- name: ClearPhotoDuplicate :one
+UPDATE photos
+SET duplicate_of = NULL,
+    analyzed_at = NULL
+WHERE id = $1
+RETURNING id, project_id, storage_key, filename, content_type, size_bytes, purpose, caption, uploaded_by, uploaded_by_name, created_at, analyzed_at, area_type, thumbnail_key, taken_at, latitude, longitude, orientation, camera_make, camera_model, perceptual_hash, duplicate_of, updated_at
+`
+
+// Resets analysis so the photo is picked up like a new upload
+func (q *Queries) ClearPhotoDuplicate(ctx context.Context, id pgtype.UUID) (Photo, error) {
+	row := q.db.QueryRow(ctx, clearPhotoDuplicate, id)
+	var i Photo
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.StorageKey,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Purpose,
+		&i.Caption,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+		&i.AnalyzedAt,
+		&i.AreaType,
+		&i.ThumbnailKey,
+		&i.TakenAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Orientation,
+		&i.CameraMake,
+		&i.CameraModel,
+		&i.PerceptualHash,
+		&i.DuplicateOf,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const countPhotosByProject = `-- name: CountPhotosByProject :one
+SELECT COUNT(*) FROM photos p
+WHERE p.project_id = $1
+  AND ($2::text IS NULL OR p.area_type = $2)
+  AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+  AND ($4::timestamptz IS NULL OR p.created_at < $4)
+`
+
+type CountPhotosByProjectParams struct {
+	ProjectID     pgtype.UUID
+	AreaType      pgtype.Text
+	CreatedFrom   pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+}
+
+func (q *Queries) CountPhotosByProject(ctx context.Context, arg CountPhotosByProjectParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPhotosByProject,
+		arg.ProjectID,
+		arg.AreaType,
+		arg.CreatedFrom,
+		arg.CreatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPhoto = `-- name: CreatePhoto :one
+INSERT INTO photos (
+  id,
+  project_id,
+  storage_key,
+  filename,
+  content_type,
+  size_bytes,
+  purpose,
+  uploaded_by,
+  uploaded_by_name,
+  caption,
+  area_type,
+  thumbnail_key,
+  taken_at,
+  latitude,
+  longitude,
+  orientation,
+  camera_make,
+  camera_model,
+  perceptual_hash,
+  duplicate_of
+) VALUES (
+  COALESCE($1::uuid, uuid_generate_v4()), $2, $3, $4,
+  $5, $6, $7, $8, $9, $10,
+  $11, $12, $13, $14, $15, $16,
+  $17, $18, $19, $20
+)
+RETURNING id, project_id, storage_key, filename, content_type, size_bytes, purpose, caption, uploaded_by, uploaded_by_name, created_at, analyzed_at, area_type, thumbnail_key, taken_at, latitude, longitude, orientation, camera_make, camera_model, perceptual_hash, duplicate_of, updated_at
+`
+
+type CreatePhotoParams struct {
+	ID             pgtype.UUID
+	ProjectID      pgtype.UUID
+	StorageKey     string
+	Filename       string
+	ContentType    string
+	SizeBytes      int64
+	Purpose        PhotoPurpose
+	UploadedBy     pgtype.UUID
+	UploadedByName string
+	Caption        string
+	AreaType       string
+	ThumbnailKey   pgtype.Text
+	TakenAt        pgtype.Timestamptz
+	Latitude       pgtype.Float8
+	Longitude      pgtype.Float8
+	Orientation    int16
+	CameraMake     string
+	CameraModel    string
+	PerceptualHash pgtype.Int8
+	DuplicateOf    pgtype.UUID
+}
+
+// A client that works offline may choose the photo's ID; otherwise one is
+// generated
+func (q *Queries) CreatePhoto(ctx context.Context, arg CreatePhotoParams) (Photo, error) {
+	row := q.db.QueryRow(ctx, createPhoto,
+		arg.ID,
+		arg.ProjectID,
+		arg.StorageKey,
+		arg.Filename,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Purpose,
+		arg.UploadedBy,
+		arg.UploadedByName,
+		arg.Caption,
+		arg.AreaType,
+		arg.ThumbnailKey,
+		arg.TakenAt,
+		arg.Latitude,
+		arg.Longitude,
+		arg.Orientation,
+		arg.CameraMake,
+		arg.CameraModel,
+		arg.PerceptualHash,
+		arg.DuplicateOf,
+	)
+	var i Photo
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.StorageKey,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Purpose,
+		&i.Caption,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+		&i.AnalyzedAt,
+		&i.AreaType,
+		&i.ThumbnailKey,
+		&i.TakenAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Orientation,
+		&i.CameraMake,
+		&i.CameraModel,
+		&i.PerceptualHash,
+		&i.DuplicateOf,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPhoto = `-- name: GetPhoto :one
+SELECT id, project_id, storage_key, filename, content_type, size_bytes, purpose, caption, uploaded_by, uploaded_by_name, created_at, analyzed_at, area_type, thumbnail_key, taken_at, latitude, longitude, orientation, camera_make, camera_model, perceptual_hash, duplicate_of, updated_at FROM photos
+WHERE id = $1 LIMIT 1
+`
+
+// Photos Table --
+func (q *Queries) GetPhoto(ctx context.Context, id pgtype.UUID) (Photo, error) {
+	row := q.db.QueryRow(ctx, getPhoto, id)
+	var i Photo
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.StorageKey,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Purpose,
+		&i.Caption,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+		&i.AnalyzedAt,
+		&i.AreaType,
+		&i.ThumbnailKey,
+		&i.TakenAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Orientation,
+		&i.CameraMake,
+		&i.CameraModel,
+		&i.PerceptualHash,
+		&i.DuplicateOf,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listInspectionPhotoIDsByProject = `-- name: ListInspectionPhotoIDsByProject :many
+SELECT id FROM photos
+WHERE project_id = $1 AND purpose = 'inspection' AND duplicate_of IS NULL
+ORDER BY created_at ASC
+`
+
+// Photos hazard detection runs on, oldest first; duplicates are left out as
+// their hazards were recorded from the original
+func (q *Queries) ListInspectionPhotoIDsByProject(ctx context.Context, projectID pgtype.UUID) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listInspectionPhotoIDsByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPhotoHashesByProject = `-- name: ListPhotoHashesByProject :many
+SELECT id, perceptual_hash::bigint AS perceptual_hash FROM photos
+WHERE project_id = $1
+  AND purpose = 'inspection'
+  AND perceptual_hash IS NOT NULL
+  AND duplicate_of IS NULL
+ORDER BY created_at ASC
+`
+
+type ListPhotoHashesByProjectRow struct {
+	ID             pgtype.UUID
+	PerceptualHash int64
+}
+
+// Original inspection photos new uploads are compared against
+func (q *Queries) ListPhotoHashesByProject(ctx context.Context, projectID pgtype.UUID) ([]ListPhotoHashesByProjectRow, error) {
+	rows, err := q.db.Query(ctx, listPhotoHashesByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPhotoHashesByProjectRow
+	for rows.Next() {
+		var i ListPhotoHashesByProjectRow
+		if err := rows.Scan(&i.ID, &i.PerceptualHash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPhotosByProject = `-- name: ListPhotosByProject :many
+SELECT
+  p.id, p.project_id, p.storage_key, p.filename, p.content_type, p.size_bytes, p.purpose, p.caption, p.uploaded_by, p.uploaded_by_name, p.created_at, p.analyzed_at, p.area_type, p.thumbnail_key, p.taken_at, p.latitude, p.longitude, p.orientation, p.camera_make, p.camera_model, p.perceptual_hash, p.duplicate_of, p.updated_at,
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.project_id = $1
+  AND ($2::text IS NULL OR p.area_type = $2)
+  AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+  AND ($4::timestamptz IS NULL OR p.created_at < $4)
+ORDER BY p.created_at DESC, p.id
+LIMIT $6 OFFSET $5
+`
+
+type ListPhotosByProjectParams struct {
+	ProjectID     pgtype.UUID
+	AreaType      pgtype.Text
+	CreatedFrom   pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	PageOffset    int32
+	PageLimit     int32
+}
+
+type ListPhotosByProjectRow struct {
+	Photo        Photo
+	ViolationIds []pgtype.UUID
+}
+
+func (q *Queries) ListPhotosByProject(ctx context.Context, arg ListPhotosByProjectParams) ([]ListPhotosByProjectRow, error) {
+	rows, err := q.db.Query(ctx, listPhotosByProject,
+		arg.ProjectID,
+		arg.AreaType,
+		arg.CreatedFrom,
+		arg.CreatedBefore,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPhotosByProjectRow
+	for rows.Next() {
+		var i ListPhotosByProjectRow
+		if err := rows.Scan(
+			&i.Photo.ID,
+			&i.Photo.ProjectID,
+			&i.Photo.StorageKey,
+			&i.Photo.Filename,
+			&i.Photo.ContentType,
+			&i.Photo.SizeBytes,
+			&i.Photo.Purpose,
+			&i.Photo.Caption,
+			&i.Photo.UploadedBy,
+			&i.Photo.UploadedByName,
+			&i.Photo.CreatedAt,
+			&i.Photo.AnalyzedAt,
+			&i.Photo.AreaType,
+			&i.Photo.ThumbnailKey,
+			&i.Photo.TakenAt,
+			&i.Photo.Latitude,
+			&i.Photo.Longitude,
+			&i.Photo.Orientation,
+			&i.Photo.CameraMake,
+			&i.Photo.CameraModel,
+			&i.Photo.PerceptualHash,
+			&i.Photo.DuplicateOf,
+			&i.Photo.UpdatedAt,
+			&i.ViolationIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPhotosPage = `-- name: ListPhotosPage :many
+SELECT
+  p.id, p.project_id, p.storage_key, p.filename, p.content_type, p.size_bytes, p.purpose, p.caption, p.uploaded_by, p.uploaded_by_name, p.created_at, p.analyzed_at, p.area_type, p.thumbnail_key, p.taken_at, p.latitude, p.longitude, p.orientation, p.camera_make, p.camera_model, p.perceptual_hash, p.duplicate_of, p.updated_at,
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.project_id = $1
+  AND ($2::text IS NULL OR p.area_type = $2)
+  AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+  AND ($4::timestamptz IS NULL OR p.created_at < $4)
+  AND ($5::uuid IS NULL OR (p.created_at, p.id) < ($6::timestamptz, $5))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $7
+`
+
+type ListPhotosPageParams struct {
+	ProjectID      pgtype.UUID
+	AreaType       pgtype.Text
+	CreatedFrom    pgtype.Timestamptz
+	CreatedBefore  pgtype.Timestamptz
+	AfterID        pgtype.UUID
+	AfterCreatedAt pgtype.Timestamptz
+	PageLimit      int32
+}
+
+type ListPhotosPageRow struct {
+	Photo        Photo
+	ViolationIds []pgtype.UUID
+}
+
+func (q *Queries) ListPhotosPage(ctx context.Context, arg ListPhotosPageParams) ([]ListPhotosPageRow, error) {
+	rows, err := q.db.Query(ctx, listPhotosPage,
+		arg.ProjectID,
+		arg.AreaType,
+		arg.CreatedFrom,
+		arg.CreatedBefore,
+		arg.AfterID,
+		arg.AfterCreatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPhotosPageRow
+	for rows.Next() {
+		var i ListPhotosPageRow
+		if err := rows.Scan(
+			&i.Photo.ID,
+			&i.Photo.ProjectID,
+			&i.Photo.StorageKey,
+			&i.Photo.Filename,
+			&i.Photo.ContentType,
+			&i.Photo.SizeBytes,
+			&i.Photo.Purpose,
+			&i.Photo.Caption,
+			&i.Photo.UploadedBy,
+			&i.Photo.UploadedByName,
+			&i.Photo.CreatedAt,
+			&i.Photo.AnalyzedAt,
+			&i.Photo.AreaType,
+			&i.Photo.ThumbnailKey,
+			&i.Photo.TakenAt,
+			&i.Photo.Latitude,
+			&i.Photo.Longitude,
+			&i.Photo.Orientation,
+			&i.Photo.CameraMake,
+			&i.Photo.CameraModel,
+			&i.Photo.PerceptualHash,
+			&i.Photo.DuplicateOf,
+			&i.Photo.UpdatedAt,
+			&i.ViolationIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnanalyzedPhotos = `-- name: ListUnanalyzedPhotos :many
+SELECT id FROM photos
+WHERE purpose = 'inspection' AND analyzed_at IS NULL AND duplicate_of IS NULL
+ORDER BY created_at ASC
+LIMIT $1
+`
+
+func (q *Queries) ListUnanalyzedPhotos(ctx context.Context, limit int32) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listUnanalyzedPhotos, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPhotoAnalyzed = `-- name: MarkPhotoAnalyzed :exec
+UPDATE photos
+SET analyzed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) MarkPhotoAnalyzed(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markPhotoAnalyzed, id)
+	return err
+}
+
+const updatePhotoCaption = `-- name: UpdatePhotoCaption :one
+UPDATE photos
+SET caption = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, project_id, storage_key, filename, content_type, size_bytes, purpose, caption, uploaded_by, uploaded_by_name, created_at, analyzed_at, area_type, thumbnail_key, taken_at, latitude, longitude, orientation, camera_make, camera_model, perceptual_hash, duplicate_of, updated_at
+`
+
+type UpdatePhotoCaptionParams struct {
+	ID      pgtype.UUID
+	Caption string
+}
+
+func (q *Queries) UpdatePhotoCaption(ctx context.Context, arg UpdatePhotoCaptionParams) (Photo, error) {
+	row := q.db.QueryRow(ctx, updatePhotoCaption, arg.ID, arg.Caption)
+	var i Photo
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.StorageKey,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Purpose,
+		&i.Caption,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+		&i.AnalyzedAt,
+		&i.AreaType,
+		&i.ThumbnailKey,
+		&i.TakenAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Orientation,
+		&i.CameraMake,
+		&i.CameraModel,
+		&i.PerceptualHash,
+		&i.DuplicateOf,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
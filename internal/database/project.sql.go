@@ -0,0 +1,614 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: project.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countUnmappedProjects = `-- name: CountUnmappedProjects :one
+SELECT COUNT(*) FROM projects
+WHERE latitude IS NULL AND status <> 'archived'
+`
+
+func (q *Queries) CountUnmappedProjects(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnmappedProjects)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createProject = `-- name: CreateProject :one
+INSERT INTO projects (
+  name,
+  description,
+  status,
+  location,
+  inspector_id
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, name, description, status, location, inspector_id, compliance_score, created_at, updated_at, latitude, longitude, coordinates_source
+`
+
+type CreateProjectParams struct {
+	Name        string
+	Description string
+	Status      ProjectStatus
+	Location    string
+	InspectorID pgtype.UUID
+}
+
+func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, createProject,
+		arg.Name,
+		arg.Description,
+		arg.Status,
+		arg.Location,
+		arg.InspectorID,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Status,
+		&i.Location,
+		&i.InspectorID,
+		&i.ComplianceScore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.CoordinatesSource,
+	)
+	return i, err
+}
+
+const getDashboardStats = `-- name: GetDashboardStats :one
+SELECT
+  (SELECT COUNT(*) FROM projects)::int AS total_inspections,
+  (SELECT COUNT(*) FROM violations WHERE status IN ('open', 'validated', 'resolved'))::int AS violations_found,
+  (SELECT COALESCE(AVG(compliance_score), 100) FROM projects WHERE status <> 'archived')::float8 AS compliance_rate,
+  (SELECT COUNT(*) FROM projects WHERE status IN ('in-progress', 'needs-review'))::int AS active_projects
+`
+
+type GetDashboardStatsRow struct {
+	TotalInspections int32
+	ViolationsFound  int32
+	ComplianceRate   float64
+	ActiveProjects   int32
+}
+
+func (q *Queries) GetDashboardStats(ctx context.Context) (GetDashboardStatsRow, error) {
+	row := q.db.QueryRow(ctx, getDashboardStats)
+	var i GetDashboardStatsRow
+	err := row.Scan(
+		&i.TotalInspections,
+		&i.ViolationsFound,
+		&i.ComplianceRate,
+		&i.ActiveProjects,
+	)
+	return i, err
+}
+
+const getProject = `-- name: GetProject :one
+SELECT
+  p.id, p.name, p.description, p.status, p.location, p.inspector_id, p.compliance_score, p.created_at, p.updated_at, p.latitude, p.longitude, p.coordinates_source,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.id = $1 LIMIT 1
+`
+
+type GetProjectRow struct {
+	Project        Project
+	InspectorName  string
+	ViolationCount int64
+	PhotoCount     int64
+}
+
+// Projects Table --
+func (q *Queries) GetProject(ctx context.Context, id pgtype.UUID) (GetProjectRow, error) {
+	row := q.db.QueryRow(ctx, getProject, id)
+	var i GetProjectRow
+	err := row.Scan(
+		&i.Project.ID,
+		&i.Project.Name,
+		&i.Project.Description,
+		&i.Project.Status,
+		&i.Project.Location,
+		&i.Project.InspectorID,
+		&i.Project.ComplianceScore,
+		&i.Project.CreatedAt,
+		&i.Project.UpdatedAt,
+		&i.Project.Latitude,
+		&i.Project.Longitude,
+		&i.Project.CoordinatesSource,
+		&i.InspectorName,
+		&i.ViolationCount,
+		&i.PhotoCount,
+	)
+	return i, err
+}
+
+const listMappedProjects = `-- name: ListMappedProjects :many
+SELECT
+  p.id,
+  p.name,
+  p.status,
+  p.location,
+  p.latitude::float8 AS latitude,
+  p.longitude::float8 AS longitude,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status IN ('open', 'validated')) AS open_violation_count
+FROM projects p
+WHERE p.latitude IS NOT NULL AND p.status <> 'archived'
+ORDER BY p.name
+`
+
+type ListMappedProjectsRow struct {
+	ID                 pgtype.UUID
+	Name               string
+	Status             ProjectStatus
+	Location           string
+	Latitude           float64
+	Longitude          float64
+	OpenViolationCount int64
+}
+
+func (q *Queries) ListMappedProjects(ctx context.Context) ([]ListMappedProjectsRow, error) {
+	rows, err := q.db.Query(ctx, listMappedProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMappedProjectsRow
+	for rows.Next() {
+		var i ListMappedProjectsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.Location,
+			&i.Latitude,
+			&i.Longitude,
+			&i.OpenViolationCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectPhotoLocations = `-- name: ListProjectPhotoLocations :many
+SELECT latitude::float8 AS latitude, longitude::float8 AS longitude
+FROM photos
+WHERE project_id = $1 AND latitude IS NOT NULL
+`
+
+type ListProjectPhotoLocationsRow struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func (q *Queries) ListProjectPhotoLocations(ctx context.Context, projectID pgtype.UUID) ([]ListProjectPhotoLocationsRow, error) {
+	rows, err := q.db.Query(ctx, listProjectPhotoLocations, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectPhotoLocationsRow
+	for rows.Next() {
+		var i ListProjectPhotoLocationsRow
+		if err := rows.Scan(&i.Latitude, &i.Longitude); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectReportsPage = `-- name: ListProjectReportsPage :many
+SELECT p.id, p.name, p.updated_at
+FROM projects p
+WHERE ($1::uuid IS NULL OR p.id = $1)
+  AND ($2::timestamptz IS NULL OR p.updated_at >= $2)
+  AND ($3::timestamptz IS NULL OR p.updated_at < $3)
+  AND ($4::uuid IS NULL OR (p.updated_at, p.id) < ($5::timestamptz, $4))
+ORDER BY p.updated_at DESC, p.id DESC
+LIMIT $6
+`
+
+type ListProjectReportsPageParams struct {
+	ProjectID      pgtype.UUID
+	UpdatedFrom    pgtype.Timestamptz
+	UpdatedBefore  pgtype.Timestamptz
+	AfterID        pgtype.UUID
+	AfterUpdatedAt pgtype.Timestamptz
+	PageLimit      int32
+}
+
+type ListProjectReportsPageRow struct {
+	ID        pgtype.UUID
+	Name      string
+	UpdatedAt pgtype.Timestamptz
+}
+
+// Each project has a safety report generated from its current state, so the
+// report dates from the project's last update
+func (q *Queries) ListProjectReportsPage(ctx context.Context, arg ListProjectReportsPageParams) ([]ListProjectReportsPageRow, error) {
+	rows, err := q.db.Query(ctx, listProjectReportsPage,
+		arg.ProjectID,
+		arg.UpdatedFrom,
+		arg.UpdatedBefore,
+		arg.AfterID,
+		arg.AfterUpdatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectReportsPageRow
+	for rows.Next() {
+		var i ListProjectReportsPageRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsNear = `-- name: ListProjectsNear :many
+SELECT
+  p.id, p.name, p.description, p.status, p.location, p.inspector_id, p.compliance_score, p.created_at, p.updated_at, p.latitude, p.longitude, p.coordinates_source,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count,
+  (6371 * 2 * ASIN(LEAST(1, SQRT(
+    POWER(SIN(RADIANS(p.latitude - $1::float8) / 2), 2) +
+    COS(RADIANS($1::float8)) * COS(RADIANS(p.latitude)) *
+    POWER(SIN(RADIANS(p.longitude - $2::float8) / 2), 2)
+  ))))::float8 AS distance_km
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.latitude BETWEEN $3::float8 AND $4::float8
+  AND p.longitude BETWEEN $5::float8 AND $6::float8
+  AND p.status <> 'archived'
+ORDER BY distance_km
+LIMIT $7
+`
+
+type ListProjectsNearParams struct {
+	Latitude     float64
+	Longitude    float64
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+	MaxResults   int32
+}
+
+type ListProjectsNearRow struct {
+	Project        Project
+	InspectorName  string
+	ViolationCount int64
+	PhotoCount     int64
+	DistanceKm     float64
+}
+
+// The bounding box narrows the scan using idx_projects_coordinates before
+// the haversine distance is computed
+func (q *Queries) ListProjectsNear(ctx context.Context, arg ListProjectsNearParams) ([]ListProjectsNearRow, error) {
+	rows, err := q.db.Query(ctx, listProjectsNear,
+		arg.Latitude,
+		arg.Longitude,
+		arg.MinLatitude,
+		arg.MaxLatitude,
+		arg.MinLongitude,
+		arg.MaxLongitude,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectsNearRow
+	for rows.Next() {
+		var i ListProjectsNearRow
+		if err := rows.Scan(
+			&i.Project.ID,
+			&i.Project.Name,
+			&i.Project.Description,
+			&i.Project.Status,
+			&i.Project.Location,
+			&i.Project.InspectorID,
+			&i.Project.ComplianceScore,
+			&i.Project.CreatedAt,
+			&i.Project.UpdatedAt,
+			&i.Project.Latitude,
+			&i.Project.Longitude,
+			&i.Project.CoordinatesSource,
+			&i.InspectorName,
+			&i.ViolationCount,
+			&i.PhotoCount,
+			&i.DistanceKm,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsPage = `-- name: ListProjectsPage :many
+SELECT
+  p.id, p.name, p.description, p.status, p.location, p.inspector_id, p.compliance_score, p.created_at, p.updated_at, p.latitude, p.longitude, p.coordinates_source,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  c.violation_count,
+  c.photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+CROSS JOIN LATERAL (
+  SELECT
+    (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+    (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+) c
+WHERE ($1::project_status IS NULL OR p.status = $1)
+  AND ($2::uuid IS NULL OR p.inspector_id = $2)
+  AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+  AND ($4::timestamptz IS NULL OR p.created_at < $4)
+  AND ($5::text IS NULL
+    OR p.name ILIKE '%' || $5 || '%'
+    OR p.location ILIKE '%' || $5 || '%'
+    OR p.description ILIKE '%' || $5 || '%')
+  AND ($6::uuid IS NULL OR CASE
+    WHEN $7::text = 'name' AND $8::bool THEN (p.name, p.id) < ($9::text, $6)
+    WHEN $7::text = 'name' THEN (p.name, p.id) > ($9::text, $6)
+    WHEN $7::text = 'violations' AND $8::bool THEN (c.violation_count::float8, p.id) < ($10::float8, $6)
+    WHEN $7::text = 'violations' THEN (c.violation_count::float8, p.id) > ($10::float8, $6)
+    WHEN $7::text = 'compliance' AND $8::bool THEN (p.compliance_score, p.id) < ($10::float8, $6)
+    WHEN $7::text = 'compliance' THEN (p.compliance_score, p.id) > ($10::float8, $6)
+    WHEN $8::bool THEN (p.created_at, p.id) < ($11::timestamptz, $6)
+    ELSE (p.created_at, p.id) > ($11::timestamptz, $6)
+  END)
+ORDER BY
+  CASE WHEN $7::text = 'name' AND NOT $8::bool THEN p.name END ASC,
+  CASE WHEN $7::text = 'name' AND $8::bool THEN p.name END DESC,
+  CASE WHEN $7::text = 'violations' AND NOT $8::bool THEN c.violation_count END ASC,
+  CASE WHEN $7::text = 'violations' AND $8::bool THEN c.violation_count END DESC,
+  CASE WHEN $7::text = 'compliance' AND NOT $8::bool THEN p.compliance_score END ASC,
+  CASE WHEN $7::text = 'compliance' AND $8::bool THEN p.compliance_score END DESC,
+  CASE WHEN $7::text = 'date' AND NOT $8::bool THEN p.created_at END ASC,
+  CASE WHEN $7::text = 'date' AND $8::bool THEN p.created_at END DESC,
+  CASE WHEN NOT $8::bool THEN p.id END ASC,
+  CASE WHEN $8::bool THEN p.id END DESC
+LIMIT $12
+`
+
+type ListProjectsPageParams struct {
+	Status        NullProjectStatus
+	InspectorID   pgtype.UUID
+	CreatedFrom   pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	Search        pgtype.Text
+	AfterID       pgtype.UUID
+	SortBy        string
+	Descending    bool
+	AfterName     string
+	AfterNumber   float64
+	AfterTime     pgtype.Timestamptz
+	PageLimit     int32
+}
+
+type ListProjectsPageRow struct {
+	Project        Project
+	InspectorName  string
+	ViolationCount int64
+	PhotoCount     int64
+}
+
+// Keyset pagination: rows after the cursor's sort value and ID in the
+// requested direction, with the ID breaking ties. created_at backs the
+// "date" sort because, unlike updated_at, it doesn't move between pages.
+func (q *Queries) ListProjectsPage(ctx context.Context, arg ListProjectsPageParams) ([]ListProjectsPageRow, error) {
+	rows, err := q.db.Query(ctx, listProjectsPage,
+		arg.Status,
+		arg.InspectorID,
+		arg.CreatedFrom,
+		arg.CreatedBefore,
+		arg.Search,
+		arg.AfterID,
+		arg.SortBy,
+		arg.Descending,
+		arg.AfterName,
+		arg.AfterNumber,
+		arg.AfterTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectsPageRow
+	for rows.Next() {
+		var i ListProjectsPageRow
+		if err := rows.Scan(
+			&i.Project.ID,
+			&i.Project.Name,
+			&i.Project.Description,
+			&i.Project.Status,
+			&i.Project.Location,
+			&i.Project.InspectorID,
+			&i.Project.ComplianceScore,
+			&i.Project.CreatedAt,
+			&i.Project.UpdatedAt,
+			&i.Project.Latitude,
+			&i.Project.Longitude,
+			&i.Project.CoordinatesSource,
+			&i.InspectorName,
+			&i.ViolationCount,
+			&i.PhotoCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentProjects = `-- name: ListRecentProjects :many
+SELECT
+  p.id, p.name, p.description, p.status, p.location, p.inspector_id, p.compliance_score, p.created_at, p.updated_at, p.latitude, p.longitude, p.coordinates_source,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.status <> 'archived'
+ORDER BY p.updated_at DESC
+LIMIT $1
+`
+
+type ListRecentProjectsRow struct {
+	Project        Project
+	InspectorName  string
+	ViolationCount int64
+	PhotoCount     int64
+}
+
+func (q *Queries) ListRecentProjects(ctx context.Context, limit int32) ([]ListRecentProjectsRow, error) {
+	rows, err := q.db.Query(ctx, listRecentProjects, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecentProjectsRow
+	for rows.Next() {
+		var i ListRecentProjectsRow
+		if err := rows.Scan(
+			&i.Project.ID,
+			&i.Project.Name,
+			&i.Project.Description,
+			&i.Project.Status,
+			&i.Project.Location,
+			&i.Project.InspectorID,
+			&i.Project.ComplianceScore,
+			&i.Project.CreatedAt,
+			&i.Project.UpdatedAt,
+			&i.Project.Latitude,
+			&i.Project.Longitude,
+			&i.Project.CoordinatesSource,
+			&i.InspectorName,
+			&i.ViolationCount,
+			&i.PhotoCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setProjectCoordinatesFromPhotos = `-- name: SetProjectCoordinatesFromPhotos :exec
+UPDATE projects
+SET latitude = $2,
+    longitude = $3,
+    coordinates_source = 'photos'
+WHERE id = $1 AND latitude IS NULL
+`
+
+type SetProjectCoordinatesFromPhotosParams struct {
+	ID        pgtype.UUID
+	Latitude  pgtype.Float8
+	Longitude pgtype.Float8
+}
+
+// Only fills in missing coordinates; entered or geocoded ones are kept
+func (q *Queries) SetProjectCoordinatesFromPhotos(ctx context.Context, arg SetProjectCoordinatesFromPhotosParams) error {
+	_, err := q.db.Exec(ctx, setProjectCoordinatesFromPhotos, arg.ID, arg.Latitude, arg.Longitude)
+	return err
+}
+
+const touchProject = `-- name: TouchProject :exec
+UPDATE projects
+SET updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) TouchProject(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchProject, id)
+	return err
+}
+
+const updateProjectLocation = `-- name: UpdateProjectLocation :one
+UPDATE projects
+SET location = $2,
+    latitude = $3,
+    longitude = $4,
+    coordinates_source = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, name, description, status, location, inspector_id, compliance_score, created_at, updated_at, latitude, longitude, coordinates_source
+`
+
+type UpdateProjectLocationParams struct {
+	ID                pgtype.UUID
+	Location          string
+	Latitude          pgtype.Float8
+	Longitude         pgtype.Float8
+	CoordinatesSource NullCoordinatesSource
+}
+
+func (q *Queries) UpdateProjectLocation(ctx context.Context, arg UpdateProjectLocationParams) (Project, error) {
+	row := q.db.QueryRow(ctx, updateProjectLocation,
+		arg.ID,
+		arg.Location,
+		arg.Latitude,
+		arg.Longitude,
+		arg.CoordinatesSource,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Status,
+		&i.Location,
+		&i.InspectorID,
+		&i.ComplianceScore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.CoordinatesSource,
+	)
+	return i, err
+}
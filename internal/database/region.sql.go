@@ -0,0 +1,263 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: region.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createRegion = `-- name: CreateRegion :one
+INSERT INTO violation_regions (
+  violation_id,
+  photo_id,
+  shape,
+  x,
+  y,
+  width,
+  height,
+  points,
+  source,
+  confidence,
+  user_id,
+  user_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING id, violation_id, photo_id, shape, x, y, width, height, points, source, confidence, user_id, user_name, created_at
+`
+
+type CreateRegionParams struct {
+	ViolationID pgtype.UUID
+	PhotoID     pgtype.UUID
+	Shape       RegionShape
+	X           float64
+	Y           float64
+	Width       float64
+	Height      float64
+	Points      []byte
+	Source      RegionSource
+	Confidence  pgtype.Float8
+	UserID      pgtype.UUID
+	UserName    string
+}
+
+func (q *Queries) CreateRegion(ctx context.Context, arg CreateRegionParams) (ViolationRegion, error) {
+	row := q.db.QueryRow(ctx, createRegion,
+		arg.ViolationID,
+		arg.PhotoID,
+		arg.Shape,
+		arg.X,
+		arg.Y,
+		arg.Width,
+		arg.Height,
+		arg.Points,
+		arg.Source,
+		arg.Confidence,
+		arg.UserID,
+		arg.UserName,
+	)
+	var i ViolationRegion
+	err := row.Scan(
+		&i.ID,
+		&i.ViolationID,
+		&i.PhotoID,
+		&i.Shape,
+		&i.X,
+		&i.Y,
+		&i.Width,
+		&i.Height,
+		&i.Points,
+		&i.Source,
+		&i.Confidence,
+		&i.UserID,
+		&i.UserName,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteRegion = `-- name: DeleteRegion :exec
+DELETE FROM violation_regions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteRegion(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRegion, id)
+	return err
+}
+
+const getRegion = `-- name: GetRegion :one
+SELECT id, violation_id, photo_id, shape, x, y, width, height, points, source, confidence, user_id, user_name, created_at FROM violation_regions
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetRegion(ctx context.Context, id pgtype.UUID) (ViolationRegion, error) {
+	row := q.db.QueryRow(ctx, getRegion, id)
+	var i ViolationRegion
+	err := row.Scan(
+		&i.ID,
+		&i.ViolationID,
+		&i.PhotoID,
+		&i.Shape,
+		&i.X,
+		&i.Y,
+		&i.Width,
+		&i.Height,
+		&i.Points,
+		&i.Source,
+		&i.Confidence,
+		&i.UserID,
+		&i.UserName,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listRegionsByPhoto = `-- name: ListRegionsByPhoto :many
+SELECT r.id, r.violation_id, r.photo_id, r.shape, r.x, r.y, r.width, r.height, r.points, r.source, r.confidence, r.user_id, r.user_name, r.created_at, v.risk_level, v.description AS violation_description
+FROM violation_regions r
+JOIN violations v ON v.id = r.violation_id
+WHERE r.photo_id = $1
+ORDER BY r.created_at ASC
+`
+
+type ListRegionsByPhotoRow struct {
+	ViolationRegion      ViolationRegion
+	RiskLevel            RiskLevel
+	ViolationDescription string
+}
+
+// Violation Regions Table --
+func (q *Queries) ListRegionsByPhoto(ctx context.Context, photoID pgtype.UUID) ([]ListRegionsByPhotoRow, error) {
+	rows, err := q.db.Query(ctx, listRegionsByPhoto, photoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRegionsByPhotoRow
+	for rows.Next() {
+		var i ListRegionsByPhotoRow
+		if err := rows.Scan(
+			&i.ViolationRegion.ID,
+			&i.ViolationRegion.ViolationID,
+			&i.ViolationRegion.PhotoID,
+			&i.ViolationRegion.Shape,
+			&i.ViolationRegion.X,
+			&i.ViolationRegion.Y,
+			&i.ViolationRegion.Width,
+			&i.ViolationRegion.Height,
+			&i.ViolationRegion.Points,
+			&i.ViolationRegion.Source,
+			&i.ViolationRegion.Confidence,
+			&i.ViolationRegion.UserID,
+			&i.ViolationRegion.UserName,
+			&i.ViolationRegion.CreatedAt,
+			&i.RiskLevel,
+			&i.ViolationDescription,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRegionsByPhotos = `-- name: ListRegionsByPhotos :many
+SELECT r.id, r.violation_id, r.photo_id, r.shape, r.x, r.y, r.width, r.height, r.points, r.source, r.confidence, r.user_id, r.user_name, r.created_at, v.risk_level, v.description AS violation_description
+FROM violation_regions r
+JOIN violations v ON v.id = r.violation_id
+WHERE r.photo_id = ANY($1::uuid[])
+ORDER BY r.created_at ASC
+`
+
+type ListRegionsByPhotosRow struct {
+	ViolationRegion      ViolationRegion
+	RiskLevel            RiskLevel
+	ViolationDescription string
+}
+
+func (q *Queries) ListRegionsByPhotos(ctx context.Context, photoIds []pgtype.UUID) ([]ListRegionsByPhotosRow, error) {
+	rows, err := q.db.Query(ctx, listRegionsByPhotos, photoIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRegionsByPhotosRow
+	for rows.Next() {
+		var i ListRegionsByPhotosRow
+		if err := rows.Scan(
+			&i.ViolationRegion.ID,
+			&i.ViolationRegion.ViolationID,
+			&i.ViolationRegion.PhotoID,
+			&i.ViolationRegion.Shape,
+			&i.ViolationRegion.X,
+			&i.ViolationRegion.Y,
+			&i.ViolationRegion.Width,
+			&i.ViolationRegion.Height,
+			&i.ViolationRegion.Points,
+			&i.ViolationRegion.Source,
+			&i.ViolationRegion.Confidence,
+			&i.ViolationRegion.UserID,
+			&i.ViolationRegion.UserName,
+			&i.ViolationRegion.CreatedAt,
+			&i.RiskLevel,
+			&i.ViolationDescription,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRegionsByViolation = `-- name: ListRegionsByViolation :many
+SELECT id, violation_id, photo_id, shape, x, y, width, height, points, source, confidence, user_id, user_name, created_at FROM violation_regions
+WHERE violation_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListRegionsByViolation(ctx context.Context, violationID pgtype.UUID) ([]ViolationRegion, error) {
+	rows, err := q.db.Query(ctx, listRegionsByViolation, violationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ViolationRegion
+	for rows.Next() {
+		var i ViolationRegion
+		if err := rows.Scan(
+			&i.ID,
+			&i.ViolationID,
+			&i.PhotoID,
+			&i.Shape,
+			&i.X,
+			&i.Y,
+			&i.Width,
+			&i.Height,
+			&i.Points,
+			&i.Source,
+			&i.Confidence,
+			&i.UserID,
+			&i.UserName,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
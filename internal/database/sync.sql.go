@@ -0,0 +1,550 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sync.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createProjectWithID = `-- name: CreateProjectWithID :one
+INSERT INTO projects (
+  id,
+  name,
+  description,
+  status,
+  location,
+  inspector_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (id) DO NOTHING
+RETURNING id, name, description, status, location, inspector_id, compliance_score, created_at, updated_at, latitude, longitude, coordinates_source
+`
+
+type CreateProjectWithIDParams struct {
+	ID          pgtype.UUID
+	Name        string
+	Description string
+	Status      ProjectStatus
+	Location    string
+	InspectorID pgtype.UUID
+}
+
+// Replaying a create that already went through inserts nothing, so no row
+// is returned
+func (q *Queries) CreateProjectWithID(ctx context.Context, arg CreateProjectWithIDParams) (Project, error) {
+	row := q.db.QueryRow(ctx, createProjectWithID,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.Status,
+		arg.Location,
+		arg.InspectorID,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Status,
+		&i.Location,
+		&i.InspectorID,
+		&i.ComplianceScore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.CoordinatesSource,
+	)
+	return i, err
+}
+
+const getSyncSnapshot = `-- name: GetSyncSnapshot :one
+SELECT pg_current_snapshot()::text AS snapshot
+`
+
+// Sync Changes Table --
+func (q *Queries) GetSyncSnapshot(ctx context.Context) (string, error) {
+	row := q.db.QueryRow(ctx, getSyncSnapshot)
+	var snapshot string
+	err := row.Scan(&snapshot)
+	return snapshot, err
+}
+
+const listPhotosByIDs = `-- name: ListPhotosByIDs :many
+SELECT
+  p.id, p.project_id, p.storage_key, p.filename, p.content_type, p.size_bytes, p.purpose, p.caption, p.uploaded_by, p.uploaded_by_name, p.created_at, p.analyzed_at, p.area_type, p.thumbnail_key, p.taken_at, p.latitude, p.longitude, p.orientation, p.camera_make, p.camera_model, p.perceptual_hash, p.duplicate_of, p.updated_at,
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.id = ANY($1::uuid[])
+`
+
+type ListPhotosByIDsRow struct {
+	Photo        Photo
+	ViolationIds []pgtype.UUID
+}
+
+func (q *Queries) ListPhotosByIDs(ctx context.Context, ids []pgtype.UUID) ([]ListPhotosByIDsRow, error) {
+	rows, err := q.db.Query(ctx, listPhotosByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPhotosByIDsRow
+	for rows.Next() {
+		var i ListPhotosByIDsRow
+		if err := rows.Scan(
+			&i.Photo.ID,
+			&i.Photo.ProjectID,
+			&i.Photo.StorageKey,
+			&i.Photo.Filename,
+			&i.Photo.ContentType,
+			&i.Photo.SizeBytes,
+			&i.Photo.Purpose,
+			&i.Photo.Caption,
+			&i.Photo.UploadedBy,
+			&i.Photo.UploadedByName,
+			&i.Photo.CreatedAt,
+			&i.Photo.AnalyzedAt,
+			&i.Photo.AreaType,
+			&i.Photo.ThumbnailKey,
+			&i.Photo.TakenAt,
+			&i.Photo.Latitude,
+			&i.Photo.Longitude,
+			&i.Photo.Orientation,
+			&i.Photo.CameraMake,
+			&i.Photo.CameraModel,
+			&i.Photo.PerceptualHash,
+			&i.Photo.DuplicateOf,
+			&i.Photo.UpdatedAt,
+			&i.ViolationIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsByIDs = `-- name: ListProjectsByIDs :many
+SELECT
+  p.id, p.name, p.description, p.status, p.location, p.inspector_id, p.compliance_score, p.created_at, p.updated_at, p.latitude, p.longitude, p.coordinates_source,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.id = ANY($1::uuid[])
+`
+
+type ListProjectsByIDsRow struct {
+	Project        Project
+	InspectorName  string
+	ViolationCount int64
+	PhotoCount     int64
+}
+
+func (q *Queries) ListProjectsByIDs(ctx context.Context, ids []pgtype.UUID) ([]ListProjectsByIDsRow, error) {
+	rows, err := q.db.Query(ctx, listProjectsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectsByIDsRow
+	for rows.Next() {
+		var i ListProjectsByIDsRow
+		if err := rows.Scan(
+			&i.Project.ID,
+			&i.Project.Name,
+			&i.Project.Description,
+			&i.Project.Status,
+			&i.Project.Location,
+			&i.Project.InspectorID,
+			&i.Project.ComplianceScore,
+			&i.Project.CreatedAt,
+			&i.Project.UpdatedAt,
+			&i.Project.Latitude,
+			&i.Project.Longitude,
+			&i.Project.CoordinatesSource,
+			&i.InspectorName,
+			&i.ViolationCount,
+			&i.PhotoCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncChanges = `-- name: ListSyncChanges :many
+SELECT seq, entity, entity_id, project_id, deleted
+FROM sync_changes
+WHERE txid >= pg_snapshot_xmin($1::text::pg_snapshot)
+  AND NOT pg_visible_in_snapshot(txid, $1::text::pg_snapshot)
+  AND pg_visible_in_snapshot(txid, $2::text::pg_snapshot)
+  AND seq > $3
+  AND ($4::uuid IS NULL OR project_id = $4)
+ORDER BY seq
+LIMIT $5
+`
+
+type ListSyncChangesParams struct {
+	Since      string
+	Until      string
+	AfterSeq   int64
+	ProjectID  pgtype.UUID
+	MaxChanges int32
+}
+
+type ListSyncChangesRow struct {
+	Seq       int64
+	Entity    SyncEntity
+	EntityID  pgtype.UUID
+	ProjectID pgtype.UUID
+	Deleted   bool
+}
+
+// Changes committed after the since snapshot was taken and before the until
+// snapshot was. Rows up to the after sequence number went out in an earlier
+// page of the same pull.
+func (q *Queries) ListSyncChanges(ctx context.Context, arg ListSyncChangesParams) ([]ListSyncChangesRow, error) {
+	rows, err := q.db.Query(ctx, listSyncChanges,
+		arg.Since,
+		arg.Until,
+		arg.AfterSeq,
+		arg.ProjectID,
+		arg.MaxChanges,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSyncChangesRow
+	for rows.Next() {
+		var i ListSyncChangesRow
+		if err := rows.Scan(
+			&i.Seq,
+			&i.Entity,
+			&i.EntityID,
+			&i.ProjectID,
+			&i.Deleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncRecords = `-- name: ListSyncRecords :many
+SELECT entity, entity_id
+FROM (
+  SELECT 'project'::sync_entity AS entity, id AS entity_id, id AS project_id FROM projects
+  UNION ALL
+  SELECT 'photo'::sync_entity, id, project_id FROM photos
+  UNION ALL
+  SELECT 'violation'::sync_entity, id, project_id FROM violations
+) records
+WHERE ($1::sync_entity IS NULL
+       OR (entity, entity_id) > ($1::sync_entity, $2::uuid))
+  AND ($3::uuid IS NULL OR project_id = $3)
+ORDER BY entity, entity_id
+LIMIT $4
+`
+
+type ListSyncRecordsParams struct {
+	AfterEntity NullSyncEntity
+	AfterID     pgtype.UUID
+	ProjectID   pgtype.UUID
+	MaxRecords  int32
+}
+
+type ListSyncRecordsRow struct {
+	Entity   SyncEntity
+	EntityID pgtype.UUID
+}
+
+// Every synced record, for clients starting over. Records up to the after
+// entity and ID went out in an earlier page.
+func (q *Queries) ListSyncRecords(ctx context.Context, arg ListSyncRecordsParams) ([]ListSyncRecordsRow, error) {
+	rows, err := q.db.Query(ctx, listSyncRecords,
+		arg.AfterEntity,
+		arg.AfterID,
+		arg.ProjectID,
+		arg.MaxRecords,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSyncRecordsRow
+	for rows.Next() {
+		var i ListSyncRecordsRow
+		if err := rows.Scan(&i.Entity, &i.EntityID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationsByIDs = `-- name: ListViolationsByIDs :many
+SELECT v.id, v.project_id, v.description, v.regulation, v.risk_level, v.category, v.location, v.notes, v.ai_confidence, v.status, v.found_at, v.resolved_at, v.created_at, v.updated_at, v.assigned_user_id, v.assigned_subcontractor, v.due_date, v.photo_id, p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.id = ANY($1::uuid[])
+`
+
+type ListViolationsByIDsRow struct {
+	Violation   Violation
+	ProjectName string
+}
+
+func (q *Queries) ListViolationsByIDs(ctx context.Context, ids []pgtype.UUID) ([]ListViolationsByIDsRow, error) {
+	rows, err := q.db.Query(ctx, listViolationsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListViolationsByIDsRow
+	for rows.Next() {
+		var i ListViolationsByIDsRow
+		if err := rows.Scan(
+			&i.Violation.ID,
+			&i.Violation.ProjectID,
+			&i.Violation.Description,
+			&i.Violation.Regulation,
+			&i.Violation.RiskLevel,
+			&i.Violation.Category,
+			&i.Violation.Location,
+			&i.Violation.Notes,
+			&i.Violation.AiConfidence,
+			&i.Violation.Status,
+			&i.Violation.FoundAt,
+			&i.Violation.ResolvedAt,
+			&i.Violation.CreatedAt,
+			&i.Violation.UpdatedAt,
+			&i.Violation.AssignedUserID,
+			&i.Violation.AssignedSubcontractor,
+			&i.Violation.DueDate,
+			&i.Violation.PhotoID,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pruneSyncChanges = `-- name: PruneSyncChanges :execrows
+DELETE FROM sync_changes
+WHERE txid < (
+  SELECT kept.txid FROM sync_changes kept
+  WHERE kept.changed_at >= $1
+     OR kept.seq = (SELECT max(newest.seq) FROM sync_changes newest)
+  ORDER BY kept.txid
+  LIMIT 1
+)
+`
+
+// Deletes the transactions that made changes before the cutoff, oldest
+// first. The newest change is always kept, so the oldest change left shows
+// how far back the feed reaches.
+func (q *Queries) PruneSyncChanges(ctx context.Context, before pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, pruneSyncChanges, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const syncSnapshotExpired = `-- name: SyncSnapshotExpired :one
+SELECT COALESCE(
+  (SELECT txid FROM sync_changes ORDER BY txid LIMIT 1) > pg_snapshot_xmin($1::text::pg_snapshot),
+  false
+)::boolean AS expired
+`
+
+// Whether changes the snapshot hadn't seen may have been pruned. Pruning
+// removes whole transactions from the oldest up, so everything the snapshot
+// is missing is still kept unless the oldest kept change is newer than the
+// snapshot's xmin.
+func (q *Queries) SyncSnapshotExpired(ctx context.Context, since string) (bool, error) {
+	row := q.db.QueryRow(ctx, syncSnapshotExpired, since)
+	var expired bool
+	err := row.Scan(&expired)
+	return expired, err
+}
+
+const updatePhotoDetails = `-- name: UpdatePhotoDetails :one
+UPDATE photos
+SET caption = $1,
+    area_type = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND updated_at = $4
+RETURNING id, project_id, storage_key, filename, content_type, size_bytes, purpose, caption, uploaded_by, uploaded_by_name, created_at, analyzed_at, area_type, thumbnail_key, taken_at, latitude, longitude, orientation, camera_make, camera_model, perceptual_hash, duplicate_of, updated_at
+`
+
+type UpdatePhotoDetailsParams struct {
+	Caption       string
+	AreaType      string
+	ID            pgtype.UUID
+	BaseUpdatedAt pgtype.Timestamptz
+}
+
+// Only succeeds if the photo hasn't changed since the client last saw it
+func (q *Queries) UpdatePhotoDetails(ctx context.Context, arg UpdatePhotoDetailsParams) (Photo, error) {
+	row := q.db.QueryRow(ctx, updatePhotoDetails,
+		arg.Caption,
+		arg.AreaType,
+		arg.ID,
+		arg.BaseUpdatedAt,
+	)
+	var i Photo
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.StorageKey,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Purpose,
+		&i.Caption,
+		&i.UploadedBy,
+		&i.UploadedByName,
+		&i.CreatedAt,
+		&i.AnalyzedAt,
+		&i.AreaType,
+		&i.ThumbnailKey,
+		&i.TakenAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Orientation,
+		&i.CameraMake,
+		&i.CameraModel,
+		&i.PerceptualHash,
+		&i.DuplicateOf,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateProjectDetails = `-- name: UpdateProjectDetails :one
+UPDATE projects
+SET name = $1,
+    description = $2,
+    status = $3,
+    location = $4,
+    latitude = CASE WHEN location <> $4 AND coordinates_source = 'geocoded' THEN NULL ELSE latitude END,
+    longitude = CASE WHEN location <> $4 AND coordinates_source = 'geocoded' THEN NULL ELSE longitude END,
+    coordinates_source = CASE WHEN location <> $4 AND coordinates_source = 'geocoded' THEN NULL ELSE coordinates_source END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $5 AND updated_at = $6
+RETURNING id, name, description, status, location, inspector_id, compliance_score, created_at, updated_at, latitude, longitude, coordinates_source
+`
+
+type UpdateProjectDetailsParams struct {
+	Name          string
+	Description   string
+	Status        ProjectStatus
+	Location      string
+	ID            pgtype.UUID
+	BaseUpdatedAt pgtype.Timestamptz
+}
+
+// Only succeeds if the project hasn't changed since the client last saw it.
+// Coordinates geocoded from an address that changed are dropped rather than
+// left pointing at the old one.
+func (q *Queries) UpdateProjectDetails(ctx context.Context, arg UpdateProjectDetailsParams) (Project, error) {
+	row := q.db.QueryRow(ctx, updateProjectDetails,
+		arg.Name,
+		arg.Description,
+		arg.Status,
+		arg.Location,
+		arg.ID,
+		arg.BaseUpdatedAt,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Status,
+		&i.Location,
+		&i.InspectorID,
+		&i.ComplianceScore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Latitude,
+		&i.Longitude,
+		&i.CoordinatesSource,
+	)
+	return i, err
+}
+
+const updateViolationNotes = `-- name: UpdateViolationNotes :one
+UPDATE violations
+SET notes = $1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND updated_at = $3
+RETURNING id, project_id, description, regulation, risk_level, category, location, notes, ai_confidence, status, found_at, resolved_at, created_at, updated_at, assigned_user_id, assigned_subcontractor, due_date, photo_id
+`
+
+type UpdateViolationNotesParams struct {
+	Notes         string
+	ID            pgtype.UUID
+	BaseUpdatedAt pgtype.Timestamptz
+}
+
+// Only succeeds if the violation hasn't changed since the client last saw it
+func (q *Queries) UpdateViolationNotes(ctx context.Context, arg UpdateViolationNotesParams) (Violation, error) {
+	row := q.db.QueryRow(ctx, updateViolationNotes, arg.Notes, arg.ID, arg.BaseUpdatedAt)
+	var i Violation
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Description,
+		&i.Regulation,
+		&i.RiskLevel,
+		&i.Category,
+		&i.Location,
+		&i.Notes,
+		&i.AiConfidence,
+		&i.Status,
+		&i.FoundAt,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AssignedUserID,
+		&i.AssignedSubcontractor,
+		&i.DueDate,
+		&i.PhotoID,
+	)
+	return i, err
+}
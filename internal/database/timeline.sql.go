@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: timeline.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createTimelineEvent = `-- name: CreateTimelineEvent :one
+INSERT INTO timeline_events (
+  project_id,
+  violation_id,
+  type,
+  description,
+  user_id,
+  user_name,
+  metadata
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, project_id, violation_id, type, description, user_id, user_name, metadata, created_at
+`
+
+type CreateTimelineEventParams struct {
+	ProjectID   pgtype.UUID
+	ViolationID pgtype.UUID
+	Type        string
+	Description string
+	UserID      pgtype.UUID
+	UserName    string
+	Metadata    []byte
+}
+
+func (q *Queries) CreateTimelineEvent(ctx context.Context, arg CreateTimelineEventParams) (TimelineEvent, error) {
+	row := q.db.QueryRow(ctx, createTimelineEvent,
+		arg.ProjectID,
+		arg.ViolationID,
+		arg.Type,
+		arg.Description,
+		arg.UserID,
+		arg.UserName,
+		arg.Metadata,
+	)
+	var i TimelineEvent
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.ViolationID,
+		&i.Type,
+		&i.Description,
+		&i.UserID,
+		&i.UserName,
+		&i.Metadata,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listTimelineEventsByProject = `-- name: ListTimelineEventsByProject :many
+SELECT id, project_id, violation_id, type, description, user_id, user_name, metadata, created_at FROM timeline_events
+WHERE project_id = $1
+ORDER BY created_at ASC
+`
+
+// Timeline Events Table --
+func (q *Queries) ListTimelineEventsByProject(ctx context.Context, projectID pgtype.UUID) ([]TimelineEvent, error) {
+	rows, err := q.db.Query(ctx, listTimelineEventsByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TimelineEvent
+	for rows.Next() {
+		var i TimelineEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.ViolationID,
+			&i.Type,
+			&i.Description,
+			&i.UserID,
+			&i.UserName,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimelineEventsByViolation = `-- name: ListTimelineEventsByViolation :many
+SELECT id, project_id, violation_id, type, description, user_id, user_name, metadata, created_at FROM timeline_events
+WHERE violation_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListTimelineEventsByViolation(ctx context.Context, violationID pgtype.UUID) ([]TimelineEvent, error) {
+	rows, err := q.db.Query(ctx, listTimelineEventsByViolation, violationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TimelineEvent
+	for rows.Next() {
+		var i TimelineEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.ViolationID,
+			&i.Type,
+			&i.Description,
+			&i.UserID,
+			&i.UserName,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
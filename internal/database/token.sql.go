@@ -0,0 +1,195 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: token.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAPIToken = `-- name: CreateAPIToken :one
+INSERT INTO api_tokens (
+  kind,
+  name,
+  token_hash,
+  token_prefix,
+  scopes,
+  user_id,
+  created_by,
+  created_by_name,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, kind, name, token_hash, token_prefix, scopes, user_id, created_by, created_by_name, created_at, last_used_at, expires_at, revoked_at
+`
+
+type CreateAPITokenParams struct {
+	Kind          ApiTokenKind
+	Name          string
+	TokenHash     []byte
+	TokenPrefix   string
+	Scopes        []string
+	UserID        pgtype.UUID
+	CreatedBy     pgtype.UUID
+	CreatedByName string
+	ExpiresAt     pgtype.Timestamptz
+}
+
+// API Tokens Table --
+func (q *Queries) CreateAPIToken(ctx context.Context, arg CreateAPITokenParams) (ApiToken, error) {
+	row := q.db.QueryRow(ctx, createAPIToken,
+		arg.Kind,
+		arg.Name,
+		arg.TokenHash,
+		arg.TokenPrefix,
+		arg.Scopes,
+		arg.UserID,
+		arg.CreatedBy,
+		arg.CreatedByName,
+		arg.ExpiresAt,
+	)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Name,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.Scopes,
+		&i.UserID,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getAPIToken = `-- name: GetAPIToken :one
+SELECT id, kind, name, token_hash, token_prefix, scopes, user_id, created_by, created_by_name, created_at, last_used_at, expires_at, revoked_at FROM api_tokens
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAPIToken(ctx context.Context, id pgtype.UUID) (ApiToken, error) {
+	row := q.db.QueryRow(ctx, getAPIToken, id)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Name,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.Scopes,
+		&i.UserID,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getActiveAPITokenByHash = `-- name: GetActiveAPITokenByHash :one
+SELECT id, kind, name, token_hash, token_prefix, scopes, user_id, created_by, created_by_name, created_at, last_used_at, expires_at, revoked_at FROM api_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1
+`
+
+func (q *Queries) GetActiveAPITokenByHash(ctx context.Context, tokenHash []byte) (ApiToken, error) {
+	row := q.db.QueryRow(ctx, getActiveAPITokenByHash, tokenHash)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Name,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.Scopes,
+		&i.UserID,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const listAPITokens = `-- name: ListAPITokens :many
+SELECT id, kind, name, token_hash, token_prefix, scopes, user_id, created_by, created_by_name, created_at, last_used_at, expires_at, revoked_at FROM api_tokens
+WHERE kind = 'service'
+   OR user_id IS NOT DISTINCT FROM $1::uuid
+ORDER BY created_at DESC
+`
+
+// Service tokens and the personal tokens of one user, newest first
+func (q *Queries) ListAPITokens(ctx context.Context, userID pgtype.UUID) ([]ApiToken, error) {
+	rows, err := q.db.Query(ctx, listAPITokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiToken
+	for rows.Next() {
+		var i ApiToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Name,
+			&i.TokenHash,
+			&i.TokenPrefix,
+			&i.Scopes,
+			&i.UserID,
+			&i.CreatedBy,
+			&i.CreatedByName,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIToken = `-- name: RevokeAPIToken :exec
+UPDATE api_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAPIToken(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, revokeAPIToken, id)
+	return err
+}
+
+const touchAPIToken = `-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+`
+
+// Recorded at most once a minute so busy integrations don't write on every
+// request
+func (q *Queries) TouchAPIToken(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchAPIToken, id)
+	return err
+}
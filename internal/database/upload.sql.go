@@ -0,0 +1,300 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: upload.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const advanceUpload = `-- name: AdvanceUpload :one
+UPDATE uploads
+SET upload_offset = upload_offset + $1,
+    updated_at = CURRENT_TIMESTAMP,
+    expires_at = $2
+WHERE id = $3
+  AND upload_offset = $4
+  AND completed_at IS NULL
+RETURNING id, project_id, upload_length, upload_offset, filename, area_type, caption, created_by, created_by_name, photo_id, created_at, updated_at, expires_at, completed_at, requested_photo_id
+`
+
+type AdvanceUploadParams struct {
+	SizeBytes      int64
+	ExpiresAt      pgtype.Timestamptz
+	ID             pgtype.UUID
+	ExpectedOffset int64
+}
+
+// Only succeeds if no other request has moved the offset since it was read
+func (q *Queries) AdvanceUpload(ctx context.Context, arg AdvanceUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, advanceUpload,
+		arg.SizeBytes,
+		arg.ExpiresAt,
+		arg.ID,
+		arg.ExpectedOffset,
+	)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.Filename,
+		&i.AreaType,
+		&i.Caption,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.PhotoID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.CompletedAt,
+		&i.RequestedPhotoID,
+	)
+	return i, err
+}
+
+const completeUpload = `-- name: CompleteUpload :one
+UPDATE uploads
+SET upload_offset = upload_length,
+    photo_id = $1,
+    updated_at = CURRENT_TIMESTAMP,
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = $2
+  AND upload_offset = $3
+  AND completed_at IS NULL
+RETURNING id, project_id, upload_length, upload_offset, filename, area_type, caption, created_by, created_by_name, photo_id, created_at, updated_at, expires_at, completed_at, requested_photo_id
+`
+
+type CompleteUploadParams struct {
+	PhotoID        pgtype.UUID
+	ID             pgtype.UUID
+	ExpectedOffset int64
+}
+
+func (q *Queries) CompleteUpload(ctx context.Context, arg CompleteUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, completeUpload, arg.PhotoID, arg.ID, arg.ExpectedOffset)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.Filename,
+		&i.AreaType,
+		&i.Caption,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.PhotoID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.CompletedAt,
+		&i.RequestedPhotoID,
+	)
+	return i, err
+}
+
+const createUpload = `-- name: CreateUpload :one
+INSERT INTO uploads (
+  project_id,
+  upload_length,
+  filename,
+  area_type,
+  caption,
+  created_by,
+  created_by_name,
+  requested_photo_id,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, project_id, upload_length, upload_offset, filename, area_type, caption, created_by, created_by_name, photo_id, created_at, updated_at, expires_at, completed_at, requested_photo_id
+`
+
+type CreateUploadParams struct {
+	ProjectID        pgtype.UUID
+	UploadLength     int64
+	Filename         string
+	AreaType         string
+	Caption          string
+	CreatedBy        pgtype.UUID
+	CreatedByName    string
+	RequestedPhotoID pgtype.UUID
+	ExpiresAt        pgtype.Timestamptz
+}
+
+// Uploads Table --
+func (q *Queries) CreateUpload(ctx context.Context, arg CreateUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, createUpload,
+		arg.ProjectID,
+		arg.UploadLength,
+		arg.Filename,
+		arg.AreaType,
+		arg.Caption,
+		arg.CreatedBy,
+		arg.CreatedByName,
+		arg.RequestedPhotoID,
+		arg.ExpiresAt,
+	)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.Filename,
+		&i.AreaType,
+		&i.Caption,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.PhotoID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.CompletedAt,
+		&i.RequestedPhotoID,
+	)
+	return i, err
+}
+
+const createUploadChunk = `-- name: CreateUploadChunk :exec
+INSERT INTO upload_chunks (
+  upload_id,
+  chunk_offset,
+  size_bytes,
+  storage_key
+) VALUES (
+  $1, $2, $3, $4
+)
+`
+
+type CreateUploadChunkParams struct {
+	UploadID    pgtype.UUID
+	ChunkOffset int64
+	SizeBytes   int64
+	StorageKey  string
+}
+
+// Upload Chunks Table --
+func (q *Queries) CreateUploadChunk(ctx context.Context, arg CreateUploadChunkParams) error {
+	_, err := q.db.Exec(ctx, createUploadChunk,
+		arg.UploadID,
+		arg.ChunkOffset,
+		arg.SizeBytes,
+		arg.StorageKey,
+	)
+	return err
+}
+
+const deleteUpload = `-- name: DeleteUpload :exec
+DELETE FROM uploads
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUpload(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUpload, id)
+	return err
+}
+
+const deleteUploadChunks = `-- name: DeleteUploadChunks :exec
+DELETE FROM upload_chunks
+WHERE upload_id = $1
+`
+
+func (q *Queries) DeleteUploadChunks(ctx context.Context, uploadID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUploadChunks, uploadID)
+	return err
+}
+
+const getUpload = `-- name: GetUpload :one
+SELECT id, project_id, upload_length, upload_offset, filename, area_type, caption, created_by, created_by_name, photo_id, created_at, updated_at, expires_at, completed_at, requested_photo_id FROM uploads
+WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP
+LIMIT 1
+`
+
+func (q *Queries) GetUpload(ctx context.Context, id pgtype.UUID) (Upload, error) {
+	row := q.db.QueryRow(ctx, getUpload, id)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.Filename,
+		&i.AreaType,
+		&i.Caption,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.PhotoID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.CompletedAt,
+		&i.RequestedPhotoID,
+	)
+	return i, err
+}
+
+const listExpiredUploads = `-- name: ListExpiredUploads :many
+SELECT id FROM uploads
+WHERE expires_at <= CURRENT_TIMESTAMP
+ORDER BY expires_at
+LIMIT $1
+`
+
+func (q *Queries) ListExpiredUploads(ctx context.Context, limit int32) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listExpiredUploads, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploadChunks = `-- name: ListUploadChunks :many
+SELECT upload_id, chunk_offset, size_bytes, storage_key, created_at FROM upload_chunks
+WHERE upload_id = $1
+ORDER BY chunk_offset
+`
+
+func (q *Queries) ListUploadChunks(ctx context.Context, uploadID pgtype.UUID) ([]UploadChunk, error) {
+	rows, err := q.db.Query(ctx, listUploadChunks, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UploadChunk
+	for rows.Next() {
+		var i UploadChunk
+		if err := rows.Scan(
+			&i.UploadID,
+			&i.ChunkOffset,
+			&i.SizeBytes,
+			&i.StorageKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
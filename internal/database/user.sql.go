@@ -348,6 +348,65 @@ func (q *Queries) ListUsersByRole(ctx context.Context, role UserRole) ([]User, e
 	return items, nil
 }
 
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT id, email, password_hash, username, login_method, first_name, last_name, profile_picture_url, timezone, is_active, email_verified, role, created_at, updated_at, last_login_at FROM users
+WHERE ($1::user_role IS NULL OR role = $1)
+  AND ($2::bool IS NULL OR is_active = $2)
+  AND ($3::uuid IS NULL OR (created_at, id) < ($4::timestamptz, $3))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListUsersPageParams struct {
+	Role           NullUserRole
+	IsActive       pgtype.Bool
+	AfterID        pgtype.UUID
+	AfterCreatedAt pgtype.Timestamptz
+	PageLimit      int32
+}
+
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersPage,
+		arg.Role,
+		arg.IsActive,
+		arg.AfterID,
+		arg.AfterCreatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.PasswordHash,
+			&i.Username,
+			&i.LoginMethod,
+			&i.FirstName,
+			&i.LastName,
+			&i.ProfilePictureUrl,
+			&i.Timezone,
+			&i.IsActive,
+			&i.EmailVerified,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastLoginAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchUsersByEmail = `-- name: SearchUsersByEmail :many
 SELECT id, email, password_hash, username, login_method, first_name, last_name, profile_picture_url, timezone, is_active, email_verified, role, created_at, updated_at, last_login_at FROM users
 WHERE email ILIKE '%' || $1 || '%'
@@ -0,0 +1,712 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: violation.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createViolation = `-- name: CreateViolation :one
+INSERT INTO violations (
+  project_id,
+  description,
+  regulation,
+  risk_level,
+  category,
+  location,
+  notes,
+  ai_confidence,
+  photo_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, project_id, description, regulation, risk_level, category, location, notes, ai_confidence, status, found_at, resolved_at, created_at, updated_at, assigned_user_id, assigned_subcontractor, due_date, photo_id
+`
+
+type CreateViolationParams struct {
+	ProjectID    pgtype.UUID
+	Description  string
+	Regulation   string
+	RiskLevel    RiskLevel
+	Category     string
+	Location     string
+	Notes        string
+	AiConfidence float64
+	PhotoID      pgtype.UUID
+}
+
+func (q *Queries) CreateViolation(ctx context.Context, arg CreateViolationParams) (Violation, error) {
+	row := q.db.QueryRow(ctx, createViolation,
+		arg.ProjectID,
+		arg.Description,
+		arg.Regulation,
+		arg.RiskLevel,
+		arg.Category,
+		arg.Location,
+		arg.Notes,
+		arg.AiConfidence,
+		arg.PhotoID,
+	)
+	var i Violation
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Description,
+		&i.Regulation,
+		&i.RiskLevel,
+		&i.Category,
+		&i.Location,
+		&i.Notes,
+		&i.AiConfidence,
+		&i.Status,
+		&i.FoundAt,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AssignedUserID,
+		&i.AssignedSubcontractor,
+		&i.DueDate,
+		&i.PhotoID,
+	)
+	return i, err
+}
+
+const createViolationComment = `-- name: CreateViolationComment :one
+INSERT INTO violation_comments (
+  violation_id,
+  user_id,
+  user_name,
+  body
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, violation_id, user_id, user_name, body, created_at
+`
+
+type CreateViolationCommentParams struct {
+	ViolationID pgtype.UUID
+	UserID      pgtype.UUID
+	UserName    string
+	Body        string
+}
+
+func (q *Queries) CreateViolationComment(ctx context.Context, arg CreateViolationCommentParams) (ViolationComment, error) {
+	row := q.db.QueryRow(ctx, createViolationComment,
+		arg.ViolationID,
+		arg.UserID,
+		arg.UserName,
+		arg.Body,
+	)
+	var i ViolationComment
+	err := row.Scan(
+		&i.ID,
+		&i.ViolationID,
+		&i.UserID,
+		&i.UserName,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createViolationResolution = `-- name: CreateViolationResolution :one
+INSERT INTO violation_resolutions (
+  violation_id,
+  photo_id,
+  note,
+  verification,
+  verification_confidence,
+  verification_detail,
+  accepted,
+  user_id,
+  user_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, violation_id, photo_id, note, verification, verification_confidence, verification_detail, accepted, user_id, user_name, created_at
+`
+
+type CreateViolationResolutionParams struct {
+	ViolationID            pgtype.UUID
+	PhotoID                pgtype.UUID
+	Note                   string
+	Verification           VerificationResult
+	VerificationConfidence pgtype.Float8
+	VerificationDetail     string
+	Accepted               bool
+	UserID                 pgtype.UUID
+	UserName               string
+}
+
+func (q *Queries) CreateViolationResolution(ctx context.Context, arg CreateViolationResolutionParams) (ViolationResolution, error) {
+	row := q.db.QueryRow(ctx, createViolationResolution,
+		arg.ViolationID,
+		arg.PhotoID,
+		arg.Note,
+		arg.Verification,
+		arg.VerificationConfidence,
+		arg.VerificationDetail,
+		arg.Accepted,
+		arg.UserID,
+		arg.UserName,
+	)
+	var i ViolationResolution
+	err := row.Scan(
+		&i.ID,
+		&i.ViolationID,
+		&i.PhotoID,
+		&i.Note,
+		&i.Verification,
+		&i.VerificationConfidence,
+		&i.VerificationDetail,
+		&i.Accepted,
+		&i.UserID,
+		&i.UserName,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteOpenDetectedViolationsByPhoto = `-- name: DeleteOpenDetectedViolationsByPhoto :exec
+DELETE FROM violations
+WHERE photo_id = $1 AND status = 'open' AND ai_confidence > 0
+`
+
+func (q *Queries) DeleteOpenDetectedViolationsByPhoto(ctx context.Context, photoID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOpenDetectedViolationsByPhoto, photoID)
+	return err
+}
+
+const getLatestViolationResolution = `-- name: GetLatestViolationResolution :one
+SELECT id, violation_id, photo_id, note, verification, verification_confidence, verification_detail, accepted, user_id, user_name, created_at FROM violation_resolutions
+WHERE violation_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+// Violation Resolutions Table --
+func (q *Queries) GetLatestViolationResolution(ctx context.Context, violationID pgtype.UUID) (ViolationResolution, error) {
+	row := q.db.QueryRow(ctx, getLatestViolationResolution, violationID)
+	var i ViolationResolution
+	err := row.Scan(
+		&i.ID,
+		&i.ViolationID,
+		&i.PhotoID,
+		&i.Note,
+		&i.Verification,
+		&i.VerificationConfidence,
+		&i.VerificationDetail,
+		&i.Accepted,
+		&i.UserID,
+		&i.UserName,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getViolation = `-- name: GetViolation :one
+SELECT
+  v.id, v.project_id, v.description, v.regulation, v.risk_level, v.category, v.location, v.notes, v.ai_confidence, v.status, v.found_at, v.resolved_at, v.created_at, v.updated_at, v.assigned_user_id, v.assigned_subcontractor, v.due_date, v.photo_id,
+  p.name AS project_name,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS assignee_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+LEFT JOIN users u ON u.id = v.assigned_user_id
+WHERE v.id = $1 LIMIT 1
+`
+
+type GetViolationRow struct {
+	Violation    Violation
+	ProjectName  string
+	AssigneeName string
+}
+
+// Violations Table --
+func (q *Queries) GetViolation(ctx context.Context, id pgtype.UUID) (GetViolationRow, error) {
+	row := q.db.QueryRow(ctx, getViolation, id)
+	var i GetViolationRow
+	err := row.Scan(
+		&i.Violation.ID,
+		&i.Violation.ProjectID,
+		&i.Violation.Description,
+		&i.Violation.Regulation,
+		&i.Violation.RiskLevel,
+		&i.Violation.Category,
+		&i.Violation.Location,
+		&i.Violation.Notes,
+		&i.Violation.AiConfidence,
+		&i.Violation.Status,
+		&i.Violation.FoundAt,
+		&i.Violation.ResolvedAt,
+		&i.Violation.CreatedAt,
+		&i.Violation.UpdatedAt,
+		&i.Violation.AssignedUserID,
+		&i.Violation.AssignedSubcontractor,
+		&i.Violation.DueDate,
+		&i.Violation.PhotoID,
+		&i.ProjectName,
+		&i.AssigneeName,
+	)
+	return i, err
+}
+
+const listAcceptedResolutionsByProject = `-- name: ListAcceptedResolutionsByProject :many
+SELECT DISTINCT ON (r.violation_id) r.id, r.violation_id, r.photo_id, r.note, r.verification, r.verification_confidence, r.verification_detail, r.accepted, r.user_id, r.user_name, r.created_at
+FROM violation_resolutions r
+JOIN violations v ON v.id = r.violation_id
+WHERE v.project_id = $1 AND r.accepted
+ORDER BY r.violation_id, r.created_at DESC
+`
+
+func (q *Queries) ListAcceptedResolutionsByProject(ctx context.Context, projectID pgtype.UUID) ([]ViolationResolution, error) {
+	rows, err := q.db.Query(ctx, listAcceptedResolutionsByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ViolationResolution
+	for rows.Next() {
+		var i ViolationResolution
+		if err := rows.Scan(
+			&i.ID,
+			&i.ViolationID,
+			&i.PhotoID,
+			&i.Note,
+			&i.Verification,
+			&i.VerificationConfidence,
+			&i.VerificationDetail,
+			&i.Accepted,
+			&i.UserID,
+			&i.UserName,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCriticalViolations = `-- name: ListCriticalViolations :many
+SELECT v.id, v.project_id, v.description, v.regulation, v.risk_level, v.category, v.location, v.notes, v.ai_confidence, v.status, v.found_at, v.resolved_at, v.created_at, v.updated_at, v.assigned_user_id, v.assigned_subcontractor, v.due_date, v.photo_id, p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.risk_level IN ('critical', 'high')
+  AND v.status IN ('open', 'validated')
+ORDER BY v.risk_level DESC, v.found_at DESC
+LIMIT $1
+`
+
+type ListCriticalViolationsRow struct {
+	Violation   Violation
+	ProjectName string
+}
+
+func (q *Queries) ListCriticalViolations(ctx context.Context, limit int32) ([]ListCriticalViolationsRow, error) {
+	rows, err := q.db.Query(ctx, listCriticalViolations, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCriticalViolationsRow
+	for rows.Next() {
+		var i ListCriticalViolationsRow
+		if err := rows.Scan(
+			&i.Violation.ID,
+			&i.Violation.ProjectID,
+			&i.Violation.Description,
+			&i.Violation.Regulation,
+			&i.Violation.RiskLevel,
+			&i.Violation.Category,
+			&i.Violation.Location,
+			&i.Violation.Notes,
+			&i.Violation.AiConfidence,
+			&i.Violation.Status,
+			&i.Violation.FoundAt,
+			&i.Violation.ResolvedAt,
+			&i.Violation.CreatedAt,
+			&i.Violation.UpdatedAt,
+			&i.Violation.AssignedUserID,
+			&i.Violation.AssignedSubcontractor,
+			&i.Violation.DueDate,
+			&i.Violation.PhotoID,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenViolationHotspots = `-- name: ListOpenViolationHotspots :many
+SELECT
+  ROUND(COALESCE(ph.latitude, p.latitude)::numeric, 3)::float8 AS latitude,
+  ROUND(COALESCE(ph.longitude, p.longitude)::numeric, 3)::float8 AS longitude,
+  COUNT(*) AS violation_count,
+  COUNT(*) FILTER (WHERE v.risk_level IN ('high', 'critical')) AS severe_count
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+LEFT JOIN photos ph ON ph.id = v.photo_id
+WHERE v.status IN ('open', 'validated')
+  AND p.status <> 'archived'
+  AND COALESCE(ph.latitude, p.latitude) IS NOT NULL
+GROUP BY 1, 2
+ORDER BY violation_count DESC
+LIMIT $1
+`
+
+type ListOpenViolationHotspotsRow struct {
+	Latitude       float64
+	Longitude      float64
+	ViolationCount int64
+	SevereCount    int64
+}
+
+// Open violations grouped into cells of about 100 m, placed where their
+// photo was taken or, failing that, at their project
+func (q *Queries) ListOpenViolationHotspots(ctx context.Context, limit int32) ([]ListOpenViolationHotspotsRow, error) {
+	rows, err := q.db.Query(ctx, listOpenViolationHotspots, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOpenViolationHotspotsRow
+	for rows.Next() {
+		var i ListOpenViolationHotspotsRow
+		if err := rows.Scan(
+			&i.Latitude,
+			&i.Longitude,
+			&i.ViolationCount,
+			&i.SevereCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationComments = `-- name: ListViolationComments :many
+SELECT id, violation_id, user_id, user_name, body, created_at FROM violation_comments
+WHERE violation_id = $1
+ORDER BY created_at ASC
+`
+
+// Violation Comments Table --
+func (q *Queries) ListViolationComments(ctx context.Context, violationID pgtype.UUID) ([]ViolationComment, error) {
+	rows, err := q.db.Query(ctx, listViolationComments, violationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ViolationComment
+	for rows.Next() {
+		var i ViolationComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ViolationID,
+			&i.UserID,
+			&i.UserName,
+			&i.Body,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationsByPhoto = `-- name: ListViolationsByPhoto :many
+SELECT id, project_id, description, regulation, risk_level, category, location, notes, ai_confidence, status, found_at, resolved_at, created_at, updated_at, assigned_user_id, assigned_subcontractor, due_date, photo_id FROM violations
+WHERE photo_id = $1
+ORDER BY found_at ASC
+`
+
+func (q *Queries) ListViolationsByPhoto(ctx context.Context, photoID pgtype.UUID) ([]Violation, error) {
+	rows, err := q.db.Query(ctx, listViolationsByPhoto, photoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Violation
+	for rows.Next() {
+		var i Violation
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Description,
+			&i.Regulation,
+			&i.RiskLevel,
+			&i.Category,
+			&i.Location,
+			&i.Notes,
+			&i.AiConfidence,
+			&i.Status,
+			&i.FoundAt,
+			&i.ResolvedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AssignedUserID,
+			&i.AssignedSubcontractor,
+			&i.DueDate,
+			&i.PhotoID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationsByProject = `-- name: ListViolationsByProject :many
+SELECT v.id, v.project_id, v.description, v.regulation, v.risk_level, v.category, v.location, v.notes, v.ai_confidence, v.status, v.found_at, v.resolved_at, v.created_at, v.updated_at, v.assigned_user_id, v.assigned_subcontractor, v.due_date, v.photo_id, p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.project_id = $1
+ORDER BY v.risk_level DESC, v.found_at DESC
+`
+
+type ListViolationsByProjectRow struct {
+	Violation   Violation
+	ProjectName string
+}
+
+func (q *Queries) ListViolationsByProject(ctx context.Context, projectID pgtype.UUID) ([]ListViolationsByProjectRow, error) {
+	rows, err := q.db.Query(ctx, listViolationsByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListViolationsByProjectRow
+	for rows.Next() {
+		var i ListViolationsByProjectRow
+		if err := rows.Scan(
+			&i.Violation.ID,
+			&i.Violation.ProjectID,
+			&i.Violation.Description,
+			&i.Violation.Regulation,
+			&i.Violation.RiskLevel,
+			&i.Violation.Category,
+			&i.Violation.Location,
+			&i.Violation.Notes,
+			&i.Violation.AiConfidence,
+			&i.Violation.Status,
+			&i.Violation.FoundAt,
+			&i.Violation.ResolvedAt,
+			&i.Violation.CreatedAt,
+			&i.Violation.UpdatedAt,
+			&i.Violation.AssignedUserID,
+			&i.Violation.AssignedSubcontractor,
+			&i.Violation.DueDate,
+			&i.Violation.PhotoID,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationsForUpdate = `-- name: ListViolationsForUpdate :many
+SELECT id, project_id, description, regulation, risk_level, category, location, notes, ai_confidence, status, found_at, resolved_at, created_at, updated_at, assigned_user_id, assigned_subcontractor, due_date, photo_id FROM violations
+WHERE project_id = $1 AND id = ANY($2::uuid[])
+FOR UPDATE
+`
+
+type ListViolationsForUpdateParams struct {
+	ProjectID pgtype.UUID
+	Ids       []pgtype.UUID
+}
+
+func (q *Queries) ListViolationsForUpdate(ctx context.Context, arg ListViolationsForUpdateParams) ([]Violation, error) {
+	rows, err := q.db.Query(ctx, listViolationsForUpdate, arg.ProjectID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Violation
+	for rows.Next() {
+		var i Violation
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Description,
+			&i.Regulation,
+			&i.RiskLevel,
+			&i.Category,
+			&i.Location,
+			&i.Notes,
+			&i.AiConfidence,
+			&i.Status,
+			&i.FoundAt,
+			&i.ResolvedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AssignedUserID,
+			&i.AssignedSubcontractor,
+			&i.DueDate,
+			&i.PhotoID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listViolationsPage = `-- name: ListViolationsPage :many
+SELECT v.id, v.project_id, v.description, v.regulation, v.risk_level, v.category, v.location, v.notes, v.ai_confidence, v.status, v.found_at, v.resolved_at, v.created_at, v.updated_at, v.assigned_user_id, v.assigned_subcontractor, v.due_date, v.photo_id, p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE ($1::uuid IS NULL OR v.project_id = $1)
+  AND ($2::violation_status IS NULL OR v.status = $2)
+  AND ($3::risk_level IS NULL OR v.risk_level = $3)
+  AND ($4::text IS NULL OR v.category = $4)
+  AND ($5::timestamptz IS NULL OR v.found_at >= $5)
+  AND ($6::timestamptz IS NULL OR v.found_at < $6)
+  AND ($7::uuid IS NULL OR (v.found_at, v.id) < ($8::timestamptz, $7))
+ORDER BY v.found_at DESC, v.id DESC
+LIMIT $9
+`
+
+type ListViolationsPageParams struct {
+	ProjectID    pgtype.UUID
+	Status       NullViolationStatus
+	RiskLevel    NullRiskLevel
+	Category     pgtype.Text
+	FoundFrom    pgtype.Timestamptz
+	FoundBefore  pgtype.Timestamptz
+	AfterID      pgtype.UUID
+	AfterFoundAt pgtype.Timestamptz
+	PageLimit    int32
+}
+
+type ListViolationsPageRow struct {
+	Violation   Violation
+	ProjectName string
+}
+
+func (q *Queries) ListViolationsPage(ctx context.Context, arg ListViolationsPageParams) ([]ListViolationsPageRow, error) {
+	rows, err := q.db.Query(ctx, listViolationsPage,
+		arg.ProjectID,
+		arg.Status,
+		arg.RiskLevel,
+		arg.Category,
+		arg.FoundFrom,
+		arg.FoundBefore,
+		arg.AfterID,
+		arg.AfterFoundAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListViolationsPageRow
+	for rows.Next() {
+		var i ListViolationsPageRow
+		if err := rows.Scan(
+			&i.Violation.ID,
+			&i.Violation.ProjectID,
+			&i.Violation.Description,
+			&i.Violation.Regulation,
+			&i.Violation.RiskLevel,
+			&i.Violation.Category,
+			&i.Violation.Location,
+			&i.Violation.Notes,
+			&i.Violation.AiConfidence,
+			&i.Violation.Status,
+			&i.Violation.FoundAt,
+			&i.Violation.ResolvedAt,
+			&i.Violation.CreatedAt,
+			&i.Violation.UpdatedAt,
+			&i.Violation.AssignedUserID,
+			&i.Violation.AssignedSubcontractor,
+			&i.Violation.DueDate,
+			&i.Violation.PhotoID,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateViolationAssignment = `-- name: UpdateViolationAssignment :exec
+UPDATE violations
+SET
+  assigned_user_id = $2,
+  assigned_subcontractor = $3,
+  due_date = $4,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateViolationAssignmentParams struct {
+	ID                    pgtype.UUID
+	AssignedUserID        pgtype.UUID
+	AssignedSubcontractor string
+	DueDate               pgtype.Date
+}
+
+func (q *Queries) UpdateViolationAssignment(ctx context.Context, arg UpdateViolationAssignmentParams) error {
+	_, err := q.db.Exec(ctx, updateViolationAssignment,
+		arg.ID,
+		arg.AssignedUserID,
+		arg.AssignedSubcontractor,
+		arg.DueDate,
+	)
+	return err
+}
+
+const updateViolationsStatus = `-- name: UpdateViolationsStatus :exec
+UPDATE violations
+SET
+  status = $1,
+  resolved_at = CASE WHEN $1 = 'resolved'::violation_status THEN CURRENT_TIMESTAMP ELSE NULL END,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ANY($2::uuid[])
+`
+
+type UpdateViolationsStatusParams struct {
+	Status ViolationStatus
+	Ids    []pgtype.UUID
+}
+
+func (q *Queries) UpdateViolationsStatus(ctx context.Context, arg UpdateViolationsStatusParams) error {
+	_, err := q.db.Exec(ctx, updateViolationsStatus, arg.Status, arg.Ids)
+	return err
+}
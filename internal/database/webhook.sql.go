@@ -0,0 +1,476 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhook.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $1::float8)
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id
+  AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event_id, d.event_type, d.occurred_at, d.data, d.attempts, s.url, s.secret
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseSeconds  float64
+	MaxDeliveries int32
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	ID         pgtype.UUID
+	EventID    pgtype.UUID
+	EventType  string
+	OccurredAt pgtype.Timestamptz
+	Data       []byte
+	Attempts   int32
+	Url        string
+	Secret     string
+}
+
+// Due deliveries are pushed back by the lease while they are sent, so other
+// instances don't send them too. A delivery whose sender died is retried
+// once the lease runs out.
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.LeaseSeconds, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.EventType,
+			&i.OccurredAt,
+			&i.Data,
+			&i.Attempts,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data)
+VALUES ($1, uuid_generate_v4(), $2, CURRENT_TIMESTAMP, $3)
+RETURNING id, subscription_id, event_id, event_type, occurred_at, data, status, attempts, next_attempt_at, last_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	SubscriptionID pgtype.UUID
+	EventType      string
+	Data           []byte
+}
+
+// Queues an event for one subscription regardless of its filters
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, createWebhookDelivery, arg.SubscriptionID, arg.EventType, arg.Data)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.EventType,
+		&i.OccurredAt,
+		&i.Data,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastAttemptAt,
+		&i.ResponseStatus,
+		&i.ResponseBody,
+		&i.Error,
+		&i.DurationMs,
+		&i.RedeliveryOf,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  name,
+  url,
+  secret,
+  event_types,
+  created_by,
+  created_by_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, name, url, secret, event_types, active, created_by, created_by_name, created_at, updated_at
+`
+
+type CreateWebhookSubscriptionParams struct {
+	Name          string
+	Url           string
+	Secret        string
+	EventTypes    []string
+	CreatedBy     pgtype.UUID
+	CreatedByName string
+}
+
+// Webhook Subscriptions Table --
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, createWebhookSubscription,
+		arg.Name,
+		arg.Url,
+		arg.Secret,
+		arg.EventTypes,
+		arg.CreatedBy,
+		arg.CreatedByName,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteWebhookSubscription, id)
+	return err
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, subscription_id, event_id, event_type, occurred_at, data, status, attempts, next_attempt_at, last_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at FROM webhook_deliveries
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id pgtype.UUID) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.EventType,
+		&i.OccurredAt,
+		&i.Data,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastAttemptAt,
+		&i.ResponseStatus,
+		&i.ResponseBody,
+		&i.Error,
+		&i.DurationMs,
+		&i.RedeliveryOf,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, name, url, secret, event_types, active, created_by, created_by_name, created_at, updated_at FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id pgtype.UUID) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, getWebhookSubscription, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, subscription_id, event_id, event_type, occurred_at, data, status, attempts, next_attempt_at, last_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type ListWebhookDeliveriesParams struct {
+	SubscriptionID pgtype.UUID
+	Limit          int32
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries, arg.SubscriptionID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.EventID,
+			&i.EventType,
+			&i.OccurredAt,
+			&i.Data,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastAttemptAt,
+			&i.ResponseStatus,
+			&i.ResponseBody,
+			&i.Error,
+			&i.DurationMs,
+			&i.RedeliveryOf,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT
+  s.id, s.name, s.url, s.secret, s.event_types, s.active, s.created_by, s.created_by_name, s.created_at, s.updated_at,
+  COALESCE(d.status::text, '')::text AS last_status,
+  d.last_attempt_at AS last_attempt_at
+FROM webhook_subscriptions s
+LEFT JOIN LATERAL (
+  SELECT status, last_attempt_at FROM webhook_deliveries
+  WHERE subscription_id = s.id AND last_attempt_at IS NOT NULL
+  ORDER BY last_attempt_at DESC
+  LIMIT 1
+) d ON TRUE
+ORDER BY s.created_at DESC
+`
+
+type ListWebhookSubscriptionsRow struct {
+	WebhookSubscription WebhookSubscription
+	LastStatus          string
+	LastAttemptAt       pgtype.Timestamptz
+}
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context) ([]ListWebhookSubscriptionsRow, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWebhookSubscriptionsRow
+	for rows.Next() {
+		var i ListWebhookSubscriptionsRow
+		if err := rows.Scan(
+			&i.WebhookSubscription.ID,
+			&i.WebhookSubscription.Name,
+			&i.WebhookSubscription.Url,
+			&i.WebhookSubscription.Secret,
+			&i.WebhookSubscription.EventTypes,
+			&i.WebhookSubscription.Active,
+			&i.WebhookSubscription.CreatedBy,
+			&i.WebhookSubscription.CreatedByName,
+			&i.WebhookSubscription.CreatedAt,
+			&i.WebhookSubscription.UpdatedAt,
+			&i.LastStatus,
+			&i.LastAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishWebhookEvent = `-- name: PublishWebhookEvent :execrows
+WITH event AS (
+  SELECT uuid_generate_v4() AS id, CURRENT_TIMESTAMP AS occurred_at
+)
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data)
+SELECT s.id, event.id, $1, event.occurred_at, $2
+FROM webhook_subscriptions s, event
+WHERE s.active AND $1::text = ANY(s.event_types)
+`
+
+type PublishWebhookEventParams struct {
+	EventType string
+	Data      []byte
+}
+
+// Webhook Deliveries Table --
+// Queues an event for every active subscription to its type
+func (q *Queries) PublishWebhookEvent(ctx context.Context, arg PublishWebhookEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, publishWebhookEvent, arg.EventType, arg.Data)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeWebhookDeliveries = `-- name: PurgeWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND created_at < $1
+`
+
+func (q *Queries) PurgeWebhookDeliveries(ctx context.Context, before pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeWebhookDeliveries, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordWebhookAttempt = `-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    status = $1,
+    last_attempt_at = CURRENT_TIMESTAMP,
+    next_attempt_at = $2,
+    response_status = $3,
+    response_body = $4,
+    error = $5,
+    duration_ms = $6
+WHERE id = $7
+`
+
+type RecordWebhookAttemptParams struct {
+	Status         WebhookDeliveryStatus
+	NextAttemptAt  pgtype.Timestamptz
+	ResponseStatus pgtype.Int4
+	ResponseBody   string
+	Error          string
+	DurationMs     pgtype.Int4
+	ID             pgtype.UUID
+}
+
+func (q *Queries) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookAttempt,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.ResponseStatus,
+		arg.ResponseBody,
+		arg.Error,
+		arg.DurationMs,
+		arg.ID,
+	)
+	return err
+}
+
+const redeliverWebhook = `-- name: RedeliverWebhook :one
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data, redelivery_of)
+SELECT subscription_id, event_id, event_type, occurred_at, data, id
+FROM webhook_deliveries
+WHERE webhook_deliveries.id = $1
+RETURNING id, subscription_id, event_id, event_type, occurred_at, data, status, attempts, next_attempt_at, last_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at
+`
+
+// Queues a fresh delivery of the same event
+func (q *Queries) RedeliverWebhook(ctx context.Context, id pgtype.UUID) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, redeliverWebhook, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.EventType,
+		&i.OccurredAt,
+		&i.Data,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastAttemptAt,
+		&i.ResponseStatus,
+		&i.ResponseBody,
+		&i.Error,
+		&i.DurationMs,
+		&i.RedeliveryOf,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET name = $2,
+    url = $3,
+    event_types = $4,
+    active = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, name, url, secret, event_types, active, created_by, created_by_name, created_at, updated_at
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	ID         pgtype.UUID
+	Name       string
+	Url        string
+	EventTypes []string
+	Active     bool
+}
+
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, updateWebhookSubscription,
+		arg.ID,
+		arg.Name,
+		arg.Url,
+		arg.EventTypes,
+		arg.Active,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedBy,
+		&i.CreatedByName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,172 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/exif"
+)
+
+const (
+	anthropicURL     = "https://api.anthropic.com/v1/messages"
+	anthropicVersion = "2023-06-01"
+
+	// DefaultModel is used when no model is configured
+	DefaultModel = "claude-sonnet-4-5"
+)
+
+const systemPrompt = `You are a construction safety inspector. Identify every OSHA construction (29 CFR 1926) violation visible in the photo.
+Respond with JSON only, in the form:
+{"findings":[{"description":"...","regulation":"1926.501","risk_level":"low|medium|high|critical","category":"...","confidence":0.0,"box":{"x":0.0,"y":0.0,"width":0.0,"height":0.0}}]}
+Use an empty findings array when no hazards are visible. confidence is your certainty from 0 to 1.
+box is the tightest rectangle around the hazard, as fractions of the image width and height measured from the top left corner.`
+
+// Claude detects hazards using the Anthropic Messages API
+type Claude struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewClaude returns a Claude detector. An empty model selects DefaultModel.
+func NewClaude(apiKey, model string) *Claude {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Claude{
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+type messageRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	System    string    `json:"system"`
+	Messages  []message `json:"messages"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *imageSource `json:"source,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type messageResponse struct {
+	Content []contentBlock `json:"content"`
+	Error   *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Detect sends img to the model and parses the findings from its reply. The
+// image's EXIF metadata is removed first so site locations aren't shared.
+func (c *Claude) Detect(ctx context.Context, img Image) ([]Finding, error) {
+	body, err := json.Marshal(messageRequest{
+		Model:     c.model,
+		MaxTokens: 2048,
+		System:    systemPrompt,
+		Messages: []message{{
+			Role: "user",
+			Content: []contentBlock{
+				{
+					Type: "image",
+					Source: &imageSource{
+						Type:      "base64",
+						MediaType: img.ContentType,
+						Data:      base64.StdEncoding.EncodeToString(exif.Strip(img.Data)),
+					},
+				},
+				{Type: "text", Text: "List the safety violations in this photo."},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode detection request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create detection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("detection request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var msg messageResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("decode detection response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if msg.Error != nil {
+			return nil, fmt.Errorf("detection request: %s: %s", msg.Error.Type, msg.Error.Message)
+		}
+		return nil, fmt.Errorf("detection request: unexpected status %s", resp.Status)
+	}
+
+	var text strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return parseFindings(text.String())
+}
+
+// parseFindings extracts the findings object from the model's reply,
+// tolerating surrounding prose or code fences
+func parseFindings(reply string) ([]Finding, error) {
+	start := strings.IndexByte(reply, '{')
+	end := strings.LastIndexByte(reply, '}')
+	if start < 0 || end < start {
+		return nil, errors.New("detection response contained no JSON object")
+	}
+
+	var out struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("parse detection findings: %w", err)
+	}
+	for i := range out.Findings {
+		f := &out.Findings[i]
+		f.Confidence = min(max(f.Confidence, 0), 1)
+		f.RiskLevel = strings.ToLower(strings.TrimSpace(f.RiskLevel))
+		switch f.RiskLevel {
+		case "low", "medium", "high", "critical":
+		default:
+			f.RiskLevel = "medium"
+		}
+		if f.Box != nil && !f.Box.clamp() {
+			f.Box = nil
+		}
+	}
+	return out.Findings, nil
+}
@@ -0,0 +1,74 @@
+package detector
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/regulations"
+)
+
+// Image is a photo submitted for hazard detection
+type Image struct {
+	Data        []byte
+	ContentType string // "image/jpeg", "image/png" or "image/webp"
+}
+
+// Finding is a single hazard identified in an image
+type Finding struct {
+	Description string  `json:"description"` // "Worker on scaffold without fall protection"
+	Regulation  string  `json:"regulation"`  // "1926.501"
+	RiskLevel   string  `json:"risk_level"`  // "low", "medium", "high", "critical"
+	Category    string  `json:"category"`    // "Fall Protection"
+	Confidence  float64 `json:"confidence"`  // 0-1
+	Box         *Box    `json:"box"`         // Where the hazard appears, nil if unknown
+}
+
+// Box is a rectangle normalized to the image size (0-1, origin top left)
+type Box struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// clamp trims b to the image and reports whether anything is left
+func (b *Box) clamp() bool {
+	x0, y0 := min(max(b.X, 0), 1), min(max(b.Y, 0), 1)
+	x1, y1 := min(max(b.X+b.Width, 0), 1), min(max(b.Y+b.Height, 0), 1)
+	b.X, b.Y, b.Width, b.Height = x0, y0, x1-x0, y1-y0
+	return b.Width > 0 && b.Height > 0
+}
+
+// Detector identifies OSHA construction safety hazards in photos
+type Detector interface {
+	Detect(ctx context.Context, img Image) ([]Finding, error)
+}
+
+// MinConfidence is the confidence below which findings are ignored when
+// deciding whether a hazard is present
+const MinConfidence = 0.5
+
+// FindHazard returns the most confident finding that matches a known
+// violation, by regulation number or, when either side has none, by category
+func FindHazard(findings []Finding, regulation, category string) (Finding, bool) {
+	want, _ := regulations.Lookup(regulation)
+
+	var best Finding
+	found := false
+	for _, f := range findings {
+		if f.Confidence < MinConfidence {
+			continue
+		}
+		got, _ := regulations.Lookup(f.Regulation)
+		var matches bool
+		if want.Number != "" && got.Number != "" {
+			matches = got.Number == want.Number
+		} else {
+			matches = category != "" && strings.EqualFold(strings.TrimSpace(f.Category), strings.TrimSpace(category))
+		}
+		if matches && (!found || f.Confidence > best.Confidence) {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
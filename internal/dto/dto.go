@@ -8,6 +8,7 @@ type AppData struct {
     CurrentPage    string          // "dashboard", "projects", etc. for nav highlighting
     User           User            // Current authenticated user
     RecentProjects []RecentProject // Recent projects for sidebar
+    Notifications  NotificationMenu // Header notification dropdown
 }
 
 // User represents the authenticated user
@@ -61,6 +62,43 @@ type Project struct {
     InspectorID          string    `json:"inspector_id"`           // Inspector user ID
     PhotoCount           int       `json:"photo_count"`            // Number of photos uploaded
     ReportGenerated      bool      `json:"report_generated"`       // Whether final report exists
+    Latitude             float64   `json:"latitude"`               // Site coordinates in decimal degrees
+    Longitude            float64   `json:"longitude"`
+    HasCoordinates       bool      `json:"has_coordinates"`        // Whether Latitude and Longitude are set
+    CoordinatesSource    string    `json:"coordinates_source"`     // "manual", "geocoded", "photos", empty without coordinates
+}
+
+// Project found by a nearby search
+type NearbyProject struct {
+    Project
+    DistanceKm float64 `json:"distance_km"` // Distance from the search position
+}
+
+// Map page data
+type MapData struct {
+    AppData
+    Projects []MapProject // Active projects with coordinates
+    Hotspots []Hotspot    // Clusters of open violations
+    Unmapped int          // Active projects without coordinates
+}
+
+// Project marker on the map
+type MapProject struct {
+    ID             string  `json:"id"`
+    Name           string  `json:"name"`
+    Status         string  `json:"status"`
+    Location       string  `json:"location"`
+    Latitude       float64 `json:"latitude"`
+    Longitude      float64 `json:"longitude"`
+    OpenViolations int     `json:"open_violations"` // Open and validated violations
+}
+
+// Cluster of open violations, about 100 m across
+type Hotspot struct {
+    Latitude       float64 `json:"latitude"`
+    Longitude      float64 `json:"longitude"`
+    ViolationCount int     `json:"violation_count"`
+    SevereCount    int     `json:"severe_count"` // High and critical risk violations
 }
 
 // Safety violation details
@@ -73,12 +111,64 @@ type Violation struct {
     RiskLevel    string    `json:"risk_level"`    // "high", "medium", "low", "critical"
     Category     string    `json:"category"`      // "PPE", "Fall Protection", "Electrical", etc.
     Location     string    `json:"location"`      // Specific location within project
+    PhotoID      string    `json:"photo_id"`      // Photo the violation was found in, empty if none
     PhotoURL     string    `json:"photo_url"`     // URL to violation photo
     Status       string    `json:"status"`        // "open", "resolved", "dismissed"
     FoundAt      time.Time `json:"found_at"`      
     ResolvedAt   *time.Time `json:"resolved_at"`   // Null if not resolved
     Notes        string    `json:"notes"`         // Additional inspector notes
     AIConfidence float64   `json:"ai_confidence"` // AI detection confidence (0-1)
+    AssigneeID    string     `json:"assignee_id"`    // User responsible for the corrective action
+    AssigneeName  string     `json:"assignee_name"`  // Display name of the assignee
+    Subcontractor string     `json:"subcontractor"`  // Responsible subcontractor (optional)
+    DueDate       *time.Time `json:"due_date"`       // Corrective action due date, null if unset
+    Resolution    *Resolution `json:"resolution"`    // Latest corrective action evidence, null if none
+    UpdatedAt     time.Time  `json:"updated_at"`     // Version a sync client sends back with changes
+}
+
+// Corrective action evidence submitted to resolve a violation
+type Resolution struct {
+    ID                     string    `json:"id"`
+    PhotoURL               string    `json:"photo_url"`               // "After" photo
+    Note                   string    `json:"note"`                    // What was done to correct the hazard
+    Verification           string    `json:"verification"`            // "not_run", "hazard_absent", "hazard_present", "failed"
+    VerificationConfidence float64   `json:"verification_confidence"` // Detector confidence when a hazard was found (0-1)
+    VerificationDetail     string    `json:"verification_detail"`     // Detector explanation
+    Accepted               bool      `json:"accepted"`                // Whether the submission resolved the violation
+    UserName               string    `json:"user_name"`
+    CreatedAt              time.Time `json:"created_at"`
+}
+
+// Violation detail page data
+type ViolationDetailData struct {
+    AppData
+    Violation  Violation         // Violation being viewed
+    Regulation Regulation        // OSHA standard cited by the violation
+    Regions    []Region          // Where the violation appears in its photo
+    Comments   []Comment         // Discussion thread, oldest first
+    History    []TimelineEvent   // Status changes and assignments, oldest first
+    Assignees  []User            // Users the corrective action can be assigned to
+    Overdue    bool              // Whether the due date has passed without resolution
+    CanEdit    bool              // Whether current user can change status and assignment
+    CanVerify  bool              // Whether hazard detection is available to verify resolutions
+}
+
+// OSHA regulation referenced by a violation
+type Regulation struct {
+    Number  string `json:"number"`  // "1926.95"
+    Title   string `json:"title"`   // "Criteria for personal protective equipment"
+    Summary string `json:"summary"` // Plain-language summary of the requirement
+    URL     string `json:"url"`     // Full text on osha.gov
+}
+
+// Comment on a violation
+type Comment struct {
+    ID          string    `json:"id"`
+    ViolationID string    `json:"violation_id"`
+    UserID      string    `json:"user_id"`
+    UserName    string    `json:"user_name"`
+    Body        string    `json:"body"`
+    CreatedAt   time.Time `json:"created_at"`
 }
 
 // Projects page data
@@ -120,6 +210,7 @@ type ProjectDetailData struct {
     Timeline    []TimelineEvent // Project activity timeline
     CanEdit     bool        // Whether current user can edit
     CanDelete   bool        // Whether current user can delete
+    GeocodeFailed bool      // The last address entered couldn't be placed on the map
 }
 
 // Project photo
@@ -131,8 +222,107 @@ type Photo struct {
     Filename    string    `json:"filename"`     // Original filename
     UploadedAt  time.Time `json:"uploaded_at"`
     UploadedBy  string    `json:"uploaded_by"`  // User ID
+    UploadedByName string `json:"uploaded_by_name"` // "John Doe"
     Caption     string    `json:"caption"`      // Optional caption
+    AreaType    string    `json:"area_type"`    // "general", "excavation", "electrical", ...
+    Purpose     string    `json:"purpose"`      // "inspection", "verification"
+    Analyzed    bool      `json:"analyzed"`     // Whether hazard detection has run
+    TakenAt     time.Time `json:"taken_at"`     // Capture time from EXIF, zero if unknown
+    Latitude    float64   `json:"latitude"`     // GPS coordinates from EXIF
+    Longitude   float64   `json:"longitude"`
+    HasLocation bool      `json:"has_location"` // Whether Latitude and Longitude are set
+    Camera      string    `json:"camera"`       // "Apple iPhone 15 Pro"
+    DistanceFromSite float64 `json:"distance_from_site"` // Kilometres from the project site, 0 if unknown
+    FarFromSite bool      `json:"far_from_site"` // Whether the photo was taken away from the project site
+    DuplicateOf string    `json:"duplicate_of"` // Earlier photo this one nearly matches, empty if none
+    DuplicateOfURL string `json:"duplicate_of_url"` // URL of that earlier photo
     ViolationIDs []string `json:"violation_ids"` // Violations found in this photo
+    Regions     []Region  `json:"regions"`      // Where violations appear in this photo
+    UpdatedAt   time.Time `json:"updated_at"`   // Version a sync client sends back with changes
+}
+
+// Project photo gallery page data
+type ProjectPhotosData struct {
+    AppData
+    Project    Project         // Project the photos belong to
+    Photos     []Photo         // Current page of photos, newest first
+    Filter     PhotoFilter     // Current filter settings
+    Pagination PhotoPagination // Pagination info
+    AreaTypes  []string        // Area types available to filter by
+    CanEdit    bool            // Whether current user can upload and edit captions
+    CanAnalyze bool            // Whether hazard detection is available for new photos
+    DuplicatesUploaded int     // Photos in the last upload flagged as duplicates
+}
+
+// Photo filtering options
+type PhotoFilter struct {
+    AreaType string `json:"area_type"` // Filter by area type
+    DateFrom string `json:"date_from"` // Uploaded on or after, "2006-01-02"
+    DateTo   string `json:"date_to"`   // Uploaded on or before, "2006-01-02"
+}
+
+// Pagination for photos
+type PhotoPagination struct {
+    CurrentPage  int  `json:"current_page"`
+    TotalPages   int  `json:"total_pages"`
+    TotalItems   int  `json:"total_items"`
+    ItemsPerPage int  `json:"items_per_page"`
+    HasPrev      bool `json:"has_prev"`
+    HasNext      bool `json:"has_next"`
+}
+
+// Add photos page data
+type AddPhotosData struct {
+    AppData
+    Project      Project  // Project the photos are added to
+    AreaTypes    []string // Area types to choose from
+    MaxFiles     int      // Most photos accepted in one upload
+    MaxFileSize  int      // Largest accepted photo, in MB
+    WillAnalyze  bool     // Whether uploads are queued for hazard detection
+    ChunkSize    int      // Bytes sent per request by resumable uploads
+}
+
+// Photo caption edit request
+type CaptionRequest struct {
+    Caption string `json:"caption"`
+}
+
+// Region of a photo where a violation appears. Coordinates are normalized to
+// the image size (0-1, origin top left); X, Y, Width and Height bound every
+// shape and polygons also list their vertices.
+type Region struct {
+    ID          string       `json:"id"`
+    ViolationID string       `json:"violation_id"`
+    PhotoID     string       `json:"photo_id"`
+    Shape       string       `json:"shape"`              // "box", "polygon"
+    X           float64      `json:"x"`
+    Y           float64      `json:"y"`
+    Width       float64      `json:"width"`
+    Height      float64      `json:"height"`
+    Points      [][2]float64 `json:"points,omitempty"`   // Polygon vertices as [x, y]
+    Source      string       `json:"source"`             // "detector", "inspector"
+    Confidence  float64      `json:"confidence"`         // Detector confidence (0-1), 0 for drawn regions
+    RiskLevel   string       `json:"risk_level"`         // Risk level of the violation, for overlay color
+    Label       string       `json:"label"`              // Violation description
+    CreatedBy   string       `json:"created_by"`         // Inspector name for drawn regions
+}
+
+// Request to draw a region on a photo
+type RegionRequest struct {
+    ViolationID string       `json:"violation_id"`
+    Shape       string       `json:"shape"`            // "box" (default) or "polygon"
+    X           float64      `json:"x"`
+    Y           float64      `json:"y"`
+    Width       float64      `json:"width"`
+    Height      float64      `json:"height"`
+    Points      [][2]float64 `json:"points,omitempty"` // Required for polygons
+}
+
+// Outcome of running hazard detection on a photo
+type AnalysisResult struct {
+    PhotoID    string      `json:"photo_id"`
+    Violations []Violation `json:"violations"` // New open violations
+    Skipped    int         `json:"skipped"`    // Findings matching violations already reviewed
 }
 
 // Timeline event for project activity
@@ -194,6 +384,36 @@ type ReportPagination struct {
     HasNext      bool `json:"has_next"`
 }
 
+// Safety report (print/PDF) data
+type SafetyReportData struct {
+    Project         Project       `json:"project"`         // Project the report covers
+    Violations      []Violation   `json:"violations"`      // Violations included in the report, with resolution evidence
+    Summary         ReportSummary `json:"summary"`         // Counts and overall assessment
+    Recommendations []string      `json:"recommendations"` // Suggested improvements (optional)
+    CompanyInfo     CompanyInfo   `json:"company_info"`    // Branding for header and footer
+    GeneratedAt     time.Time     `json:"generated_at"`
+    GeneratedBy     User          `json:"generated_by"`
+    BaseURL         string        `json:"-"`               // App address photo links resolve against, for reports saved outside the app
+}
+
+// Safety report summary
+type ReportSummary struct {
+    CriticalCount     int     `json:"critical_count"`
+    HighCount         int     `json:"high_count"`
+    MediumCount       int     `json:"medium_count"`
+    LowCount          int     `json:"low_count"`
+    ComplianceRate    float64 `json:"compliance_rate"`    // Percentage
+    OverallAssessment string  `json:"overall_assessment"` // One-sentence verdict
+}
+
+// Company details printed on reports
+type CompanyInfo struct {
+    Name    string `json:"name"`
+    Phone   string `json:"phone"`
+    Email   string `json:"email"`
+    License string `json:"license"` // Contractor license number (optional)
+}
+
 // Team management data
 type TeamData struct {
     AppData
@@ -220,3 +440,270 @@ type Role struct {
     Description string   `json:"description"` // Role description
     Permissions []string `json:"permissions"` // List of permissions
 }
+
+// Bulk violation review request
+type BulkViolationRequest struct {
+    Action       string   `json:"action"`        // "validate", "dismiss", "reopen"
+    ViolationIDs []string `json:"violation_ids"` // Violations to transition
+}
+
+// Bulk violation review response
+type BulkViolationResponse struct {
+    Action  string                `json:"action"`
+    Updated int                   `json:"updated"` // Number of violations that changed status
+    Results []BulkViolationResult `json:"results"` // One entry per requested ID, in request order
+}
+
+// Outcome of a bulk action for a single violation
+type BulkViolationResult struct {
+    ID      string `json:"id"`
+    OK      bool   `json:"ok"`
+    Status  string `json:"status,omitempty"`  // Status after the request
+    Error   string `json:"error,omitempty"`   // "not_found", "already_resolved", "unchanged", "not_permitted"
+    Message string `json:"message,omitempty"` // Human-readable explanation
+}
+
+// Changes pulled by an offline client
+type SyncPullResponse struct {
+    Cursor     string        `json:"cursor"`     // Opaque position to pull from next time
+    HasMore    bool          `json:"has_more"`   // Whether more changes are waiting; pull again with Cursor straight away
+    Reset      bool          `json:"reset"`      // Whether to drop every record from earlier pulls first; set when the cursor was too old and the pull starts over
+    Projects   []Project     `json:"projects"`   // Projects created or changed since the last pull
+    Photos     []Photo       `json:"photos"`
+    Violations []Violation   `json:"violations"`
+    Deleted    []SyncDeleted `json:"deleted"`    // Records the client should drop
+}
+
+// Record deleted on the server
+type SyncDeleted struct {
+    Entity string `json:"entity"` // "project", "photo", "violation"
+    ID     string `json:"id"`
+}
+
+// Changes queued by an offline client. Photos themselves are uploaded
+// separately through the resumable upload endpoint.
+type SyncPushRequest struct {
+    Projects       []SyncProjectChange   `json:"projects"`
+    Photos         []SyncPhotoChange     `json:"photos"`
+    ViolationNotes []SyncViolationNotes  `json:"violation_notes"`
+}
+
+// Project created or edited offline
+type SyncProjectChange struct {
+    Op            string     `json:"op"`              // "create", "update"
+    ID            string     `json:"id"`              // Client-generated UUID for creates
+    BaseUpdatedAt *time.Time `json:"base_updated_at"` // last_updated of the version edited; required for updates
+    Name          string     `json:"name"`
+    Description   string     `json:"description"`
+    Location      string     `json:"location"`
+    Status        string     `json:"status"`          // Defaults to "in-progress" on create
+}
+
+// Photo details edited offline
+type SyncPhotoChange struct {
+    ID            string    `json:"id"`
+    BaseUpdatedAt time.Time `json:"base_updated_at"` // updated_at of the version edited
+    Caption       string    `json:"caption"`
+    AreaType      string    `json:"area_type"`
+}
+
+// Violation notes edited offline
+type SyncViolationNotes struct {
+    ID            string    `json:"id"`
+    BaseUpdatedAt time.Time `json:"base_updated_at"` // updated_at of the version edited
+    Notes         string    `json:"notes"`
+}
+
+// Outcome of a push
+type SyncPushResponse struct {
+    Results []SyncResult `json:"results"` // One entry per change, projects then photos then violation notes, each in request order
+}
+
+// Outcome of a single pushed change
+type SyncResult struct {
+    Entity    string     `json:"entity"`              // "project", "photo", "violation"
+    ID        string     `json:"id"`
+    Status    string     `json:"status"`              // "applied", "conflict", "rejected"
+    Error     string     `json:"error,omitempty"`     // "not_found", "invalid", "not_permitted" when rejected
+    Message   string     `json:"message,omitempty"`   // Human-readable explanation
+    Project   *Project   `json:"project,omitempty"`   // Server's version: the result of an applied change, or the one that conflicted
+    Photo     *Photo     `json:"photo,omitempty"`
+    Violation *Violation `json:"violation,omitempty"`
+}
+
+// JSON API error response
+type ErrorResponse struct {
+    Error APIError `json:"error"`
+}
+
+// JSON API error
+type APIError struct {
+    Code      string `json:"code"`                 // "invalid_request", "unauthorized", "forbidden", "insufficient_scope", "not_found", "conflict", "internal"
+    Message   string `json:"message"`              // Human-readable explanation
+    Field     string `json:"field,omitempty"`      // Query parameter or body field at fault, if any
+    RequestID string `json:"request_id,omitempty"` // Quote when reporting a problem
+}
+
+// JSON API response holding a single resource
+type DataResponse[T any] struct {
+    Data T `json:"data"`
+}
+
+// JSON API response holding a page of resources
+type ListResponse[T any] struct {
+    Data       []T    `json:"data"`
+    NextCursor string `json:"next_cursor,omitempty"` // Pass as ?cursor= with the same filters for the next page
+    HasMore    bool   `json:"has_more"`              // Whether there is a next page
+}
+
+// Project create request
+type ProjectRequest struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+    Location    string `json:"location"`
+    Status      string `json:"status"` // Defaults to "in-progress"
+}
+
+// Single violation review request
+type ViolationStatusRequest struct {
+    Action string `json:"action"` // "validate", "dismiss", "reopen"
+}
+
+// API tokens settings page data
+type TokensData struct {
+    AppData
+    Tokens   []APIToken
+    Scopes   []string  // Scopes a new token can be granted
+    CanAdmin bool      // Whether the user may issue and revoke service tokens
+    NewToken *APIToken // Token just created, shown once with its secret
+}
+
+// API token as listed in settings
+type APIToken struct {
+    ID            string
+    Kind          string // "personal", "service"
+    Name          string
+    Prefix        string // Start of the token, to tell tokens apart
+    Secret        string // Only set right after the token is created
+    Scopes        []string
+    CreatedByName string
+    CreatedAt     time.Time
+    LastUsedAt    *time.Time
+    ExpiresAt     *time.Time
+    Revoked       bool
+    Expired       bool
+    CanRevoke     bool
+}
+
+// Webhooks settings page data
+type WebhooksData struct {
+    AppData
+    Subscriptions []WebhookSubscription
+    Events        []string // Event types a subscription can receive
+    CanAdmin      bool     // Whether the user may manage subscriptions
+}
+
+// Webhook subscription page data
+type WebhookData struct {
+    AppData
+    Subscription WebhookSubscription
+    Deliveries   []WebhookDelivery // Most recent first
+    Events       []string
+    CanAdmin     bool
+}
+
+// Webhook subscription as shown in settings
+type WebhookSubscription struct {
+    ID            string
+    Name          string
+    URL           string
+    Secret        string // Only set for admins
+    EventTypes    []string
+    Active        bool
+    CreatedByName string
+    CreatedAt     time.Time
+    LastStatus    string     // Outcome of the latest attempt: "pending" (retrying), "succeeded", "failed", empty if none yet
+    LastAttemptAt *time.Time
+}
+
+// Webhook delivery in the delivery log
+type WebhookDelivery struct {
+    ID             string
+    EventID        string
+    EventType      string
+    Status         string // "pending", "succeeded", "failed"
+    Attempts       int
+    ResponseStatus int    // 0 if no response was received
+    ResponseBody   string
+    Error          string
+    DurationMs     int
+    Payload        string // Event data, indented
+    Redelivery     bool
+    CreatedAt      time.Time
+    LastAttemptAt  *time.Time
+    NextAttemptAt  *time.Time // Set while a retry is pending
+}
+
+// Notification settings page data
+type NotificationSettingsData struct {
+    AppData
+    Preferences NotificationPreferences
+    Modes       []string // Delivery modes: "immediate", "digest", "off"
+    Frequencies []string // Safety digest frequencies: "off", "daily", "weekly"
+    Timezone    string   // Timezone quiet hours and the digest time are in
+    HasAccount  bool     // False for users without a users row, who can't save settings
+    Saved       bool     // Settings were just saved
+    Alerts      []Notification // Most recent first
+}
+
+// Notification preferences as edited in settings
+type NotificationPreferences struct {
+    CriticalViolations string
+    OverdueActions     string
+    DigestTime         string // "15:04"
+    QuietHours         bool
+    QuietHoursStart    string // "15:04"
+    QuietHoursEnd      string
+    SafetyDigest       string // "off", "daily", "weekly"
+}
+
+// Notification shown to a user
+type Notification struct {
+    ID        string
+    Kind      string // "critical_violation", "overdue_action", "mention", "assignment", "status_change"
+    Title     string
+    Body      string
+    Link      string // App path the notification points to
+    ActorName string // Who raised it, empty for alerts
+    Read      bool
+    CreatedAt time.Time
+}
+
+// Notification dropdown in the app header
+type NotificationMenu struct {
+    Unread int64          // Unread notifications in total
+    Recent []Notification // Latest few, most recent first
+}
+
+// Notification center page data
+type NotificationsData struct {
+    AppData
+    List       []Notification // Most recent first
+    UnreadOnly bool           // Only unread notifications are listed
+    NextCursor string         // Cursor for older notifications, empty on the last page
+    HasAccount bool           // False for users without a users row, who have no notifications
+}
+
+// Readiness probe response
+type Readiness struct {
+    Status string            `json:"status"` // "ok", or "unavailable" when any check failed
+    Checks map[string]string `json:"checks"` // "ok" or what went wrong, by check name
+}
+
+// Error page data
+type ErrorPageData struct {
+    Status    int    // HTTP status code
+    Title     string // Status text, such as "Not Found"
+    Message   string // What went wrong, safe to show
+    RequestID string // Quote when reporting a problem
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Listener settings
+const (
+	bufferSize   = 64               // Events held for a subscriber before it is dropped
+	minReconnect = time.Second      // Delay before the first reconnect attempt
+	maxReconnect = 30 * time.Second // Longest delay between reconnect attempts
+)
+
+// Broker listens for project events on a dedicated database connection and
+// fans them out to subscribers in this process. Events are sent through
+// Postgres, so a change made on one app instance reaches subscribers on all
+// of them.
+type Broker struct {
+	db *pgxpool.Pool
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// New returns a Broker. Subscribers receive nothing until Run is called.
+func New(db *pgxpool.Pool) *Broker {
+	return &Broker{
+		db:   db,
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events of some or all projects
+type Subscription struct {
+	b        *Broker
+	projects map[string]bool
+	events   chan Event
+}
+
+// Subscribe returns a subscription to events of the given projects, or of
+// every project if none are given. The caller must Close it.
+func (b *Broker) Subscribe(projectIDs ...string) *Subscription {
+	s := &Subscription{
+		b:      b,
+		events: make(chan Event, bufferSize),
+	}
+	if len(projectIDs) > 0 {
+		s.projects = make(map[string]bool, len(projectIDs))
+		for _, id := range projectIDs {
+			s.projects[id] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.events)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is closed, when the broker stops, and when the subscriber
+// falls so far behind that events would be lost; a client that reconnects
+// should reload what it shows.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery to the subscription
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.remove(s)
+}
+
+// remove closes a subscription's channel. b.mu must be held.
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// Run listens for events until ctx is cancelled, reconnecting with backoff
+// when the connection is lost. Events sent while disconnected are missed.
+// Subscriptions are closed when it returns.
+func (b *Broker) Run(ctx context.Context, logger *slog.Logger) {
+	defer func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for s := range b.subs {
+			b.remove(s)
+		}
+		b.closed = true
+	}()
+
+	delay := minReconnect
+	for {
+		connected, err := b.listen(ctx, logger)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minReconnect
+		}
+		logger.Error("project event listener disconnected", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnect)
+	}
+}
+
+// listen holds a connection open for LISTEN and delivers what arrives on it.
+// It reports whether it got as far as listening.
+func (b *Broker) listen(ctx context.Context, logger *slog.Logger) (bool, error) {
+	pooled, err := b.db.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire connection: %w", err)
+	}
+	// the connection stays subscribed to the channel, so it is taken out of
+	// the pool rather than returned to it
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return false, fmt.Errorf("listen: %w", err)
+	}
+	logger.Info("listening for project events")
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(n.Payload), &event); err != nil {
+			logger.Warn("ignored malformed project event", "error", err)
+			continue
+		}
+		b.broadcast(event)
+	}
+}
+
+// broadcast delivers an event to its subscribers without blocking. A
+// subscriber whose buffer is full is dropped rather than left to miss
+// events silently.
+func (b *Broker) broadcast(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if s.projects != nil && !s.projects[event.ProjectID] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			b.remove(s)
+		}
+	}
+}
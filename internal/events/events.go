@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// channel is the Postgres NOTIFY channel every project event is sent on.
+// The violation and timeline triggers send on it too.
+const channel = "project_events"
+
+// Event kinds
+const (
+	KindAnalysis  = "analysis"  // Photo analysis progress, sent by the Analyzer
+	KindViolation = "violation" // Violation created or its status changed, sent by a trigger
+	KindTimeline  = "timeline"  // Activity feed entry added, sent by a trigger
+)
+
+// Analysis statuses
+const (
+	AnalysisStarted   = "started"
+	AnalysisCompleted = "completed"
+	AnalysisFailed    = "failed"
+)
+
+// Event is a change to a project. Data depends on the kind.
+type Event struct {
+	Kind      string          `json:"kind"`
+	ProjectID string          `json:"project_id"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Analysis is the data of analysis events
+type Analysis struct {
+	PhotoID    string `json:"photo_id"`
+	Status     string `json:"status"`
+	Violations int    `json:"violations"` // New open violations, once completed
+	Skipped    int    `json:"skipped"`    // Findings matching reviewed violations, once completed
+}
+
+// Publish notifies every app instance of an event. Inside a transaction the
+// notification is only sent if it commits.
+func Publish(ctx context.Context, q *database.Queries, kind string, projectID pgtype.UUID, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", kind, err)
+	}
+	payload, err := json.Marshal(Event{
+		Kind:      kind,
+		ProjectID: projectID.String(),
+		Data:      encoded,
+	})
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", kind, err)
+	}
+	if err := q.NotifyProjectEvent(ctx, string(payload)); err != nil {
+		return fmt.Errorf("send %s event: %w", kind, err)
+	}
+	return nil
+}
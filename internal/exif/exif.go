@@ -0,0 +1,270 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an image carries no EXIF block
+var ErrNotFound = errors.New("exif: no metadata found")
+
+// errMalformed is returned when the EXIF block can't be read
+var errMalformed = errors.New("exif: malformed metadata")
+
+// Metadata is the subset of EXIF recorded for inspection photos
+type Metadata struct {
+	TakenAt     time.Time // When the photo was taken, zero if unknown
+	Latitude    float64   // Decimal degrees, north positive
+	Longitude   float64   // Decimal degrees, east positive
+	HasLocation bool      // Whether Latitude and Longitude are set
+	Orientation int       // 1-8 as defined by EXIF, 1 when unknown
+	Make        string    // "Apple"
+	Model       string    // "iPhone 15 Pro"
+}
+
+// EXIF tags read by Parse
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetTimeOrig   = 0x9011
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+// TIFF field types
+const (
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// Parse extracts the capture details from the EXIF block of a JPEG, PNG or
+// WebP image without decoding the image itself
+func Parse(data []byte) (Metadata, error) {
+	meta := Metadata{Orientation: 1}
+	tiff := find(data)
+	if tiff == nil {
+		return meta, ErrNotFound
+	}
+
+	r, err := newReader(tiff)
+	if err != nil {
+		return meta, err
+	}
+	ifd0, err := r.ifd(r.u32(4))
+	if err != nil {
+		return meta, err
+	}
+
+	meta.Make = r.str(ifd0[tagMake])
+	meta.Model = r.str(ifd0[tagModel])
+	if o, ok := r.short(ifd0[tagOrientation]); ok && o >= 1 && o <= 8 {
+		meta.Orientation = int(o)
+	}
+
+	taken, offset := r.str(ifd0[tagDateTime]), ""
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if sub, err := r.ifd(e.value); err == nil {
+			if s := r.str(sub[tagDateTimeOriginal]); s != "" {
+				taken = s
+			}
+			offset = r.str(sub[tagOffsetTimeOrig])
+		}
+	}
+	meta.TakenAt = parseTime(taken, offset)
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := r.ifd(e.value); err == nil {
+			lat, latOK := r.degrees(gps[tagGPSLatitude])
+			lon, lonOK := r.degrees(gps[tagGPSLongitude])
+			if latOK && lonOK {
+				if strings.EqualFold(r.str(gps[tagGPSLatitudeRef]), "S") {
+					lat = -lat
+				}
+				if strings.EqualFold(r.str(gps[tagGPSLongitudeRef]), "W") {
+					lon = -lon
+				}
+				// 0,0 is what some cameras write when they have no fix
+				if math.Abs(lat) <= 90 && math.Abs(lon) <= 180 && (lat != 0 || lon != 0) {
+					meta.Latitude, meta.Longitude, meta.HasLocation = lat, lon, true
+				}
+			}
+		}
+	}
+	return meta, nil
+}
+
+// parseTime reads an EXIF timestamp. Cameras that don't record the UTC
+// offset write local time, which is taken to be the server's zone.
+func parseTime(s, offset string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if offset = strings.TrimSpace(offset); offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", s+offset); err == nil {
+			return t
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// find returns the TIFF structure holding the EXIF data, or nil
+func find(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		for _, seg := range jpegSegments(data) {
+			if seg.marker == 0xe1 && bytes.HasPrefix(seg.payload, exifHeader) {
+				return seg.payload[len(exifHeader):]
+			}
+		}
+	case isPNG(data):
+		for _, c := range pngChunks(data) {
+			if c.kind == "eXIf" {
+				return c.payload
+			}
+		}
+	case isWebP(data):
+		for _, c := range webpChunks(data) {
+			if c.kind == "EXIF" {
+				// some encoders keep the JPEG style header
+				return bytes.TrimPrefix(c.payload, exifHeader)
+			}
+		}
+	}
+	return nil
+}
+
+// entry is one field of an image file directory
+type entry struct {
+	typ   uint16
+	count uint32
+	value uint32 // The value itself when it fits in four bytes, else its offset
+	raw   []byte // The four value bytes as stored
+}
+
+type reader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newReader(tiff []byte) (*reader, error) {
+	if len(tiff) < 8 {
+		return nil, errMalformed
+	}
+	r := &reader{data: tiff}
+	switch string(tiff[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil, errMalformed
+	}
+	if r.order.Uint16(tiff[2:]) != 42 {
+		return nil, errMalformed
+	}
+	return r, nil
+}
+
+func (r *reader) u32(off uint32) uint32 {
+	if uint64(off)+4 > uint64(len(r.data)) {
+		return 0
+	}
+	return r.order.Uint32(r.data[off:])
+}
+
+// ifd reads the directory at off, keyed by tag
+func (r *reader) ifd(off uint32) (map[uint16]entry, error) {
+	if off < 8 || uint64(off)+2 > uint64(len(r.data)) {
+		return nil, errMalformed
+	}
+	n := int(r.order.Uint16(r.data[off:]))
+	start := int(off) + 2
+	if start+n*12 > len(r.data) {
+		return nil, errMalformed
+	}
+	entries := make(map[uint16]entry, n)
+	for i := range n {
+		b := r.data[start+i*12:]
+		entries[r.order.Uint16(b)] = entry{
+			typ:   r.order.Uint16(b[2:]),
+			count: r.order.Uint32(b[4:]),
+			value: r.order.Uint32(b[8:]),
+			raw:   b[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// bytes returns the value of e, which holds size byte units
+func (r *reader) bytes(e entry, size int) ([]byte, bool) {
+	n := uint64(e.count) * uint64(size)
+	if n <= 4 {
+		return e.raw[:n], true
+	}
+	if uint64(e.value)+n > uint64(len(r.data)) {
+		return nil, false
+	}
+	return r.data[e.value : uint64(e.value)+n], true
+}
+
+func (r *reader) str(e entry) string {
+	if e.typ != typeASCII {
+		return ""
+	}
+	b, ok := r.bytes(e, 1)
+	if !ok {
+		return ""
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(b), ""))
+}
+
+func (r *reader) short(e entry) (uint16, bool) {
+	switch {
+	case e.typ == typeShort && e.count >= 1:
+		return r.order.Uint16(e.raw), true
+	case e.typ == typeLong && e.count >= 1:
+		return uint16(e.value), true
+	}
+	return 0, false
+}
+
+// degrees reads a GPS coordinate stored as degrees, minutes and seconds
+func (r *reader) degrees(e entry) (float64, bool) {
+	if e.typ != typeRational || e.count != 3 {
+		return 0, false
+	}
+	b, ok := r.bytes(e, 8)
+	if !ok {
+		return 0, false
+	}
+	var dms [3]float64
+	for i := range dms {
+		num, den := r.order.Uint32(b[i*8:]), r.order.Uint32(b[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		dms[i] = float64(num) / float64(den)
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600, true
+}
@@ -0,0 +1,388 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// field is a TIFF directory entry to write. Values of up to four bytes are
+// stored in the entry itself, longer ones after the directory.
+type field struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// byteOrder is binary.LittleEndian or binary.BigEndian
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// tiffWriter assembles a TIFF structure like the ones cameras embed
+type tiffWriter struct {
+	order byteOrder
+	buf   []byte
+}
+
+func newTIFF(order byteOrder) *tiffWriter {
+	w := &tiffWriter{order: order}
+	if order == binary.LittleEndian {
+		w.buf = append(w.buf, "II"...)
+	} else {
+		w.buf = append(w.buf, "MM"...)
+	}
+	w.buf = order.AppendUint16(w.buf, 42)
+	w.buf = order.AppendUint32(w.buf, 0) // set by root
+	return w
+}
+
+// ifd appends a directory and returns its offset
+func (w *tiffWriter) ifd(fields ...field) uint32 {
+	off := uint32(len(w.buf))
+	dataOff := off + 2 + uint32(len(fields))*12 + 4
+	var data []byte
+	w.buf = w.order.AppendUint16(w.buf, uint16(len(fields)))
+	for _, f := range fields {
+		w.buf = w.order.AppendUint16(w.buf, f.tag)
+		w.buf = w.order.AppendUint16(w.buf, f.typ)
+		w.buf = w.order.AppendUint32(w.buf, f.count)
+		if len(f.value) <= 4 {
+			var inline [4]byte
+			copy(inline[:], f.value)
+			w.buf = append(w.buf, inline[:]...)
+		} else {
+			w.buf = w.order.AppendUint32(w.buf, dataOff+uint32(len(data)))
+			data = append(data, f.value...)
+		}
+	}
+	w.buf = w.order.AppendUint32(w.buf, 0)
+	w.buf = append(w.buf, data...)
+	return off
+}
+
+// root appends IFD0 and points the header at it
+func (w *tiffWriter) root(fields ...field) []byte {
+	off := w.ifd(fields...)
+	w.order.PutUint32(w.buf[4:], off)
+	return w.buf
+}
+
+func (w *tiffWriter) ascii(tag uint16, s string) field {
+	return field{tag: tag, typ: typeASCII, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func (w *tiffWriter) short(tag, v uint16) field {
+	return field{tag: tag, typ: typeShort, count: 1, value: w.order.AppendUint16(nil, v)}
+}
+
+func (w *tiffWriter) long(tag uint16, v uint32) field {
+	return field{tag: tag, typ: typeLong, count: 1, value: w.order.AppendUint32(nil, v)}
+}
+
+func (w *tiffWriter) rationals(tag uint16, vals ...[2]uint32) field {
+	var b []byte
+	for _, v := range vals {
+		b = w.order.AppendUint32(b, v[0])
+		b = w.order.AppendUint32(b, v[1])
+	}
+	return field{tag: tag, typ: typeRational, count: uint32(len(vals)), value: b}
+}
+
+// location is a GPS position in the form cameras record it
+type location struct {
+	latRef, lonRef string
+	lat, lon       [3][2]uint32
+}
+
+var seattle = location{
+	latRef: "N", lat: [3][2]uint32{{47, 1}, {36, 1}, {3600, 100}},
+	lonRef: "W", lon: [3][2]uint32{{122, 1}, {19, 1}, {48, 1}},
+}
+
+// cameraTIFF returns the EXIF block of a photo taken by a phone
+func cameraTIFF(order byteOrder, orientation uint16, loc *location) []byte {
+	w := newTIFF(order)
+	exifIFD := w.ifd(
+		w.ascii(tagDateTimeOriginal, "2024:05:17 09:30:00"),
+		w.ascii(tagOffsetTimeOrig, "+02:00"),
+	)
+	fields := []field{
+		w.ascii(tagMake, "Apple"),
+		w.ascii(tagModel, "iPhone 15 Pro"),
+		w.short(tagOrientation, orientation),
+		w.ascii(tagDateTime, "2024:05:18 10:00:00"),
+		w.long(tagExifIFD, exifIFD),
+	}
+	if loc != nil {
+		gpsIFD := w.ifd(
+			w.ascii(tagGPSLatitudeRef, loc.latRef),
+			w.rationals(tagGPSLatitude, loc.lat[:]...),
+			w.ascii(tagGPSLongitudeRef, loc.lonRef),
+			w.rationals(tagGPSLongitude, loc.lon[:]...),
+		)
+		fields = append(fields, w.long(tagGPSIFD, gpsIFD))
+	}
+	return w.root(fields...)
+}
+
+// plainJPEG returns a small JPEG as encoded without any metadata
+func plainJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withSegments inserts marker segments after the JPEG start of image
+func withSegments(data []byte, segs ...[]byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, s := range segs {
+		out = append(out, s...)
+	}
+	return append(out, data[2:]...)
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xff, marker}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func app1(tiff []byte) []byte {
+	return jpegSegment(0xe1, append(append([]byte{}, exifHeader...), tiff...))
+}
+
+// plainPNG returns a small PNG as encoded without any metadata
+func plainPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withChunks inserts chunks after the PNG header chunk
+func withChunks(data []byte, chunks ...[]byte) []byte {
+	end := len(pngMagic) + 25 // IHDR is always 13 bytes of data
+	out := append([]byte{}, data[:end]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, data[end:]...)
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	c := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	c = append(c, kind...)
+	c = append(c, payload...)
+	return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+}
+
+func TestParse(t *testing.T) {
+	taken := time.Date(2024, 5, 17, 9, 30, 0, 0, time.FixedZone("", 2*60*60))
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Metadata
+		wantErr error
+	}{
+		{
+			name: "little endian",
+			data: withSegments(plainJPEG(t), app1(cameraTIFF(binary.LittleEndian, 6, &seattle))),
+			want: Metadata{TakenAt: taken, Latitude: 47.61, Longitude: -122.33, HasLocation: true, Orientation: 6, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "big endian",
+			data: withSegments(plainJPEG(t), app1(cameraTIFF(binary.BigEndian, 3, &seattle))),
+			want: Metadata{TakenAt: taken, Latitude: 47.61, Longitude: -122.33, HasLocation: true, Orientation: 3, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "after other segments",
+			data: withSegments(plainJPEG(t), jpegSegment(0xe0, []byte("JFIF\x00\x01\x01")), app1(cameraTIFF(binary.BigEndian, 1, nil))),
+			want: Metadata{TakenAt: taken, Orientation: 1, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "southern and eastern hemisphere",
+			data: withSegments(plainJPEG(t), app1(cameraTIFF(binary.LittleEndian, 1, &location{
+				latRef: "S", lat: [3][2]uint32{{33, 1}, {52, 1}, {0, 1}},
+				lonRef: "E", lon: [3][2]uint32{{151, 1}, {12, 1}, {0, 1}},
+			}))),
+			want: Metadata{TakenAt: taken, Latitude: -33 - 52.0/60, Longitude: 151.2, HasLocation: true, Orientation: 1, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "no gps fix",
+			data: withSegments(plainJPEG(t), app1(cameraTIFF(binary.LittleEndian, 1, &location{
+				latRef: "N", lat: [3][2]uint32{{0, 1}, {0, 1}, {0, 1}},
+				lonRef: "E", lon: [3][2]uint32{{0, 1}, {0, 1}, {0, 1}},
+			}))),
+			want: Metadata{TakenAt: taken, Orientation: 1, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "orientation out of range",
+			data: withSegments(plainJPEG(t), app1(cameraTIFF(binary.BigEndian, 9, nil))),
+			want: Metadata{TakenAt: taken, Orientation: 1, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name: "png",
+			data: withChunks(plainPNG(t), pngChunk("eXIf", cameraTIFF(binary.BigEndian, 8, nil))),
+			want: Metadata{TakenAt: taken, Orientation: 8, Make: "Apple", Model: "iPhone 15 Pro"},
+		},
+		{
+			name:    "without exif",
+			data:    plainJPEG(t),
+			want:    Metadata{Orientation: 1},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "unknown byte order",
+			data:    withSegments(plainJPEG(t), app1([]byte("XX\x00\x2a\x00\x00\x00\x08"))),
+			want:    Metadata{Orientation: 1},
+			wantErr: errMalformed,
+		},
+		{
+			name:    "ifd past the end",
+			data:    withSegments(plainJPEG(t), app1([]byte("II\x2a\x00\xff\x00\x00\x00"))),
+			want:    Metadata{Orientation: 1},
+			wantErr: errMalformed,
+		},
+		{
+			name:    "not an image",
+			data:    []byte("hello"),
+			want:    Metadata{Orientation: 1},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if !got.TakenAt.Equal(tt.want.TakenAt) {
+				t.Errorf("TakenAt = %v, want %v", got.TakenAt, tt.want.TakenAt)
+			}
+			if got.HasLocation != tt.want.HasLocation ||
+				math.Abs(got.Latitude-tt.want.Latitude) > 1e-6 ||
+				math.Abs(got.Longitude-tt.want.Longitude) > 1e-6 {
+				t.Errorf("location = %v,%v (%v), want %v,%v (%v)",
+					got.Latitude, got.Longitude, got.HasLocation,
+					tt.want.Latitude, tt.want.Longitude, tt.want.HasLocation)
+			}
+			if got.Orientation != tt.want.Orientation {
+				t.Errorf("Orientation = %d, want %d", got.Orientation, tt.want.Orientation)
+			}
+			if got.Make != tt.want.Make || got.Model != tt.want.Model {
+				t.Errorf("camera = %q %q, want %q %q", got.Make, got.Model, tt.want.Make, tt.want.Model)
+			}
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	plain := plainJPEG(t)
+	jfif := jpegSegment(0xe0, []byte("JFIF\x00\x01\x01"))
+	iptc := jpegSegment(0xed, []byte("Photoshop 3.0\x00"))
+
+	pngData := plainPNG(t)
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{
+			name: "jpeg little endian",
+			data: withSegments(plain, jfif, app1(cameraTIFF(binary.LittleEndian, 6, &seattle)), iptc),
+			want: withSegments(plain, jfif),
+		},
+		{
+			name: "jpeg big endian",
+			data: withSegments(plain, app1(cameraTIFF(binary.BigEndian, 6, &seattle)), jfif),
+			want: withSegments(plain, jfif),
+		},
+		{
+			name: "jpeg without exif",
+			data: withSegments(plain, jfif),
+			want: withSegments(plain, jfif),
+		},
+		{
+			name: "png",
+			data: withChunks(pngData,
+				pngChunk("eXIf", cameraTIFF(binary.LittleEndian, 1, &seattle)),
+				pngChunk("tEXt", []byte("Comment\x00taken on site")),
+			),
+			want: pngData,
+		},
+		{
+			name: "webp",
+			data: webp(
+				webpChunk("VP8X", []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 3, 0, 0, 3, 0, 0}),
+				webpChunk("VP8L", []byte("pixels")),
+				webpChunk("EXIF", cameraTIFF(binary.LittleEndian, 1, &seattle)),
+				webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+			),
+			want: webp(
+				webpChunk("VP8X", []byte{0x10, 0, 0, 0, 3, 0, 0, 3, 0, 0}),
+				webpChunk("VP8L", []byte("pixels")),
+			),
+		},
+		{
+			name: "other formats",
+			data: []byte("GIF89a"),
+			want: []byte("GIF89a"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Strip(tt.data)
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("Strip() = % x\nwant % x", got, tt.want)
+			}
+			if _, err := Parse(got); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Parse(Strip()) error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestStripKeepsJPEGDecodable(t *testing.T) {
+	data := withSegments(plainJPEG(t), app1(cameraTIFF(binary.BigEndian, 6, &seattle)))
+	img, err := jpeg.Decode(bytes.NewReader(Strip(data)))
+	if err != nil {
+		t.Fatalf("decode stripped image: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 16, 8) {
+		t.Errorf("stripped image bounds = %v, want 16x8", got)
+	}
+}
+
+func webp(chunks ...[]byte) []byte {
+	var body []byte
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	out = append(out, "WEBP"...)
+	return append(out, body...)
+}
+
+func webpChunk(kind string, payload []byte) []byte {
+	c := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	c = append(c, payload...)
+	if len(payload)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
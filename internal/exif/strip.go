@@ -0,0 +1,177 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	pngMagic   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+func isJPEG(data []byte) bool {
+	return len(data) > 2 && data[0] == 0xff && data[1] == 0xd8
+}
+
+func isPNG(data []byte) bool {
+	return bytes.HasPrefix(data, pngMagic)
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// segment is a JPEG marker segment before the image data
+type segment struct {
+	marker  byte
+	start   int // Offset of the 0xff marker byte
+	end     int // Offset just past the segment
+	payload []byte
+}
+
+// jpegSegments lists the marker segments up to the start of scan. Parsing
+// stops at the first malformed segment.
+func jpegSegments(data []byte) []segment {
+	var segs []segment
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff {
+		marker := data[pos+1]
+		if marker == 0xff { // fill byte
+			pos++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 { // start of scan, end of image
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if n < 2 || pos+2+n > len(data) {
+			break
+		}
+		segs = append(segs, segment{
+			marker:  marker,
+			start:   pos,
+			end:     pos + 2 + n,
+			payload: data[pos+4 : pos+2+n],
+		})
+		pos += 2 + n
+	}
+	return segs
+}
+
+// chunk is a PNG or WebP chunk
+type chunk struct {
+	kind    string
+	start   int
+	end     int
+	payload []byte
+}
+
+func pngChunks(data []byte) []chunk {
+	var chunks []chunk
+	pos := len(pngMagic)
+	for pos+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + n
+		if n < 0 || end > len(data) || end < pos {
+			break
+		}
+		chunks = append(chunks, chunk{
+			kind:    string(data[pos+4 : pos+8]),
+			start:   pos,
+			end:     end,
+			payload: data[pos+8 : pos+8+n],
+		})
+		pos = end
+	}
+	return chunks
+}
+
+func webpChunks(data []byte) []chunk {
+	var chunks []chunk
+	pos := 12
+	for pos+8 <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + n + n%2 // chunks are padded to an even length
+		if n < 0 || end > len(data) || end < pos {
+			break
+		}
+		chunks = append(chunks, chunk{
+			kind:    string(data[pos : pos+4]),
+			start:   pos,
+			end:     end,
+			payload: data[pos+8 : pos+8+n],
+		})
+		pos = end
+	}
+	return chunks
+}
+
+// Strip returns a copy of a JPEG, PNG or WebP image without its EXIF, XMP
+// and IPTC metadata, so location and device details don't travel with photos
+// shared outside the app. The image data is copied as is, not re-encoded.
+// Other formats are returned unchanged.
+func Strip(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		return stripJPEG(data)
+	case isPNG(data):
+		return stripPNG(data)
+	case isWebP(data):
+		return stripWebP(data)
+	}
+	return data
+}
+
+func stripJPEG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	pos := 2
+	for _, seg := range jpegSegments(data) {
+		// APP1 holds EXIF and XMP, APP13 holds IPTC
+		if seg.marker == 0xe1 || seg.marker == 0xed {
+			out = append(out, data[pos:seg.start]...)
+			pos = seg.end
+		}
+	}
+	return append(out, data[pos:]...)
+}
+
+func stripPNG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:len(pngMagic)]...)
+	pos := len(pngMagic)
+	for _, c := range pngChunks(data) {
+		// eXIf holds EXIF; XMP and free text live in the text chunks
+		switch c.kind {
+		case "eXIf", "iTXt", "tEXt", "zTXt":
+			out = append(out, data[pos:c.start]...)
+			pos = c.end
+		}
+	}
+	return append(out, data[pos:]...)
+}
+
+func stripWebP(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	pos := 12
+	vp8x := -1
+	for _, c := range webpChunks(data) {
+		switch c.kind {
+		case "EXIF", "XMP ":
+			out = append(out, data[pos:c.start]...)
+			pos = c.end
+		case "VP8X":
+			if len(c.payload) >= 1 {
+				vp8x = len(out) + (c.start - pos) + 8
+			}
+		}
+	}
+	out = append(out, data[pos:]...)
+	if vp8x >= 0 {
+		out[vp8x] &^= 0x08 | 0x04 // clear the EXIF and XMP flags
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
@@ -0,0 +1,76 @@
+package geo
+
+import (
+	"math"
+	"slices"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// Point is a position in decimal degrees
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Valid reports whether p is a position on the globe
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// Distance returns the great-circle distance in kilometres between two
+// points given in decimal degrees, using the haversine formula
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1, φ2 := radians(lat1), radians(lat2)
+	dφ, dλ := radians(lat2-lat1), radians(lon2-lon1)
+	a := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox returns the south-west and north-east corners of a box
+// containing every point within radiusKm of center, for narrowing a search
+// before measuring distances. Near the poles or the antimeridian the box
+// widens to all longitudes.
+func BoundingBox(center Point, radiusKm float64) (sw, ne Point) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	sw.Latitude = math.Max(center.Latitude-dLat, -90)
+	ne.Latitude = math.Min(center.Latitude+dLat, 90)
+
+	sw.Longitude, ne.Longitude = -180, 180
+	if cos := math.Cos(radians(center.Latitude)); ne.Latitude < 90 && sw.Latitude > -90 && cos > 0 {
+		dLon := dLat / cos
+		if center.Longitude-dLon >= -180 && center.Longitude+dLon <= 180 {
+			sw.Longitude, ne.Longitude = center.Longitude-dLon, center.Longitude+dLon
+		}
+	}
+	return sw, ne
+}
+
+// Median returns the component-wise median of points, which ignores the odd
+// photo taken off site better than an average would. It returns false when
+// points is empty.
+func Median(points []Point) (Point, bool) {
+	if len(points) == 0 {
+		return Point{}, false
+	}
+	lats := make([]float64, len(points))
+	lons := make([]float64, len(points))
+	for i, p := range points {
+		lats[i], lons[i] = p.Latitude, p.Longitude
+	}
+	return Point{Latitude: median(lats), Longitude: median(lons)}, true
+}
+
+func median(v []float64) float64 {
+	slices.Sort(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,65 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrNoMatch is returned when an address can't be located
+var ErrNoMatch = errors.New("geo: address not found")
+
+// Geocoder turns a free-text address into coordinates
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (Point, error)
+}
+
+// Stub is an offline Geocoder. It resolves addresses from a fixed table and
+// accepts addresses written as coordinates ("45.5152, -122.6784"), which is
+// enough for development and for sites without a street address.
+type Stub struct {
+	known map[string]Point
+}
+
+// NewStub returns a Stub resolving the given addresses. Lookups ignore case
+// and spacing.
+func NewStub(known map[string]Point) *Stub {
+	s := &Stub{known: make(map[string]Point, len(known))}
+	for address, p := range known {
+		s.known[normalizeAddress(address)] = p
+	}
+	return s
+}
+
+// Geocode returns the position of address or ErrNoMatch
+func (s *Stub) Geocode(ctx context.Context, address string) (Point, error) {
+	if p, ok := ParseCoordinates(address); ok {
+		return p, nil
+	}
+	if p, ok := s.known[normalizeAddress(address)]; ok {
+		return p, nil
+	}
+	return Point{}, ErrNoMatch
+}
+
+// ParseCoordinates reads a "latitude, longitude" pair in decimal degrees
+func ParseCoordinates(s string) (Point, bool) {
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, false
+	}
+	var p Point
+	var err error
+	if p.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return Point{}, false
+	}
+	if p.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil {
+		return Point{}, false
+	}
+	return p, p.Valid()
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
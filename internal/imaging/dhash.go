@@ -0,0 +1,46 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// DuplicateDistance is the largest Hamming distance between two DHash values
+// still treated as the same shot. Re-uploads and recompressed copies land
+// inside it; a second photo of the same scene from a step to the side
+// usually doesn't.
+const DuplicateDistance = 6
+
+// DHash decodes a JPEG, PNG or WebP image and returns its 64-bit difference
+// hash: the image is shrunk to 9x8 grayscale pixels and each bit records
+// whether a pixel is brighter than its right neighbour. Similar images have
+// hashes a small Hamming distance apart.
+func DHash(data []byte) (uint64, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("decode image: %w", err)
+	}
+
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(small, small.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// Distance returns the number of bits that differ between two hashes
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ThumbnailSize is the longest edge of a gallery thumbnail, in pixels
+const ThumbnailSize = 480
+
+// Thumbnail decodes a JPEG, PNG or WebP image, scales it so its longest edge
+// is at most size pixels and writes the result to w as a JPEG. Images already
+// smaller than size are re-encoded without scaling.
+func Thumbnail(w io.Writer, data []byte, size int) error {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	b := src.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	if err := jpeg.Encode(w, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return fmt.Errorf("encode image: %w", err)
+	}
+	return nil
+}
+
+// Orient decodes an image, applies an EXIF orientation (2-8) so the pixels
+// are stored upright and returns the result as a JPEG. Orientation 1 means
+// the image is already upright and is not re-encoded.
+func Orient(data []byte, orientation int) ([]byte, error) {
+	if orientation < 2 || orientation > 8 {
+		return data, nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	b := src.Bounds()
+	in := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	outW, outH := w, h
+	if orientation >= 5 {
+		outW, outH = h, w // 5-8 swap the axes
+	}
+	out := image.NewRGBA(image.Rect(0, 0, outW, outH))
+	for y := range h {
+		for x := range w {
+			dx, dy := orientPoint(orientation, x, y, w, h)
+			copy(out.Pix[out.PixOffset(dx, dy):][:4], in.Pix[in.PixOffset(x, y):][:4])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: 92}); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// orientPoint maps a stored pixel to its upright position for an EXIF
+// orientation. w and h are the stored dimensions.
+func orientPoint(orientation, x, y, w, h int) (int, int) {
+	switch orientation {
+	case 2: // mirrored horizontally
+		return w - 1 - x, y
+	case 3: // rotated 180
+		return w - 1 - x, h - 1 - y
+	case 4: // mirrored vertically
+		return x, h - 1 - y
+	case 5: // mirrored along the top-left diagonal
+		return y, x
+	case 6: // rotated 90 clockwise to view
+		return h - 1 - y, x
+	case 7: // mirrored along the top-right diagonal
+		return h - 1 - y, w - 1 - x
+	case 8: // rotated 90 counter-clockwise to view
+		return y, w - 1 - x
+	}
+	return x, y
+}
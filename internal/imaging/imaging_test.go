@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"testing"
+)
+
+// blockSize is the edge of each colored block in the test images, large
+// enough that JPEG compression leaves the block centers intact
+const blockSize = 16
+
+var palette = map[byte]color.RGBA{
+	'A': {255, 0, 0, 255},
+	'B': {0, 255, 0, 255},
+	'C': {0, 0, 255, 255},
+	'D': {255, 255, 0, 255},
+	'E': {255, 0, 255, 255},
+	'F': {0, 255, 255, 255},
+}
+
+// blocks draws rows of colored blocks named by palette letters
+func blocks(rows ...string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0])*blockSize, len(rows)*blockSize))
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			img.SetRGBA(x, y, palette[rows[y/blockSize][x/blockSize]])
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrient(t *testing.T) {
+	// the photo as stored; each case is how it should look once upright
+	stored := encodePNG(t, blocks("ABC", "DEF"))
+	tests := []struct {
+		orientation int
+		want        []string
+	}{
+		{2, []string{"CBA", "FED"}},
+		{3, []string{"FED", "CBA"}},
+		{4, []string{"DEF", "ABC"}},
+		{5, []string{"AD", "BE", "CF"}},
+		{6, []string{"DA", "EB", "FC"}},
+		{7, []string{"FC", "EB", "DA"}},
+		{8, []string{"CF", "BE", "AD"}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.orientation), func(t *testing.T) {
+			out, err := Orient(stored, tt.orientation)
+			if err != nil {
+				t.Fatalf("Orient() error = %v", err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("decode result: %v", err)
+			}
+			wantBounds := image.Rect(0, 0, len(tt.want[0])*blockSize, len(tt.want)*blockSize)
+			if img.Bounds() != wantBounds {
+				t.Fatalf("bounds = %v, want %v", img.Bounds(), wantBounds)
+			}
+			for by, row := range tt.want {
+				for bx := range len(row) {
+					x, y := bx*blockSize+blockSize/2, by*blockSize+blockSize/2
+					if got, want := img.At(x, y), palette[row[bx]]; !near(got, want) {
+						t.Errorf("block %d,%d = %v, want %c %v", bx, by, got, row[bx], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOrientUpright(t *testing.T) {
+	stored := encodePNG(t, blocks("AB"))
+	for _, orientation := range []int{0, 1, 9} {
+		out, err := Orient(stored, orientation)
+		if err != nil {
+			t.Fatalf("Orient(%d) error = %v", orientation, err)
+		}
+		if !bytes.Equal(out, stored) {
+			t.Errorf("Orient(%d) re-encoded an upright image", orientation)
+		}
+	}
+}
+
+func TestDHash(t *testing.T) {
+	// a 9x8 grid of gray levels far enough apart to survive recompression
+	levels := []uint8{200, 40, 160, 80, 240, 0, 120, 220, 60}
+	scene := image.NewGray(image.Rect(0, 0, 9*blockSize, 8*blockSize))
+	mirrored := image.NewGray(scene.Bounds())
+	for y := range scene.Bounds().Dy() {
+		for x := range scene.Bounds().Dx() {
+			level := levels[(x/blockSize+y/blockSize)%len(levels)]
+			scene.SetGray(x, y, color.Gray{Y: level})
+			mirrored.SetGray(scene.Bounds().Dx()-1-x, y, color.Gray{Y: level})
+		}
+	}
+	original := encodePNG(t, scene)
+	var recompressed bytes.Buffer
+	if err := jpeg.Encode(&recompressed, scene, &jpeg.Options{Quality: 40}); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := func(data []byte) uint64 {
+		t.Helper()
+		h, err := DHash(data)
+		if err != nil {
+			t.Fatalf("DHash() error = %v", err)
+		}
+		return h
+	}
+	base := hash(original)
+
+	if d := Distance(base, hash(original)); d != 0 {
+		t.Errorf("same image distance = %d, want 0", d)
+	}
+	if d := Distance(base, hash(recompressed.Bytes())); d > DuplicateDistance {
+		t.Errorf("recompressed image distance = %d, want at most %d", d, DuplicateDistance)
+	}
+	if d := Distance(base, hash(encodePNG(t, mirrored))); d <= DuplicateDistance {
+		t.Errorf("different image distance = %d, want more than %d", d, DuplicateDistance)
+	}
+	if _, err := DHash([]byte("not an image")); err == nil {
+		t.Error("DHash() of invalid data succeeded")
+	}
+}
+
+// near reports whether two colors match within what JPEG compression
+// changes
+func near(a, b color.Color) bool {
+	const tolerance = 40 << 8
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	return diff(ar, br) <= tolerance && diff(ag, bg) <= tolerance && diff(ab, bb) <= tolerance
+}
+
+func diff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey string
+
+// LoggerKey is the context key of the request-scoped logger
+const LoggerKey contextKey = "logger"
+
+// NewContext returns a copy of ctx carrying l, for FromContext to find in
+// the layers and background jobs ctx is passed to
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, LoggerKey, l)
+}
+
+// FromContext returns the logger added by NewContext, falling back to the
+// default logger when there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(LoggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,170 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAddress is returned for a recipient that isn't an email address
+var ErrInvalidAddress = errors.New("mail: invalid address")
+
+// sendTimeout limits a single SMTP conversation when ctx has no deadline
+const sendTimeout = 30 * time.Second
+
+// headerLine removes line breaks from header values
+var headerLine = strings.NewReplacer("\r", " ", "\n", " ")
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTP sends email through an SMTP server, upgrading to TLS when the server
+// offers STARTTLS
+type SMTP struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     netmail.Address
+}
+
+// NewSMTP returns an SMTP mailer. Authentication is skipped when username is
+// empty.
+func NewSMTP(host, port, username, password, from string) (*SMTP, error) {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid from address %q: %w", from, err)
+	}
+	return &SMTP{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     *addr,
+	}, nil
+}
+
+// Send delivers msg
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidAddress, msg.To)
+	}
+	data, err := compose(m.from, *to, msg)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(m.host, m.port))
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// compose renders msg as a MIME message with a quoted-printable UTF-8 body.
+// Line breaks are stripped from the subject so it can't add headers.
+func compose(from, to netmail.Address, msg Message) ([]byte, error) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerLine.Replace(msg.Subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	// line breaks in the body are written as CRLF
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, fmt.Errorf("encode body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("encode body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Log writes email to the log instead of sending it, for development
+// without an SMTP server
+type Log struct {
+	logger *slog.Logger
+}
+
+// NewLog returns a Log mailer
+func NewLog(logger *slog.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+// Send logs msg
+func (m *Log) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("email not sent, no SMTP server configured", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
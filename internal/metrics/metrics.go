@@ -0,0 +1,186 @@
+// Package metrics records request metrics and writes them, with any other
+// values the app reports, in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// buckets are the upper bounds, in seconds, of the request duration
+// histogram, the same as the Prometheus client's defaults
+var buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Requests counts HTTP requests and their durations by route and status
+type Requests struct {
+	mu     sync.Mutex
+	series map[requestKey]*requestSeries
+}
+
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+type requestSeries struct {
+	counts []uint64 // Requests per bucket, not cumulative; the last is +Inf
+	sum    float64  // Total seconds
+}
+
+// NewRequests returns an empty Requests
+func NewRequests() *Requests {
+	return &Requests{series: make(map[requestKey]*requestSeries)}
+}
+
+// Observe records a request. route should be the pattern the request
+// matched rather than its path, so the number of series stays bounded.
+func (m *Requests) Observe(method, route string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+	i, _ := slices.BinarySearch(buckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := requestKey{method: method, route: route, status: status}
+	s, ok := m.series[key]
+	if !ok {
+		s = &requestSeries{counts: make([]uint64, len(buckets)+1)}
+		m.series[key] = s
+	}
+	s.counts[i]++
+	s.sum += seconds
+}
+
+// Write writes the request counter and duration histogram
+func (m *Requests) Write(w *Writer) {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.series))
+	series := make(map[requestKey]requestSeries, len(m.series))
+	for k, s := range m.series {
+		keys = append(keys, k)
+		series[k] = requestSeries{counts: slices.Clone(s.counts), sum: s.sum}
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(keys, func(a, b requestKey) int {
+		if c := strings.Compare(a.route, b.route); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.method, b.method); c != 0 {
+			return c
+		}
+		return a.status - b.status
+	})
+
+	w.header("http_requests_total", "counter", "HTTP requests handled, by route and status.")
+	for _, k := range keys {
+		var total uint64
+		for _, n := range series[k].counts {
+			total += n
+		}
+		w.sample("http_requests_total", k.labels(), float64(total))
+	}
+
+	w.header("http_request_duration_seconds", "histogram", "Time taken to handle HTTP requests, by route and status.")
+	for _, k := range keys {
+		s := series[k]
+		var cumulative uint64
+		for i, n := range s.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(buckets) {
+				le = formatFloat(buckets[i])
+			}
+			w.sample("http_request_duration_seconds_bucket", append(k.labels(), "le", le), float64(cumulative))
+		}
+		w.sample("http_request_duration_seconds_sum", k.labels(), s.sum)
+		w.sample("http_request_duration_seconds_count", k.labels(), float64(cumulative))
+	}
+}
+
+func (k requestKey) labels() []string {
+	return []string{"method", k.method, "route", k.route, "status", strconv.Itoa(k.status)}
+}
+
+// Writer writes metrics in the text exposition format. After a write
+// fails, later writes do nothing and Err returns the error.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first error writing metrics
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Gauge writes a metric whose value can go up and down
+func (w *Writer) Gauge(name, help string, value float64) {
+	w.header(name, "gauge", help)
+	w.sample(name, nil, value)
+}
+
+// GaugeVec writes a gauge with one sample for each value of label
+func (w *Writer) GaugeVec(name, help, label string, values map[string]float64) {
+	w.header(name, "gauge", help)
+	for _, v := range slices.Sorted(maps.Keys(values)) {
+		w.sample(name, []string{label, v}, values[v])
+	}
+}
+
+// Counter writes a metric whose value only goes up
+func (w *Writer) Counter(name, help string, value float64) {
+	w.header(name, "counter", help)
+	w.sample(name, nil, value)
+}
+
+func (w *Writer) header(name, kind, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, kind)
+}
+
+// sample writes one sample; labels are alternating names and values
+func (w *Writer) sample(name string, labels []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	w.printf("%s %s\n", b.String(), formatFloat(value))
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,392 @@
+// Package migrate applies the goose-annotated SQL migrations embedded in the
+// binary. It records versions in goose's own table, so databases migrated
+// with the goose command line carry on where they left off.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockID keys the advisory lock held while migrating, so instances started
+// together don't apply the same migration twice
+const lockID int64 = 0x69726f6e6d616e // "ironman"
+
+// ErrNoApplied is returned when rolling back a database with no migrations
+// applied
+var ErrNoApplied = errors.New("migrate: no migrations applied")
+
+// Migration is one migration file
+type Migration struct {
+	Version int64
+	Name    string // File name
+
+	up   []string
+	down []string
+	noTx bool // Run outside a transaction, for statements that can't run in one
+}
+
+// Status is a migration and when it was applied
+type Status struct {
+	Migration
+	AppliedAt time.Time // Zero while pending
+}
+
+// Applied reports whether the migration has been applied
+func (s Status) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration // In version order
+}
+
+// New returns a Migrator for the .sql files at the root of fsys, named
+// <version>_<description>.sql
+func New(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db}
+	for _, name := range names {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		mig, err := parse(name, string(src))
+		if err != nil {
+			return nil, err
+		}
+		m.migrations = append(m.migrations, mig)
+	}
+	slices.SortFunc(m.migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version == m.migrations[i-1].Version {
+			return nil, fmt.Errorf("migrate: %s and %s have the same version", m.migrations[i-1].Name, m.migrations[i].Name)
+		}
+	}
+	return m, nil
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied
+func (m *Migrator) Up(ctx context.Context, logger *slog.Logger) (int, error) {
+	count := 0
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			logger.Info("applied migration", "version", mig.Version, "name", mig.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the latest applied migration and returns it
+func (m *Migrator) Down(ctx context.Context, logger *slog.Logger) (Migration, error) {
+	var rolledBack Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		mig, err := m.latest(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+		logger.Info("rolled back migration", "version", mig.Version, "name", mig.Name)
+		rolledBack = mig
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the latest applied migration and applies it again, and
+// returns it
+func (m *Migrator) Redo(ctx context.Context, logger *slog.Logger) (Migration, error) {
+	var redone Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		mig, err := m.latest(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+		if err := apply(ctx, conn, mig, true); err != nil {
+			return err
+		}
+		logger.Info("reapplied migration", "version", mig.Version, "name", mig.Name)
+		redone = mig
+		return nil
+	})
+	return redone, err
+}
+
+// Status lists every migration in version order with when it was applied.
+// Like Pending, it doesn't wait for the migration lock.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		statuses = append(statuses, Status{Migration: mig, AppliedAt: applied[mig.Version]})
+	}
+	return statuses, nil
+}
+
+// Pending returns how many migrations haven't been applied. Unlike the
+// operations that change the schema it doesn't wait for the migration
+// lock, so it can be polled while another instance is migrating.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// applied reads when each applied migration was applied without taking the
+// migration lock. A migration still running elsewhere isn't recorded until
+// it commits, so it reads as pending. A database that has never been
+// migrated has nothing applied.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	c, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: acquire connection: %w", err)
+	}
+	defer c.Release()
+
+	var exists bool
+	if err := c.QueryRow(ctx, "SELECT to_regclass('goose_db_version') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("migrate: find version table: %w", err)
+	}
+	if !exists {
+		return map[int64]time.Time{}, nil
+	}
+	return appliedVersions(ctx, c.Conn())
+}
+
+// locked runs fn on a connection holding the migration lock, after making
+// sure the version table exists
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	c, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: acquire connection: %w", err)
+	}
+	defer c.Release()
+
+	if _, err := c.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	// the lock belongs to the session, so it is released even if ctx was
+	// cancelled part way through
+	defer c.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+
+	if err := ensureVersionTable(ctx, c.Conn()); err != nil {
+		return err
+	}
+	return fn(c.Conn())
+}
+
+// latest returns the applied migration with the highest version
+func (m *Migrator) latest(ctx context.Context, conn *pgx.Conn) (Migration, error) {
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return Migration{}, err
+	}
+	for _, mig := range slices.Backward(m.migrations) {
+		if _, ok := applied[mig.Version]; ok {
+			return mig, nil
+		}
+	}
+	return Migration{}, ErrNoApplied
+}
+
+// ensureVersionTable creates goose's version table if it doesn't exist,
+// seeded the way goose seeds it
+func ensureVersionTable(ctx context.Context, conn *pgx.Conn) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('goose_db_version') IS NOT NULL").Scan(&exists); err != nil {
+		return fmt.Errorf("migrate: find version table: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE goose_db_version (
+			id SERIAL PRIMARY KEY,
+			version_id BIGINT NOT NULL,
+			is_applied BOOLEAN NOT NULL,
+			tstamp TIMESTAMP DEFAULT now()
+		);
+		INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, true);`)
+	if err != nil {
+		return fmt.Errorf("migrate: create version table: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions returns when each applied migration was applied
+func appliedVersions(ctx context.Context, conn *pgx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT version_id, MAX(tstamp) FROM goose_db_version
+		WHERE is_applied AND version_id > 0
+		GROUP BY version_id`)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: list applied versions: %w", err)
+	}
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("migrate: list applied versions: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration up or down and records it in the version table,
+// in one transaction unless the migration opts out
+func apply(ctx context.Context, conn *pgx.Conn, mig Migration, up bool) error {
+	statements, record := mig.down, "DELETE FROM goose_db_version WHERE version_id = $1"
+	if up {
+		statements, record = mig.up, "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)"
+	}
+	run := func(exec func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)) error {
+		for _, stmt := range statements {
+			if _, err := exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := exec(ctx, record, mig.Version)
+		return err
+	}
+
+	if mig.noTx {
+		if err := run(conn.Exec); err != nil {
+			return fmt.Errorf("migrate: %s: %w", mig.Name, err)
+		}
+		return nil
+	}
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		return run(tx.Exec)
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %s: %w", mig.Name, err)
+	}
+	return nil
+}
+
+// parse reads a goose migration file. Statements end with a semicolon at
+// the end of a line, except between StatementBegin and StatementEnd, which
+// are sent as one.
+func parse(name, src string) (Migration, error) {
+	mig := Migration{Name: name}
+	prefix, _, ok := strings.Cut(path.Base(name), "_")
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if !ok || err != nil || version < 1 {
+		return mig, fmt.Errorf("migrate: %s: name must start with a version number", name)
+	}
+	mig.Version = version
+
+	var section *[]string
+	var stmt strings.Builder
+	inBlock := false
+	flush := func() {
+		if hasSQL(stmt.String()) {
+			*section = append(*section, stmt.String())
+		}
+		stmt.Reset()
+	}
+
+	for line := range strings.Lines(src) {
+		trimmed := strings.TrimSpace(line)
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &mig.up
+			case "Down":
+				if section != nil {
+					flush()
+				}
+				section = &mig.down
+			case "StatementBegin":
+				inBlock = true
+			case "StatementEnd":
+				flush()
+				inBlock = false
+			case "NO TRANSACTION":
+				mig.noTx = true
+			default:
+				return mig, fmt.Errorf("migrate: %s: unknown annotation %q", name, trimmed)
+			}
+			continue
+		}
+		if section == nil {
+			continue
+		}
+		stmt.WriteString(line)
+		if !inBlock && strings.HasSuffix(trimmed, ";") && !strings.HasPrefix(trimmed, "--") {
+			flush()
+		}
+	}
+
+	switch {
+	case section == nil:
+		return mig, fmt.Errorf("migrate: %s: missing -- +goose Up", name)
+	case inBlock:
+		return mig, fmt.Errorf("migrate: %s: missing -- +goose StatementEnd", name)
+	case hasSQL(stmt.String()):
+		return mig, fmt.Errorf("migrate: %s: last statement has no closing semicolon", name)
+	}
+	return mig, nil
+}
+
+// hasSQL reports whether s has anything besides blank lines and comments
+func hasSQL(s string) bool {
+	for line := range strings.Lines(s) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			return true
+		}
+	}
+	return false
+}
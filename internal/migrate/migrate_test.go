@@ -0,0 +1,201 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		src     string
+		want    Migration
+		wantErr string
+	}{
+		{
+			name: "statements split on semicolons",
+			file: "20240101120000_create_sites.sql",
+			src: `-- +goose Up
+CREATE TABLE sites (id UUID PRIMARY KEY);
+-- trailing comments and blank lines are dropped
+
+CREATE INDEX idx_sites ON sites(id);
+
+-- +goose Down
+DROP TABLE sites;
+`,
+			want: Migration{
+				Version: 20240101120000,
+				Name:    "20240101120000_create_sites.sql",
+				up: []string{
+					"CREATE TABLE sites (id UUID PRIMARY KEY);\n",
+					"-- trailing comments and blank lines are dropped\n\nCREATE INDEX idx_sites ON sites(id);\n",
+				},
+				down: []string{"DROP TABLE sites;\n"},
+			},
+		},
+		{
+			name: "statement spanning lines",
+			file: "2_multiline.sql",
+			src: `-- +goose Up
+ALTER TABLE sites
+    ADD COLUMN name TEXT
+    DEFAULT '';
+-- a comment ending in a semicolon;
+UPDATE sites SET name = '';
+`,
+			want: Migration{
+				Version: 2,
+				Name:    "2_multiline.sql",
+				up: []string{
+					"ALTER TABLE sites\n    ADD COLUMN name TEXT\n    DEFAULT '';\n",
+					"-- a comment ending in a semicolon;\nUPDATE sites SET name = '';\n",
+				},
+			},
+		},
+		{
+			name: "statement block",
+			file: "3_function.sql",
+			src: `-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION touch();
+-- +goose StatementEnd
+`,
+			want: Migration{
+				Version: 3,
+				Name:    "3_function.sql",
+				up: []string{
+					"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n    NEW.updated_at := now();\n    RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\n",
+				},
+				down: []string{"DROP FUNCTION touch();\n"},
+			},
+		},
+		{
+			name: "no transaction",
+			file: "migrations/4_concurrent_index.sql",
+			src: `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY idx_sites_name ON sites(name);
+`,
+			want: Migration{
+				Version: 4,
+				Name:    "migrations/4_concurrent_index.sql",
+				up:      []string{"CREATE INDEX CONCURRENTLY idx_sites_name ON sites(name);\n"},
+				noTx:    true,
+			},
+		},
+		{
+			name: "text before up is ignored",
+			file: "5_header.sql",
+			src: `-- Adds nothing
+SELECT 'ignored';
+-- +goose Up
+SELECT 1;
+`,
+			want: Migration{
+				Version: 5,
+				Name:    "5_header.sql",
+				up:      []string{"SELECT 1;\n"},
+			},
+		},
+		{
+			name:    "name without version",
+			file:    "create_sites.sql",
+			src:     "-- +goose Up\nSELECT 1;\n",
+			wantErr: "name must start with a version number",
+		},
+		{
+			name:    "version zero",
+			file:    "0_base.sql",
+			src:     "-- +goose Up\nSELECT 1;\n",
+			wantErr: "name must start with a version number",
+		},
+		{
+			name:    "missing up",
+			file:    "6_empty.sql",
+			src:     "SELECT 1;\n",
+			wantErr: "missing -- +goose Up",
+		},
+		{
+			name:    "unclosed block",
+			file:    "7_unclosed.sql",
+			src:     "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n",
+			wantErr: "missing -- +goose StatementEnd",
+		},
+		{
+			name:    "unknown annotation",
+			file:    "8_typo.sql",
+			src:     "-- +goose Up\n-- +goose StatmentBegin\nSELECT 1;\n",
+			wantErr: "unknown annotation",
+		},
+		{
+			name:    "missing semicolon",
+			file:    "9_unterminated.sql",
+			src:     "-- +goose Up\nSELECT 1;\n-- +goose Down\nSELECT 2\n",
+			wantErr: "last statement has no closing semicolon",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.file, tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parse() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got.Version != tt.want.Version || got.Name != tt.want.Name || got.noTx != tt.want.noTx {
+				t.Errorf("parse() = %d %q noTx=%v, want %d %q noTx=%v",
+					got.Version, got.Name, got.noTx, tt.want.Version, tt.want.Name, tt.want.noTx)
+			}
+			if !slices.Equal(got.up, tt.want.up) {
+				t.Errorf("up = %q\nwant %q", got.up, tt.want.up)
+			}
+			if !slices.Equal(got.down, tt.want.down) {
+				t.Errorf("down = %q\nwant %q", got.down, tt.want.down)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+
+	m, err := New(nil, fstest.MapFS{
+		"20_second.sql": up,
+		"3_first.sql":   up,
+		"100_third.sql": up,
+		"README.md":     {Data: []byte("not a migration")},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var versions []int64
+	for _, mig := range m.migrations {
+		versions = append(versions, mig.Version)
+	}
+	if want := []int64{3, 20, 100}; !slices.Equal(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+
+	_, err = New(nil, fstest.MapFS{"3_first.sql": up, "003_again.sql": up})
+	if err == nil || !strings.Contains(err.Error(), "have the same version") {
+		t.Errorf("New() with duplicate versions error = %v, want one about the same version", err)
+	}
+}
@@ -0,0 +1,124 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Create project status enum type
+CREATE TYPE project_status AS ENUM ('in-progress', 'needs-review', 'completed', 'archived');
+
+-- Create risk level enum type (ordered from least to most severe)
+CREATE TYPE risk_level AS ENUM ('low', 'medium', 'high', 'critical');
+
+-- Create violation status enum type
+CREATE TYPE violation_status AS ENUM ('open', 'validated', 'dismissed', 'resolved');
+
+-- Create projects table
+CREATE TABLE projects (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- Project information
+    name VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status project_status NOT NULL DEFAULT 'in-progress',
+    location VARCHAR(500) NOT NULL DEFAULT '',
+    inspector_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    compliance_score DOUBLE PRECISION NOT NULL DEFAULT 100,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT compliance_score_range CHECK (compliance_score >= 0 AND compliance_score <= 100)
+);
+
+-- Create violations table
+CREATE TABLE violations (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- Violation details
+    description TEXT NOT NULL,
+    regulation VARCHAR(50) NOT NULL DEFAULT '',
+    risk_level risk_level NOT NULL DEFAULT 'medium',
+    category VARCHAR(100) NOT NULL DEFAULT '',
+    location VARCHAR(500) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    ai_confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+
+    -- Review status
+    status violation_status NOT NULL DEFAULT 'open',
+    found_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT ai_confidence_range CHECK (ai_confidence >= 0 AND ai_confidence <= 1)
+);
+
+-- Create timeline events table
+CREATE TABLE timeline_events (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    violation_id UUID REFERENCES violations(id) ON DELETE CASCADE,
+
+    -- Event details
+    type VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_name VARCHAR(200) NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for performance
+CREATE INDEX idx_projects_status ON projects(status);
+CREATE INDEX idx_projects_updated_at ON projects(updated_at);
+CREATE INDEX idx_violations_project_id ON violations(project_id);
+CREATE INDEX idx_violations_status ON violations(status);
+CREATE INDEX idx_violations_risk_level ON violations(risk_level);
+CREATE INDEX idx_timeline_events_project_id ON timeline_events(project_id, created_at);
+CREATE INDEX idx_timeline_events_violation_id ON timeline_events(violation_id);
+
+-- Create triggers to automatically update updated_at
+CREATE TRIGGER update_projects_updated_at
+    BEFORE UPDATE ON projects
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_violations_updated_at
+    BEFORE UPDATE ON violations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Add comments for documentation
+COMMENT ON TABLE projects IS 'Construction sites under inspection';
+COMMENT ON COLUMN projects.compliance_score IS 'Percentage of inspected items found compliant (0-100)';
+COMMENT ON TABLE violations IS 'Safety violations detected by AI or recorded by inspectors';
+COMMENT ON COLUMN violations.regulation IS 'OSHA regulation number, e.g. 1926.95';
+COMMENT ON COLUMN violations.ai_confidence IS 'AI detection confidence (0-1)';
+COMMENT ON COLUMN violations.status IS 'open (pending review), validated, dismissed or resolved';
+COMMENT ON TABLE timeline_events IS 'Project activity feed';
+COMMENT ON COLUMN timeline_events.user_name IS 'Display name of the actor at the time of the event';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS update_violations_updated_at ON violations;
+DROP TRIGGER IF EXISTS update_projects_updated_at ON projects;
+DROP TABLE IF EXISTS timeline_events;
+DROP TABLE IF EXISTS violations;
+DROP TABLE IF EXISTS projects;
+DROP TYPE IF EXISTS violation_status;
+DROP TYPE IF EXISTS risk_level;
+DROP TYPE IF EXISTS project_status;
+
+-- +goose StatementEnd
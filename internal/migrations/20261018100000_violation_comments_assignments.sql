@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Add corrective action ownership to violations
+ALTER TABLE violations
+    ADD COLUMN assigned_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN assigned_subcontractor VARCHAR(200) NOT NULL DEFAULT '',
+    ADD COLUMN due_date DATE;
+
+-- Create violation comments table
+CREATE TABLE violation_comments (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    violation_id UUID NOT NULL REFERENCES violations(id) ON DELETE CASCADE,
+
+    -- Comment details
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_name VARCHAR(200) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT body_not_empty CHECK (char_length(trim(body)) > 0)
+);
+
+-- Create indexes for performance
+CREATE INDEX idx_violations_assigned_user_id ON violations(assigned_user_id);
+CREATE INDEX idx_violations_due_date ON violations(due_date) WHERE due_date IS NOT NULL;
+CREATE INDEX idx_violation_comments_violation_id ON violation_comments(violation_id, created_at);
+
+-- Add comments for documentation
+COMMENT ON COLUMN violations.assigned_user_id IS 'User responsible for the corrective action';
+COMMENT ON COLUMN violations.assigned_subcontractor IS 'Subcontractor responsible for the corrective action';
+COMMENT ON COLUMN violations.due_date IS 'Date the corrective action must be completed by';
+COMMENT ON TABLE violation_comments IS 'Discussion thread on a violation';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS violation_comments;
+ALTER TABLE violations
+    DROP COLUMN IF EXISTS due_date,
+    DROP COLUMN IF EXISTS assigned_subcontractor,
+    DROP COLUMN IF EXISTS assigned_user_id;
+
+-- +goose StatementEnd
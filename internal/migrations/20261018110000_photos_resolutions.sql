@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Create photo purpose enum type
+CREATE TYPE photo_purpose AS ENUM ('inspection', 'verification');
+
+-- Create verification result enum type
+CREATE TYPE verification_result AS ENUM ('not_run', 'hazard_absent', 'hazard_present', 'failed');
+
+-- Create photos table
+CREATE TABLE photos (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- Stored object
+    storage_key VARCHAR(500) NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+
+    -- Photo details
+    purpose photo_purpose NOT NULL DEFAULT 'inspection',
+    caption TEXT NOT NULL DEFAULT '',
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    uploaded_by_name VARCHAR(200) NOT NULL DEFAULT '',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT size_bytes_positive CHECK (size_bytes > 0)
+);
+
+-- Link violations to the photo they were found in
+ALTER TABLE violations
+    ADD COLUMN photo_id UUID REFERENCES photos(id) ON DELETE SET NULL;
+
+-- Create violation resolutions table
+CREATE TABLE violation_resolutions (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    violation_id UUID NOT NULL REFERENCES violations(id) ON DELETE CASCADE,
+    photo_id UUID NOT NULL REFERENCES photos(id),
+
+    -- Evidence
+    note TEXT NOT NULL,
+    verification verification_result NOT NULL DEFAULT 'not_run',
+    verification_confidence DOUBLE PRECISION,
+    verification_detail TEXT NOT NULL DEFAULT '',
+    accepted BOOLEAN NOT NULL,
+
+    -- Actor
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_name VARCHAR(200) NOT NULL DEFAULT '',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT note_not_empty CHECK (char_length(trim(note)) > 0),
+    CONSTRAINT verification_confidence_range CHECK (verification_confidence >= 0 AND verification_confidence <= 1)
+);
+
+-- Create indexes for performance
+CREATE INDEX idx_photos_project_id ON photos(project_id, created_at);
+CREATE INDEX idx_violations_photo_id ON violations(photo_id);
+CREATE INDEX idx_violation_resolutions_violation_id ON violation_resolutions(violation_id, created_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE photos IS 'Photos uploaded to a project; file contents live in the blob store';
+COMMENT ON COLUMN photos.storage_key IS 'Blob store key of the original upload';
+COMMENT ON COLUMN photos.purpose IS 'inspection (site survey) or verification (corrective action evidence)';
+COMMENT ON COLUMN violations.photo_id IS 'Photo the violation was found in (the "before" photo)';
+COMMENT ON TABLE violation_resolutions IS 'Corrective action evidence submitted to resolve a violation';
+COMMENT ON COLUMN violation_resolutions.verification IS 'Result of re-running hazard detection on the after photo';
+COMMENT ON COLUMN violation_resolutions.accepted IS 'Whether the submission resolved the violation';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS violation_resolutions;
+ALTER TABLE violations DROP COLUMN IF EXISTS photo_id;
+DROP TABLE IF EXISTS photos;
+DROP TYPE IF EXISTS verification_result;
+DROP TYPE IF EXISTS photo_purpose;
+
+-- +goose StatementEnd
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Create region shape enum type
+CREATE TYPE region_shape AS ENUM ('box', 'polygon');
+
+-- Create region source enum type
+CREATE TYPE region_source AS ENUM ('detector', 'inspector');
+
+-- Create violation regions table
+CREATE TABLE violation_regions (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    violation_id UUID NOT NULL REFERENCES violations(id) ON DELETE CASCADE,
+    photo_id UUID NOT NULL REFERENCES photos(id) ON DELETE CASCADE,
+
+    -- Geometry, normalized to the image size (0-1, origin top left).
+    -- x, y, width and height are the bounding box for every shape;
+    -- polygons additionally store their vertices.
+    shape region_shape NOT NULL DEFAULT 'box',
+    x DOUBLE PRECISION NOT NULL,
+    y DOUBLE PRECISION NOT NULL,
+    width DOUBLE PRECISION NOT NULL,
+    height DOUBLE PRECISION NOT NULL,
+    points JSONB,
+
+    -- Provenance
+    source region_source NOT NULL,
+    confidence DOUBLE PRECISION,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_name VARCHAR(200) NOT NULL DEFAULT '',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT region_within_image CHECK (
+        x >= 0 AND y >= 0 AND width > 0 AND height > 0
+        AND x + width <= 1.000001 AND y + height <= 1.000001
+    ),
+    CONSTRAINT polygon_has_points CHECK (shape <> 'polygon' OR points IS NOT NULL),
+    CONSTRAINT confidence_range CHECK (confidence >= 0 AND confidence <= 1)
+);
+
+-- Track when hazard detection last ran on a photo
+ALTER TABLE photos
+    ADD COLUMN analyzed_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes for performance
+CREATE INDEX idx_violation_regions_photo_id ON violation_regions(photo_id);
+CREATE INDEX idx_violation_regions_violation_id ON violation_regions(violation_id);
+
+-- Add comments for documentation
+COMMENT ON TABLE violation_regions IS 'Where in a photo a violation appears';
+COMMENT ON COLUMN violation_regions.points IS 'Polygon vertices as [[x, y], ...], normalized 0-1';
+COMMENT ON COLUMN violation_regions.source IS 'detector (AI) or inspector (drawn by hand)';
+COMMENT ON COLUMN photos.analyzed_at IS 'When hazard detection last ran, null if never';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE photos DROP COLUMN IF EXISTS analyzed_at;
+DROP TABLE IF EXISTS violation_regions;
+DROP TYPE IF EXISTS region_source;
+DROP TYPE IF EXISTS region_shape;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Gallery details for photos
+ALTER TABLE photos
+    ADD COLUMN area_type VARCHAR(50) NOT NULL DEFAULT 'general',
+    ADD COLUMN thumbnail_key VARCHAR(500);
+
+-- Create indexes for performance
+CREATE INDEX idx_photos_project_area_type ON photos(project_id, area_type, created_at);
+
+-- Add comments for documentation
+COMMENT ON COLUMN photos.area_type IS 'Work area shown: general, excavation, electrical, roofing, mechanical, concrete, demolition, other';
+COMMENT ON COLUMN photos.thumbnail_key IS 'Blob key of the gallery thumbnail, null for photos uploaded before thumbnails';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_photos_project_area_type;
+ALTER TABLE photos DROP COLUMN IF EXISTS thumbnail_key;
+ALTER TABLE photos DROP COLUMN IF EXISTS area_type;
+
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Capture details read from photo EXIF
+ALTER TABLE photos
+    ADD COLUMN taken_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN latitude DOUBLE PRECISION,
+    ADD COLUMN longitude DOUBLE PRECISION,
+    ADD COLUMN orientation SMALLINT NOT NULL DEFAULT 1,
+    ADD COLUMN camera_make VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN camera_model VARCHAR(100) NOT NULL DEFAULT '',
+    ADD CONSTRAINT photo_coordinates_paired CHECK ((latitude IS NULL) = (longitude IS NULL)),
+    ADD CONSTRAINT photo_latitude_range CHECK (latitude BETWEEN -90 AND 90),
+    ADD CONSTRAINT photo_longitude_range CHECK (longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT photo_orientation_range CHECK (orientation BETWEEN 1 AND 8);
+
+-- Site coordinates photos are checked against
+ALTER TABLE projects
+    ADD COLUMN latitude DOUBLE PRECISION,
+    ADD COLUMN longitude DOUBLE PRECISION,
+    ADD CONSTRAINT project_coordinates_paired CHECK ((latitude IS NULL) = (longitude IS NULL)),
+    ADD CONSTRAINT project_latitude_range CHECK (latitude BETWEEN -90 AND 90),
+    ADD CONSTRAINT project_longitude_range CHECK (longitude BETWEEN -180 AND 180);
+
+-- Add comments for documentation
+COMMENT ON COLUMN photos.taken_at IS 'Capture time from EXIF, null if the camera did not record it';
+COMMENT ON COLUMN photos.latitude IS 'GPS latitude from EXIF in decimal degrees, null if not recorded';
+COMMENT ON COLUMN photos.longitude IS 'GPS longitude from EXIF in decimal degrees, null if not recorded';
+COMMENT ON COLUMN photos.orientation IS 'EXIF orientation of the upload; stored pixels are always upright';
+COMMENT ON COLUMN projects.latitude IS 'Site latitude in decimal degrees, null if unknown';
+COMMENT ON COLUMN projects.longitude IS 'Site longitude in decimal degrees, null if unknown';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects
+    DROP CONSTRAINT IF EXISTS project_longitude_range,
+    DROP CONSTRAINT IF EXISTS project_latitude_range,
+    DROP CONSTRAINT IF EXISTS project_coordinates_paired,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
+
+ALTER TABLE photos
+    DROP CONSTRAINT IF EXISTS photo_orientation_range,
+    DROP CONSTRAINT IF EXISTS photo_longitude_range,
+    DROP CONSTRAINT IF EXISTS photo_latitude_range,
+    DROP CONSTRAINT IF EXISTS photo_coordinates_paired,
+    DROP COLUMN IF EXISTS camera_model,
+    DROP COLUMN IF EXISTS camera_make,
+    DROP COLUMN IF EXISTS orientation,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS taken_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Record where project coordinates came from
+CREATE TYPE coordinates_source AS ENUM ('manual', 'geocoded', 'photos');
+
+ALTER TABLE projects
+    ADD COLUMN coordinates_source coordinates_source,
+    ADD CONSTRAINT project_coordinates_source CHECK ((latitude IS NULL) = (coordinates_source IS NULL));
+
+-- Create indexes for performance
+CREATE INDEX idx_projects_coordinates ON projects(latitude, longitude) WHERE latitude IS NOT NULL;
+CREATE INDEX idx_photos_location ON photos(project_id) WHERE latitude IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN projects.coordinates_source IS 'manual (entered), geocoded (from the address) or photos (from photo GPS), null without coordinates';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_photos_location;
+DROP INDEX IF EXISTS idx_projects_coordinates;
+ALTER TABLE projects
+    DROP CONSTRAINT IF EXISTS project_coordinates_source,
+    DROP COLUMN IF EXISTS coordinates_source;
+DROP TYPE IF EXISTS coordinates_source;
+
+-- +goose StatementEnd
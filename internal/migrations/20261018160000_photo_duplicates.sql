@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Perceptual hashes used to spot the same shot uploaded twice
+ALTER TABLE photos
+    ADD COLUMN perceptual_hash BIGINT,
+    ADD COLUMN duplicate_of UUID REFERENCES photos(id) ON DELETE SET NULL,
+    ADD CONSTRAINT photo_not_own_duplicate CHECK (duplicate_of <> id);
+
+CREATE INDEX idx_photos_duplicate_of ON photos(duplicate_of) WHERE duplicate_of IS NOT NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN photos.perceptual_hash IS '64-bit difference hash of the stored image, null for photos uploaded before hashing';
+COMMENT ON COLUMN photos.duplicate_of IS 'Earlier photo in the same project this one nearly matches; duplicates are not analyzed';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_photos_duplicate_of;
+
+ALTER TABLE photos
+    DROP CONSTRAINT IF EXISTS photo_not_own_duplicate,
+    DROP COLUMN IF EXISTS duplicate_of,
+    DROP COLUMN IF EXISTS perceptual_hash;
+
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Resumable photo uploads in progress. Bytes arrive in chunks that are kept
+-- in the blob store until the last one completes the photo.
+CREATE TABLE uploads (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- Progress
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+
+    -- Details for the photo created on completion
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    area_type VARCHAR(50) NOT NULL DEFAULT 'general',
+    caption TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_name VARCHAR(200) NOT NULL DEFAULT '',
+    photo_id UUID REFERENCES photos(id) ON DELETE SET NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    -- Constraints
+    CONSTRAINT upload_length_positive CHECK (upload_length > 0),
+    CONSTRAINT upload_offset_range CHECK (upload_offset >= 0 AND upload_offset <= upload_length)
+);
+
+-- Chunks received so far, in offset order
+CREATE TABLE upload_chunks (
+    upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(500) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (upload_id, chunk_offset),
+    CONSTRAINT chunk_size_positive CHECK (size_bytes > 0)
+);
+
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE uploads IS 'Resumable photo uploads, kept until they expire so clients can check on finished ones';
+COMMENT ON COLUMN uploads.upload_offset IS 'Bytes received; equals upload_length once the photo is created';
+COMMENT ON COLUMN uploads.expires_at IS 'When an idle upload and its chunks are purged';
+COMMENT ON TABLE upload_chunks IS 'Blob store objects holding the bytes of unfinished uploads';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS upload_chunks;
+DROP TABLE IF EXISTS uploads;
+
+-- +goose StatementEnd
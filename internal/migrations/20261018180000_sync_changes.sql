@@ -0,0 +1,95 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Photo edits are checked for conflicts like projects and violations
+ALTER TABLE photos
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE photos SET updated_at = created_at;
+
+-- Resumable uploads can carry the ID an offline client gave the photo
+ALTER TABLE uploads
+    ADD COLUMN requested_photo_id UUID;
+
+-- Entities offline clients keep copies of
+CREATE TYPE sync_entity AS ENUM ('project', 'photo', 'violation');
+
+-- Every insert, update and delete of a synced row. Clients pull the changes
+-- committed between two snapshots, which unlike reading past a sequence
+-- number never skips a transaction that commits late.
+CREATE TABLE sync_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    entity sync_entity NOT NULL,
+    entity_id UUID NOT NULL,
+    project_id UUID NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    txid XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sync_changes_txid ON sync_changes(txid);
+
+-- Records a change to the row; the trigger arguments are the entity and the
+-- column holding its project
+CREATE FUNCTION record_sync_change() RETURNS trigger AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    INSERT INTO sync_changes (entity, entity_id, project_id, deleted)
+    VALUES (
+        TG_ARGV[0]::sync_entity,
+        changed.id,
+        (to_jsonb(changed) ->> TG_ARGV[1])::uuid,
+        TG_OP = 'DELETE'
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER projects_sync_change
+    AFTER INSERT OR UPDATE OR DELETE ON projects
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('project', 'id');
+
+CREATE TRIGGER photos_sync_change
+    AFTER INSERT OR UPDATE OR DELETE ON photos
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('photo', 'project_id');
+
+CREATE TRIGGER violations_sync_change
+    AFTER INSERT OR UPDATE OR DELETE ON violations
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('violation', 'project_id');
+
+-- Existing rows are the first changes a new client pulls
+INSERT INTO sync_changes (entity, entity_id, project_id)
+SELECT 'project', id, id FROM projects;
+INSERT INTO sync_changes (entity, entity_id, project_id)
+SELECT 'photo', id, project_id FROM photos;
+INSERT INTO sync_changes (entity, entity_id, project_id)
+SELECT 'violation', id, project_id FROM violations;
+
+-- Add comments for documentation
+COMMENT ON TABLE sync_changes IS 'Change feed for offline clients, written by triggers';
+COMMENT ON COLUMN sync_changes.txid IS 'Transaction that made the change, compared against client snapshots';
+COMMENT ON COLUMN photos.updated_at IS 'When the caption or area type last changed';
+COMMENT ON COLUMN uploads.requested_photo_id IS 'Client-generated ID for the photo, null to let the server choose';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE uploads DROP COLUMN IF EXISTS requested_photo_id;
+ALTER TABLE photos DROP COLUMN IF EXISTS updated_at;
+
+DROP TRIGGER IF EXISTS violations_sync_change ON violations;
+DROP TRIGGER IF EXISTS photos_sync_change ON photos;
+DROP TRIGGER IF EXISTS projects_sync_change ON projects;
+DROP FUNCTION IF EXISTS record_sync_change();
+DROP TABLE IF EXISTS sync_changes;
+DROP TYPE IF EXISTS sync_entity;
+
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Personal tokens act as the user who owns them; service tokens belong to
+-- the organization and outlive any one user
+CREATE TYPE api_token_kind AS ENUM ('personal', 'service');
+
+-- Bearer tokens for calling the JSON API without a browser session. Only a
+-- hash of each token is kept; the token itself is shown once when issued.
+CREATE TABLE api_tokens (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind api_token_kind NOT NULL,
+    name VARCHAR(100) NOT NULL,
+
+    -- Secret
+    token_hash BYTEA NOT NULL UNIQUE,
+    token_prefix VARCHAR(20) NOT NULL,
+    scopes TEXT[] NOT NULL,
+
+    -- Ownership
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_name VARCHAR(200) NOT NULL DEFAULT '',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+
+    -- Constraints
+    CONSTRAINT service_token_has_no_user CHECK (kind = 'personal' OR user_id IS NULL)
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+
+-- Add comments for documentation
+COMMENT ON TABLE api_tokens IS 'Personal and service tokens accepted as Authorization: Bearer by the JSON API';
+COMMENT ON COLUMN api_tokens.token_hash IS 'SHA-256 of the token; the token itself is never stored';
+COMMENT ON COLUMN api_tokens.token_prefix IS 'Start of the token, shown so users can tell their tokens apart';
+COMMENT ON COLUMN api_tokens.user_id IS 'User a personal token acts as, null for service tokens';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_api_tokens_user_id;
+DROP TABLE IF EXISTS api_tokens;
+DROP TYPE IF EXISTS api_token_kind;
+
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- pending until delivered or out of attempts
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'succeeded', 'failed');
+
+-- Endpoints notified of violation and report events
+CREATE TABLE webhook_subscriptions (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+
+    -- Key payloads are signed with; the receiver checks signatures with it
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- Ownership
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_name VARCHAR(200) NOT NULL DEFAULT '',
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One event sent to one subscription, and the outcome of the latest attempt.
+-- Rows are written in the same transaction as the change they describe, so
+-- an event goes out if and only if the change commits.
+CREATE TABLE webhook_deliveries (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+
+    -- Event
+    event_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    data JSONB NOT NULL,
+
+    -- Delivery state
+    status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    response_status INTEGER,
+    response_body TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER,
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE webhook_subscriptions IS 'Outbound webhook endpoints and the event types they receive';
+COMMENT ON COLUMN webhook_subscriptions.event_types IS 'Event types delivered, e.g. violation.critical';
+COMMENT ON TABLE webhook_deliveries IS 'Webhook events queued for or sent to a subscription';
+COMMENT ON COLUMN webhook_deliveries.event_id IS 'Shared by every delivery of the same event, including redeliveries';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When a pending delivery is next tried';
+COMMENT ON COLUMN webhook_deliveries.redelivery_of IS 'Delivery this one was manually resent from';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TYPE IF EXISTS webhook_delivery_status;
+
+-- +goose StatementEnd
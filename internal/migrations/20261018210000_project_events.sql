@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tells every listening app instance about a violation, so pages open on any
+-- instance can update without a refresh. Descriptions are shortened to keep
+-- payloads well under the 8000 byte NOTIFY limit.
+CREATE FUNCTION notify_violation_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('project_events', json_build_object(
+        'kind', 'violation',
+        'project_id', NEW.project_id,
+        'data', json_build_object(
+            'id', NEW.id,
+            'photo_id', NEW.photo_id,
+            'description', left(NEW.description, 200),
+            'risk_level', NEW.risk_level,
+            'status', NEW.status,
+            'previous_status', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END
+        )
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER violations_notify_insert
+    AFTER INSERT ON violations
+    FOR EACH ROW EXECUTE FUNCTION notify_violation_event();
+
+CREATE TRIGGER violations_notify_status
+    AFTER UPDATE OF status ON violations
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION notify_violation_event();
+
+-- Same for the project activity feed
+CREATE FUNCTION notify_timeline_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('project_events', json_build_object(
+        'kind', 'timeline',
+        'project_id', NEW.project_id,
+        'data', json_build_object(
+            'id', NEW.id,
+            'violation_id', NEW.violation_id,
+            'type', NEW.type,
+            'description', left(NEW.description, 500),
+            'user_name', NEW.user_name,
+            'created_at', NEW.created_at
+        )
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER timeline_events_notify
+    AFTER INSERT ON timeline_events
+    FOR EACH ROW EXECUTE FUNCTION notify_timeline_event();
+
+-- Add comments for documentation
+COMMENT ON FUNCTION notify_violation_event() IS 'Sends new violations and status changes on the project_events channel';
+COMMENT ON FUNCTION notify_timeline_event() IS 'Sends new activity feed entries on the project_events channel';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS timeline_events_notify ON timeline_events;
+DROP TRIGGER IF EXISTS violations_notify_status ON violations;
+DROP TRIGGER IF EXISTS violations_notify_insert ON violations;
+DROP FUNCTION IF EXISTS notify_timeline_event();
+DROP FUNCTION IF EXISTS notify_violation_event();
+
+-- +goose StatementEnd
@@ -0,0 +1,89 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Alerts sent to users
+CREATE TYPE notification_kind AS ENUM ('critical_violation', 'overdue_action');
+
+-- How a user wants to hear about a kind of alert: emailed right away (after
+-- quiet hours), collected into one email a day, or not at all
+CREATE TYPE notification_mode AS ENUM ('immediate', 'digest', 'off');
+
+-- pending until emailed or out of attempts; none for alerts that are only
+-- shown in the app
+CREATE TYPE notification_email_status AS ENUM ('pending', 'sent', 'failed', 'none');
+
+-- Per-user alert settings. Users without a row get the column defaults.
+CREATE TABLE notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Delivery per kind of alert
+    critical_violations notification_mode NOT NULL DEFAULT 'immediate',
+    overdue_actions notification_mode NOT NULL DEFAULT 'immediate',
+
+    -- Local times, in the user's timezone
+    digest_time TIME NOT NULL DEFAULT '07:00',
+    quiet_hours_start TIME,
+    quiet_hours_end TIME,
+
+    -- Timestamps
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT quiet_hours_pair CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL))
+);
+
+-- Alerts shown in the app, and the email each is due to go out in
+CREATE TABLE notifications (
+    -- Primary identifier
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind notification_kind NOT NULL,
+
+    -- What the alert is about
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    violation_id UUID REFERENCES violations(id) ON DELETE CASCADE,
+    title VARCHAR(300) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    link VARCHAR(500) NOT NULL DEFAULT '',
+
+    -- Email delivery; alerts due together are sent as one email
+    email_status notification_email_status NOT NULL,
+    email_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    email_attempts INTEGER NOT NULL DEFAULT 0,
+    email_error TEXT NOT NULL DEFAULT '',
+    emailed_at TIMESTAMP WITH TIME ZONE,
+
+    -- Timestamps
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each user is alerted about a violation once
+CREATE UNIQUE INDEX idx_notifications_alert ON notifications(user_id, kind, violation_id)
+    WHERE kind IN ('critical_violation', 'overdue_action');
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_email_due ON notifications(email_after) WHERE email_status = 'pending';
+
+-- Add comments for documentation
+COMMENT ON TABLE notification_preferences IS 'How each user is alerted, with quiet hours in their timezone';
+COMMENT ON COLUMN notification_preferences.digest_time IS 'Local time digest-mode alerts are emailed';
+COMMENT ON COLUMN notification_preferences.quiet_hours_start IS 'Local time from which immediate emails are held; may be after quiet_hours_end to span midnight';
+COMMENT ON TABLE notifications IS 'Alerts for users, shown in the app and emailed';
+COMMENT ON COLUMN notifications.link IS 'App path the alert points to';
+COMMENT ON COLUMN notifications.email_after IS 'When a pending email is next tried';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_notifications_email_due;
+DROP INDEX IF EXISTS idx_notifications_user;
+DROP INDEX IF EXISTS idx_notifications_alert;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TYPE IF EXISTS notification_email_status;
+DROP TYPE IF EXISTS notification_mode;
+DROP TYPE IF EXISTS notification_kind;
+
+-- +goose StatementEnd
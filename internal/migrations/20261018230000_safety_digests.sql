@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- How often a user gets the safety summary email
+CREATE TYPE digest_frequency AS ENUM ('off', 'daily', 'weekly');
+
+ALTER TABLE notification_preferences
+    ADD COLUMN safety_digest digest_frequency NOT NULL DEFAULT 'off';
+
+-- One row per summary period per user, claimed before the email is sent so
+-- a period is never summarized twice
+CREATE TABLE safety_digests (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency digest_frequency NOT NULL,
+
+    -- Local dates in the user's timezone; period_end is exclusive
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+
+    -- Delivery
+    attempts INTEGER NOT NULL DEFAULT 1,
+    claimed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    error TEXT NOT NULL DEFAULT '',
+
+    PRIMARY KEY (user_id, frequency, period_start)
+);
+
+-- Every compliance score a project has had, so summaries can show how it
+-- changed over a period
+CREATE TABLE project_compliance_history (
+    id BIGSERIAL PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    compliance_score DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_project_compliance_history_project ON project_compliance_history(project_id, recorded_at DESC);
+
+CREATE FUNCTION record_compliance_history() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.compliance_score = NEW.compliance_score THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO project_compliance_history (project_id, compliance_score)
+    VALUES (NEW.id, NEW.compliance_score);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER projects_compliance_history
+    AFTER INSERT OR UPDATE OF compliance_score ON projects
+    FOR EACH ROW EXECUTE FUNCTION record_compliance_history();
+
+-- History starts with today's scores
+INSERT INTO project_compliance_history (project_id, compliance_score)
+SELECT id, compliance_score FROM projects;
+
+-- Add comments for documentation
+COMMENT ON COLUMN notification_preferences.safety_digest IS 'Summary email frequency; sent at digest_time, weekly ones on Mondays';
+COMMENT ON TABLE safety_digests IS 'Summary emails sent or being sent, one per user and period';
+COMMENT ON COLUMN safety_digests.claimed_at IS 'When the latest attempt started; an unsent period is retried once the claim is stale';
+COMMENT ON TABLE project_compliance_history IS 'Compliance score changes, written by a trigger on projects';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS projects_compliance_history ON projects;
+DROP FUNCTION IF EXISTS record_compliance_history();
+DROP INDEX IF EXISTS idx_project_compliance_history_project;
+DROP TABLE IF EXISTS project_compliance_history;
+DROP TABLE IF EXISTS safety_digests;
+ALTER TABLE notification_preferences
+    DROP COLUMN IF EXISTS safety_digest;
+DROP TYPE IF EXISTS digest_frequency;
+
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Activity on violations a user is involved in
+ALTER TYPE notification_kind ADD VALUE 'mention';
+ALTER TYPE notification_kind ADD VALUE 'assignment';
+ALTER TYPE notification_kind ADD VALUE 'status_change';
+
+-- Who caused the notification, for activity rather than alerts
+ALTER TABLE notifications
+    ADD COLUMN actor_name VARCHAR(200) NOT NULL DEFAULT '';
+
+-- Unread counts in the header of every page
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN notifications.actor_name IS 'Name of the user whose comment, assignment or review raised the notification';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_notifications_unread;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS actor_name;
+
+-- Enum values can't be dropped, so the type is rebuilt without them
+DELETE FROM notifications WHERE kind IN ('mention', 'assignment', 'status_change');
+DROP INDEX IF EXISTS idx_notifications_alert;
+ALTER TYPE notification_kind RENAME TO notification_kind_old;
+CREATE TYPE notification_kind AS ENUM ('critical_violation', 'overdue_action');
+ALTER TABLE notifications
+    ALTER COLUMN kind TYPE notification_kind USING kind::text::notification_kind;
+DROP TYPE notification_kind_old;
+CREATE UNIQUE INDEX idx_notifications_alert ON notifications(user_id, kind, violation_id)
+    WHERE kind IN ('critical_violation', 'overdue_action');
+
+-- +goose StatementEnd
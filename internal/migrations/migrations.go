@@ -0,0 +1,10 @@
+// Package migrations holds the database schema as goose-annotated SQL files,
+// embedded so the binary can apply them itself.
+package migrations
+
+import "embed"
+
+// FS contains the migration files, named <version>_<description>.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxActivityBody caps how much of a violation description or comment an
+// activity notification repeats
+const maxActivityBody = 500
+
+// mentionPattern matches @username and @name@example.com mentions that
+// don't follow a word character, so email addresses in a comment aren't
+// mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// Actor is the user whose change raised a notification. An invalid ID is
+// allowed for users without an account.
+type Actor struct {
+	ID   pgtype.UUID
+	Name string
+}
+
+// statusTitles name each status a violation can move to
+var statusTitles = map[database.ViolationStatus]string{
+	database.ViolationStatusOpen:      "Violation reopened",
+	database.ViolationStatusValidated: "Violation validated",
+	database.ViolationStatusDismissed: "Violation dismissed",
+	database.ViolationStatusResolved:  "Violation resolved",
+}
+
+// Mentioned notifies the users a comment on a violation mentions by their
+// full email address or their username. Pass the queries of the
+// transaction making the change, so the notifications are only recorded if
+// it commits; the same applies to Assigned and StatusChanged.
+func Mentioned(ctx context.Context, q *database.Queries, v database.Violation, comment string, actor Actor) error {
+	handles := mentions(comment)
+	if len(handles) == 0 {
+		return nil
+	}
+	users, err := q.ListMentionedUsers(ctx, handles)
+	if err != nil {
+		return fmt.Errorf("find mentioned users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return activity(ctx, q, database.CreateActivityNotificationsParams{
+		Kind:        database.NotificationKindMention,
+		Title:       actorName(actor) + " mentioned you",
+		Body:        truncate(comment, maxActivityBody),
+		Link:        violationPath(v.ProjectID, v.ID) + "#comments",
+		UserIds:     users,
+		ViolationID: v.ID,
+	}, actor)
+}
+
+// Assigned notifies a user who was made responsible for correcting a
+// violation
+func Assigned(ctx context.Context, q *database.Queries, v database.Violation, assignee pgtype.UUID, actor Actor) error {
+	if !assignee.Valid {
+		return nil
+	}
+	return activity(ctx, q, database.CreateActivityNotificationsParams{
+		Kind:        database.NotificationKindAssignment,
+		Title:       "Corrective action assigned to you",
+		Body:        truncate(v.Description, maxActivityBody),
+		Link:        violationPath(v.ProjectID, v.ID),
+		UserIds:     []pgtype.UUID{assignee},
+		ViolationID: v.ID,
+	}, actor)
+}
+
+// StatusChanged notifies a violation's project inspector and assignee that
+// it moved to a new status
+func StatusChanged(ctx context.Context, q *database.Queries, v database.Violation, to database.ViolationStatus, actor Actor) error {
+	return activity(ctx, q, database.CreateActivityNotificationsParams{
+		Kind:        database.NotificationKindStatusChange,
+		Title:       statusTitles[to],
+		Body:        truncate(v.Description, maxActivityBody),
+		Link:        violationPath(v.ProjectID, v.ID),
+		Watchers:    true,
+		ViolationID: v.ID,
+	}, actor)
+}
+
+// activity records an activity notification on behalf of actor
+func activity(ctx context.Context, q *database.Queries, params database.CreateActivityNotificationsParams, actor Actor) error {
+	params.ActorID = actor.ID
+	params.ActorName = actor.Name
+	if params.UserIds == nil {
+		params.UserIds = []pgtype.UUID{}
+	}
+	if _, err := q.CreateActivityNotifications(ctx, params); err != nil {
+		return fmt.Errorf("record %s notification: %w", params.Kind, err)
+	}
+	return nil
+}
+
+// actorName returns the name to show for actor
+func actorName(actor Actor) string {
+	if actor.Name == "" {
+		return "Someone"
+	}
+	return actor.Name
+}
+
+// mentions returns the distinct handles mentioned in text, lowercased
+func mentions(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// a mention ending a sentence keeps its full stop out
+		handle := strings.ToLower(strings.TrimRight(m[1], "."))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
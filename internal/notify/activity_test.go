@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "North wall guardrail is missing", nil},
+		{"username", "@bob please check", []string{"bob"}},
+		{"email address", "@bob@a.com please check", []string{"bob@a.com"}},
+		{"same username at different domains", "@bob@a.com and @bob@b.com", []string{"bob@a.com", "bob@b.com"}},
+		{"lowercased", "@Bob@A.com", []string{"bob@a.com"}},
+		{"trailing period", "Thanks @bob.", []string{"bob"}},
+		{"email address ending a sentence", "Sent to @bob@a.com.", []string{"bob@a.com"}},
+		{"period inside a username", "@bob.smith to review", []string{"bob.smith"}},
+		{"punctuation", "(@bob), @carol! @dave? @erin: @frank;", []string{"bob", "carol", "dave", "erin", "frank"}},
+		{"quoted", `"@bob"`, []string{"bob"}},
+		{"line start", "first\n@bob", []string{"bob"}},
+		{"email address in text", "reported by bob@a.com", nil},
+		{"email address after a period", "see j.@bob", nil},
+		{"double at", "@@bob", nil},
+		{"repeated", "@bob @carol @bob @BOB.", []string{"bob", "carol"}},
+		{"repeated email address", "@bob@a.com, then @BOB@a.com", []string{"bob@a.com"}},
+		{"lone at", "meet @ 3pm", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentions(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("mentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/mail"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Digest settings
+const (
+	digestRetry       = 15 * time.Minute // Time before an unsent digest is claimed again
+	digestMaxAttempts = 5                // Attempts before a digest period is given up on
+	digestMaxOverdue  = 25               // Overdue actions listed in one digest
+)
+
+// DigestFrequencies lists the safety digest frequencies in the order they
+// are offered
+var DigestFrequencies = []database.DigestFrequency{
+	database.DigestFrequencyOff,
+	database.DigestFrequencyDaily,
+	database.DigestFrequencyWeekly,
+}
+
+// digest is the data a safety digest email is rendered from
+type digest struct {
+	Name        string
+	Frequency   database.DigestFrequency
+	Period      string
+	New         riskCounts
+	Projects    []digestProject
+	Unchanged   int // Projects left out because nothing happened on them
+	Overdue     []digestAction
+	MoreOverdue bool
+	SettingsURL string
+}
+
+// riskCounts counts violations by risk level
+type riskCounts struct {
+	Critical, High, Medium, Low int64
+}
+
+// Total returns the number of violations counted
+func (c riskCounts) Total() int64 {
+	return c.Critical + c.High + c.Medium + c.Low
+}
+
+type digestProject struct {
+	Name       string
+	New        riskCounts
+	Compliance float64
+	Change     string // Compliance change over the period, or empty if unknown
+	URL        string
+}
+
+type digestAction struct {
+	Description string
+	ProjectName string
+	RiskLevel   database.RiskLevel
+	DueDate     string
+	DaysOverdue int
+	Assignee    string
+	URL         string
+}
+
+// sendDigests emails each subscriber the safety digest for the latest
+// period they haven't been sent. A period is claimed before it is sent, so
+// it goes out once however many instances are running.
+func (n *Notifier) sendDigests(ctx context.Context, logger *slog.Logger) {
+	subscribers, err := n.q.ListSafetyDigestSubscribers(ctx)
+	if err != nil {
+		logger.Error("failed to list safety digest subscribers", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, s := range subscribers {
+		if ctx.Err() != nil {
+			return
+		}
+		loc := location(s.Timezone)
+		start, end := digestPeriod(now, loc, s.DigestTime, s.SafetyDigest)
+		period := localDate(start)
+		if s.LastPeriodStart.Valid && !s.LastPeriodStart.Time.Before(period.Time) {
+			continue
+		}
+
+		claim, err := n.q.ClaimSafetyDigest(ctx, database.ClaimSafetyDigestParams{
+			UserID:       s.UserID,
+			Frequency:    s.SafetyDigest,
+			PeriodStart:  period,
+			PeriodEnd:    localDate(end),
+			RetrySeconds: digestRetry.Seconds(),
+			MaxAttempts:  digestMaxAttempts,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to claim safety digest", "user_id", s.UserID.String(), "error", err)
+			continue
+		}
+		// a claimed digest is finished on shutdown rather than left to wait
+		// out its claim
+		n.digest(context.WithoutCancel(ctx), logger, s, claim, now, loc, start, end)
+	}
+}
+
+// digest builds, sends and records one claimed safety digest
+func (n *Notifier) digest(ctx context.Context, logger *slog.Logger, s database.ListSafetyDigestSubscribersRow, claim database.SafetyDigest, now time.Time, loc *time.Location, start, end time.Time) {
+	logger = logger.With("user_id", s.UserID.String(), "frequency", s.SafetyDigest, "period_start", start.Format(time.DateOnly))
+
+	msg, err := n.composeDigest(ctx, s, now, loc, start, end)
+	if err == nil {
+		err = n.mailer.Send(ctx, msg)
+	}
+
+	params := database.RecordSafetyDigestParams{
+		UserID:      claim.UserID,
+		Frequency:   claim.Frequency,
+		PeriodStart: claim.PeriodStart,
+	}
+	if err == nil {
+		params.SentAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		logger.Info("safety digest sent")
+	} else {
+		params.Error = err.Error()
+		if claim.Attempts >= digestMaxAttempts {
+			logger.Warn("safety digest failed, giving up", "error", err)
+		} else {
+			logger.Info("safety digest failed, will retry", "error", err)
+		}
+	}
+	if err := n.q.RecordSafetyDigest(ctx, params); err != nil {
+		logger.Error("failed to record safety digest", "error", err)
+	}
+}
+
+// composeDigest gathers a subscriber's projects and overdue actions for a
+// period and renders the digest email
+func (n *Notifier) composeDigest(ctx context.Context, s database.ListSafetyDigestSubscribersRow, now time.Time, loc *time.Location, start, end time.Time) (mail.Message, error) {
+	allProjects := s.Role == database.UserRoleAdmin
+	projects, err := n.q.ListDigestProjects(ctx, database.ListDigestProjectsParams{
+		PeriodStart: pgtype.Timestamptz{Time: start, Valid: true},
+		PeriodEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		AllProjects: allProjects,
+		UserID:      s.UserID,
+	})
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("list digest projects: %w", err)
+	}
+	today := localDate(now.In(loc))
+	actions, err := n.q.ListDigestOverdueActions(ctx, database.ListDigestOverdueActionsParams{
+		Today:       today,
+		AllProjects: allProjects,
+		UserID:      s.UserID,
+		MaxActions:  digestMaxOverdue + 1,
+	})
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("list overdue actions: %w", err)
+	}
+
+	data := digest{
+		Name:        s.UserName,
+		Frequency:   s.SafetyDigest,
+		Period:      periodLabel(start, end),
+		SettingsURL: n.baseURL + SettingsPath,
+	}
+	for _, p := range projects {
+		counts := riskCounts{Critical: p.CriticalCount, High: p.HighCount, Medium: p.MediumCount, Low: p.LowCount}
+		data.New.Critical += counts.Critical
+		data.New.High += counts.High
+		data.New.Medium += counts.Medium
+		data.New.Low += counts.Low
+
+		var change string
+		if p.HasHistory {
+			change = complianceChange(p.EndCompliance - p.StartCompliance)
+		}
+		if counts.Total() == 0 && (change == "" || change == "no change") {
+			data.Unchanged++
+			continue
+		}
+		data.Projects = append(data.Projects, digestProject{
+			Name:       p.Name,
+			New:        counts,
+			Compliance: p.ComplianceScore,
+			Change:     change,
+			URL:        n.baseURL + "/app/projects/" + p.ID.String(),
+		})
+	}
+	if len(actions) > digestMaxOverdue {
+		actions, data.MoreOverdue = actions[:digestMaxOverdue], true
+	}
+	for _, a := range actions {
+		data.Overdue = append(data.Overdue, digestAction{
+			Description: a.Description,
+			ProjectName: a.ProjectName,
+			RiskLevel:   a.RiskLevel,
+			DueDate:     a.DueDate.Time.Format("Jan 2"),
+			DaysOverdue: int(today.Time.Sub(a.DueDate.Time).Hours() / 24),
+			Assignee:    a.AssignedSubcontractor,
+			URL:         n.baseURL + violationPath(a.ProjectID, a.ID),
+		})
+	}
+
+	var body bytes.Buffer
+	if err := emails.ExecuteTemplate(&body, "digest.txt", data); err != nil {
+		return mail.Message{}, fmt.Errorf("render safety digest: %w", err)
+	}
+	subject := "Daily safety digest for " + data.Period
+	if s.SafetyDigest == database.DigestFrequencyWeekly {
+		subject = "Weekly safety digest for " + data.Period
+	}
+	return mail.Message{
+		To:      s.Email,
+		Subject: subject,
+		Body:    body.String(),
+	}, nil
+}
+
+// periodLabel describes a period given as local midnights with an
+// exclusive end, such as "Mon, Oct 12" or "Oct 12 – Oct 18"
+func periodLabel(start, end time.Time) string {
+	last := end.AddDate(0, 0, -1)
+	if !last.After(start) {
+		return start.Format("Mon, Jan 2")
+	}
+	return start.Format("Jan 2") + " – " + last.Format("Jan 2")
+}
+
+// complianceChange describes how far a compliance score moved, to the
+// precision it is shown with
+func complianceChange(delta float64) string {
+	switch {
+	case delta >= 0.05:
+		return fmt.Sprintf("up %.1f points", delta)
+	case delta <= -0.05:
+		return fmt.Sprintf("down %.1f points", -delta)
+	}
+	return "no change"
+}
@@ -0,0 +1,356 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/dukerupert/ironman/internal/mail"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Alert settings
+const (
+	pollInterval     = time.Minute      // Time between checks for new alerts and due emails
+	criticalLookback = 24 * time.Hour   // Age past which a critical violation is no longer alerted
+	batchSize        = 100              // Notifications emailed per check
+	claimLease       = 5 * time.Minute  // Time claimed notifications are held before another instance may email them
+	maxAttempts      = 5                // Attempts before an email is marked failed
+	retryDelay       = 10 * time.Minute // Delay before retrying a failed email, multiplied by the attempts so far
+)
+
+// Retention settings
+const (
+	cleanupInterval = time.Hour            // Time between removals of old notifications
+	readRetention   = 30 * 24 * time.Hour  // Age past which read notifications are removed
+	retention       = 180 * 24 * time.Hour // Age past which all notifications are removed
+)
+
+// DefaultDigestTime is when digest emails go out for users who haven't
+// chosen a time. It matches the column default.
+const DefaultDigestTime = 7 * time.Hour
+
+// SettingsPath is the page where users change their notification settings
+const SettingsPath = "/app/settings/notifications"
+
+// Modes lists the delivery modes in the order they are offered
+var Modes = []database.NotificationMode{
+	database.NotificationModeImmediate,
+	database.NotificationModeDigest,
+	database.NotificationModeOff,
+}
+
+//go:embed templates/*.txt
+var templateFS embed.FS
+
+var emails = template.Must(template.ParseFS(templateFS, "templates/*.txt"))
+
+// Notifier raises alerts for project owners about new critical violations
+// and overdue corrective actions, and emails them according to each user's
+// preferences. Alerts always appear in the app; preferences only decide
+// when, or whether, they are emailed. It also sends the daily and weekly
+// safety digests users subscribe to. Emails and digest periods are claimed
+// before sending, so several app instances can run a Notifier against the
+// same database.
+type Notifier struct {
+	q       *database.Queries
+	mailer  mail.Mailer
+	baseURL string
+}
+
+// New returns a Notifier. baseURL is the address of the app, used for links
+// in emails.
+func New(db *pgxpool.Pool, mailer mail.Mailer, baseURL string) *Notifier {
+	return &Notifier{
+		q:       database.New(db),
+		mailer:  mailer,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// DefaultPreferences returns the settings of a user who has never saved any
+func DefaultPreferences(userID pgtype.UUID) database.NotificationPreference {
+	return database.NotificationPreference{
+		UserID:             userID,
+		CriticalViolations: database.NotificationModeImmediate,
+		OverdueActions:     database.NotificationModeImmediate,
+		SafetyDigest:       database.DigestFrequencyOff,
+		DigestTime:         pgtype.Time{Microseconds: DefaultDigestTime.Microseconds(), Valid: true},
+	}
+}
+
+// Preferences returns a user's notification settings, or the defaults if
+// they have never saved any
+func Preferences(ctx context.Context, q *database.Queries, userID pgtype.UUID) (database.NotificationPreference, error) {
+	p, err := q.GetNotificationPreferences(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultPreferences(userID), nil
+	}
+	return p, err
+}
+
+// Run raises alerts, sends due emails and safety digests, and removes old
+// notifications until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context, logger *slog.Logger) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			n.raiseAlerts(ctx, logger)
+			n.sendDue(ctx, logger)
+			n.sendDigests(ctx, logger)
+		case <-cleanup.C:
+			n.cleanup(ctx, logger)
+		}
+	}
+}
+
+// cleanup removes notifications past their retention
+func (n *Notifier) cleanup(ctx context.Context, logger *slog.Logger) {
+	now := time.Now()
+	removed, err := n.q.DeleteExpiredNotifications(ctx, database.DeleteExpiredNotificationsParams{
+		ReadBefore:    pgtype.Timestamptz{Time: now.Add(-readRetention), Valid: true},
+		CreatedBefore: pgtype.Timestamptz{Time: now.Add(-retention), Valid: true},
+	})
+	if err != nil {
+		logger.Error("failed to remove expired notifications", "error", err)
+		return
+	}
+	if removed > 0 {
+		logger.Info("removed expired notifications", "count", removed)
+	}
+}
+
+// raiseAlerts records a notification for each owner of a new critical
+// violation and each owner or assignee of an overdue corrective action who
+// hasn't been alerted yet
+func (n *Notifier) raiseAlerts(ctx context.Context, logger *slog.Logger) {
+	now := time.Now()
+	prefs := make(map[pgtype.UUID]database.NotificationPreference)
+
+	critical, err := n.q.ListCriticalViolationAlerts(ctx, pgtype.Timestamptz{Time: now.Add(-criticalLookback), Valid: true})
+	if err != nil {
+		logger.Error("failed to list critical violation alerts", "error", err)
+	}
+	for _, a := range critical {
+		body := a.Description
+		if a.Regulation != "" {
+			body += "\nRegulation: " + a.Regulation
+		}
+		n.raise(ctx, logger, prefs, now, a.Timezone, database.CreateAlertNotificationParams{
+			UserID:      a.UserID,
+			Kind:        database.NotificationKindCriticalViolation,
+			ProjectID:   a.ProjectID,
+			ViolationID: a.ViolationID,
+			Title:       "Critical violation at " + a.ProjectName,
+			Body:        body,
+			Link:        violationPath(a.ProjectID, a.ViolationID),
+		})
+	}
+
+	// due dates are local to each user, and the date has already turned in
+	// timezones ahead of UTC
+	tomorrow := now.UTC().AddDate(0, 0, 1)
+	overdueActions, err := n.q.ListOverdueActionAlerts(ctx, pgtype.Date{Time: tomorrow, Valid: true})
+	if err != nil {
+		logger.Error("failed to list overdue action alerts", "error", err)
+	}
+	for _, a := range overdueActions {
+		if !overdue(a.DueDate, now, location(a.Timezone)) {
+			continue
+		}
+		body := a.Description + "\nDue " + a.DueDate.Time.Format("Jan 2, 2006")
+		if a.AssignedSubcontractor != "" {
+			body += ", assigned to " + a.AssignedSubcontractor
+		}
+		n.raise(ctx, logger, prefs, now, a.Timezone, database.CreateAlertNotificationParams{
+			UserID:      a.UserID,
+			Kind:        database.NotificationKindOverdueAction,
+			ProjectID:   a.ProjectID,
+			ViolationID: a.ViolationID,
+			Title:       "Corrective action overdue at " + a.ProjectName,
+			Body:        body,
+			Link:        violationPath(a.ProjectID, a.ViolationID),
+		})
+	}
+}
+
+// raise records one alert, scheduling its email by the user's preferences.
+// prefs caches preferences across the alerts of one check.
+func (n *Notifier) raise(ctx context.Context, logger *slog.Logger, prefs map[pgtype.UUID]database.NotificationPreference, now time.Time, timezone string, alert database.CreateAlertNotificationParams) {
+	p, ok := prefs[alert.UserID]
+	if !ok {
+		var err error
+		p, err = Preferences(ctx, n.q, alert.UserID)
+		if err != nil {
+			logger.Error("failed to load notification preferences", "user_id", alert.UserID.String(), "error", err)
+			return
+		}
+		prefs[alert.UserID] = p
+	}
+
+	mode := p.CriticalViolations
+	if alert.Kind == database.NotificationKindOverdueAction {
+		mode = p.OverdueActions
+	}
+	alert.EmailStatus = database.NotificationEmailStatusNone
+	alert.EmailAfter = pgtype.Timestamptz{Time: now, Valid: true}
+	if after, ok := emailAfter(now, location(timezone), p, mode); ok {
+		alert.EmailStatus = database.NotificationEmailStatusPending
+		alert.EmailAfter.Time = after
+	}
+
+	if _, err := n.q.CreateAlertNotification(ctx, alert); err != nil {
+		logger.Error("failed to record alert", "kind", alert.Kind, "violation_id", alert.ViolationID.String(), "error", err)
+	}
+}
+
+// sendDue claims the notifications whose emails are due and sends them, one
+// email per user, repeating while full batches come back
+func (n *Notifier) sendDue(ctx context.Context, logger *slog.Logger) {
+	for ctx.Err() == nil {
+		due, err := n.q.ClaimNotificationEmails(ctx, database.ClaimNotificationEmailsParams{
+			LeaseSeconds:     claimLease.Seconds(),
+			MaxNotifications: batchSize,
+		})
+		if err != nil {
+			logger.Error("failed to claim notification emails", "error", err)
+			return
+		}
+
+		var users []pgtype.UUID
+		byUser := make(map[pgtype.UUID][]database.ClaimNotificationEmailsRow)
+		for _, row := range due {
+			if _, ok := byUser[row.UserID]; !ok {
+				users = append(users, row.UserID)
+			}
+			byUser[row.UserID] = append(byUser[row.UserID], row)
+		}
+		for _, user := range users {
+			// a claimed email is finished on shutdown rather than left to
+			// wait out its lease
+			n.email(context.WithoutCancel(ctx), logger, byUser[user])
+		}
+
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+// email sends one user's due notifications as a single email and records
+// the outcome
+func (n *Notifier) email(ctx context.Context, logger *slog.Logger, rows []database.ClaimNotificationEmailsRow) {
+	logger = logger.With("user_id", rows[0].UserID.String(), "notifications", len(rows))
+
+	msg, err := n.compose(rows)
+	if err == nil {
+		err = n.mailer.Send(ctx, msg)
+	}
+
+	now := time.Now()
+	if err == nil {
+		n.record(ctx, logger, rows, database.RecordNotificationEmailsParams{
+			EmailStatus: database.NotificationEmailStatusSent,
+			EmailAfter:  pgtype.Timestamptz{Time: now, Valid: true},
+			EmailedAt:   pgtype.Timestamptz{Time: now, Valid: true},
+		})
+		logger.Info("notification email sent")
+		return
+	}
+
+	// notifications claimed together may have been tried a different
+	// number of times
+	var retry, failed []database.ClaimNotificationEmailsRow
+	for _, row := range rows {
+		if row.EmailAttempts >= maxAttempts {
+			failed = append(failed, row)
+		} else {
+			retry = append(retry, row)
+		}
+	}
+	if len(failed) > 0 {
+		n.record(ctx, logger, failed, database.RecordNotificationEmailsParams{
+			EmailStatus: database.NotificationEmailStatusFailed,
+			EmailAfter:  pgtype.Timestamptz{Time: now, Valid: true},
+			EmailError:  err.Error(),
+		})
+		logger.Warn("notification email failed, giving up", "error", err)
+	}
+	if len(retry) > 0 {
+		n.record(ctx, logger, retry, database.RecordNotificationEmailsParams{
+			EmailStatus: database.NotificationEmailStatusPending,
+			EmailAfter:  pgtype.Timestamptz{Time: now.Add(retryDelay * time.Duration(retry[0].EmailAttempts)), Valid: true},
+			EmailError:  err.Error(),
+		})
+		logger.Info("notification email failed, will retry", "error", err)
+	}
+}
+
+// record stores the outcome of an email for the notifications it carried
+func (n *Notifier) record(ctx context.Context, logger *slog.Logger, rows []database.ClaimNotificationEmailsRow, params database.RecordNotificationEmailsParams) {
+	for _, row := range rows {
+		params.Ids = append(params.Ids, row.ID)
+	}
+	if err := n.q.RecordNotificationEmails(ctx, params); err != nil {
+		logger.Error("failed to record notification email", "error", err)
+	}
+}
+
+// compose renders the email for a user's due notifications
+func (n *Notifier) compose(rows []database.ClaimNotificationEmailsRow) (mail.Message, error) {
+	type alert struct {
+		Title string
+		Body  string
+		URL   string
+	}
+	data := struct {
+		Name        string
+		Alerts      []alert
+		SettingsURL string
+	}{
+		Name:        rows[0].UserName,
+		SettingsURL: n.baseURL + SettingsPath,
+	}
+	for _, row := range rows {
+		data.Alerts = append(data.Alerts, alert{
+			Title: row.Title,
+			Body:  row.Body,
+			URL:   n.baseURL + row.Link,
+		})
+	}
+
+	var body bytes.Buffer
+	if err := emails.ExecuteTemplate(&body, "alerts.txt", data); err != nil {
+		return mail.Message{}, fmt.Errorf("render alert email: %w", err)
+	}
+	subject := rows[0].Title
+	if len(rows) > 1 {
+		subject = fmt.Sprintf("%d safety alerts", len(rows))
+	}
+	return mail.Message{
+		To:      rows[0].Email,
+		Subject: subject,
+		Body:    body.String(),
+	}, nil
+}
+
+// violationPath returns the app path of a violation
+func violationPath(projectID, violationID pgtype.UUID) string {
+	return "/app/projects/" + projectID.String() + "/violations/" + violationID.String()
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// location returns the named timezone, or UTC if it is empty or unknown
+func location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// clock returns a time of day as the time since midnight
+func clock(t pgtype.Time) time.Duration {
+	return time.Duration(t.Microseconds) * time.Microsecond
+}
+
+// at returns the time of day d on t's calendar day, in t's location. It goes
+// through time.Date rather than adding to midnight so that days with a
+// daylight saving change still land on the right wall-clock time.
+func at(t time.Time, d time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// sinceMidnight returns the wall-clock time of day of t
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// quietUntil returns when the user's quiet hours around now end, or now if
+// they aren't in quiet hours. Quiet hours that start after they end span
+// midnight; ones that start when they end are empty.
+func quietUntil(now time.Time, loc *time.Location, p database.NotificationPreference) time.Time {
+	if !p.QuietHoursStart.Valid || !p.QuietHoursEnd.Valid {
+		return now
+	}
+	start, end := clock(p.QuietHoursStart), clock(p.QuietHoursEnd)
+	local := now.In(loc)
+	current := sinceMidnight(local)
+
+	switch {
+	case start < end && current >= start && current < end:
+		return at(local, end)
+	case start > end && current >= start:
+		return at(local.AddDate(0, 0, 1), end)
+	case start > end && current < end:
+		return at(local, end)
+	}
+	return now
+}
+
+// nextDigest returns the next time after now the user's digest goes out
+func nextDigest(now time.Time, loc *time.Location, p database.NotificationPreference) time.Time {
+	local := now.In(loc)
+	next := at(local, clock(p.DigestTime))
+	if !next.After(local) {
+		next = at(local.AddDate(0, 0, 1), clock(p.DigestTime))
+	}
+	return next
+}
+
+// emailAfter returns when an alert raised at now should be emailed, and
+// false if it shouldn't be
+func emailAfter(now time.Time, loc *time.Location, p database.NotificationPreference, mode database.NotificationMode) (time.Time, bool) {
+	switch mode {
+	case database.NotificationModeOff:
+		return time.Time{}, false
+	case database.NotificationModeDigest:
+		return nextDigest(now, loc, p), true
+	default:
+		return quietUntil(now, loc, p), true
+	}
+}
+
+// overdue reports whether a due date has passed in the user's timezone
+func overdue(due pgtype.Date, now time.Time, loc *time.Location) bool {
+	y, m, d := now.In(loc).Date()
+	return due.Time.Before(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
+
+// digestPeriod returns the latest summary period that has ended and whose
+// digest is due at now, as local midnights with an exclusive end. A daily
+// digest covers the previous day and goes out at the digest time; a weekly
+// one covers the previous Monday to Sunday and goes out on Monday.
+func digestPeriod(now time.Time, loc *time.Location, digestTime pgtype.Time, frequency database.DigestFrequency) (start, end time.Time) {
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	beforeSend := local.Before(at(local, clock(digestTime)))
+
+	if frequency == database.DigestFrequencyWeekly {
+		sinceMonday := (int(local.Weekday()) + 6) % 7
+		end = today.AddDate(0, 0, -sinceMonday)
+		if sinceMonday == 0 && beforeSend {
+			end = end.AddDate(0, 0, -7)
+		}
+		return end.AddDate(0, 0, -7), end
+	}
+	end = today
+	if beforeSend {
+		end = end.AddDate(0, 0, -1)
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// localDate returns the calendar date of a local midnight as a date column
+func localDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
@@ -0,0 +1,422 @@
+package notify
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// denver observes daylight saving time: in 2026 clocks go forward at 2am on
+// Sunday March 8 and back at 2am on Sunday November 1
+var denver = mustLoadLocation("America/Denver")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// timeOfDay returns a time column holding h:m
+func timeOfDay(h, m int) pgtype.Time {
+	return pgtype.Time{Microseconds: int64(h*60+m) * 60 * 1e6, Valid: true}
+}
+
+// quietHours returns preferences with quiet hours from start to end, given
+// as hours and minutes
+func quietHours(startH, startM, endH, endM int) database.NotificationPreference {
+	return database.NotificationPreference{
+		QuietHoursStart: timeOfDay(startH, startM),
+		QuietHoursEnd:   timeOfDay(endH, endM),
+		DigestTime:      timeOfDay(7, 0),
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	overnight := quietHours(22, 0, 7, 0)
+	tests := []struct {
+		name string
+		now  time.Time
+		loc  *time.Location
+		p    database.NotificationPreference
+		want time.Time // zero for now
+	}{
+		{
+			name: "no quiet hours",
+			now:  time.Date(2026, 1, 15, 23, 0, 0, 0, denver),
+			loc:  denver,
+			p:    database.NotificationPreference{},
+		},
+		{
+			name: "inside a daytime window",
+			now:  time.Date(2026, 1, 15, 12, 30, 0, 0, denver),
+			loc:  denver,
+			p:    quietHours(12, 0, 13, 0),
+			want: time.Date(2026, 1, 15, 13, 0, 0, 0, denver),
+		},
+		{
+			name: "daytime window end is exclusive",
+			now:  time.Date(2026, 1, 15, 13, 0, 0, 0, denver),
+			loc:  denver,
+			p:    quietHours(12, 0, 13, 0),
+		},
+		{
+			name: "overnight window before midnight",
+			now:  time.Date(2026, 1, 15, 23, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "overnight window start is inclusive",
+			now:  time.Date(2026, 1, 15, 22, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "overnight window after midnight",
+			now:  time.Date(2026, 1, 16, 3, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "overnight window over the end of the year",
+			now:  time.Date(2026, 12, 31, 23, 30, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2027, 1, 1, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "outside an overnight window",
+			now:  time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+		},
+		{
+			name: "just before an overnight window",
+			now:  time.Date(2026, 1, 15, 21, 59, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+		},
+		{
+			name: "start equal to end is no quiet hours",
+			now:  time.Date(2026, 1, 15, 22, 0, 0, 0, denver),
+			loc:  denver,
+			p:    quietHours(22, 0, 22, 0),
+		},
+		{
+			name: "judged in the user's timezone",
+			now:  time.Date(2026, 1, 16, 5, 0, 0, 0, time.UTC), // 10pm in Denver
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "not quiet in the user's timezone though it would be in UTC",
+			now:  time.Date(2026, 1, 15, 23, 0, 0, 0, time.UTC), // 4pm in Denver
+			loc:  denver,
+			p:    overnight,
+		},
+		{
+			name: "night the clocks go forward",
+			now:  time.Date(2026, 3, 7, 23, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC), // 7am MDT
+		},
+		{
+			name: "after the clocks go forward",
+			now:  time.Date(2026, 3, 8, 3, 30, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "night the clocks go back",
+			now:  time.Date(2026, 10, 31, 23, 0, 0, 0, denver),
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC), // 7am MST
+		},
+		{
+			name: "second 1:30am of the night the clocks go back",
+			now:  time.Date(2026, 11, 1, 8, 30, 0, 0, time.UTC), // 1:30am MST
+			loc:  denver,
+			p:    overnight,
+			want: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want.IsZero() {
+				want = tt.now
+			}
+			if got := quietUntil(tt.now, tt.loc, tt.p); !got.Equal(want) {
+				t.Errorf("quietUntil() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestEmailAfter(t *testing.T) {
+	p := quietHours(22, 0, 7, 0)
+	p.DigestTime = timeOfDay(17, 30)
+	tests := []struct {
+		name   string
+		now    time.Time
+		mode   database.NotificationMode
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "immediate outside quiet hours",
+			now:    time.Date(2026, 1, 15, 12, 0, 0, 0, denver),
+			mode:   database.NotificationModeImmediate,
+			want:   time.Date(2026, 1, 15, 12, 0, 0, 0, denver),
+			wantOK: true,
+		},
+		{
+			name:   "immediate held until quiet hours end",
+			now:    time.Date(2026, 1, 15, 23, 0, 0, 0, denver),
+			mode:   database.NotificationModeImmediate,
+			want:   time.Date(2026, 1, 16, 7, 0, 0, 0, denver),
+			wantOK: true,
+		},
+		{
+			name:   "digest waits for the digest time",
+			now:    time.Date(2026, 1, 15, 12, 0, 0, 0, denver),
+			mode:   database.NotificationModeDigest,
+			want:   time.Date(2026, 1, 15, 17, 30, 0, 0, denver),
+			wantOK: true,
+		},
+		{
+			name:   "digest ignores quiet hours",
+			now:    time.Date(2026, 1, 15, 23, 0, 0, 0, denver),
+			mode:   database.NotificationModeDigest,
+			want:   time.Date(2026, 1, 16, 17, 30, 0, 0, denver),
+			wantOK: true,
+		},
+		{
+			name: "off",
+			now:  time.Date(2026, 1, 15, 12, 0, 0, 0, denver),
+			mode: database.NotificationModeOff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := emailAfter(tt.now, denver, p, tt.mode)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("emailAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	p := database.NotificationPreference{DigestTime: timeOfDay(7, 0)}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "later today",
+			now:  time.Date(2026, 1, 14, 6, 0, 0, 0, denver),
+			want: time.Date(2026, 1, 14, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "at the digest time",
+			now:  time.Date(2026, 1, 14, 7, 0, 0, 0, denver),
+			want: time.Date(2026, 1, 15, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "over the end of the week",
+			now:  time.Date(2026, 1, 18, 8, 0, 0, 0, denver), // Sunday
+			want: time.Date(2026, 1, 19, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "over the end of the year",
+			now:  time.Date(2026, 12, 31, 8, 0, 0, 0, denver),
+			want: time.Date(2027, 1, 1, 7, 0, 0, 0, denver),
+		},
+		{
+			name: "in the user's timezone",
+			now:  time.Date(2026, 1, 14, 13, 30, 0, 0, time.UTC), // 6:30am in Denver
+			want: time.Date(2026, 1, 14, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day the clocks go forward",
+			now:  time.Date(2026, 3, 7, 8, 0, 0, 0, denver),
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC), // 7am MDT
+		},
+		{
+			name: "day the clocks go back",
+			now:  time.Date(2026, 10, 31, 8, 0, 0, 0, denver),
+			want: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC), // 7am MST
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDigest(tt.now, denver, p); !got.Equal(tt.want) {
+				t.Errorf("nextDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestPeriod(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, denver)
+	}
+	tests := []struct {
+		name      string
+		now       time.Time
+		frequency database.DigestFrequency
+		start     time.Time
+		end       time.Time
+	}{
+		{
+			name:      "daily after the digest time",
+			now:       time.Date(2026, 1, 14, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(1, 13),
+			end:       day(1, 14),
+		},
+		{
+			name:      "daily at the digest time",
+			now:       time.Date(2026, 1, 14, 7, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(1, 13),
+			end:       day(1, 14),
+		},
+		{
+			name:      "daily before the digest time",
+			now:       time.Date(2026, 1, 14, 6, 59, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(1, 12),
+			end:       day(1, 13),
+		},
+		{
+			name:      "daily before the digest time on new year's day",
+			now:       time.Date(2026, 1, 1, 6, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     time.Date(2025, 12, 30, 0, 0, 0, 0, denver),
+			end:       time.Date(2025, 12, 31, 0, 0, 0, 0, denver),
+		},
+		{
+			name:      "daily the day the clocks go forward",
+			now:       time.Date(2026, 3, 9, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(3, 8),
+			end:       day(3, 9),
+		},
+		{
+			name:      "daily the day the clocks go back",
+			now:       time.Date(2026, 11, 2, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(11, 1),
+			end:       day(11, 2),
+		},
+		{
+			name:      "weekly on Monday after the digest time",
+			now:       time.Date(2026, 1, 19, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(1, 12),
+			end:       day(1, 19),
+		},
+		{
+			name:      "weekly on Monday before the digest time",
+			now:       time.Date(2026, 1, 19, 6, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(1, 5),
+			end:       day(1, 12),
+		},
+		{
+			name:      "weekly late on Sunday",
+			now:       time.Date(2026, 1, 18, 23, 59, 0, 0, denver),
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(1, 5),
+			end:       day(1, 12),
+		},
+		{
+			name:      "weekly Monday judged in the user's timezone",
+			now:       time.Date(2026, 1, 19, 10, 0, 0, 0, time.UTC), // 3am Monday in Denver
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(1, 5),
+			end:       day(1, 12),
+		},
+		{
+			name:      "weekly over the end of the year",
+			now:       time.Date(2026, 1, 5, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyWeekly,
+			start:     time.Date(2025, 12, 29, 0, 0, 0, 0, denver),
+			end:       day(1, 5),
+		},
+		{
+			name:      "weekly over the clocks going forward",
+			now:       time.Date(2026, 3, 9, 8, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(3, 2),
+			end:       day(3, 9),
+		},
+		// ticks missed while the notifier was down only send the latest
+		// period, not every one that was missed
+		{
+			name:      "daily after days of missed ticks",
+			now:       time.Date(2026, 1, 20, 12, 0, 0, 0, denver),
+			frequency: database.DigestFrequencyDaily,
+			start:     day(1, 19),
+			end:       day(1, 20),
+		},
+		{
+			name:      "weekly after missing Monday",
+			now:       time.Date(2026, 1, 22, 12, 0, 0, 0, denver), // Thursday
+			frequency: database.DigestFrequencyWeekly,
+			start:     day(1, 12),
+			end:       day(1, 19),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := digestPeriod(tt.now, denver, timeOfDay(7, 0), tt.frequency)
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("digestPeriod() = %v to %v, want %v to %v", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	date := func(month time.Month, d int) pgtype.Date {
+		return pgtype.Date{Time: time.Date(2026, month, d, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, denver)
+	tests := []struct {
+		name string
+		due  pgtype.Date
+		now  time.Time
+		want bool
+	}{
+		{"due yesterday", date(1, 14), now, true},
+		{"due today", date(1, 15), now, false},
+		{"due tomorrow", date(1, 16), now, false},
+		{"due weeks ago, after missed ticks", date(1, 1), now, true},
+		{"due today in the user's timezone, though tomorrow has begun in UTC", date(1, 15), time.Date(2026, 1, 16, 3, 0, 0, 0, time.UTC), false},
+		{"due yesterday just after local midnight", date(1, 14), time.Date(2026, 1, 15, 0, 0, 0, 0, denver), true},
+		{"due yesterday, the day the clocks went forward", date(3, 8), time.Date(2026, 3, 9, 0, 30, 0, 0, denver), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overdue(tt.due, tt.now, denver); got != tt.want {
+				t.Errorf("overdue(%s) = %v, want %v", tt.due.Time.Format(time.DateOnly), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+-- Safety Digests Table --
+-- name: ListSafetyDigestSubscribers :many
+-- Users who get summary emails, with the start of the latest period they
+-- were sent
+SELECT
+  u.id AS user_id,
+  u.email,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email)::text AS user_name,
+  u.role,
+  COALESCE(u.timezone, '')::text AS timezone,
+  np.safety_digest,
+  np.digest_time,
+  last.period_start AS last_period_start
+FROM notification_preferences np
+JOIN users u ON u.id = np.user_id AND u.is_active
+LEFT JOIN LATERAL (
+  SELECT d.period_start FROM safety_digests d
+  WHERE d.user_id = u.id AND d.frequency = np.safety_digest AND d.sent_at IS NOT NULL
+  ORDER BY d.period_start DESC
+  LIMIT 1
+) last ON TRUE
+WHERE np.safety_digest <> 'off';
+
+-- name: ClaimSafetyDigest :one
+-- Returns no row when the period was already sent, is being sent by another
+-- instance, or has run out of attempts
+INSERT INTO safety_digests (user_id, frequency, period_start, period_end)
+VALUES (@user_id, @frequency, @period_start, @period_end)
+ON CONFLICT (user_id, frequency, period_start) DO UPDATE
+SET attempts = safety_digests.attempts + 1,
+    claimed_at = CURRENT_TIMESTAMP
+WHERE safety_digests.sent_at IS NULL
+  AND safety_digests.claimed_at < CURRENT_TIMESTAMP - make_interval(secs => @retry_seconds::float8)
+  AND safety_digests.attempts < @max_attempts::int
+RETURNING *;
+
+-- name: RecordSafetyDigest :exec
+UPDATE safety_digests
+SET sent_at = sqlc.narg(sent_at),
+    error = @error
+WHERE user_id = @user_id AND frequency = @frequency AND period_start = @period_start;
+
+-- name: ListDigestProjects :many
+-- Projects in a user's summary, with the violations found and the
+-- compliance score at each end of the period. Projects without history from
+-- before the period started have no change to report. Admins see every
+-- project; others see the projects they inspect.
+SELECT
+  p.id,
+  p.name,
+  p.compliance_score,
+  (start_score.compliance_score IS NOT NULL)::bool AS has_history,
+  COALESCE(start_score.compliance_score, p.compliance_score)::float8 AS start_compliance,
+  COALESCE(end_score.compliance_score, p.compliance_score)::float8 AS end_compliance,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'critical') AS critical_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'high') AS high_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'medium') AS medium_count,
+  COUNT(v.id) FILTER (WHERE v.risk_level = 'low') AS low_count
+FROM projects p
+LEFT JOIN violations v ON v.project_id = p.id
+  AND v.created_at >= @period_start AND v.created_at < @period_end
+LEFT JOIN LATERAL (
+  SELECT h.compliance_score FROM project_compliance_history h
+  WHERE h.project_id = p.id AND h.recorded_at < @period_start
+  ORDER BY h.recorded_at DESC
+  LIMIT 1
+) start_score ON TRUE
+LEFT JOIN LATERAL (
+  SELECT h.compliance_score FROM project_compliance_history h
+  WHERE h.project_id = p.id AND h.recorded_at < @period_end
+  ORDER BY h.recorded_at DESC
+  LIMIT 1
+) end_score ON TRUE
+WHERE p.status <> 'archived'
+  AND (@all_projects::bool OR p.inspector_id = @user_id)
+GROUP BY p.id, start_score.compliance_score, end_score.compliance_score
+ORDER BY p.name;
+
+-- name: ListDigestOverdueActions :many
+-- Unresolved violations past their due date on the projects in a user's
+-- summary or assigned to them, most overdue first
+SELECT
+  v.id,
+  v.project_id,
+  v.description,
+  v.risk_level,
+  v.due_date,
+  v.assigned_subcontractor,
+  p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.status IN ('open', 'validated')
+  AND v.due_date < @today::date
+  AND p.status <> 'archived'
+  AND (@all_projects::bool OR p.inspector_id = @user_id OR v.assigned_user_id = @user_id)
+ORDER BY v.due_date, v.id
+LIMIT @max_actions;
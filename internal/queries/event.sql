@@ -0,0 +1,3 @@
+-- Project Events --
+-- name: NotifyProjectEvent :exec
+SELECT pg_notify('project_events', sqlc.arg(payload)::text);
@@ -0,0 +1,8 @@
+-- Job Queues --
+-- name: GetQueueDepths :one
+-- Work waiting for the background workers
+SELECT
+    (SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')::bigint AS webhook_deliveries,
+    (SELECT COUNT(*) FROM notifications WHERE email_status = 'pending')::bigint AS notification_emails,
+    (SELECT COUNT(*) FROM photos
+     WHERE purpose = 'inspection' AND analyzed_at IS NULL AND duplicate_of IS NULL)::bigint AS unanalyzed_photos;
@@ -0,0 +1,204 @@
+-- Notification Preferences Table --
+-- name: GetNotificationPreferences :one
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (
+  user_id,
+  critical_violations,
+  overdue_actions,
+  digest_time,
+  quiet_hours_start,
+  quiet_hours_end,
+  safety_digest
+) VALUES (
+  @user_id, @critical_violations, @overdue_actions, @digest_time,
+  sqlc.narg(quiet_hours_start), sqlc.narg(quiet_hours_end), @safety_digest
+)
+ON CONFLICT (user_id) DO UPDATE
+SET critical_violations = EXCLUDED.critical_violations,
+    overdue_actions = EXCLUDED.overdue_actions,
+    digest_time = EXCLUDED.digest_time,
+    quiet_hours_start = EXCLUDED.quiet_hours_start,
+    quiet_hours_end = EXCLUDED.quiet_hours_end,
+    safety_digest = EXCLUDED.safety_digest,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- Notifications Table --
+-- name: ListCriticalViolationAlerts :many
+-- Critical violations found since the given time that a project owner hasn't
+-- been alerted about
+SELECT
+  v.id AS violation_id,
+  v.project_id,
+  v.description,
+  v.regulation,
+  v.found_at,
+  p.name AS project_name,
+  u.id AS user_id,
+  COALESCE(u.timezone, '')::text AS timezone
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.id = p.inspector_id AND u.is_active
+WHERE v.risk_level = 'critical'
+  AND v.status IN ('open', 'validated')
+  AND v.created_at > @since
+  AND NOT EXISTS (
+    SELECT 1 FROM notifications n
+    WHERE n.user_id = u.id AND n.kind = 'critical_violation' AND n.violation_id = v.id
+  )
+ORDER BY v.created_at;
+
+-- name: ListOverdueActionAlerts :many
+-- Unresolved violations due before the given date that the project owner or
+-- assignee hasn't been alerted about. Whether a due date has passed depends
+-- on the user's timezone, so callers pass a date far enough ahead to cover
+-- every timezone and check each row.
+SELECT
+  v.id AS violation_id,
+  v.project_id,
+  v.description,
+  v.due_date,
+  v.assigned_subcontractor,
+  p.name AS project_name,
+  u.id AS user_id,
+  COALESCE(u.timezone, '')::text AS timezone
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.is_active AND (u.id = p.inspector_id OR u.id = v.assigned_user_id)
+WHERE v.status IN ('open', 'validated')
+  AND v.due_date < @before::date
+  AND NOT EXISTS (
+    SELECT 1 FROM notifications n
+    WHERE n.user_id = u.id AND n.kind = 'overdue_action' AND n.violation_id = v.id
+  )
+ORDER BY v.due_date;
+
+-- name: CreateAlertNotification :execrows
+-- Does nothing if the user was already alerted about the violation
+INSERT INTO notifications (
+  user_id,
+  kind,
+  project_id,
+  violation_id,
+  title,
+  body,
+  link,
+  email_status,
+  email_after
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (user_id, kind, violation_id) WHERE kind IN ('critical_violation', 'overdue_action')
+DO NOTHING;
+
+-- name: ListNotificationsByUser :many
+SELECT * FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimNotificationEmails :many
+-- Due emails are pushed back by the lease while they are sent, so other
+-- instances don't send them too
+UPDATE notifications n
+SET email_after = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::float8),
+    email_attempts = n.email_attempts + 1
+FROM users u
+WHERE u.id = n.user_id
+  AND n.id IN (
+    SELECT id FROM notifications
+    WHERE email_status = 'pending' AND email_after <= CURRENT_TIMESTAMP
+    ORDER BY email_after
+    LIMIT @max_notifications
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING n.id, n.user_id, n.title, n.body, n.link, n.email_attempts, n.created_at,
+  u.email, COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email)::text AS user_name;
+
+-- name: RecordNotificationEmails :exec
+UPDATE notifications
+SET email_status = @email_status,
+    email_after = @email_after,
+    email_error = @email_error,
+    emailed_at = sqlc.narg(emailed_at)
+WHERE id = ANY(@ids::uuid[]);
+
+-- name: CreateActivityNotifications :execrows
+-- Records an in-app notification about a violation for the given users and,
+-- when watchers is set, its project's inspector and assignee. The user who
+-- caused it and inactive accounts are skipped.
+INSERT INTO notifications (
+  user_id,
+  kind,
+  project_id,
+  violation_id,
+  title,
+  body,
+  link,
+  actor_name,
+  email_status
+)
+SELECT DISTINCT
+  u.id, @kind, v.project_id, v.id, CONCAT(@title::text, ' at ', p.name), @body, @link, @actor_name, 'none'::notification_email_status
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+JOIN users u ON u.is_active
+  AND (u.id = ANY(@user_ids::uuid[])
+    OR (@watchers::bool AND (u.id = p.inspector_id OR u.id = v.assigned_user_id)))
+WHERE v.id = @violation_id
+  AND u.id IS DISTINCT FROM sqlc.narg(actor_id)::uuid;
+
+-- name: ListMentionedUsers :many
+-- Active users whose email address or username is one of the handles.
+-- Usernames that differ only in case are ambiguous, so they match no one.
+SELECT id FROM users u
+WHERE u.is_active
+  AND (LOWER(u.email) = ANY(@handles::text[])
+    OR (LOWER(u.username) = ANY(@handles::text[])
+      AND NOT EXISTS (
+        SELECT 1 FROM users o
+        WHERE LOWER(o.username) = LOWER(u.username) AND o.id <> u.id
+      )));
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: ListNotificationsPage :many
+-- A user's notifications, newest first, older than the cursor if one is given
+SELECT * FROM notifications
+WHERE user_id = @user_id
+  AND (sqlc.narg(before_created_at)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(before_created_at)::timestamptz, sqlc.narg(before_id)::uuid))
+  AND (NOT @unread_only::bool OR read_at IS NULL)
+ORDER BY created_at DESC, id DESC
+LIMIT @max_notifications;
+
+-- name: GetNotification :one
+SELECT * FROM notifications
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkNotificationRead :exec
+UPDATE notifications
+SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: DeleteExpiredNotifications :execrows
+-- Removes notifications read before read_before and any created before
+-- created_before, keeping those with an email still to send and overdue
+-- alerts for actions that are still open, which stop them being raised again
+DELETE FROM notifications n
+WHERE (n.read_at < @read_before OR n.created_at < @created_before)
+  AND n.email_status <> 'pending'
+  AND NOT (n.kind = 'overdue_action' AND EXISTS (
+    SELECT 1 FROM violations v
+    WHERE v.id = n.violation_id AND v.status IN ('open', 'validated')
+  ));
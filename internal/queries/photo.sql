@@ -0,0 +1,118 @@
+-- Photos Table --
+-- name: GetPhoto :one
+SELECT * FROM photos
+WHERE id = $1 LIMIT 1;
+
+-- name: ListPhotosByProject :many
+SELECT
+  sqlc.embed(p),
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.project_id = @project_id
+  AND (sqlc.narg(area_type)::text IS NULL OR p.area_type = sqlc.narg(area_type))
+  AND (sqlc.narg(created_from)::timestamptz IS NULL OR p.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR p.created_at < sqlc.narg(created_before))
+ORDER BY p.created_at DESC, p.id
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: ListPhotosPage :many
+SELECT
+  sqlc.embed(p),
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.project_id = @project_id
+  AND (sqlc.narg(area_type)::text IS NULL OR p.area_type = sqlc.narg(area_type))
+  AND (sqlc.narg(created_from)::timestamptz IS NULL OR p.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR p.created_at < sqlc.narg(created_before))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (p.created_at, p.id) < (@after_created_at::timestamptz, sqlc.narg(after_id)))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT @page_limit;
+
+-- name: CountPhotosByProject :one
+SELECT COUNT(*) FROM photos p
+WHERE p.project_id = @project_id
+  AND (sqlc.narg(area_type)::text IS NULL OR p.area_type = sqlc.narg(area_type))
+  AND (sqlc.narg(created_from)::timestamptz IS NULL OR p.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR p.created_at < sqlc.narg(created_before));
+
+-- name: ListUnanalyzedPhotos :many
+SELECT id FROM photos
+WHERE purpose = 'inspection' AND analyzed_at IS NULL AND duplicate_of IS NULL
+ORDER BY created_at ASC
+LIMIT $1;
+
+-- name: CreatePhoto :one
+-- A client that works offline may choose the photo's ID; otherwise one is
+-- generated
+INSERT INTO photos (
+  id,
+  project_id,
+  storage_key,
+  filename,
+  content_type,
+  size_bytes,
+  purpose,
+  uploaded_by,
+  uploaded_by_name,
+  caption,
+  area_type,
+  thumbnail_key,
+  taken_at,
+  latitude,
+  longitude,
+  orientation,
+  camera_make,
+  camera_model,
+  perceptual_hash,
+  duplicate_of
+) VALUES (
+  COALESCE(sqlc.narg(id)::uuid, uuid_generate_v4()), @project_id, @storage_key, @filename,
+  @content_type, @size_bytes, @purpose, @uploaded_by, @uploaded_by_name, @caption,
+  @area_type, @thumbnail_key, @taken_at, @latitude, @longitude, @orientation,
+  @camera_make, @camera_model, @perceptual_hash, @duplicate_of
+)
+RETURNING *;
+
+-- name: ListPhotoHashesByProject :many
+-- Original inspection photos new uploads are compared against
+SELECT id, perceptual_hash::bigint AS perceptual_hash FROM photos
+WHERE project_id = $1
+  AND purpose = 'inspection'
+  AND perceptual_hash IS NOT NULL
+  AND duplicate_of IS NULL
+ORDER BY created_at ASC;
+
+-- name: ClearPhotoDuplicate :one
+-- Resets analysis so the photo is picked up like a new upload
+UPDATE photos
+SET duplicate_of = NULL,
+    analyzed_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdatePhotoCaption :one
+UPDATE photos
+SET caption = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkPhotoAnalyzed :exec
+UPDATE photos
+SET analyzed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ListInspectionPhotoIDsByProject :many
+-- Photos hazard detection runs on, oldest first; duplicates are left out as
+-- their hazards were recorded from the original
+SELECT id FROM photos
+WHERE project_id = $1 AND purpose = 'inspection' AND duplicate_of IS NULL
+ORDER BY created_at ASC;
@@ -0,0 +1,166 @@
+-- Projects Table --
+-- name: GetProject :one
+SELECT
+  sqlc.embed(p),
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.id = $1 LIMIT 1;
+
+-- name: ListRecentProjects :many
+SELECT
+  sqlc.embed(p),
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.status <> 'archived'
+ORDER BY p.updated_at DESC
+LIMIT $1;
+
+-- name: CreateProject :one
+INSERT INTO projects (
+  name,
+  description,
+  status,
+  location,
+  inspector_id
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: UpdateProjectLocation :one
+UPDATE projects
+SET location = $2,
+    latitude = $3,
+    longitude = $4,
+    coordinates_source = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetProjectCoordinatesFromPhotos :exec
+-- Only fills in missing coordinates; entered or geocoded ones are kept
+UPDATE projects
+SET latitude = $2,
+    longitude = $3,
+    coordinates_source = 'photos'
+WHERE id = $1 AND latitude IS NULL;
+
+-- name: ListProjectPhotoLocations :many
+SELECT latitude::float8 AS latitude, longitude::float8 AS longitude
+FROM photos
+WHERE project_id = $1 AND latitude IS NOT NULL;
+
+-- name: ListMappedProjects :many
+SELECT
+  p.id,
+  p.name,
+  p.status,
+  p.location,
+  p.latitude::float8 AS latitude,
+  p.longitude::float8 AS longitude,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status IN ('open', 'validated')) AS open_violation_count
+FROM projects p
+WHERE p.latitude IS NOT NULL AND p.status <> 'archived'
+ORDER BY p.name;
+
+-- name: CountUnmappedProjects :one
+SELECT COUNT(*) FROM projects
+WHERE latitude IS NULL AND status <> 'archived';
+
+-- name: ListProjectsNear :many
+-- The bounding box narrows the scan using idx_projects_coordinates before
+-- the haversine distance is computed
+SELECT
+  sqlc.embed(p),
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count,
+  (6371 * 2 * ASIN(LEAST(1, SQRT(
+    POWER(SIN(RADIANS(p.latitude - @latitude::float8) / 2), 2) +
+    COS(RADIANS(@latitude::float8)) * COS(RADIANS(p.latitude)) *
+    POWER(SIN(RADIANS(p.longitude - @longitude::float8) / 2), 2)
+  ))))::float8 AS distance_km
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.latitude BETWEEN @min_latitude::float8 AND @max_latitude::float8
+  AND p.longitude BETWEEN @min_longitude::float8 AND @max_longitude::float8
+  AND p.status <> 'archived'
+ORDER BY distance_km
+LIMIT @max_results;
+
+-- name: TouchProject :exec
+UPDATE projects
+SET updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetDashboardStats :one
+SELECT
+  (SELECT COUNT(*) FROM projects)::int AS total_inspections,
+  (SELECT COUNT(*) FROM violations WHERE status IN ('open', 'validated', 'resolved'))::int AS violations_found,
+  (SELECT COALESCE(AVG(compliance_score), 100) FROM projects WHERE status <> 'archived')::float8 AS compliance_rate,
+  (SELECT COUNT(*) FROM projects WHERE status IN ('in-progress', 'needs-review'))::int AS active_projects;
+
+-- name: ListProjectsPage :many
+-- Keyset pagination: rows after the cursor's sort value and ID in the
+-- requested direction, with the ID breaking ties. created_at backs the
+-- "date" sort because, unlike updated_at, it doesn't move between pages.
+SELECT
+  sqlc.embed(p),
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  c.violation_count,
+  c.photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+CROSS JOIN LATERAL (
+  SELECT
+    (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+    (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+) c
+WHERE (sqlc.narg(status)::project_status IS NULL OR p.status = sqlc.narg(status))
+  AND (sqlc.narg(inspector_id)::uuid IS NULL OR p.inspector_id = sqlc.narg(inspector_id))
+  AND (sqlc.narg(created_from)::timestamptz IS NULL OR p.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR p.created_at < sqlc.narg(created_before))
+  AND (sqlc.narg(search)::text IS NULL
+    OR p.name ILIKE '%' || sqlc.narg(search) || '%'
+    OR p.location ILIKE '%' || sqlc.narg(search) || '%'
+    OR p.description ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(after_id)::uuid IS NULL OR CASE
+    WHEN @sort_by::text = 'name' AND @descending::bool THEN (p.name, p.id) < (@after_name::text, sqlc.narg(after_id))
+    WHEN @sort_by::text = 'name' THEN (p.name, p.id) > (@after_name::text, sqlc.narg(after_id))
+    WHEN @sort_by::text = 'violations' AND @descending::bool THEN (c.violation_count::float8, p.id) < (@after_number::float8, sqlc.narg(after_id))
+    WHEN @sort_by::text = 'violations' THEN (c.violation_count::float8, p.id) > (@after_number::float8, sqlc.narg(after_id))
+    WHEN @sort_by::text = 'compliance' AND @descending::bool THEN (p.compliance_score, p.id) < (@after_number::float8, sqlc.narg(after_id))
+    WHEN @sort_by::text = 'compliance' THEN (p.compliance_score, p.id) > (@after_number::float8, sqlc.narg(after_id))
+    WHEN @descending::bool THEN (p.created_at, p.id) < (@after_time::timestamptz, sqlc.narg(after_id))
+    ELSE (p.created_at, p.id) > (@after_time::timestamptz, sqlc.narg(after_id))
+  END)
+ORDER BY
+  CASE WHEN @sort_by::text = 'name' AND NOT @descending::bool THEN p.name END ASC,
+  CASE WHEN @sort_by::text = 'name' AND @descending::bool THEN p.name END DESC,
+  CASE WHEN @sort_by::text = 'violations' AND NOT @descending::bool THEN c.violation_count END ASC,
+  CASE WHEN @sort_by::text = 'violations' AND @descending::bool THEN c.violation_count END DESC,
+  CASE WHEN @sort_by::text = 'compliance' AND NOT @descending::bool THEN p.compliance_score END ASC,
+  CASE WHEN @sort_by::text = 'compliance' AND @descending::bool THEN p.compliance_score END DESC,
+  CASE WHEN @sort_by::text = 'date' AND NOT @descending::bool THEN p.created_at END ASC,
+  CASE WHEN @sort_by::text = 'date' AND @descending::bool THEN p.created_at END DESC,
+  CASE WHEN NOT @descending::bool THEN p.id END ASC,
+  CASE WHEN @descending::bool THEN p.id END DESC
+LIMIT @page_limit;
+
+-- name: ListProjectReportsPage :many
+-- Each project has a safety report generated from its current state, so the
+-- report dates from the project's last update
+SELECT p.id, p.name, p.updated_at
+FROM projects p
+WHERE (sqlc.narg(project_id)::uuid IS NULL OR p.id = sqlc.narg(project_id))
+  AND (sqlc.narg(updated_from)::timestamptz IS NULL OR p.updated_at >= sqlc.narg(updated_from))
+  AND (sqlc.narg(updated_before)::timestamptz IS NULL OR p.updated_at < sqlc.narg(updated_before))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (p.updated_at, p.id) < (@after_updated_at::timestamptz, sqlc.narg(after_id)))
+ORDER BY p.updated_at DESC, p.id DESC
+LIMIT @page_limit;
@@ -0,0 +1,46 @@
+-- Violation Regions Table --
+-- name: ListRegionsByPhoto :many
+SELECT sqlc.embed(r), v.risk_level, v.description AS violation_description
+FROM violation_regions r
+JOIN violations v ON v.id = r.violation_id
+WHERE r.photo_id = $1
+ORDER BY r.created_at ASC;
+
+-- name: ListRegionsByPhotos :many
+SELECT sqlc.embed(r), v.risk_level, v.description AS violation_description
+FROM violation_regions r
+JOIN violations v ON v.id = r.violation_id
+WHERE r.photo_id = ANY(@photo_ids::uuid[])
+ORDER BY r.created_at ASC;
+
+-- name: ListRegionsByViolation :many
+SELECT * FROM violation_regions
+WHERE violation_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetRegion :one
+SELECT * FROM violation_regions
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateRegion :one
+INSERT INTO violation_regions (
+  violation_id,
+  photo_id,
+  shape,
+  x,
+  y,
+  width,
+  height,
+  points,
+  source,
+  confidence,
+  user_id,
+  user_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: DeleteRegion :exec
+DELETE FROM violation_regions
+WHERE id = $1;
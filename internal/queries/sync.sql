@@ -0,0 +1,133 @@
+-- Sync Changes Table --
+-- name: GetSyncSnapshot :one
+SELECT pg_current_snapshot()::text AS snapshot;
+
+-- name: ListSyncChanges :many
+-- Changes committed after the since snapshot was taken and before the until
+-- snapshot was. Rows up to the after sequence number went out in an earlier
+-- page of the same pull.
+SELECT seq, entity, entity_id, project_id, deleted
+FROM sync_changes
+WHERE txid >= pg_snapshot_xmin(sqlc.arg(since)::text::pg_snapshot)
+  AND NOT pg_visible_in_snapshot(txid, sqlc.arg(since)::text::pg_snapshot)
+  AND pg_visible_in_snapshot(txid, sqlc.arg(until)::text::pg_snapshot)
+  AND seq > @after_seq
+  AND (sqlc.narg(project_id)::uuid IS NULL OR project_id = sqlc.narg(project_id))
+ORDER BY seq
+LIMIT @max_changes;
+
+-- name: SyncSnapshotExpired :one
+-- Whether changes the snapshot hadn't seen may have been pruned. Pruning
+-- removes whole transactions from the oldest up, so everything the snapshot
+-- is missing is still kept unless the oldest kept change is newer than the
+-- snapshot's xmin.
+SELECT COALESCE(
+  (SELECT txid FROM sync_changes ORDER BY txid LIMIT 1) > pg_snapshot_xmin(sqlc.arg(since)::text::pg_snapshot),
+  false
+)::boolean AS expired;
+
+-- name: PruneSyncChanges :execrows
+-- Deletes the transactions that made changes before the cutoff, oldest
+-- first. The newest change is always kept, so the oldest change left shows
+-- how far back the feed reaches.
+DELETE FROM sync_changes
+WHERE txid < (
+  SELECT kept.txid FROM sync_changes kept
+  WHERE kept.changed_at >= @before
+     OR kept.seq = (SELECT max(newest.seq) FROM sync_changes newest)
+  ORDER BY kept.txid
+  LIMIT 1
+);
+
+-- name: ListSyncRecords :many
+-- Every synced record, for clients starting over. Records up to the after
+-- entity and ID went out in an earlier page.
+SELECT entity, entity_id
+FROM (
+  SELECT 'project'::sync_entity AS entity, id AS entity_id, id AS project_id FROM projects
+  UNION ALL
+  SELECT 'photo'::sync_entity, id, project_id FROM photos
+  UNION ALL
+  SELECT 'violation'::sync_entity, id, project_id FROM violations
+) records
+WHERE (sqlc.narg(after_entity)::sync_entity IS NULL
+       OR (entity, entity_id) > (sqlc.narg(after_entity)::sync_entity, sqlc.narg(after_id)::uuid))
+  AND (sqlc.narg(project_id)::uuid IS NULL OR project_id = sqlc.narg(project_id))
+ORDER BY entity, entity_id
+LIMIT @max_records;
+
+-- name: ListProjectsByIDs :many
+SELECT
+  sqlc.embed(p),
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS inspector_name,
+  (SELECT COUNT(*) FROM violations v WHERE v.project_id = p.id AND v.status <> 'dismissed') AS violation_count,
+  (SELECT COUNT(*) FROM photos ph WHERE ph.project_id = p.id) AS photo_count
+FROM projects p
+LEFT JOIN users u ON u.id = p.inspector_id
+WHERE p.id = ANY(@ids::uuid[]);
+
+-- name: ListPhotosByIDs :many
+SELECT
+  sqlc.embed(p),
+  ARRAY(
+    SELECT v.id FROM violations v
+    WHERE v.photo_id = p.id AND v.status <> 'dismissed'
+    ORDER BY v.created_at
+  )::uuid[] AS violation_ids
+FROM photos p
+WHERE p.id = ANY(@ids::uuid[]);
+
+-- name: ListViolationsByIDs :many
+SELECT sqlc.embed(v), p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.id = ANY(@ids::uuid[]);
+
+-- name: CreateProjectWithID :one
+-- Replaying a create that already went through inserts nothing, so no row
+-- is returned
+INSERT INTO projects (
+  id,
+  name,
+  description,
+  status,
+  location,
+  inspector_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (id) DO NOTHING
+RETURNING *;
+
+-- name: UpdateProjectDetails :one
+-- Only succeeds if the project hasn't changed since the client last saw it.
+-- Coordinates geocoded from an address that changed are dropped rather than
+-- left pointing at the old one.
+UPDATE projects
+SET name = @name,
+    description = @description,
+    status = @status,
+    location = @location,
+    latitude = CASE WHEN location <> @location AND coordinates_source = 'geocoded' THEN NULL ELSE latitude END,
+    longitude = CASE WHEN location <> @location AND coordinates_source = 'geocoded' THEN NULL ELSE longitude END,
+    coordinates_source = CASE WHEN location <> @location AND coordinates_source = 'geocoded' THEN NULL ELSE coordinates_source END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND updated_at = @base_updated_at
+RETURNING *;
+
+-- name: UpdatePhotoDetails :one
+-- Only succeeds if the photo hasn't changed since the client last saw it
+UPDATE photos
+SET caption = @caption,
+    area_type = @area_type,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND updated_at = @base_updated_at
+RETURNING *;
+
+-- name: UpdateViolationNotes :one
+-- Only succeeds if the violation hasn't changed since the client last saw it
+UPDATE violations
+SET notes = @notes,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND updated_at = @base_updated_at
+RETURNING *;
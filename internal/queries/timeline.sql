@@ -0,0 +1,24 @@
+-- Timeline Events Table --
+-- name: ListTimelineEventsByProject :many
+SELECT * FROM timeline_events
+WHERE project_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListTimelineEventsByViolation :many
+SELECT * FROM timeline_events
+WHERE violation_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateTimelineEvent :one
+INSERT INTO timeline_events (
+  project_id,
+  violation_id,
+  type,
+  description,
+  user_id,
+  user_name,
+  metadata
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
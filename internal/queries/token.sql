@@ -0,0 +1,47 @@
+-- API Tokens Table --
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (
+  kind,
+  name,
+  token_hash,
+  token_prefix,
+  scopes,
+  user_id,
+  created_by,
+  created_by_name,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetActiveAPITokenByHash :one
+SELECT * FROM api_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1;
+
+-- name: ListAPITokens :many
+-- Service tokens and the personal tokens of one user, newest first
+SELECT * FROM api_tokens
+WHERE kind = 'service'
+   OR user_id IS NOT DISTINCT FROM sqlc.narg(user_id)::uuid
+ORDER BY created_at DESC;
+
+-- name: GetAPIToken :one
+SELECT * FROM api_tokens
+WHERE id = $1 LIMIT 1;
+
+-- name: TouchAPIToken :exec
+-- Recorded at most once a minute so busy integrations don't write on every
+-- request
+UPDATE api_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
+
+-- name: RevokeAPIToken :exec
+UPDATE api_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
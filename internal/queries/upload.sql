@@ -0,0 +1,73 @@
+-- Uploads Table --
+-- name: CreateUpload :one
+INSERT INTO uploads (
+  project_id,
+  upload_length,
+  filename,
+  area_type,
+  caption,
+  created_by,
+  created_by_name,
+  requested_photo_id,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetUpload :one
+SELECT * FROM uploads
+WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP
+LIMIT 1;
+
+-- name: AdvanceUpload :one
+-- Only succeeds if no other request has moved the offset since it was read
+UPDATE uploads
+SET upload_offset = upload_offset + @size_bytes,
+    updated_at = CURRENT_TIMESTAMP,
+    expires_at = @expires_at
+WHERE id = @id
+  AND upload_offset = @expected_offset
+  AND completed_at IS NULL
+RETURNING *;
+
+-- name: CompleteUpload :one
+UPDATE uploads
+SET upload_offset = upload_length,
+    photo_id = @photo_id,
+    updated_at = CURRENT_TIMESTAMP,
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = @id
+  AND upload_offset = @expected_offset
+  AND completed_at IS NULL
+RETURNING *;
+
+-- name: DeleteUpload :exec
+DELETE FROM uploads
+WHERE id = $1;
+
+-- name: ListExpiredUploads :many
+SELECT id FROM uploads
+WHERE expires_at <= CURRENT_TIMESTAMP
+ORDER BY expires_at
+LIMIT $1;
+
+-- Upload Chunks Table --
+-- name: CreateUploadChunk :exec
+INSERT INTO upload_chunks (
+  upload_id,
+  chunk_offset,
+  size_bytes,
+  storage_key
+) VALUES (
+  $1, $2, $3, $4
+);
+
+-- name: ListUploadChunks :many
+SELECT * FROM upload_chunks
+WHERE upload_id = $1
+ORDER BY chunk_offset;
+
+-- name: DeleteUploadChunks :exec
+DELETE FROM upload_chunks
+WHERE upload_id = $1;
@@ -15,6 +15,14 @@ WHERE username = $1 LIMIT 1;
 SELECT * FROM users
 ORDER BY created_at DESC;
 
+-- name: ListUsersPage :many
+SELECT * FROM users
+WHERE (sqlc.narg(role)::user_role IS NULL OR role = sqlc.narg(role))
+  AND (sqlc.narg(is_active)::bool IS NULL OR is_active = sqlc.narg(is_active))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (created_at, id) < (@after_created_at::timestamptz, sqlc.narg(after_id)))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit;
+
 -- name: ListActiveUsers :many
 SELECT * FROM users
 WHERE is_active = true
@@ -0,0 +1,153 @@
+-- Violations Table --
+-- name: GetViolation :one
+SELECT
+  sqlc.embed(v),
+  p.name AS project_name,
+  COALESCE(NULLIF(CONCAT_WS(' ', u.first_name, u.last_name), ''), u.email, '')::text AS assignee_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+LEFT JOIN users u ON u.id = v.assigned_user_id
+WHERE v.id = $1 LIMIT 1;
+
+-- name: ListViolationsByProject :many
+SELECT sqlc.embed(v), p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.project_id = $1
+ORDER BY v.risk_level DESC, v.found_at DESC;
+
+-- name: ListCriticalViolations :many
+SELECT sqlc.embed(v), p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE v.risk_level IN ('critical', 'high')
+  AND v.status IN ('open', 'validated')
+ORDER BY v.risk_level DESC, v.found_at DESC
+LIMIT $1;
+
+-- name: ListOpenViolationHotspots :many
+-- Open violations grouped into cells of about 100 m, placed where their
+-- photo was taken or, failing that, at their project
+SELECT
+  ROUND(COALESCE(ph.latitude, p.latitude)::numeric, 3)::float8 AS latitude,
+  ROUND(COALESCE(ph.longitude, p.longitude)::numeric, 3)::float8 AS longitude,
+  COUNT(*) AS violation_count,
+  COUNT(*) FILTER (WHERE v.risk_level IN ('high', 'critical')) AS severe_count
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+LEFT JOIN photos ph ON ph.id = v.photo_id
+WHERE v.status IN ('open', 'validated')
+  AND p.status <> 'archived'
+  AND COALESCE(ph.latitude, p.latitude) IS NOT NULL
+GROUP BY 1, 2
+ORDER BY violation_count DESC
+LIMIT $1;
+
+-- name: ListViolationsByPhoto :many
+SELECT * FROM violations
+WHERE photo_id = $1
+ORDER BY found_at ASC;
+
+-- name: DeleteOpenDetectedViolationsByPhoto :exec
+DELETE FROM violations
+WHERE photo_id = $1 AND status = 'open' AND ai_confidence > 0;
+
+-- name: ListViolationsForUpdate :many
+SELECT * FROM violations
+WHERE project_id = @project_id AND id = ANY(@ids::uuid[])
+FOR UPDATE;
+
+-- name: CreateViolation :one
+INSERT INTO violations (
+  project_id,
+  description,
+  regulation,
+  risk_level,
+  category,
+  location,
+  notes,
+  ai_confidence,
+  photo_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: UpdateViolationsStatus :exec
+UPDATE violations
+SET
+  status = @status,
+  resolved_at = CASE WHEN @status = 'resolved'::violation_status THEN CURRENT_TIMESTAMP ELSE NULL END,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ANY(@ids::uuid[]);
+
+-- name: UpdateViolationAssignment :exec
+UPDATE violations
+SET
+  assigned_user_id = $2,
+  assigned_subcontractor = $3,
+  due_date = $4,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- Violation Comments Table --
+-- name: ListViolationComments :many
+SELECT * FROM violation_comments
+WHERE violation_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateViolationComment :one
+INSERT INTO violation_comments (
+  violation_id,
+  user_id,
+  user_name,
+  body
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- Violation Resolutions Table --
+-- name: GetLatestViolationResolution :one
+SELECT * FROM violation_resolutions
+WHERE violation_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListAcceptedResolutionsByProject :many
+SELECT DISTINCT ON (r.violation_id) r.*
+FROM violation_resolutions r
+JOIN violations v ON v.id = r.violation_id
+WHERE v.project_id = $1 AND r.accepted
+ORDER BY r.violation_id, r.created_at DESC;
+
+-- name: CreateViolationResolution :one
+INSERT INTO violation_resolutions (
+  violation_id,
+  photo_id,
+  note,
+  verification,
+  verification_confidence,
+  verification_detail,
+  accepted,
+  user_id,
+  user_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: ListViolationsPage :many
+SELECT sqlc.embed(v), p.name AS project_name
+FROM violations v
+JOIN projects p ON p.id = v.project_id
+WHERE (sqlc.narg(project_id)::uuid IS NULL OR v.project_id = sqlc.narg(project_id))
+  AND (sqlc.narg(status)::violation_status IS NULL OR v.status = sqlc.narg(status))
+  AND (sqlc.narg(risk_level)::risk_level IS NULL OR v.risk_level = sqlc.narg(risk_level))
+  AND (sqlc.narg(category)::text IS NULL OR v.category = sqlc.narg(category))
+  AND (sqlc.narg(found_from)::timestamptz IS NULL OR v.found_at >= sqlc.narg(found_from))
+  AND (sqlc.narg(found_before)::timestamptz IS NULL OR v.found_at < sqlc.narg(found_before))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (v.found_at, v.id) < (@after_found_at::timestamptz, sqlc.narg(after_id)))
+ORDER BY v.found_at DESC, v.id DESC
+LIMIT @page_limit;
+
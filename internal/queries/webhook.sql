@@ -0,0 +1,113 @@
+-- Webhook Subscriptions Table --
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  name,
+  url,
+  secret,
+  event_types,
+  created_by,
+  created_by_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT
+  sqlc.embed(s),
+  COALESCE(d.status::text, '')::text AS last_status,
+  d.last_attempt_at AS last_attempt_at
+FROM webhook_subscriptions s
+LEFT JOIN LATERAL (
+  SELECT status, last_attempt_at FROM webhook_deliveries
+  WHERE subscription_id = s.id AND last_attempt_at IS NOT NULL
+  ORDER BY last_attempt_at DESC
+  LIMIT 1
+) d ON TRUE
+ORDER BY s.created_at DESC;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET name = $2,
+    url = $3,
+    event_types = $4,
+    active = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- Webhook Deliveries Table --
+-- name: PublishWebhookEvent :execrows
+-- Queues an event for every active subscription to its type
+WITH event AS (
+  SELECT uuid_generate_v4() AS id, CURRENT_TIMESTAMP AS occurred_at
+)
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data)
+SELECT s.id, event.id, @event_type, event.occurred_at, @data
+FROM webhook_subscriptions s, event
+WHERE s.active AND @event_type::text = ANY(s.event_types);
+
+-- name: CreateWebhookDelivery :one
+-- Queues an event for one subscription regardless of its filters
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data)
+VALUES (@subscription_id, uuid_generate_v4(), @event_type, CURRENT_TIMESTAMP, @data)
+RETURNING *;
+
+-- name: RedeliverWebhook :one
+-- Queues a fresh delivery of the same event
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, occurred_at, data, redelivery_of)
+SELECT subscription_id, event_id, event_type, occurred_at, data, id
+FROM webhook_deliveries
+WHERE webhook_deliveries.id = $1
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimWebhookDeliveries :many
+-- Due deliveries are pushed back by the lease while they are sent, so other
+-- instances don't send them too. A delivery whose sender died is retried
+-- once the lease runs out.
+UPDATE webhook_deliveries d
+SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::float8)
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id
+  AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at
+    LIMIT @max_deliveries
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event_id, d.event_type, d.occurred_at, d.data, d.attempts, s.url, s.secret;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    status = @status,
+    last_attempt_at = CURRENT_TIMESTAMP,
+    next_attempt_at = @next_attempt_at,
+    response_status = sqlc.narg(response_status),
+    response_body = @response_body,
+    error = @error,
+    duration_ms = @duration_ms
+WHERE id = @id;
+
+-- name: PurgeWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND created_at < @before;
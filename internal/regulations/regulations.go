@@ -0,0 +1,115 @@
+package regulations
+
+import (
+	"strings"
+)
+
+// Regulation is an OSHA construction standard (29 CFR 1926) referenced by
+// violations
+type Regulation struct {
+	Number  string // "1926.95"
+	Title   string // "Criteria for personal protective equipment"
+	Summary string // Plain-language summary of what the standard requires
+	URL     string // Link to the full text on osha.gov
+}
+
+// standards holds the construction standards most often cited on site
+var standards = map[string]Regulation{
+	"1926.20": {
+		Title:   "General safety and health provisions",
+		Summary: "Employers must run an accident prevention program with frequent and regular inspections of job sites, materials and equipment by competent persons.",
+	},
+	"1926.21": {
+		Title:   "Safety training and education",
+		Summary: "Employees must be instructed to recognize and avoid unsafe conditions and know the regulations that apply to their work environment.",
+	},
+	"1926.25": {
+		Title:   "Housekeeping",
+		Summary: "Form and scrap lumber, debris and waste must be kept cleared from work areas, passageways and stairs.",
+	},
+	"1926.28": {
+		Title:   "Personal protective equipment",
+		Summary: "Employees must wear appropriate personal protective equipment wherever there is exposure to hazardous conditions.",
+	},
+	"1926.95": {
+		Title:   "Criteria for personal protective equipment",
+		Summary: "Protective equipment must be provided, used and maintained in a sanitary and reliable condition wherever hazards can cause injury through absorption, inhalation or physical contact.",
+	},
+	"1926.100": {
+		Title:   "Head protection",
+		Summary: "Employees working where there is a possible danger of head injury from impact, falling or flying objects, or electrical shock must wear protective helmets.",
+	},
+	"1926.102": {
+		Title:   "Eye and face protection",
+		Summary: "Eye and face protection is required when machines or operations present potential eye or face injury from physical, chemical or radiation agents.",
+	},
+	"1926.150": {
+		Title:   "Fire protection",
+		Summary: "A fire protection program must be followed throughout all phases of construction, with firefighting equipment conspicuously located and maintained.",
+	},
+	"1926.405": {
+		Title:   "Wiring methods, components, and equipment for general use",
+		Summary: "Temporary wiring, boxes, cords and equipment must be installed and protected so that live parts are guarded and conductors are not damaged.",
+	},
+	"1926.416": {
+		Title:   "Electrical safety-related work practices",
+		Summary: "Employees must not work near electric power circuits unless protected by de-energizing and grounding the circuit or by guarding it effectively.",
+	},
+	"1926.417": {
+		Title:   "Lockout and tagging of circuits",
+		Summary: "Controls that are deactivated during work on energized or de-energized equipment must be tagged, and de-energized equipment must be locked out.",
+	},
+	"1926.451": {
+		Title:   "General requirements for scaffolds",
+		Summary: "Scaffolds must be designed by a qualified person, fully planked, able to support four times the intended load and fitted with guardrails or personal fall arrest above 10 feet.",
+	},
+	"1926.501": {
+		Title:   "Duty to have fall protection",
+		Summary: "Employees on walking or working surfaces with an unprotected side or edge 6 feet or more above a lower level must be protected by guardrails, safety nets or personal fall arrest.",
+	},
+	"1926.502": {
+		Title:   "Fall protection systems criteria and practices",
+		Summary: "Guardrails, safety nets, personal fall arrest systems and covers must meet strength, height and installation requirements.",
+	},
+	"1926.503": {
+		Title:   "Fall protection training requirements",
+		Summary: "Employees exposed to fall hazards must be trained by a competent person to recognize the hazards and follow procedures to minimize them.",
+	},
+	"1926.651": {
+		Title:   "Specific excavation requirements",
+		Summary: "Excavations must have safe means of egress, protection from falling loads and materials, warning systems for mobile equipment and daily inspections by a competent person.",
+	},
+	"1926.652": {
+		Title:   "Requirements for protective systems",
+		Summary: "Employees in an excavation 5 feet or deeper must be protected from cave-ins by sloping, benching, shoring or shielding.",
+	},
+	"1926.1052": {
+		Title:   "Stairways",
+		Summary: "Stairways with four or more risers or rising more than 30 inches must have at least one handrail, and open sides must be guarded by stair rails.",
+	},
+	"1926.1053": {
+		Title:   "Ladders",
+		Summary: "Ladders must be used at the proper angle, extend 3 feet above the upper landing, be kept free of defects and only be used for their designed purpose.",
+	},
+}
+
+// Lookup returns the standard for a regulation number such as "1926.95" or
+// "1926.501(b)(1)". Unknown numbers still return a Regulation with a link to
+// the full text; ok reports whether a title and summary are available.
+func Lookup(number string) (reg Regulation, ok bool) {
+	number = strings.TrimSpace(number)
+	number = strings.TrimPrefix(number, "29 CFR ")
+	if i := strings.IndexByte(number, '('); i >= 0 {
+		number = number[:i]
+	}
+	if number == "" {
+		return Regulation{}, false
+	}
+
+	reg, ok = standards[number]
+	reg.Number = number
+	if part, _, found := strings.Cut(number, "."); found {
+		reg.URL = "https://www.osha.gov/laws-regs/regulations/standardnumber/" + part + "/" + number
+	}
+	return reg, ok
+}
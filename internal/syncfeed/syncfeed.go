@@ -0,0 +1,57 @@
+package syncfeed
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Prune settings
+const (
+	pruneInterval = time.Hour           // Time between sweeps for old changes
+	retention     = 90 * 24 * time.Hour // Age after which changes are deleted
+)
+
+// Pruner deletes old entries from the change feed offline clients pull
+// from. A client whose cursor is older than the oldest change kept gets
+// every record again instead.
+type Pruner struct {
+	q *database.Queries
+}
+
+// New returns a Pruner
+func New(db *pgxpool.Pool) *Pruner {
+	return &Pruner{q: database.New(db)}
+}
+
+// Run prunes the feed every pruneInterval until ctx is cancelled
+func (p *Pruner) Run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		p.prune(ctx, logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pruner) prune(ctx context.Context, logger *slog.Logger) {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true}
+	n, err := p.q.PruneSyncChanges(ctx, before)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to prune sync changes", "error", err)
+		}
+		return
+	}
+	if n > 0 {
+		logger.Info("pruned sync changes", "count", n)
+	}
+}
@@ -0,0 +1,145 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scopes a token can be granted. Each JSON API endpoint requires one or
+// more of them.
+const (
+	ReadProjects    = "read:projects"
+	WriteProjects   = "write:projects"
+	ReadPhotos      = "read:photos"
+	WritePhotos     = "write:photos"
+	ReadViolations  = "read:violations"
+	WriteViolations = "write:violations"
+	ReadReports     = "read:reports"
+	ReadUsers       = "read:users"
+)
+
+// Scopes lists every scope in the order they are offered
+var Scopes = []string{
+	ReadProjects, WriteProjects,
+	ReadPhotos, WritePhotos,
+	ReadViolations, WriteViolations,
+	ReadReports,
+	ReadUsers,
+}
+
+// Token prefixes, which tell the two kinds apart at a glance and make
+// leaked tokens easy to find with secret scanners
+const (
+	personalPrefix = "ssi_pat_"
+	servicePrefix  = "ssi_svc_"
+)
+
+// shownPrefix is how many characters of a token are kept to identify it in
+// lists
+const shownPrefix = 12
+
+var (
+	// ErrInvalid is returned for tokens that don't exist, have expired or
+	// were revoked
+	ErrInvalid = errors.New("tokens: invalid token")
+	// ErrNotFound is returned when revoking a token that doesn't exist
+	ErrNotFound = errors.New("tokens: token not found")
+	// ErrScope is returned when creating a token with an unknown scope
+	ErrScope = errors.New("tokens: unknown scope")
+)
+
+// Manager issues and checks API tokens. Only a hash of each token is
+// stored, so a token can't be recovered once Create has returned it.
+type Manager struct {
+	q *database.Queries
+}
+
+// New returns a Manager. db is normally a *pgxpool.Pool.
+func New(db database.DBTX) *Manager {
+	return &Manager{q: database.New(db)}
+}
+
+// Create issues a token and returns it along with the secret the caller
+// presents as a bearer token. The hash and prefix in params are filled in.
+func (m *Manager) Create(ctx context.Context, params database.CreateAPITokenParams) (database.ApiToken, string, error) {
+	for _, scope := range params.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return database.ApiToken{}, "", fmt.Errorf("%w: %q", ErrScope, scope)
+		}
+	}
+
+	prefix := personalPrefix
+	if params.Kind == database.ApiTokenKindService {
+		prefix = servicePrefix
+	}
+	secret := prefix + rand.Text()
+	params.TokenHash = hash(secret)
+	params.TokenPrefix = secret[:shownPrefix]
+
+	t, err := m.q.CreateAPIToken(ctx, params)
+	if err != nil {
+		return t, "", err
+	}
+	return t, secret, nil
+}
+
+// Authenticate returns the active token matching secret and records that
+// it was used
+func (m *Manager) Authenticate(ctx context.Context, secret string) (database.ApiToken, error) {
+	if !strings.HasPrefix(secret, personalPrefix) && !strings.HasPrefix(secret, servicePrefix) {
+		return database.ApiToken{}, ErrInvalid
+	}
+	t, err := m.q.GetActiveAPITokenByHash(ctx, hash(secret))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrInvalid
+	}
+	if err != nil {
+		return t, err
+	}
+	if err := m.q.TouchAPIToken(ctx, t.ID); err != nil {
+		return t, fmt.Errorf("recording token use: %w", err)
+	}
+	return t, nil
+}
+
+// List returns the service tokens and the personal tokens of userID,
+// including expired and revoked ones
+func (m *Manager) List(ctx context.Context, userID pgtype.UUID) ([]database.ApiToken, error) {
+	return m.q.ListAPITokens(ctx, userID)
+}
+
+// Get returns a token by ID
+func (m *Manager) Get(ctx context.Context, id pgtype.UUID) (database.ApiToken, error) {
+	t, err := m.q.GetAPIToken(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// Revoke stops a token from being accepted. Revoking a token twice is not
+// an error.
+func (m *Manager) Revoke(ctx context.Context, id pgtype.UUID) error {
+	return m.q.RevokeAPIToken(ctx, id)
+}
+
+// HasScope reports whether t was granted scope
+func HasScope(t database.ApiToken, scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// hash returns the stored form of a token. Tokens are long and random, so
+// a fast unsalted hash is enough.
+func hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
@@ -0,0 +1,68 @@
+// Package tracing sets up OpenTelemetry tracing: spans are exported over
+// OTLP and trace context is propagated with W3C traceparent headers. It also
+// traces database queries, blob store operations and hazard detection.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer the app's spans are created with
+const instrumentation = "github.com/dukerupert/ironman"
+
+// Tracer returns the tracer for the app's spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs the W3C trace context propagator and, when endpoint is set,
+// a tracer provider exporting to the OTLP/HTTP collector at endpoint, such
+// as http://localhost:4318. Without an endpoint no spans are recorded, but
+// trace context is still passed on. The returned function flushes pending
+// spans and must be called before exiting.
+func Setup(ctx context.Context, endpoint, serviceName string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+	tp := Install(exporter, serviceName, sampleRatio)
+	return tp.Shutdown, nil
+}
+
+// Install makes exporter the destination of the app's spans and returns the
+// provider, whose ForceFlush sends any still buffered. Tests can pass an
+// in-memory exporter from the SDK's tracetest package. Traces started
+// elsewhere are sampled as their caller decided; new ones at sampleRatio.
+func Install(exporter sdktrace.SpanExporter, serviceName string, sampleRatio float64) *sdktrace.TracerProvider {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp
+}
+
+// End records err, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"io"
+
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/detector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Store returns s with a span recorded for each operation
+func Store(s blob.Store) blob.Store {
+	return tracedStore{s}
+}
+
+type tracedStore struct {
+	blob.Store
+}
+
+func (s tracedStore) Put(ctx context.Context, key string, r io.Reader) error {
+	ctx, span := s.start(ctx, "blob.Put", key)
+	err := s.Store.Put(ctx, key, r)
+	End(span, err)
+	return err
+}
+
+// Open records finding the blob, not reading it
+func (s tracedStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, span := s.start(ctx, "blob.Open", key)
+	rc, err := s.Store.Open(ctx, key)
+	End(span, err)
+	return rc, err
+}
+
+func (s tracedStore) Delete(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "blob.Delete", key)
+	err := s.Store.Delete(ctx, key)
+	End(span, err)
+	return err
+}
+
+func (s tracedStore) start(ctx context.Context, name, key string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attribute.String("blob.key", key)))
+}
+
+// Detector returns d with a span recorded for each detection. A nil d stays
+// nil, so callers can still tell detection is unavailable.
+func Detector(d detector.Detector) detector.Detector {
+	if d == nil {
+		return nil
+	}
+	return tracedDetector{d}
+}
+
+type tracedDetector struct {
+	detector.Detector
+}
+
+func (d tracedDetector) Detect(ctx context.Context, img detector.Image) ([]detector.Finding, error) {
+	ctx, span := Tracer().Start(ctx, "detector.Detect",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("image.content_type", img.ContentType),
+			attribute.Int("image.size", len(img.Data)),
+		),
+	)
+	findings, err := d.Detector.Detect(ctx, img)
+	span.SetAttributes(attribute.Int("detector.findings", len(findings)))
+	End(span, err)
+	return findings, err
+}
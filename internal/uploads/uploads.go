@@ -0,0 +1,249 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/blob"
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Expiry is how long an upload may sit idle before it is purged. Each chunk
+// received pushes it back.
+const Expiry = 24 * time.Hour
+
+// Purge settings
+const (
+	purgeInterval = time.Hour // Time between sweeps for expired uploads
+	purgeBatch    = 100       // Uploads purged per sweep
+)
+
+var (
+	// ErrNotFound is returned for uploads that don't exist or have expired
+	ErrNotFound = errors.New("uploads: upload not found")
+	// ErrConflict is returned when data is sent for an offset other than
+	// the upload's current one, usually because another request got there
+	// first
+	ErrConflict = errors.New("uploads: offset does not match the upload")
+	// ErrTooLong is returned when data runs past the declared length
+	ErrTooLong = errors.New("uploads: data exceeds the upload length")
+)
+
+// Manager keeps the chunks of resumable uploads in the blob store until the
+// last one arrives. Completing an upload is left to the caller, which turns
+// the assembled bytes into a photo and calls Complete in the same
+// transaction.
+type Manager struct {
+	db    *pgxpool.Pool
+	q     *database.Queries
+	store blob.Store
+}
+
+// New returns a Manager
+func New(db *pgxpool.Pool, store blob.Store) *Manager {
+	return &Manager{
+		db:    db,
+		q:     database.New(db),
+		store: store,
+	}
+}
+
+// Create starts an upload of params.UploadLength bytes
+func (m *Manager) Create(ctx context.Context, params database.CreateUploadParams) (database.Upload, error) {
+	params.ExpiresAt = expiresAt()
+	return m.q.CreateUpload(ctx, params)
+}
+
+// Get returns an upload that hasn't expired
+func (m *Manager) Get(ctx context.Context, id pgtype.UUID) (database.Upload, error) {
+	u, err := m.q.GetUpload(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+// Append stores data received at offset and returns the updated upload.
+// The data must stop short of the end of the upload; the final bytes go to
+// Assemble instead so the upload only finishes once its photo exists.
+func (m *Manager) Append(ctx context.Context, u database.Upload, offset int64, data []byte) (database.Upload, error) {
+	if offset != u.UploadOffset || u.CompletedAt.Valid {
+		return u, ErrConflict
+	}
+	if offset+int64(len(data)) >= u.UploadLength {
+		return u, ErrTooLong
+	}
+	if len(data) == 0 {
+		return u, nil
+	}
+
+	key := fmt.Sprintf("uploads/%s/%s", u.ID.String(), strings.ToLower(rand.Text()))
+	if err := m.store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return u, fmt.Errorf("store chunk: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			m.store.Delete(context.WithoutCancel(ctx), key)
+		}
+	}()
+
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return u, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	qtx := m.q.WithTx(tx)
+
+	updated, err := qtx.AdvanceUpload(ctx, database.AdvanceUploadParams{
+		ID:             u.ID,
+		ExpectedOffset: offset,
+		SizeBytes:      int64(len(data)),
+		ExpiresAt:      expiresAt(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, ErrConflict
+		}
+		return u, fmt.Errorf("advance upload: %w", err)
+	}
+	if err := qtx.CreateUploadChunk(ctx, database.CreateUploadChunkParams{
+		UploadID:    u.ID,
+		ChunkOffset: offset,
+		SizeBytes:   int64(len(data)),
+		StorageKey:  key,
+	}); err != nil {
+		return u, fmt.Errorf("record chunk: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return u, fmt.Errorf("commit chunk: %w", err)
+	}
+	committed = true
+	return updated, nil
+}
+
+// Assemble returns the whole upload: the stored chunks followed by final,
+// the bytes that complete it
+func (m *Manager) Assemble(ctx context.Context, u database.Upload, final []byte) ([]byte, error) {
+	if u.UploadOffset+int64(len(final)) != u.UploadLength {
+		return nil, ErrTooLong
+	}
+	chunks, err := m.q.ListUploadChunks(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list chunks: %w", err)
+	}
+
+	data := make([]byte, 0, u.UploadLength)
+	for _, c := range chunks {
+		if c.ChunkOffset != int64(len(data)) {
+			return nil, fmt.Errorf("chunk at %d of upload %s is out of sequence", c.ChunkOffset, u.ID.String())
+		}
+		f, err := m.store.Open(ctx, c.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("open chunk: %w", err)
+		}
+		data, err = appendAll(data, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+	}
+	if int64(len(data)) != u.UploadOffset {
+		return nil, fmt.Errorf("upload %s has %d bytes stored, expected %d", u.ID.String(), len(data), u.UploadOffset)
+	}
+	return append(data, final...), nil
+}
+
+// Complete marks an upload as finished with the photo made from it. It runs
+// in the caller's transaction; once that commits, call Discard to remove the
+// chunks.
+func (m *Manager) Complete(ctx context.Context, qtx *database.Queries, u database.Upload, photoID pgtype.UUID) (database.Upload, error) {
+	updated, err := qtx.CompleteUpload(ctx, database.CompleteUploadParams{
+		ID:             u.ID,
+		ExpectedOffset: u.UploadOffset,
+		PhotoID:        photoID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrConflict
+	}
+	return updated, err
+}
+
+// Discard removes the stored chunks of an upload, leaving the upload itself
+// so clients can still see that it finished
+func (m *Manager) Discard(ctx context.Context, id pgtype.UUID) error {
+	chunks, err := m.q.ListUploadChunks(ctx, id)
+	if err != nil {
+		return fmt.Errorf("list chunks: %w", err)
+	}
+	var errs []error
+	for _, c := range chunks {
+		errs = append(errs, m.store.Delete(ctx, c.StorageKey))
+	}
+	if err := errors.Join(errs...); err != nil {
+		// the rows are kept so a later purge can retry
+		return fmt.Errorf("delete chunks: %w", err)
+	}
+	return m.q.DeleteUploadChunks(ctx, id)
+}
+
+// Terminate deletes an upload and its chunks
+func (m *Manager) Terminate(ctx context.Context, id pgtype.UUID) error {
+	if err := m.Discard(ctx, id); err != nil {
+		return err
+	}
+	return m.q.DeleteUpload(ctx, id)
+}
+
+// Run purges expired uploads every purgeInterval until ctx is cancelled
+func (m *Manager) Run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		m.purge(ctx, logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) purge(ctx context.Context, logger *slog.Logger) {
+	expired, err := m.q.ListExpiredUploads(ctx, purgeBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to list expired uploads", "error", err)
+		}
+		return
+	}
+	for _, id := range expired {
+		if err := m.Terminate(ctx, id); err != nil {
+			logger.Warn("failed to purge expired upload", "upload_id", id.String(), "error", err)
+		}
+	}
+	if len(expired) > 0 {
+		logger.Info("purged expired uploads", "count", len(expired))
+	}
+}
+
+func expiresAt() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(Expiry), Valid: true}
+}
+
+// appendAll reads r to the end onto data
+func appendAll(data []byte, r io.Reader) ([]byte, error) {
+	buf := bytes.NewBuffer(data)
+	_, err := buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
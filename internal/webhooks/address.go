@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned when a delivery would connect to an
+// address inside the network, such as a loopback, private or link-local one
+var ErrForbiddenAddress = errors.New("webhooks: destination address is not allowed")
+
+// blockedPrefixes are ranges netip doesn't classify that still aren't
+// reachable on the public internet
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, and broadcast
+}
+
+// publicAddr reports whether deliveries may connect to addr. Endpoints get
+// to see the response bodies in the delivery log, so anything that could
+// reach services inside the network, such as the cloud metadata service on
+// 169.254.169.254, is refused.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedHost reports whether a subscription URL may name host. Only IP
+// addresses and localhost can be judged up front; other names are checked
+// each time a delivery connects, against the address they resolve to then.
+func AllowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return publicAddr(addr)
+	}
+	return true
+}
+
+// newTransport returns a transport that only connects to public addresses.
+// The check runs on the address being dialled, after DNS resolution, so a
+// name that resolves to an internal address, or is rebound to one after
+// the subscription was saved, is refused too.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialled in place of the endpoint, skipping the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"hooks.example.com", true},
+		{"93.184.215.14", true},
+		{"localhost", false},
+		{"LOCALHOST.", false},
+		{"api.localhost", false},
+		{"169.254.169.254", false},
+		{"::1", false},
+	}
+	for _, tt := range tests {
+		if got := AllowedHost(tt.host); got != tt.want {
+			t.Errorf("AllowedHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestTransportRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal secret"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newTransport()}
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+	// a name is refused by the address it resolves to, not by how it looks
+	for _, url := range []string{srv.URL, "http://localhost" + port} {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("GET %s succeeded, want it refused", url)
+			continue
+		}
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("GET %s error = %v, want %v", url, err, ErrForbiddenAddress)
+		}
+	}
+}
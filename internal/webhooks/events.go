@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+)
+
+// Event types a subscription can receive
+const (
+	ViolationCreated       = "violation.created"        // Hazard detected in a photo
+	ViolationCritical      = "violation.critical"       // Critical hazard detected, sent alongside violation.created
+	ViolationStatusChanged = "violation.status_changed" // Validated, dismissed or reopened
+	ViolationResolved      = "violation.resolved"       // Corrective action accepted
+	ReportCompleted        = "report.completed"         // Project marked completed, its safety report final
+)
+
+// Ping is sent to test a subscription and can't be subscribed to
+const Ping = "ping"
+
+// Events lists the event types in the order they are offered
+var Events = []string{
+	ViolationCreated,
+	ViolationCritical,
+	ViolationStatusChanged,
+	ViolationResolved,
+	ReportCompleted,
+}
+
+// Violation is the data of violation events
+type Violation struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"project_id"`
+	PhotoID        string    `json:"photo_id,omitempty"`
+	Description    string    `json:"description"`
+	Regulation     string    `json:"regulation"`
+	Category       string    `json:"category"`
+	RiskLevel      string    `json:"risk_level"`
+	Status         string    `json:"status"`
+	AIConfidence   float64   `json:"ai_confidence"`
+	FoundAt        time.Time `json:"found_at"`
+	PreviousStatus string    `json:"previous_status,omitempty"` // violation.status_changed only
+	ChangedBy      string    `json:"changed_by,omitempty"`      // Who changed the status or resolved it
+}
+
+// ViolationData returns the event data for a violation
+func ViolationData(v database.Violation) Violation {
+	data := Violation{
+		ID:           v.ID.String(),
+		ProjectID:    v.ProjectID.String(),
+		Description:  v.Description,
+		Regulation:   v.Regulation,
+		Category:     v.Category,
+		RiskLevel:    string(v.RiskLevel),
+		Status:       string(v.Status),
+		AIConfidence: v.AiConfidence,
+		FoundAt:      v.FoundAt.Time,
+	}
+	if v.PhotoID.Valid {
+		data.PhotoID = v.PhotoID.String()
+	}
+	return data
+}
+
+// Report is the data of report.completed
+type Report struct {
+	ProjectID         string    `json:"project_id"`
+	ProjectName       string    `json:"project_name"`
+	Location          string    `json:"location"`
+	ComplianceRate    float64   `json:"compliance_rate"`
+	CriticalCount     int       `json:"critical_count"`
+	HighCount         int       `json:"high_count"`
+	MediumCount       int       `json:"medium_count"`
+	LowCount          int       `json:"low_count"`
+	OverallAssessment string    `json:"overall_assessment"`
+	CompletedBy       string    `json:"completed_by"`
+	CompletedAt       time.Time `json:"completed_at"`
+}
+
+// Publish queues an event for every active subscription to eventType. Pass
+// the queries of the transaction making the change, so the event is only
+// sent if the change commits.
+func Publish(ctx context.Context, q *database.Queries, eventType string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	if _, err := q.PublishWebhookEvent(ctx, database.PublishWebhookEventParams{
+		EventType: eventType,
+		Data:      encoded,
+	}); err != nil {
+		return fmt.Errorf("queue %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dukerupert/ironman/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Delivery settings
+const (
+	pollInterval   = 5 * time.Second  // Time between checks for due deliveries
+	batchSize      = 20               // Deliveries sent concurrently per check
+	requestTimeout = 10 * time.Second // Limit on a single delivery request
+	claimLease     = 2 * time.Minute  // Time a claimed delivery is held before another instance may retry it
+	maxAttempts    = 10               // Attempts before a delivery is marked failed
+	baseBackoff    = 30 * time.Second // Delay before the first retry, doubled for each one after
+	maxBackoff     = 6 * time.Hour    // Longest delay between retries
+	maxResponse    = 2048             // Bytes of response body kept for the delivery log
+)
+
+// Purge settings
+const (
+	purgeInterval = time.Hour           // Time between sweeps for old deliveries
+	retention     = 30 * 24 * time.Hour // Age after which finished deliveries are deleted
+)
+
+// Request headers
+const (
+	SignatureHeader = "X-SafeSite-Signature" // t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	EventHeader     = "X-SafeSite-Event"
+	DeliveryHeader  = "X-SafeSite-Delivery"
+)
+
+// ErrNotFound is returned when redelivering a delivery that doesn't exist
+var ErrNotFound = errors.New("webhooks: not found")
+
+// Dispatcher sends queued webhook deliveries, retrying failures with
+// exponential backoff. Deliveries are claimed with a lease, so several app
+// instances can run a Dispatcher against the same database.
+type Dispatcher struct {
+	q      *database.Queries
+	client *http.Client
+}
+
+// New returns a Dispatcher
+func New(db *pgxpool.Pool) *Dispatcher {
+	return &Dispatcher{
+		q: database.New(db),
+		client: &http.Client{
+			Transport: newTransport(),
+			Timeout:   requestTimeout,
+			// a redirect is reported as a failure rather than followed, so
+			// payloads only ever go to the configured URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Create adds a subscription with a new signing secret
+func (d *Dispatcher) Create(ctx context.Context, params database.CreateWebhookSubscriptionParams) (database.WebhookSubscription, error) {
+	params.Secret = "whsec_" + rand.Text()
+	return d.q.CreateWebhookSubscription(ctx, params)
+}
+
+// Ping queues a ping to a subscription, whatever event types it receives
+func (d *Dispatcher) Ping(ctx context.Context, subscriptionID pgtype.UUID) (database.WebhookDelivery, error) {
+	data, err := json.Marshal(map[string]string{"subscription_id": subscriptionID.String()})
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+	return d.q.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		SubscriptionID: subscriptionID,
+		EventType:      Ping,
+		Data:           data,
+	})
+}
+
+// Redeliver queues the event of a past delivery to be sent again. The new
+// delivery keeps the event ID, so receivers can tell it is a repeat.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID pgtype.UUID) (database.WebhookDelivery, error) {
+	delivery, err := d.q.RedeliverWebhook(ctx, deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return delivery, ErrNotFound
+	}
+	return delivery, err
+}
+
+// Run sends due deliveries until ctx is cancelled, and periodically deletes
+// finished deliveries older than the retention period
+func (d *Dispatcher) Run(ctx context.Context, logger *slog.Logger) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			d.sendDue(ctx, logger)
+		case <-purge.C:
+			before := pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true}
+			n, err := d.q.PurgeWebhookDeliveries(ctx, before)
+			if err != nil {
+				logger.Error("failed to purge webhook deliveries", "error", err)
+				continue
+			}
+			if n > 0 {
+				logger.Info("purged webhook deliveries", "count", n)
+			}
+		}
+	}
+}
+
+// sendDue claims the deliveries that are due and sends them concurrently,
+// repeating while full batches come back
+func (d *Dispatcher) sendDue(ctx context.Context, logger *slog.Logger) {
+	for ctx.Err() == nil {
+		due, err := d.q.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+			LeaseSeconds:  claimLease.Seconds(),
+			MaxDeliveries: batchSize,
+		})
+		if err != nil {
+			logger.Error("failed to claim webhook deliveries", "error", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range due {
+			wg.Go(func() {
+				// a claimed delivery is finished on shutdown rather than
+				// left to wait out its lease
+				d.send(context.WithoutCancel(ctx), logger, delivery)
+			})
+		}
+		wg.Wait()
+
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+// send makes one delivery attempt and records the outcome
+func (d *Dispatcher) send(ctx context.Context, logger *slog.Logger, delivery database.ClaimWebhookDeliveriesRow) {
+	logger = logger.With("delivery_id", delivery.ID.String(), "event", delivery.EventType, "attempt", delivery.Attempts+1)
+
+	body, err := json.Marshal(struct {
+		ID         string          `json:"id"`
+		Type       string          `json:"type"`
+		OccurredAt time.Time       `json:"occurred_at"`
+		Data       json.RawMessage `json:"data"`
+	}{
+		ID:         delivery.EventID.String(),
+		Type:       delivery.EventType,
+		OccurredAt: delivery.OccurredAt.Time,
+		Data:       delivery.Data,
+	})
+	if err != nil {
+		logger.Error("failed to encode webhook payload", "error", err)
+		return
+	}
+
+	params := database.RecordWebhookAttemptParams{ID: delivery.ID}
+	start := time.Now()
+	status, respBody, err := d.post(ctx, delivery, body)
+	params.DurationMs = pgtype.Int4{Int32: int32(time.Since(start).Milliseconds()), Valid: true}
+	params.ResponseBody = respBody
+	if status != 0 {
+		params.ResponseStatus = pgtype.Int4{Int32: int32(status), Valid: true}
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	switch {
+	case err == nil:
+		params.Status = database.WebhookDeliveryStatusSucceeded
+		params.NextAttemptAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		logger.Info("webhook delivered", "status", status)
+	case attempts >= maxAttempts:
+		params.Status = database.WebhookDeliveryStatusFailed
+		params.Error = err.Error()
+		params.NextAttemptAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		logger.Warn("webhook delivery failed, giving up", "error", err)
+	default:
+		params.Status = database.WebhookDeliveryStatusPending
+		params.Error = err.Error()
+		params.NextAttemptAt = pgtype.Timestamptz{Time: time.Now().Add(backoff(attempts)), Valid: true}
+		logger.Info("webhook delivery failed, will retry", "error", err, "next_attempt_at", params.NextAttemptAt.Time)
+	}
+
+	if err := d.q.RecordWebhookAttempt(ctx, params); err != nil {
+		logger.Error("failed to record webhook attempt", "error", err)
+	}
+}
+
+// post sends a signed payload and returns the response status and the
+// start of the response body. Any status outside 2xx is an error.
+func (d *Dispatcher) post(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SafeSite-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	// Postgres text can't hold invalid UTF-8 or NUL bytes
+	respBody := strings.ReplaceAll(strings.ToValidUTF8(string(raw), "\uFFFD"), "\x00", "")
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, respBody, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// Sign returns the signature header for a payload sent at t. Receivers
+// recompute the HMAC over "<t>.<body>" with the subscription's secret,
+// compare it in constant time, and reject old timestamps to stop replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before retrying a delivery that has failed
+// attempts times: doubling from baseBackoff up to maxBackoff, with jitter
+// so deliveries that failed together don't all retry together
+func backoff(attempts int) time.Duration {
+	delay := maxBackoff
+	if attempts-1 < 20 {
+		delay = min(baseBackoff<<(attempts-1), maxBackoff)
+	}
+	jitter := time.Duration(mrand.Int64N(int64(delay) / 5))
+	return delay - delay/10 + jitter
+}
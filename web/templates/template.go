@@ -34,13 +34,13 @@ func NewTemplate() (t *Template, err error) {
 
 func (t *Template) parseTemplates() error {
 	// Get all layout files
-	layoutFiles, err := t.getFilesFromEmbedded("public/views/layout/*.html")
+	layoutFiles, err := t.getFilesFromEmbedded("layout/*.html")
 	if err != nil {
 		return fmt.Errorf("error finding layout files: %w", err)
 	}
 
 	// Get all partial files
-	partialFiles, err := t.getFilesFromEmbedded("public/views/partials/*.html")
+	partialFiles, err := t.getFilesFromEmbedded("partials/*.html")
 	if err != nil {
 		return fmt.Errorf("error finding partial files: %w", err)
 	}
@@ -95,6 +95,11 @@ func (t *Template) parseTemplates() error {
 					return 0
 				}
 			},
+
+			// Request functions, bound to the request by RenderRequest
+			"csrfToken": func() string { return "" },
+			"csrfField": func() template.HTML { return "" },
+			"cspNonce":  func() string { return "" },
 		}
 
 		// Parse the combined templates from embedded filesystem
@@ -159,9 +164,9 @@ func (t *Template) getAllPageFilesFromEmbedded() ([]string, error) {
 
 	// Define all the directories where page templates can be found
 	pageDirs := []string{
-		"public/views/pages/auth",
-		"public/views/pages/app", 
-		"public/views/pages",
+		"pages/auth",
+		"pages/app", 
+		"pages",
 	}
 
 	for _, dir := range pageDirs {
@@ -188,11 +193,41 @@ func (t *Template) getAllPageFilesFromEmbedded() ([]string, error) {
 	return allFiles, nil
 }
 
+// CSRFField is the name of the form field csrfField writes
+const CSRFField = "csrf_token"
+
+// Request holds the values a page takes from the request it answers
+type Request struct {
+	CSRFToken string // Sent back by forms and scripts to show a request came from the app
+	Nonce     string // Allows the page's inline scripts under the Content-Security-Policy
+}
+
+// Render renders a page outside a request, such as a report written to a
+// file. Forms and inline scripts get empty tokens and nonces.
 func (t *Template) Render(w io.Writer, name string, data interface{}) error {
+	return t.RenderRequest(w, name, data, Request{})
+}
+
+// RenderRequest renders a page with the CSRF token and script nonce in req.
+// Each call executes a copy of the page, as the functions returning them
+// differ per request and the parsed pages are shared.
+func (t *Template) RenderRequest(w io.Writer, name string, data interface{}, req Request) error {
 	tmpl, exists := t.templates[name]
 	if !exists {
 		return fmt.Errorf("template %s not found", name)
 	}
 
-	return tmpl.Execute(w, data)
+	page, err := tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("error copying template %s: %w", name, err)
+	}
+	page.Funcs(template.FuncMap{
+		"csrfToken": func() string { return req.CSRFToken },
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + CSRFField + `" value="` + template.HTMLEscapeString(req.CSRFToken) + `">`)
+		},
+		"cspNonce": func() string { return req.Nonce },
+	})
+
+	return page.Execute(w, data)
 }